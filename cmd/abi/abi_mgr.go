@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/ontio/ontology/common/log"
@@ -30,13 +31,15 @@ import (
 var DefAbiMgr = NewAbiMgr()
 
 type AbiMgr struct {
-	Path       string
-	nativeAbis map[string]*NativeContractAbi
+	Path        string
+	nativeAbis  map[string]*NativeContractAbi
+	nativeNames map[string]string //abi file name without suffix -> contract address
 }
 
 func NewAbiMgr() *AbiMgr {
 	return &AbiMgr{
-		nativeAbis: make(map[string]*NativeContractAbi),
+		nativeAbis:  make(map[string]*NativeContractAbi),
+		nativeNames: make(map[string]string),
 	}
 }
 
@@ -48,6 +51,25 @@ func (this *AbiMgr) GetNativeAbi(address string) *NativeContractAbi {
 	return nil
 }
 
+//GetNativeAbiByName return the native abi by the name of abi file, such as ont, ong
+func (this *AbiMgr) GetNativeAbiByName(name string) *NativeContractAbi {
+	address, ok := this.nativeNames[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	return this.GetNativeAbi(address)
+}
+
+//GetNativeAbiNames return the sorted names of the native abis loaded
+func (this *AbiMgr) GetNativeAbiNames() []string {
+	names := make([]string, 0, len(this.nativeNames))
+	for name := range this.nativeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (this *AbiMgr) Init(path string) {
 	this.Path = path
 	this.loadNativeAbi()
@@ -79,6 +101,7 @@ func (this *AbiMgr) loadNativeAbi() {
 			continue
 		}
 		this.nativeAbis[nativeAbi.Address] = nativeAbi
+		this.nativeNames[strings.ToLower(strings.TrimSuffix(fileName, ".json"))] = nativeAbi.Address
 		log.Infof("Native contract name:%s address:%s abi load success", fileName, nativeAbi.Address)
 	}
 }
@@ -50,6 +50,8 @@ var AssetCommand = cli.Command{
 				utils.TransactionFromFlag,
 				utils.TransactionToFlag,
 				utils.TransactionAmountFlag,
+				utils.TransactionNotBeforeFlag,
+				utils.TransactionValidUntilFlag,
 				utils.ForceSendTxFlag,
 				utils.WalletFileFlag,
 			},
@@ -208,7 +210,15 @@ func transfer(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	txHash, err := utils.Transfer(gasPrice, gasLimit, signer, asset, fromAddr, toAddr, amount)
+	mutTx, err := utils.TransferTx(gasPrice, gasLimit, asset, signer.Address.ToBase58(), toAddr, amount)
+	if err != nil {
+		return fmt.Errorf("transfer error:%s", err)
+	}
+	err = setValidityWindow(ctx, mutTx)
+	if err != nil {
+		return err
+	}
+	txHash, err := utils.InvokeSmartContract(signer, mutTx)
 	if err != nil {
 		return fmt.Errorf("transfer error:%s", err)
 	}
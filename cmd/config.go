@@ -41,6 +41,7 @@ func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
 	setRpcConfig(ctx, cfg.Rpc)
 	setRestfulConfig(ctx, cfg.Restful)
 	setWebSocketConfig(ctx, cfg.Ws)
+	setMetricsConfig(ctx, cfg.Metrics)
 	if cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
 		cfg.Ws.EnableHttpWs = true
 		cfg.Restful.EnableHttpRestful = true
@@ -50,6 +51,14 @@ func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
 		cfg.P2PNode.NetworkMagic = config.GetNetworkMagic(cfg.P2PNode.NetworkId)
 		cfg.Common.GasPrice = 0
 	}
+	if cfg.Common.LightMode {
+		if cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+			return nil, fmt.Errorf("light mode is not supported by solo consensus")
+		}
+		if cfg.Consensus.EnableConsensus {
+			return nil, fmt.Errorf("light node cannot take part in consensus")
+		}
+	}
 	if cfg.P2PNode.NetworkId == config.NETWORK_ID_MAIN_NET ||
 		cfg.P2PNode.NetworkId == config.NETWORK_ID_POLARIS_NET {
 		defNetworkId, err := cfg.GetDefaultNetworkId()
@@ -132,10 +141,21 @@ func setGenesis(ctx *cli.Context, cfg *config.OntologyConfig) error {
 
 func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.LogLevel = ctx.Uint(utils.GetFlagName(utils.LogLevelFlag))
+	cfg.LogFormat = ctx.String(utils.GetFlagName(utils.LogFormatFlag))
+	cfg.LogModuleLevels = ctx.String(utils.GetFlagName(utils.LogModuleLevelsFlag))
 	cfg.EnableEventLog = !ctx.Bool(utils.GetFlagName(utils.DisableEventLogFlag))
 	cfg.GasLimit = ctx.Uint64(utils.GetFlagName(utils.GasLimitFlag))
 	cfg.GasPrice = ctx.Uint64(utils.GetFlagName(utils.GasPriceFlag))
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
+	cfg.EnableArchive = ctx.Bool(utils.GetFlagName(utils.EnableArchiveFlag))
+	cfg.EnableStateProof = ctx.Bool(utils.GetFlagName(utils.EnableStateProofFlag))
+	cfg.EnableAddressIndex = ctx.Bool(utils.GetFlagName(utils.EnableAddressIndexFlag))
+	cfg.EnableContractIndex = ctx.Bool(utils.GetFlagName(utils.EnableContractIndexFlag))
+	cfg.UndoBlockNum = uint32(ctx.Uint(utils.GetFlagName(utils.UndoBlockNumFlag)))
+	cfg.TxPoolPriceBump = ctx.Uint64(utils.GetFlagName(utils.TxpoolPriceBumpFlag))
+	cfg.TxPoolCapacity = ctx.Uint(utils.GetFlagName(utils.TxpoolCapacityFlag))
+	cfg.TxPoolPayerCapacity = ctx.Uint(utils.GetFlagName(utils.TxpoolPayerCapacityFlag))
+	cfg.LightMode = ctx.Bool(utils.GetFlagName(utils.LightModeFlag))
 }
 
 func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
@@ -153,6 +173,13 @@ func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
 	cfg.MaxConnInBound = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundFlag))
 	cfg.MaxConnOutBound = ctx.Uint(utils.GetFlagName(utils.MaxConnOutBoundFlag))
 	cfg.MaxConnInBoundForSingleIP = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundForSingleIPFlag))
+	cfg.SyncMaxFlightHeaders = ctx.Uint(utils.GetFlagName(utils.SyncMaxFlightHeadersFlag))
+	cfg.SyncMaxFlightBlocks = ctx.Uint(utils.GetFlagName(utils.SyncMaxFlightBlocksFlag))
+	cfg.SyncMaxHeaderForward = uint32(ctx.Uint(utils.GetFlagName(utils.SyncMaxHeaderForwardFlag)))
+	cfg.SyncMaxBlockCache = ctx.Uint(utils.GetFlagName(utils.SyncMaxBlockCacheFlag))
+	cfg.RateLimits = ctx.String(utils.GetFlagName(utils.P2PRateLimitsFlag))
+	cfg.PeerRateLimit = ctx.Uint(utils.GetFlagName(utils.P2PPeerRateLimitFlag))
+	cfg.RateLimitPolicy = ctx.String(utils.GetFlagName(utils.P2PRateLimitPolicyFlag))
 
 	rsvfile := ctx.String(utils.GetFlagName(utils.ReservedPeersFileFlag))
 	if cfg.ReservedPeersOnly {
@@ -179,6 +206,9 @@ func setRpcConfig(ctx *cli.Context, cfg *config.RpcConfig) {
 	cfg.EnableHttpJsonRpc = !ctx.Bool(utils.GetFlagName(utils.RPCDisabledFlag))
 	cfg.HttpJsonPort = ctx.Uint(utils.GetFlagName(utils.RPCPortFlag))
 	cfg.HttpLocalPort = ctx.Uint(utils.GetFlagName(utils.RPCLocalProtFlag))
+	cfg.MaxBatchSize = ctx.Uint(utils.GetFlagName(utils.RPCMaxBatchSizeFlag))
+	cfg.LocalAdminToken = ctx.String(utils.GetFlagName(utils.RPCLocalAdminTokenFlag))
+	cfg.LocalAuditLog = ctx.String(utils.GetFlagName(utils.RPCLocalAuditLogFlag))
 }
 
 func setRestfulConfig(ctx *cli.Context, cfg *config.RestfulConfig) {
@@ -192,6 +222,11 @@ func setWebSocketConfig(ctx *cli.Context, cfg *config.WebSocketConfig) {
 	cfg.HttpWsPort = ctx.Uint(utils.GetFlagName(utils.WsPortFlag))
 }
 
+func setMetricsConfig(ctx *cli.Context, cfg *config.MetricsConfig) {
+	cfg.EnableMetrics = ctx.Bool(utils.GetFlagName(utils.MetricsEnableFlag))
+	cfg.MetricsPort = ctx.Uint(utils.GetFlagName(utils.MetricsPortFlag))
+}
+
 func SetRpcPort(ctx *cli.Context) {
 	if ctx.IsSet(utils.GetFlagName(utils.RPCPortFlag)) {
 		config.DefConfig.Rpc.HttpJsonPort = ctx.Uint(utils.GetFlagName(utils.RPCPortFlag))
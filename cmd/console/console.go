@@ -0,0 +1,542 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package console provides an interactive console attached to a running node
+package console
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/cmd/abi"
+	cmdcom "github.com/ontio/ontology/cmd/common"
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/peterh/liner"
+)
+
+const PROMPT = "ontology> "
+
+//command of console
+type command struct {
+	name   string
+	args   string
+	usage  string
+	action func(args []string) error
+}
+
+//Console keeps an unlocked account, and runs the commands input by user against the node
+type Console struct {
+	wallet   account.Client
+	signer   *account.Account
+	gasPrice uint64
+	gasLimit uint64
+	tx       *types.MutableTransaction //The transaction built or loaded, which is signed, pre-executed and sent
+	line     *liner.State
+	out      io.Writer
+	commands []*command
+}
+
+//NewConsole return a console with the unlocked account of wallet
+func NewConsole(wallet account.Client, signer *account.Account, gasPrice, gasLimit uint64) *Console {
+	c := &Console{
+		wallet:   wallet,
+		signer:   signer,
+		gasPrice: gasPrice,
+		gasLimit: gasLimit,
+		out:      os.Stdout,
+	}
+	c.commands = []*command{
+		{"help", "", "Show the commands of console", c.help},
+		{"account", "", "Show the unlocked account", c.account},
+		{"accounts", "", "List the accounts of wallet", c.accounts},
+		{"unlock", "<address|label|index>", "Unlock another account of wallet", c.unlock},
+		{"height", "", "Show the current block height", c.height},
+		{"balance", "[address]", "Show the balance of address, the unlocked account by default", c.balance},
+		{"gasprice", "[price]", "Show or set the gas price of transactions", c.setGasPrice},
+		{"gaslimit", "[limit]", "Show or set the gas limit of transactions", c.setGasLimit},
+		{"build", "<contract> <method> [params...]", "Build a native contract invoke transaction", c.build},
+		{"load", "<rawtx>", "Load a raw transaction in hex", c.load},
+		{"showtx", "", "Show the transaction built or loaded", c.showTx},
+		{"sign", "", "Sign the transaction with the unlocked account", c.sign},
+		{"preexec", "", "Pre-execute the transaction", c.preExec},
+		{"send", "", "Send the transaction to the node", c.send},
+		{"call", "<contract> <method> [params...]", "Build and pre-execute a native contract invoke transaction", c.call},
+		{"invoke", "<contract> <method> [params...]", "Build, sign and send a native contract invoke transaction", c.invoke},
+		{"tx", "<txhash>", "Show the transaction of hash", c.getTx},
+		{"event", "<txhash>", "Show the smart contract events of transaction", c.getEvent},
+		{"exit", "", "Exit the console", nil},
+	}
+	return c
+}
+
+//Run reads the commands from terminal and executes them until exit
+func (c *Console) Run() {
+	c.line = liner.NewLiner()
+	defer c.line.Close()
+	c.line.SetCtrlCAborts(true)
+	c.line.SetCompleter(c.complete)
+
+	fmt.Fprintf(c.out, "Welcome to the Ontology console, account %s unlocked.\n", c.signer.Address.ToBase58())
+	fmt.Fprintf(c.out, "Type help to show the commands, press tab to complete them.\n")
+	for {
+		input, err := c.line.Prompt(PROMPT)
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		c.line.AppendHistory(input)
+		if input == "exit" || input == "quit" {
+			return
+		}
+		if err := c.Execute(input); err != nil {
+			fmt.Fprintf(c.out, "Error: %s\n", err)
+		}
+	}
+}
+
+//Execute runs a command line
+func (c *Console) Execute(input string) error {
+	args, err := splitArgs(input)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	cmd := c.getCommand(args[0])
+	if cmd == nil || cmd.action == nil {
+		return fmt.Errorf("unknown command %s, type help to show the commands", args[0])
+	}
+	return cmd.action(args[1:])
+}
+
+func (c *Console) getCommand(name string) *command {
+	for _, cmd := range c.commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (c *Console) help(args []string) error {
+	for _, cmd := range c.commands {
+		fmt.Fprintf(c.out, "  %-50s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.usage)
+	}
+	fmt.Fprintf(c.out, "\nContracts: %s\n", strings.Join(abi.DefAbiMgr.GetNativeAbiNames(), ", "))
+	fmt.Fprintf(c.out, "Params of struct or array type are in json, such as [[\"from\",\"to\",\"100\"]]\n")
+	return nil
+}
+
+func (c *Console) account(args []string) error {
+	fmt.Fprintf(c.out, "%s\n", c.signer.Address.ToBase58())
+	return nil
+}
+
+func (c *Console) accounts(args []string) error {
+	for i := 1; i <= c.wallet.GetAccountNum(); i++ {
+		accMeta := c.wallet.GetAccountMetadataByIndex(i)
+		if accMeta == nil {
+			continue
+		}
+		flag := " "
+		if accMeta.Address == c.signer.Address.ToBase58() {
+			flag = "*"
+		}
+		fmt.Fprintf(c.out, "%s %d\t%s\t%s\n", flag, i, accMeta.Address, accMeta.Label)
+	}
+	return nil
+}
+
+func (c *Console) unlock(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing argument, address, label or index expected")
+	}
+	passwd, err := c.line.PasswordPrompt("Password:")
+	if err != nil {
+		return err
+	}
+	signer, err := cmdcom.GetAccountMulti(c.wallet, []byte(passwd), args[0])
+	if err != nil {
+		return err
+	}
+	c.signer = signer
+	fmt.Fprintf(c.out, "Account %s unlocked\n", signer.Address.ToBase58())
+	return nil
+}
+
+func (c *Console) height(args []string) error {
+	count, err := utils.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%d\n", count-1)
+	return nil
+}
+
+func (c *Console) balance(args []string) error {
+	address := c.signer.Address.ToBase58()
+	if len(args) > 0 {
+		address = args[0]
+	}
+	balance, err := utils.GetBalance(address)
+	if err != nil {
+		return err
+	}
+	ong, err := strconv.ParseUint(balance.Ong, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ong balance %s", balance.Ong)
+	}
+	fmt.Fprintf(c.out, "ONT: %s\nONG: %s\nHeight: %s\n", balance.Ont, utils.FormatOng(ong), balance.Height)
+	return nil
+}
+
+func (c *Console) setGasPrice(args []string) error {
+	if len(args) > 0 {
+		gasPrice, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid gas price %s", args[0])
+		}
+		c.gasPrice = gasPrice
+	}
+	fmt.Fprintf(c.out, "%d\n", c.gasPrice)
+	return nil
+}
+
+func (c *Console) setGasLimit(args []string) error {
+	if len(args) > 0 {
+		gasLimit, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid gas limit %s", args[0])
+		}
+		c.gasLimit = gasLimit
+	}
+	fmt.Fprintf(c.out, "%d\n", c.gasLimit)
+	return nil
+}
+
+func (c *Console) build(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("missing argument, contract and method expected")
+	}
+	contractAddr, nativeAbi, err := getNativeAbi(args[0])
+	if err != nil {
+		return err
+	}
+	funcAbi := nativeAbi.GetFunc(args[1])
+	if funcAbi == nil {
+		return fmt.Errorf("method %s not found in contract %s", args[1], args[0])
+	}
+	params := make([]interface{}, 0, len(args)-2)
+	for _, arg := range args[2:] {
+		param, err := parseParam(arg)
+		if err != nil {
+			return fmt.Errorf("invalid param %s: %s", arg, err)
+		}
+		params = append(params, param)
+	}
+	tx, err := utils.NewNativeInvokeTransaction(c.gasPrice, c.gasLimit, contractAddr, 0, params, funcAbi)
+	if err != nil {
+		return err
+	}
+	tx.Payer = c.signer.Address
+	c.tx = tx
+	return c.showTx(nil)
+}
+
+func (c *Console) load(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing argument, raw transaction expected")
+	}
+	raw, err := hex.DecodeString(args[0])
+	if err != nil {
+		return fmt.Errorf("hex.DecodeString error:%s", err)
+	}
+	tx, err := types.TransactionFromRawBytes(raw)
+	if err != nil {
+		return fmt.Errorf("invalid raw transaction:%s", err)
+	}
+	c.tx, err = tx.IntoMutable()
+	if err != nil {
+		return err
+	}
+	return c.showTx(nil)
+}
+
+//rawTx return the hash and raw data of the current transaction
+func (c *Console) rawTx() (common.Uint256, string, error) {
+	if c.tx == nil {
+		return common.UINT256_EMPTY, "", fmt.Errorf("no transaction, build or load one first")
+	}
+	tx, err := c.tx.IntoImmutable()
+	if err != nil {
+		return common.UINT256_EMPTY, "", fmt.Errorf("convert immutable transaction error:%s", err)
+	}
+	return tx.Hash(), hex.EncodeToString(common.SerializeToBytes(tx)), nil
+}
+
+func (c *Console) showTx(args []string) error {
+	txHash, rawTx, err := c.rawTx()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "TxHash: %s\nSigs: %d\nRawTx: %s\n", txHash.ToHexString(), len(c.tx.Sigs), rawTx)
+	return nil
+}
+
+func (c *Console) sign(args []string) error {
+	if c.tx == nil {
+		return fmt.Errorf("no transaction, build or load one first")
+	}
+	err := utils.SignTransaction(c.signer, c.tx)
+	if err != nil {
+		return err
+	}
+	return c.showTx(nil)
+}
+
+func (c *Console) preExec(args []string) error {
+	_, rawTx, err := c.rawTx()
+	if err != nil {
+		return err
+	}
+	result, err := utils.PrepareSendRawTransaction(rawTx)
+	if err != nil {
+		return err
+	}
+	return c.printJson(result)
+}
+
+func (c *Console) send(args []string) error {
+	_, rawTx, err := c.rawTx()
+	if err != nil {
+		return err
+	}
+	txHash, err := utils.SendRawTransactionData(rawTx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "TxHash: %s\n", txHash)
+	return nil
+}
+
+func (c *Console) call(args []string) error {
+	if err := c.build(args); err != nil {
+		return err
+	}
+	return c.preExec(nil)
+}
+
+func (c *Console) invoke(args []string) error {
+	if err := c.build(args); err != nil {
+		return err
+	}
+	if err := c.sign(nil); err != nil {
+		return err
+	}
+	return c.send(nil)
+}
+
+func (c *Console) getTx(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing argument, tx hash expected")
+	}
+	data, err := utils.GetRawTransaction(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printJsonData(data)
+}
+
+func (c *Console) getEvent(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing argument, tx hash expected")
+	}
+	data, err := utils.GetSmartContractEventInfo(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printJsonData(data)
+}
+
+func (c *Console) printJson(obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.printJsonData(data)
+}
+
+func (c *Console) printJsonData(data []byte) error {
+	var out bytes.Buffer
+	err := json.Indent(&out, data, "", "   ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%s\n", out.String())
+	return nil
+}
+
+//getNativeAbi return the address and abi of native contract by abi name, address in hex or base58
+func getNativeAbi(contract string) (common.Address, *abi.NativeContractAbi, error) {
+	nativeAbi := abi.DefAbiMgr.GetNativeAbiByName(contract)
+	if nativeAbi == nil {
+		if addr, err := common.AddressFromBase58(contract); err == nil {
+			contract = addr.ToHexString()
+		}
+		nativeAbi = abi.DefAbiMgr.GetNativeAbi(contract)
+	}
+	if nativeAbi == nil {
+		return common.ADDRESS_EMPTY, nil, fmt.Errorf("abi of contract %s not found", contract)
+	}
+	addr, err := common.AddressFromHexString(nativeAbi.Address)
+	if err != nil {
+		return common.ADDRESS_EMPTY, nil, fmt.Errorf("invalid contract address %s", nativeAbi.Address)
+	}
+	return addr, nativeAbi, nil
+}
+
+//parseParam parse the param of native contract, the param of struct or array is in json, and all the values
+//in it are converted to string as the native param parser expected
+func parseParam(arg string) (interface{}, error) {
+	if !strings.HasPrefix(arg, "[") {
+		return arg, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(arg))
+	decoder.UseNumber()
+	var param []interface{}
+	if err := decoder.Decode(&param); err != nil {
+		return nil, err
+	}
+	return stringifyParam(param), nil
+}
+
+func stringifyParam(param interface{}) interface{} {
+	switch v := param.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringifyParam(item)
+		}
+		return v
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+//splitArgs split command line by blanks, the blanks in quotes or brackets are kept
+func splitArgs(input string) ([]string, error) {
+	args := make([]string, 0)
+	var arg strings.Builder
+	hasArg := false
+	depth := 0
+	quoted := false
+	for _, ch := range input {
+		switch {
+		case ch == '"' && depth == 0:
+			quoted = !quoted
+			hasArg = true
+			continue
+		case ch == '"':
+			quoted = !quoted
+		case quoted:
+		case ch == '[':
+			depth++
+		case ch == ']':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unmatched bracket")
+			}
+		case (ch == ' ' || ch == '\t') && depth == 0:
+			if hasArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				hasArg = false
+			}
+			continue
+		}
+		arg.WriteRune(ch)
+		hasArg = true
+	}
+	if quoted {
+		return nil, fmt.Errorf("unmatched quote")
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unmatched bracket")
+	}
+	if hasArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+//complete return the candidates of command line, the commands, the contract names and the methods of contract
+//are completed
+func (c *Console) complete(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasSuffix(line, " ") {
+		fields = append(fields, "")
+	}
+	word := fields[len(fields)-1]
+	prefix := line[:len(line)-len(word)]
+
+	candidates := make([]string, 0)
+	switch len(fields) {
+	case 1:
+		for _, cmd := range c.commands {
+			candidates = append(candidates, cmd.name)
+		}
+	case 2:
+		switch fields[0] {
+		case "build", "call", "invoke":
+			candidates = abi.DefAbiMgr.GetNativeAbiNames()
+		}
+	case 3:
+		switch fields[0] {
+		case "build", "call", "invoke":
+			if _, nativeAbi, err := getNativeAbi(fields[1]); err == nil {
+				for _, funcAbi := range nativeAbi.Functions {
+					candidates = append(candidates, funcAbi.Name)
+				}
+			}
+		}
+	}
+	completions := make([]string, 0)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, prefix+candidate)
+		}
+	}
+	return completions
+}
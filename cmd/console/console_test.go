@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package console
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/cmd/abi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`build  ont transfer [["a", "b", 100]] "a b"`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"build", "ont", "transfer", `[["a", "b", 100]]`, "a b"}, args)
+
+	_, err = splitArgs(`build ont transfer [["a", "b", 100]`)
+	assert.NotNil(t, err)
+	_, err = splitArgs(`load "abc`)
+	assert.NotNil(t, err)
+}
+
+func TestParseParam(t *testing.T) {
+	param, err := parseParam(`[["a", "b", 100]]`)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"a", "b", "100"}}, param)
+
+	param, err = parseParam("100")
+	assert.Nil(t, err)
+	assert.Equal(t, "100", param)
+}
+
+func TestConsole(t *testing.T) {
+	abi.DefAbiMgr.Init("../abi/native_abi_script")
+	c := NewConsole(nil, account.NewAccount(""), 2500, 20000)
+	out := new(bytes.Buffer)
+	c.out = out
+
+	assert.Equal(t, []string{"balance", "build"}, c.complete("b"))
+	assert.Equal(t, []string{"call ong", "call ont", "call ontid"}, c.complete("call on"))
+	assert.Equal(t, []string{"call ont transfer", "call ont transferFrom"}, c.complete("call ont tr"))
+	assert.Empty(t, c.complete("height x"))
+
+	assert.NotNil(t, c.Execute("sign"))
+	assert.NotNil(t, c.Execute("build ont unknown"))
+	assert.NotNil(t, c.Execute("unknown"))
+
+	assert.Nil(t, c.Execute("gasprice 0"))
+	assert.Equal(t, uint64(0), c.gasPrice)
+	to := account.NewAccount("").Address.ToBase58()
+	err := c.Execute(`build ont transfer [["` + c.signer.Address.ToBase58() + `","` + to + `",100]]`)
+	assert.Nil(t, err)
+	assert.NotNil(t, c.tx)
+	assert.Equal(t, c.signer.Address, c.tx.Payer)
+	assert.Equal(t, uint64(0), c.tx.GasPrice)
+
+	assert.Nil(t, c.Execute("sign"))
+	assert.Equal(t, 1, len(c.tx.Sigs))
+	txHash, rawTx, err := c.rawTx()
+	assert.Nil(t, err)
+
+	c.tx = nil
+	assert.Nil(t, c.Execute("load "+rawTx))
+	loadedHash, _, err := c.rawTx()
+	assert.Nil(t, err)
+	assert.Equal(t, txHash, loadedHash)
+	assert.Contains(t, out.String(), txHash.ToHexString())
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/cmd/abi"
+	cmdcom "github.com/ontio/ontology/cmd/common"
+	"github.com/ontio/ontology/cmd/console"
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/http/localrpc"
+	"github.com/urfave/cli"
+)
+
+var ConsoleCommand = cli.Command{
+	Action:    startConsole,
+	Name:      "console",
+	Usage:     "Start an interactive console attached to a running node",
+	ArgsUsage: " ",
+	Flags: []cli.Flag{
+		utils.RPCLocalProtFlag,
+		utils.WalletFileFlag,
+		utils.AccountAddressFlag,
+		utils.AccountPassFlag,
+		utils.TransactionGasPriceFlag,
+		utils.TransactionGasLimitFlag,
+		utils.CliABIPathFlag,
+	},
+	Description: `The console is attached to the local rpc server of a running node, which should be started with --localrpc flag.
+The account is unlocked when the console starts, and used to sign the transactions built in console.`,
+}
+
+func startConsole(ctx *cli.Context) error {
+	localPort := ctx.Uint(utils.GetFlagName(utils.RPCLocalProtFlag))
+	utils.SetRpcAddress(fmt.Sprintf("http://%s:%d%s", localrpc.LOCAL_HOST, localPort, localrpc.LOCAL_DIR))
+	abi.DefAbiMgr.Init(ctx.String(utils.GetFlagName(utils.CliABIPathFlag)))
+
+	wallet, err := cmdcom.OpenWallet(ctx)
+	if err != nil {
+		return fmt.Errorf("open wallet error:%s", err)
+	}
+	passwd, err := cmdcom.GetPasswd(ctx)
+	if err != nil {
+		return err
+	}
+	defer cmdcom.ClearPasswd(passwd)
+	signer, err := cmdcom.GetAccountMulti(wallet, passwd, ctx.String(utils.GetFlagName(utils.AccountAddressFlag)))
+	if err != nil {
+		return fmt.Errorf("get account error:%s", err)
+	}
+
+	gasPrice := ctx.Uint64(utils.GetFlagName(utils.TransactionGasPriceFlag))
+	gasLimit := ctx.Uint64(utils.GetFlagName(utils.TransactionGasLimitFlag))
+	console.NewConsole(wallet, signer, gasPrice, gasLimit).Run()
+	return nil
+}
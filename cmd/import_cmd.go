@@ -49,6 +49,7 @@ var ImportCommand = cli.Command{
 		utils.ConfigFlag,
 		utils.NetworkIdFlag,
 		utils.DisableEventLogFlag,
+		utils.UndoBlockNumFlag,
 	},
 	Description: "Note that import cmd doesn't support testmode",
 }
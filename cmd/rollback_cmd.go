@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/urfave/cli"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/types"
+)
+
+var RollbackCommand = cli.Command{
+	Name:      "rollback",
+	Usage:     "Rollback the ledger in DB to a block height",
+	ArgsUsage: "",
+	Action:    rollbackBlocks,
+	Flags: []cli.Flag{
+		utils.RollbackHeightFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.EnableArchiveFlag,
+		utils.EnableStateProofFlag,
+		utils.EnableAddressIndexFlag,
+		utils.EnableContractIndexFlag,
+		utils.UndoBlockNumFlag,
+	},
+	Description: "Only the latest blocks whose undo data is kept (see --undo-blocks) can be rolled back. The node should be stopped before rollback",
+}
+
+func rollbackBlocks(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	if !ctx.IsSet(utils.GetFlagName(utils.RollbackHeightFlag)) {
+		PrintErrorMsg("Missing %s argument.", utils.RollbackHeightFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	height := uint32(ctx.Uint(utils.GetFlagName(utils.RollbackHeightFlag)))
+
+	bookKeepers, genesisBlock, err := openLedger(ctx)
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	if err != nil {
+		return fmt.Errorf("init ledger error:%s", err)
+	}
+
+	currBlockHeight := ledger.DefLedger.GetCurrentBlockHeight()
+	if height >= currBlockHeight {
+		PrintWarnMsg("CurrentBlockHeight:%d lower than or equal to rollback height:%d, No blocks to rollback.", currBlockHeight, height)
+		return nil
+	}
+	PrintInfoMsg("Start rollback blocks from height:%d to height:%d.", currBlockHeight, height)
+	err = ledger.DefLedger.RollbackTo(height)
+	if err != nil {
+		return fmt.Errorf("rollback error:%s", err)
+	}
+	PrintInfoMsg("Rollback completed, current block height:%d.", ledger.DefLedger.GetCurrentBlockHeight())
+	return nil
+}
+
+//openLedger open the ledger in data dir to ledger.DefLedger without initializing, and return the bookkeepers and genesis block of config
+func openLedger(ctx *cli.Context) ([]keypair.PublicKey, *types.Block, error) {
+	cfg, err := SetOntologyConfig(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SetOntologyConfig error:%s", err)
+	}
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
+	if err != nil {
+		return nil, nil, fmt.Errorf("NewLedger error:%s", err)
+	}
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		ledger.DefLedger.Close()
+		return nil, nil, fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		ledger.DefLedger.Close()
+		return nil, nil, fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	return bookKeepers, genesisBlock, nil
+}
@@ -77,13 +77,13 @@ func SigMutilRawTransaction(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpc
 	for _, pkStr := range rawReq.PubKeys {
 		pkData, err := hex.DecodeString(pkStr)
 		if err != nil {
-			log.Info("Cli Qid:%s SigMutilRawTransaction pk hex.DecodeString error:%s", req.Qid, err)
+			log.Infof("Cli Qid:%s SigMutilRawTransaction pk hex.DecodeString error:%s", req.Qid, err)
 			resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
 			return
 		}
 		pk, err := keypair.DeserializePublicKey(pkData)
 		if err != nil {
-			log.Info("Cli Qid:%s SigMutilRawTransaction keypair.DeserializePublicKey error:%s", req.Qid, err)
+			log.Infof("Cli Qid:%s SigMutilRawTransaction keypair.DeserializePublicKey error:%s", req.Qid, err)
 			resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
 			return
 		}
@@ -30,13 +30,15 @@ import (
 )
 
 type SigNativeInvokeTxReq struct {
-	GasPrice uint64        `json:"gas_price"`
-	GasLimit uint64        `json:"gas_limit"`
-	Address  string        `json:"address"`
-	Method   string        `json:"method"`
-	Params   []interface{} `json:"params"`
-	Payer    string        `json:"payer"`
-	Version  byte          `json:"version"`
+	GasPrice   uint64        `json:"gas_price"`
+	GasLimit   uint64        `json:"gas_limit"`
+	Address    string        `json:"address"`
+	Method     string        `json:"method"`
+	Params     []interface{} `json:"params"`
+	Payer      string        `json:"payer"`
+	Version    byte          `json:"version"`
+	NotBefore  uint32        `json:"not_before"`
+	ValidUntil uint32        `json:"valid_until"`
 }
 
 type SigNativeInvokeTxRsp struct {
@@ -82,6 +84,7 @@ func SigNativeInvokeTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcRespo
 		}
 		tx.Payer = payerAddress
 	}
+	tx.SetValidityWindow(rawReq.NotBefore, rawReq.ValidUntil)
 
 	signer, err := req.GetAccount()
 	if err != nil {
@@ -97,7 +100,7 @@ func SigNativeInvokeTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcRespo
 	}
 	immutable, err := tx.IntoImmutable()
 	if err != nil {
-		log.Infof("convert to immutable transaction error:%s", req.Qid, err)
+		log.Infof("Cli Qid:%s convert to immutable transaction error:%s", req.Qid, err)
 		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
 		return
 	}
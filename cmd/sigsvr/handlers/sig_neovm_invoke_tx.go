@@ -31,11 +31,13 @@ import (
 )
 
 type SigNeoVMInvokeTxReq struct {
-	GasPrice uint64        `json:"gas_price"`
-	GasLimit uint64        `json:"gas_limit"`
-	Address  string        `json:"address"`
-	Payer    string        `json:"payer"`
-	Params   []interface{} `json:"params"`
+	GasPrice   uint64        `json:"gas_price"`
+	GasLimit   uint64        `json:"gas_limit"`
+	Address    string        `json:"address"`
+	Payer      string        `json:"payer"`
+	Params     []interface{} `json:"params"`
+	NotBefore  uint32        `json:"not_before"`
+	ValidUntil uint32        `json:"valid_until"`
 }
 
 type SigNeoVMInvokeTxRsp struct {
@@ -77,6 +79,7 @@ func SigNeoVMInvokeTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcRespon
 		}
 		mutable.Payer = payerAddress
 	}
+	mutable.SetValidityWindow(rawReq.NotBefore, rawReq.ValidUntil)
 	signer, err := req.GetAccount()
 	if err != nil {
 		log.Infof("Cli Qid:%s SigNeoVMInvokeTx GetAccount:%s", req.Qid, err)
@@ -36,6 +36,8 @@ type SigNeoVMInvokeTxAbiReq struct {
 	Params      []string        `json:"params"`
 	Payer       string          `json:"payer"`
 	ContractAbi json.RawMessage `json:"contract_abi"`
+	NotBefore   uint32          `json:"not_before"`
+	ValidUntil  uint32          `json:"valid_until"`
 }
 
 type SigNeoVMInvokeTxAbiRsp struct {
@@ -88,6 +90,7 @@ func SigNeoVMInvokeAbiTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcRes
 		}
 		mutable.Payer = payerAddress
 	}
+	mutable.SetValidityWindow(rawReq.NotBefore, rawReq.ValidUntil)
 	signer, err := req.GetAccount()
 	if err != nil {
 		log.Infof("Cli Qid:%s SigNeoVMInvokeAbiTx GetAccount:%s", req.Qid, err)
@@ -47,7 +47,7 @@ func TestMain(m *testing.M) {
 	var err error
 	testWallet, err = account.Open(testWalletPath)
 	if err != nil {
-		log.Errorf("account.Open :%s error:%s", testWalletPath)
+		log.Errorf("account.Open :%s error:%s", testWalletPath, err)
 		return
 	}
 
@@ -30,13 +30,15 @@ import (
 )
 
 type SigTransferTransactionReq struct {
-	GasPrice uint64 `json:"gas_price"`
-	GasLimit uint64 `json:"gas_limit"`
-	Asset    string `json:"asset"`
-	From     string `json:"from"`
-	To       string `json:"to"`
-	Amount   string `json:"amount"`
-	Payer    string `json:"payer"`
+	GasPrice   uint64 `json:"gas_price"`
+	GasLimit   uint64 `json:"gas_limit"`
+	Asset      string `json:"asset"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Amount     string `json:"amount"`
+	Payer      string `json:"payer"`
+	NotBefore  uint32 `json:"not_before"`
+	ValidUntil uint32 `json:"valid_until"`
 }
 
 type SinTransferTransactionRsp struct {
@@ -75,6 +77,7 @@ func SigTransferTransaction(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpc
 		}
 		mutable.Payer = payerAddress
 	}
+	mutable.SetValidityWindow(rawReq.NotBefore, rawReq.ValidUntil)
 
 	signer, err := req.GetAccount()
 	if err != nil {
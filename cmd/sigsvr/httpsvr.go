@@ -87,12 +87,12 @@ func (this *CliRpcServer) Handler(w http.ResponseWriter, r *http.Request) {
 		}
 		data, err := json.Marshal(resp)
 		if err != nil {
-			log.Error("CliRpcServer json.Marshal JsonRpcResponse:%+v error:%s", resp, err)
+			log.Errorf("CliRpcServer json.Marshal JsonRpcResponse:%+v error:%s", resp, err)
 			return
 		}
 		_, err = w.Write(data)
 		if err != nil {
-			log.Error("CliRpcServer Write:%s error %s", data, err)
+			log.Errorf("CliRpcServer Write:%s error %s", data, err)
 			return
 		}
 		log.Infof("[CliRpcResponse]%s", data)
@@ -104,7 +104,7 @@ func (this *CliRpcServer) Handler(w http.ResponseWriter, r *http.Request) {
 	}
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Error("CliRpcServer read body error:%s", err)
+		log.Errorf("CliRpcServer read body error:%s", err)
 		resp.ErrorCode = common.CLIERR_INVALID_REQUEST
 		resp.ErrorInfo = "invalid body"
 		return
@@ -140,6 +140,6 @@ func (this *CliRpcServer) Handler(w http.ResponseWriter, r *http.Request) {
 func (this *CliRpcServer) Close() {
 	err := this.httpSvr.Close()
 	if err != nil {
-		log.Error("httpSvr close error:%s", err)
+		log.Errorf("httpSvr close error:%s", err)
 	}
 }
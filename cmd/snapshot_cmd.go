@@ -0,0 +1,158 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"compress/zlib"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/ontio/ontology/cmd/utils"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/ledger"
+)
+
+var SnapshotCommand = cli.Command{
+	Name:      "snapshot",
+	Usage:     "Create or restore the ledger snapshot",
+	ArgsUsage: "[arguments...]",
+	Action:    cli.ShowSubcommandHelp,
+	Subcommands: []cli.Command{
+		{
+			Action:    createSnapshot,
+			Name:      "create",
+			Usage:     "Create the snapshot of current block from DB",
+			ArgsUsage: "",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+				utils.EnableArchiveFlag,
+				utils.EnableStateProofFlag,
+				utils.EnableAddressIndexFlag,
+				utils.EnableContractIndexFlag,
+				utils.UndoBlockNumFlag,
+			},
+			Description: "The snapshot contains the header chain and the states at current block. The node should be stopped before creating snapshot",
+		},
+		{
+			Action:    restoreSnapshot,
+			Name:      "restore",
+			Usage:     "Restore an empty DB from the snapshot",
+			ArgsUsage: "",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.SnapshotTrustFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+				utils.EnableArchiveFlag,
+				utils.EnableStateProofFlag,
+				utils.EnableAddressIndexFlag,
+				utils.EnableContractIndexFlag,
+				utils.UndoBlockNumFlag,
+			},
+			Description: "Transactions and events before the snapshot height are not available in the restored ledger",
+		},
+	},
+}
+
+func createSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	bookKeepers, genesisBlock, err := openLedger(ctx)
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	if err != nil {
+		return fmt.Errorf("init ledger error:%s", err)
+	}
+
+	ofile, err := os.OpenFile(snapshotFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	fWriter := bufio.NewWriter(ofile)
+	zWriter := zlib.NewWriter(fWriter)
+
+	PrintInfoMsg("Start create snapshot of block height:%d.", ledger.DefLedger.GetCurrentBlockHeight())
+	err = ledger.DefLedger.CreateSnapshot(zWriter)
+	if err == nil {
+		err = zWriter.Close()
+	}
+	if err == nil {
+		err = fWriter.Flush()
+	}
+	ofile.Close()
+	if err != nil {
+		os.Remove(snapshotFile)
+		return fmt.Errorf("create snapshot error:%s", err)
+	}
+	PrintInfoMsg("Create snapshot completed, snapshot file:%s.", snapshotFile)
+	return nil
+}
+
+func restoreSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	trusted := ctx.Bool(utils.GetFlagName(utils.SnapshotTrustFlag))
+	_, genesisBlock, err := openLedger(ctx)
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+
+	ifile, err := os.OpenFile(snapshotFile, os.O_RDONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	defer ifile.Close()
+	zReader, err := zlib.NewReader(bufio.NewReader(ifile))
+	if err != nil {
+		return fmt.Errorf("read snapshot error:%s", err)
+	}
+	defer zReader.Close()
+
+	PrintInfoMsg("Start restore snapshot.")
+	metadata, err := ledger.DefLedger.RestoreSnapshot(zReader, genesisBlock, trusted)
+	if err != nil {
+		return fmt.Errorf("restore snapshot error:%s", err)
+	}
+	PrintInfoMsg("Restore snapshot completed, current block height:%d, state merkle root:%s.",
+		metadata.Height, metadata.StateMerkleRoot.ToHexString())
+	return nil
+}
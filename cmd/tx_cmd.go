@@ -20,12 +20,14 @@ package cmd
 import (
 	"encoding/hex"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
 	cmdcom "github.com/ontio/ontology/cmd/common"
 	"github.com/ontio/ontology/cmd/utils"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
 	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
 	"github.com/urfave/cli"
 )
@@ -98,6 +100,8 @@ var TransferTxCommond = cli.Command{
 		utils.TransactionGasPriceFlag,
 		utils.TransactionGasLimitFlag,
 		utils.TransactionPayerFlag,
+		utils.TransactionNotBeforeFlag,
+		utils.TransactionValidUntilFlag,
 		utils.TransactionAssetFlag,
 		utils.TransactionFromFlag,
 		utils.TransactionToFlag,
@@ -115,6 +119,8 @@ var ApproveTxCommond = cli.Command{
 		utils.TransactionGasPriceFlag,
 		utils.TransactionGasLimitFlag,
 		utils.TransactionPayerFlag,
+		utils.TransactionNotBeforeFlag,
+		utils.TransactionValidUntilFlag,
 		utils.ApproveAssetFlag,
 		utils.ApproveAssetFromFlag,
 		utils.ApproveAssetToFlag,
@@ -133,6 +139,8 @@ var TransferFromTxCommond = cli.Command{
 		utils.TransactionGasLimitFlag,
 		utils.ApproveAssetFlag,
 		utils.TransactionPayerFlag,
+		utils.TransactionNotBeforeFlag,
+		utils.TransactionValidUntilFlag,
 		utils.TransferFromSenderFlag,
 		utils.ApproveAssetFromFlag,
 		utils.ApproveAssetToFlag,
@@ -152,11 +160,28 @@ var WithdrawONGTxCommond = cli.Command{
 		utils.TransactionGasPriceFlag,
 		utils.TransactionGasLimitFlag,
 		utils.TransactionPayerFlag,
+		utils.TransactionNotBeforeFlag,
+		utils.TransactionValidUntilFlag,
 		utils.WithdrawONGAmountFlag,
 		utils.WithdrawONGReceiveAccountFlag,
 	},
 }
 
+//setValidityWindow set the validity window of transaction by the notbefore and validuntil flags
+func setValidityWindow(ctx *cli.Context, mutTx *types.MutableTransaction) error {
+	notBefore := ctx.Uint(utils.GetFlagName(utils.TransactionNotBeforeFlag))
+	validUntil := ctx.Uint(utils.GetFlagName(utils.TransactionValidUntilFlag))
+	if validUntil > math.MaxUint32 || notBefore > math.MaxUint32 {
+		return fmt.Errorf("invalid block height")
+	}
+	if validUntil != 0 && notBefore > validUntil {
+		return fmt.Errorf("%s %d is greater than %s %d", utils.TransactionNotBeforeFlag.Name, notBefore,
+			utils.TransactionValidUntilFlag.Name, validUntil)
+	}
+	mutTx.SetValidityWindow(uint32(notBefore), uint32(validUntil))
+	return nil
+}
+
 func transferTx(ctx *cli.Context) error {
 	if !ctx.IsSet(utils.GetFlagName(utils.TransactionToFlag)) ||
 		!ctx.IsSet(utils.GetFlagName(utils.TransactionFromFlag)) ||
@@ -223,6 +248,10 @@ func transferTx(ctx *cli.Context) error {
 		return err
 	}
 	mutTx.Payer = payer
+	err = setValidityWindow(ctx, mutTx)
+	if err != nil {
+		return err
+	}
 
 	tx, err := mutTx.IntoImmutable()
 	if err != nil {
@@ -298,6 +327,10 @@ func approveTx(ctx *cli.Context) error {
 		return err
 	}
 	mutTx.Payer = payer
+	err = setValidityWindow(ctx, mutTx)
+	if err != nil {
+		return err
+	}
 
 	tx, err := mutTx.IntoImmutable()
 	if err != nil {
@@ -384,6 +417,10 @@ func transferFromTx(ctx *cli.Context) error {
 		return err
 	}
 	mutTx.Payer = payer
+	err = setValidityWindow(ctx, mutTx)
+	if err != nil {
+		return err
+	}
 
 	tx, err := mutTx.IntoImmutable()
 	if err != nil {
@@ -472,6 +509,10 @@ func withdrawONGTx(ctx *cli.Context) error {
 	}
 
 	mutTx.Payer = payer
+	err = setValidityWindow(ctx, mutTx)
+	if err != nil {
+		return err
+	}
 	tx, err := mutTx.IntoImmutable()
 	if err != nil {
 		return fmt.Errorf("IntoImmutable error:%s", err)
@@ -100,10 +100,18 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.ConfigFlag,
 			utils.LogLevelFlag,
+			utils.LogFormatFlag,
+			utils.LogModuleLevelsFlag,
 			utils.LogDirFlag,
 			utils.DisableLogFileFlag,
 			utils.DisableEventLogFlag,
 			utils.DataDirFlag,
+			utils.EnableArchiveFlag,
+			utils.EnableStateProofFlag,
+			utils.EnableAddressIndexFlag,
+			utils.EnableContractIndexFlag,
+			utils.UndoBlockNumFlag,
+			utils.LightModeFlag,
 			utils.WasmVerifyMethodFlag,
 		},
 	},
@@ -145,6 +153,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxpoolPreExecDisableFlag,
 			utils.DisableSyncVerifyTxFlag,
 			utils.DisableBroadcastNetTxFlag,
+			utils.EnableTxPoolJournalFlag,
+			utils.TxpoolPriceBumpFlag,
+			utils.TxpoolCapacityFlag,
+			utils.TxpoolPayerCapacityFlag,
 		},
 	},
 	{
@@ -158,6 +170,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MaxConnInBoundFlag,
 			utils.MaxConnOutBoundFlag,
 			utils.MaxConnInBoundForSingleIPFlag,
+			utils.SyncMaxFlightHeadersFlag,
+			utils.SyncMaxFlightBlocksFlag,
+			utils.SyncMaxHeaderForwardFlag,
+			utils.SyncMaxBlockCacheFlag,
+			utils.P2PRateLimitsFlag,
+			utils.P2PPeerRateLimitFlag,
+			utils.P2PRateLimitPolicyFlag,
 		},
 	},
 	{
@@ -165,8 +184,11 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.RPCDisabledFlag,
 			utils.RPCPortFlag,
+			utils.RPCMaxBatchSizeFlag,
 			utils.RPCLocalEnableFlag,
 			utils.RPCLocalProtFlag,
+			utils.RPCLocalAdminTokenFlag,
+			utils.RPCLocalAuditLogFlag,
 		},
 	},
 	{
@@ -184,6 +206,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WsPortFlag,
 		},
 	},
+	{
+		Name: "METRICS",
+		Flags: []cli.Flag{
+			utils.MetricsEnableFlag,
+			utils.MetricsPortFlag,
+		},
+	},
 	{
 		Name: "TEST MODE",
 		Flags: []cli.Flag{
@@ -226,6 +255,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.SendTxFlag,
 			utils.ForceSendTxFlag,
 			utils.TransactionPayerFlag,
+			utils.TransactionNotBeforeFlag,
+			utils.TransactionValidUntilFlag,
 			utils.PrepareExecTransactionFlag,
 			utils.TransferFromAmountFlag,
 			utils.WithdrawONGReceiveAccountFlag,
@@ -31,6 +31,7 @@ const (
 	DEFAULT_EXPORT_FILE   = "./OntBlocks.dat"
 	DEFAULT_ABI_PATH      = "./abi"
 	DEFAULT_EXPORT_HEIGHT = 0
+	DEFAULT_SNAPSHOT_FILE = "./OntSnapshot.dat"
 	DEFAULT_WALLET_PATH   = "./wallet_data"
 )
 
@@ -45,6 +46,15 @@ var (
 		Usage: "Set the log level to `<level>` (0~6). 0:Trace 1:Debug 2:Info 3:Warn 4:Error 5:Fatal 6:MaxLevel",
 		Value: config.DEFAULT_LOG_LEVEL,
 	}
+	LogFormatFlag = cli.StringFlag{
+		Name:  "log-format",
+		Usage: "Set the log format to `<format>`, text or json",
+		Value: log.TEXT_FORMAT,
+	}
+	LogModuleLevelsFlag = cli.StringFlag{
+		Name:  "log-modules",
+		Usage: "Set the log levels of the modules `<levels>`, e.g. p2pserver=debug,consensus=info",
+	}
 	DisableLogFileFlag = cli.BoolFlag{
 		Name:  "disable-log-file",
 		Usage: "Discard log output to file",
@@ -58,6 +68,31 @@ var (
 		Name:  "disable-event-log",
 		Usage: "Discard event log output by smart contract execution",
 	}
+	EnableArchiveFlag = cli.BoolFlag{
+		Name:  "enable-archive",
+		Usage: "Keep the contract states of every block, so that states can be queried at a given height",
+	}
+	EnableStateProofFlag = cli.BoolFlag{
+		Name:  "enable-state-proof",
+		Usage: "Maintain the state merkle trie, so that storage proofs can be queried. Required on vbft consensus nodes if the genesis config sets StateTrieRootHeight. The trie is built from current states at startup if missing, which may take a while",
+	}
+	EnableAddressIndexFlag = cli.BoolFlag{
+		Name:  "enable-address-index",
+		Usage: "Index the transactions by payer and transfer addresses, so that the transaction history of an address can be queried",
+	}
+	EnableContractIndexFlag = cli.BoolFlag{
+		Name:  "enable-contract-index",
+		Usage: "Index the transactions by the contracts notifying events, so that the event logs can be filtered by contract without scanning every block",
+	}
+	UndoBlockNumFlag = cli.UintFlag{
+		Name:  "undo-blocks",
+		Usage: "Keep the undo data of the latest `<number>` blocks, so that the ledger can be rolled back to any of them. Disabled by default",
+		Value: config.DEFAULT_UNDO_BLOCK_NUM,
+	}
+	LightModeFlag = cli.BoolFlag{
+		Name:  "light",
+		Usage: "Run as a light node, which only syncs and verifies the block headers. The blocks are fetched from the full nodes on demand",
+	}
 	WasmVerifyMethodFlag = cli.BoolFlag{
 		Name:  "enable-wasmjit-verifier",
 		Usage: "Enable wasmjit verifier to verify wasm contract",
@@ -155,6 +190,40 @@ var (
 		Usage: "Max connection `<number>` in bound for single ip",
 		Value: config.DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
 	}
+	SyncMaxFlightHeadersFlag = cli.UintFlag{
+		Name:  "sync-flight-headers",
+		Usage: "Max `<number>` of header ranges requested from peers at the same time when syncing",
+		Value: config.DEFAULT_SYNC_MAX_FLIGHT_HEADERS,
+	}
+	SyncMaxFlightBlocksFlag = cli.UintFlag{
+		Name:  "sync-flight-blocks",
+		Usage: "Max `<number>` of blocks requested from peers at the same time when syncing",
+		Value: config.DEFAULT_SYNC_MAX_FLIGHT_BLOCKS,
+	}
+	SyncMaxHeaderForwardFlag = cli.UintFlag{
+		Name:  "sync-header-forward",
+		Usage: "Max `<number>` of headers synced ahead of the current block height",
+		Value: config.DEFAULT_SYNC_MAX_HEADER_FORWARD,
+	}
+	SyncMaxBlockCacheFlag = cli.UintFlag{
+		Name:  "sync-block-cache",
+		Usage: "Max `<number>` of synced blocks waiting to be committed to the ledger",
+		Value: config.DEFAULT_SYNC_MAX_BLOCK_CACHE,
+	}
+	P2PRateLimitsFlag = cli.StringFlag{
+		Name:  "p2p-rate-limits",
+		Usage: "Token bucket limits of the messages from a peer by `<type:rate:burst,...>`, the rate is in messages per second",
+		Value: config.DEFAULT_P2P_RATE_LIMITS,
+	}
+	P2PPeerRateLimitFlag = cli.UintFlag{
+		Name:  "p2p-peer-rate-limit",
+		Usage: "Max `<number>` of messages per second from a peer, bursts up to twice the number are allowed, 0 means no limit",
+	}
+	P2PRateLimitPolicyFlag = cli.StringFlag{
+		Name:  "p2p-rate-limit-policy",
+		Usage: "Policy on the messages over the rate limits, `<drop|disconnect>` the peer",
+		Value: config.DEFAULT_P2P_RATE_LIMIT_POLICY,
+	}
 	// RPC settings
 	RPCDisabledFlag = cli.BoolFlag{
 		Name:  "disable-rpc",
@@ -165,6 +234,11 @@ var (
 		Usage: "Json rpc server listening port `<number>`",
 		Value: config.DEFAULT_RPC_PORT,
 	}
+	RPCMaxBatchSizeFlag = cli.UintFlag{
+		Name:  "rpc-max-batch-size",
+		Usage: "Max number of requests in a json rpc batch `<number>`. 0 for no limit",
+		Value: config.DEFAULT_RPC_MAX_BATCH_SIZE,
+	}
 	RPCLocalEnableFlag = cli.BoolFlag{
 		Name:  "localrpc",
 		Usage: "Enable local rpc server",
@@ -174,6 +248,14 @@ var (
 		Usage: "Json rpc local server listening port `<number>`",
 		Value: config.DEFAULT_RPC_LOCAL_PORT,
 	}
+	RPCLocalAdminTokenFlag = cli.StringFlag{
+		Name:  "localrpc-admin-token",
+		Usage: "Token `<token>` of the admin methods of the local rpc server, sent in the header \"Authorization: Bearer <token>\". No auth if empty",
+	}
+	RPCLocalAuditLogFlag = cli.StringFlag{
+		Name:  "localrpc-audit-log",
+		Usage: "Append the audit log of the admin methods of the local rpc server to `<file>`, besides the node log",
+	}
 
 	//Websocket setting
 	WsEnabledFlag = cli.BoolFlag{
@@ -186,6 +268,17 @@ var (
 		Value: config.DEFAULT_WS_PORT,
 	}
 
+	//Metrics setting
+	MetricsEnableFlag = cli.BoolFlag{
+		Name:  "metrics",
+		Usage: "Enable prometheus metrics server",
+	}
+	MetricsPortFlag = cli.UintFlag{
+		Name:  "metricsport",
+		Usage: "Metrics server listening port `<number>`",
+		Value: config.DEFAULT_METRICS_PORT,
+	}
+
 	//Restful setting
 	RestfulEnableFlag = cli.BoolFlag{
 		Name:  "rest",
@@ -379,6 +472,14 @@ var (
 		Name:  "payer",
 		Usage: "Transaction fee payer `<address>`,Default is the signer address",
 	}
+	TransactionNotBeforeFlag = cli.UintFlag{
+		Name:  "notbefore",
+		Usage: "The first block `<height>` the transaction can be packed in. 0 means no limit",
+	}
+	TransactionValidUntilFlag = cli.UintFlag{
+		Name:  "validuntil",
+		Usage: "The last block `<height>` the transaction can be packed in. 0 means no limit",
+	}
 
 	//Asset setting
 	ApproveAssetFromFlag = cli.StringFlag{
@@ -479,6 +580,23 @@ var (
 		Value: "m",
 	}
 
+	//Rollback setting
+	RollbackHeightFlag = cli.UintFlag{
+		Name:  "height",
+		Usage: "Rollback the ledger to block `<height>`",
+	}
+
+	//Snapshot setting
+	SnapshotFileFlag = cli.StringFlag{
+		Name:  "snapshot-file",
+		Usage: "Path of snapshot `<file>`",
+		Value: DEFAULT_SNAPSHOT_FILE,
+	}
+	SnapshotTrustFlag = cli.BoolFlag{
+		Name:  "trust-snapshot",
+		Usage: "Trust the origin of the snapshot. Required if the block headers do not commit the states, since the restored states can not be verified",
+	}
+
 	//PreExecute switcher
 	TxpoolPreExecDisableFlag = cli.BoolFlag{
 		Name:  "disable-tx-pool-pre-exec",
@@ -495,6 +613,25 @@ var (
 		Name:  "disable-broadcast-net-tx",
 		Usage: "Disable broadcast tx from network in tx pool",
 	}
+	EnableTxPoolJournalFlag = cli.BoolFlag{
+		Name:  "enable-tx-pool-journal",
+		Usage: "Keep the transactions in tx pool across node restarts. The transactions accepted since the last journal rotation, every 100 blocks, may be lost on crash",
+	}
+	TxpoolPriceBumpFlag = cli.Uint64Flag{
+		Name:  "tx-pool-price-bump",
+		Usage: "Min gas price bump `<percent>` for a transaction to replace a pending one with the same payer and nonce",
+		Value: config.DEFAULT_TX_POOL_PRICE_BUMP,
+	}
+	TxpoolCapacityFlag = cli.UintFlag{
+		Name:  "tx-pool-capacity",
+		Usage: "Max transaction `<number>` in tx pool, the ones with the lowest gas price are evicted when full. 0 means no limit",
+		Value: config.DEFAULT_TX_POOL_CAPACITY,
+	}
+	TxpoolPayerCapacityFlag = cli.UintFlag{
+		Name:  "tx-pool-payer-capacity",
+		Usage: "Max transaction `<number>` of a single payer in tx pool. 0 means no limit",
+		Value: config.DEFAULT_TX_POOL_PAYER_CAPACITY,
+	}
 
 	NonOptionFlag = cli.StringFlag{
 		Name:  "option",
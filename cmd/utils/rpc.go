@@ -71,6 +71,14 @@ type JsonRpcResponse struct {
 	Result json.RawMessage `json:"result"`
 }
 
+//rpcAddress is the address the rpc requests are sent to, the json rpc port of local node is used if it is empty
+var rpcAddress string
+
+//SetRpcAddress set the address of rpc server, such as the local rpc server of a running node
+func SetRpcAddress(address string) {
+	rpcAddress = address
+}
+
 func sendRpcRequest(method string, params []interface{}) ([]byte, *OntologyError) {
 	rpcReq := &JsonRpcRequest{
 		Version: JSON_RPC_VERSION,
@@ -83,7 +91,10 @@ func sendRpcRequest(method string, params []interface{}) ([]byte, *OntologyError
 		return nil, NewOntologyError(fmt.Errorf("JsonRpcRequest json.Marshal error:%s", err))
 	}
 
-	addr := fmt.Sprintf("http://localhost:%d", config.DefConfig.Rpc.HttpJsonPort)
+	addr := rpcAddress
+	if addr == "" {
+		addr = fmt.Sprintf("http://localhost:%d", config.DefConfig.Rpc.HttpJsonPort)
+	}
 	resp, err := http.Post(addr, "application/json", strings.NewReader(string(data)))
 	if err != nil {
 		return nil, NewOntologyError(err)
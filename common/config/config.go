@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
@@ -60,8 +61,10 @@ const (
 	DEFAULT_NODE_PORT                       = 20338
 	DEFAULT_RPC_PORT                        = 20336
 	DEFAULT_RPC_LOCAL_PORT                  = 20337
+	DEFAULT_RPC_MAX_BATCH_SIZE              = 100
 	DEFAULT_REST_PORT                       = 20334
 	DEFAULT_WS_PORT                         = 20335
+	DEFAULT_METRICS_PORT                    = 20339
 	DEFAULT_REST_MAX_CONN                   = 1024
 	DEFAULT_MAX_CONN_IN_BOUND               = 1024
 	DEFAULT_MAX_CONN_OUT_BOUND              = 1024
@@ -69,13 +72,23 @@ const (
 	DEFAULT_HTTP_INFO_PORT                  = 0
 	DEFAULT_MAX_TX_IN_BLOCK                 = 60000
 	DEFAULT_MAX_SYNC_HEADER                 = 500
+	DEFAULT_SYNC_MAX_FLIGHT_HEADERS         = 4
+	DEFAULT_SYNC_MAX_FLIGHT_BLOCKS          = 100
+	DEFAULT_SYNC_MAX_HEADER_FORWARD         = 5000
+	DEFAULT_SYNC_MAX_BLOCK_CACHE            = 500
+	DEFAULT_P2P_RATE_LIMITS                 = "getdata:1000:2000,getheaders:50:100,gethdrrange:50:100,tx:2000:4000,inv:500:1000"
+	DEFAULT_P2P_RATE_LIMIT_POLICY           = "drop"
 	DEFAULT_ENABLE_EVENT_LOG                = true
 	DEFAULT_CLI_RPC_PORT                    = uint(20000)
 	DEFUALT_CLI_RPC_ADDRESS                 = "127.0.0.1"
 	DEFAULT_GAS_LIMIT                       = 20000
 	DEFAULT_GAS_PRICE                       = 500
+	DEFAULT_TX_POOL_PRICE_BUMP              = 10
+	DEFAULT_TX_POOL_CAPACITY                = 100140
+	DEFAULT_TX_POOL_PAYER_CAPACITY          = 4096
 	DEFAULT_WASM_GAS_FACTOR                 = uint64(10)
 	DEFAULT_WASM_MAX_STEPCOUNT              = uint64(8000000)
+	DEFAULT_UNDO_BLOCK_NUM                  = 0
 
 	DEFAULT_DATA_DIR      = "./Chain/"
 	DEFAULT_RESERVED_FILE = "./peers.rsv"
@@ -188,6 +201,28 @@ func GetNewOntIdHeight() uint32 {
 	}
 }
 
+func GetTxValidityWindowHeight() uint32 {
+	switch DefConfig.P2PNode.NetworkId {
+	case NETWORK_ID_MAIN_NET:
+		return constants.BLOCKHEIGHT_TX_VALIDITY_WINDOW_MAINNET
+	case NETWORK_ID_POLARIS_NET:
+		return constants.BLOCKHEIGHT_TX_VALIDITY_WINDOW_POLARIS
+	default:
+		return DefConfig.Genesis.TxValidityWindowHeight
+	}
+}
+
+func GetStateTrieRootHeight() uint32 {
+	switch DefConfig.P2PNode.NetworkId {
+	case NETWORK_ID_MAIN_NET:
+		return constants.BLOCKHEIGHT_STATE_TRIE_ROOT_MAINNET
+	case NETWORK_ID_POLARIS_NET:
+		return constants.BLOCKHEIGHT_STATE_TRIE_ROOT_POLARIS
+	default:
+		return DefConfig.Genesis.StateTrieRootHeight
+	}
+}
+
 func GetNetworkName(id uint32) string {
 	name, ok := NETWORK_NAME[id]
 	if ok {
@@ -202,7 +237,9 @@ var PolarisConfig = &GenesisConfig{
 		"polaris2.ont.io:20338",
 		"polaris3.ont.io:20338",
 		"polaris4.ont.io:20338"},
-	ConsensusType: CONSENSUS_TYPE_VBFT,
+	ConsensusType:          CONSENSUS_TYPE_VBFT,
+	StateTrieRootHeight:    constants.BLOCKHEIGHT_STATE_TRIE_ROOT_POLARIS,
+	TxValidityWindowHeight: constants.BLOCKHEIGHT_TX_VALIDITY_WINDOW_POLARIS,
 	VBFT: &VBFTConfig{
 		N:                    7,
 		C:                    2,
@@ -272,7 +309,9 @@ var MainNetConfig = &GenesisConfig{
 		"seed3.ont.io:20338",
 		"seed4.ont.io:20338",
 		"seed5.ont.io:20338"},
-	ConsensusType: CONSENSUS_TYPE_VBFT,
+	ConsensusType:          CONSENSUS_TYPE_VBFT,
+	StateTrieRootHeight:    constants.BLOCKHEIGHT_STATE_TRIE_ROOT_MAINNET,
+	TxValidityWindowHeight: constants.BLOCKHEIGHT_TX_VALIDITY_WINDOW_MAINNET,
 	VBFT: &VBFTConfig{
 		N:                    7,
 		C:                    2,
@@ -333,18 +372,24 @@ var DefConfig = NewOntologyConfig()
 type GenesisConfig struct {
 	SeedList      []string
 	ConsensusType string
-	VBFT          *VBFTConfig
-	DBFT          *DBFTConfig
-	SOLO          *SOLOConfig
+	// vbft blocks commit the state trie root of previous block since this height, disabled by default
+	StateTrieRootHeight uint32
+	// transactions with validity window attributes are accepted since this height, disabled by default
+	TxValidityWindowHeight uint32
+	VBFT                   *VBFTConfig
+	DBFT                   *DBFTConfig
+	SOLO                   *SOLOConfig
 }
 
 func NewGenesisConfig() *GenesisConfig {
 	return &GenesisConfig{
-		SeedList:      make([]string, 0),
-		ConsensusType: CONSENSUS_TYPE_DBFT,
-		VBFT:          &VBFTConfig{},
-		DBFT:          &DBFTConfig{},
-		SOLO:          &SOLOConfig{},
+		SeedList:               make([]string, 0),
+		ConsensusType:          CONSENSUS_TYPE_DBFT,
+		StateTrieRootHeight:    math.MaxUint32,
+		TxValidityWindowHeight: math.MaxUint32,
+		VBFT:                   &VBFTConfig{},
+		DBFT:                   &DBFTConfig{},
+		SOLO:                   &SOLOConfig{},
 	}
 }
 
@@ -539,14 +584,25 @@ type SOLOConfig struct {
 }
 
 type CommonConfig struct {
-	LogLevel         uint
-	NodeType         string
-	EnableEventLog   bool
-	SystemFee        map[string]int64
-	GasLimit         uint64
-	GasPrice         uint64
-	DataDir          string
-	WasmVerifyMethod VerifyMethod
+	LogLevel            uint
+	LogFormat           string
+	LogModuleLevels     string
+	NodeType            string
+	EnableEventLog      bool
+	SystemFee           map[string]int64
+	GasLimit            uint64
+	GasPrice            uint64
+	DataDir             string
+	WasmVerifyMethod    VerifyMethod
+	EnableArchive       bool
+	EnableStateProof    bool
+	EnableAddressIndex  bool
+	EnableContractIndex bool
+	UndoBlockNum        uint32
+	TxPoolPriceBump     uint64
+	TxPoolCapacity      uint
+	TxPoolPayerCapacity uint
+	LightMode           bool
 }
 
 type ConsensusConfig struct {
@@ -575,12 +631,22 @@ type P2PNodeConfig struct {
 	MaxConnInBound            uint
 	MaxConnOutBound           uint
 	MaxConnInBoundForSingleIP uint
+	SyncMaxFlightHeaders      uint   //Number of header ranges on flight
+	SyncMaxFlightBlocks       uint   //Number of blocks on flight
+	SyncMaxHeaderForward      uint32 //Max headers synced ahead of the current block height
+	SyncMaxBlockCache         uint   //Max blocks waiting to be committed to the ledger
+	RateLimits                string //Token bucket limits of the messages from a peer per type, as "type:rate:burst,..."
+	PeerRateLimit             uint   //Max messages per second from a peer, no limit if 0
+	RateLimitPolicy           string //Policy on the messages over the limits, "drop" or "disconnect"
 }
 
 type RpcConfig struct {
 	EnableHttpJsonRpc bool
 	HttpJsonPort      uint
 	HttpLocalPort     uint
+	MaxBatchSize      uint
+	LocalAdminToken   string // token of the admin methods of the local rpc server, no auth if empty
+	LocalAuditLog     string // file the audit log of the admin methods is appended to, besides the node log
 }
 
 type RestfulConfig struct {
@@ -598,6 +664,11 @@ type WebSocketConfig struct {
 	HttpKeyPath  string
 }
 
+type MetricsConfig struct {
+	EnableMetrics bool
+	MetricsPort   uint
+}
+
 type OntologyConfig struct {
 	Genesis   *GenesisConfig
 	Common    *CommonConfig
@@ -606,18 +677,24 @@ type OntologyConfig struct {
 	Rpc       *RpcConfig
 	Restful   *RestfulConfig
 	Ws        *WebSocketConfig
+	Metrics   *MetricsConfig
 }
 
 func NewOntologyConfig() *OntologyConfig {
 	return &OntologyConfig{
 		Genesis: MainNetConfig,
 		Common: &CommonConfig{
-			LogLevel:         DEFAULT_LOG_LEVEL,
-			EnableEventLog:   DEFAULT_ENABLE_EVENT_LOG,
-			SystemFee:        make(map[string]int64),
-			GasLimit:         DEFAULT_GAS_LIMIT,
-			DataDir:          DEFAULT_DATA_DIR,
-			WasmVerifyMethod: InterpVerifyMethod,
+			LogLevel:            DEFAULT_LOG_LEVEL,
+			LogFormat:           log.TEXT_FORMAT,
+			EnableEventLog:      DEFAULT_ENABLE_EVENT_LOG,
+			SystemFee:           make(map[string]int64),
+			GasLimit:            DEFAULT_GAS_LIMIT,
+			DataDir:             DEFAULT_DATA_DIR,
+			WasmVerifyMethod:    InterpVerifyMethod,
+			UndoBlockNum:        DEFAULT_UNDO_BLOCK_NUM,
+			TxPoolPriceBump:     DEFAULT_TX_POOL_PRICE_BUMP,
+			TxPoolCapacity:      DEFAULT_TX_POOL_CAPACITY,
+			TxPoolPayerCapacity: DEFAULT_TX_POOL_PAYER_CAPACITY,
 		},
 		Consensus: &ConsensusConfig{
 			EnableConsensus: true,
@@ -639,11 +716,18 @@ func NewOntologyConfig() *OntologyConfig {
 			MaxConnInBound:            DEFAULT_MAX_CONN_IN_BOUND,
 			MaxConnOutBound:           DEFAULT_MAX_CONN_OUT_BOUND,
 			MaxConnInBoundForSingleIP: DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
+			SyncMaxFlightHeaders:      DEFAULT_SYNC_MAX_FLIGHT_HEADERS,
+			SyncMaxFlightBlocks:       DEFAULT_SYNC_MAX_FLIGHT_BLOCKS,
+			SyncMaxHeaderForward:      DEFAULT_SYNC_MAX_HEADER_FORWARD,
+			SyncMaxBlockCache:         DEFAULT_SYNC_MAX_BLOCK_CACHE,
+			RateLimits:                DEFAULT_P2P_RATE_LIMITS,
+			RateLimitPolicy:           DEFAULT_P2P_RATE_LIMIT_POLICY,
 		},
 		Rpc: &RpcConfig{
 			EnableHttpJsonRpc: true,
 			HttpJsonPort:      DEFAULT_RPC_PORT,
 			HttpLocalPort:     DEFAULT_RPC_LOCAL_PORT,
+			MaxBatchSize:      DEFAULT_RPC_MAX_BATCH_SIZE,
 		},
 		Restful: &RestfulConfig{
 			EnableHttpRestful: true,
@@ -653,6 +737,9 @@ func NewOntologyConfig() *OntologyConfig {
 			EnableHttpWs: true,
 			HttpWsPort:   DEFAULT_WS_PORT,
 		},
+		Metrics: &MetricsConfig{
+			MetricsPort: DEFAULT_METRICS_PORT,
+		},
 	}
 }
 
@@ -19,6 +19,7 @@
 package constants
 
 import (
+	"math"
 	"time"
 )
 
@@ -103,3 +104,11 @@ const BLOCKHEIGHT_NEW_ONTID_POLARIS = 12150000
 
 const BLOCKHEIGHT_ONTFS_MAINNET = 8550000
 const BLOCKHEIGHT_ONTFS_POLARIS = 12250000
+
+// transaction validity window attributes enable height, not scheduled yet
+const BLOCKHEIGHT_TX_VALIDITY_WINDOW_MAINNET = math.MaxUint32
+const BLOCKHEIGHT_TX_VALIDITY_WINDOW_POLARIS = math.MaxUint32
+
+// state trie root committed in vbft consensus payload enable height, not scheduled yet
+const BLOCKHEIGHT_STATE_TRIE_ROOT_MAINNET = math.MaxUint32
+const BLOCKHEIGHT_STATE_TRIE_ROOT_POLARIS = math.MaxUint32
@@ -115,6 +115,7 @@ func NameLevel(name string) int {
 type Logger struct {
 	level   int
 	logger  *log.Logger
+	raw     *log.Logger // writes the json lines without prefix
 	logFile *os.File
 }
 
@@ -122,6 +123,7 @@ func New(out io.Writer, prefix string, flag, level int, file *os.File) *Logger {
 	return &Logger{
 		level:   level,
 		logger:  log.New(out, prefix, flag),
+		raw:     log.New(out, "", 0),
 		logFile: file,
 	}
 }
@@ -135,26 +137,56 @@ func (l *Logger) SetDebugLevel(level int) error {
 	return nil
 }
 
-func (l *Logger) Output(level int, a ...interface{}) error {
+func (l *Logger) GetDebugLevel() int {
+	return l.level
+}
+
+//mayLog returns whether the level may be logged by the logger or any module
+func (l *Logger) mayLog(level int) bool {
 	if level >= l.level {
-		gid := GetGID()
-		gidStr := strconv.FormatUint(gid, 10)
+		return true
+	}
+	mls := loadModuleLevels()
+	return mls != nil && level >= mls.min
+}
 
-		a = append([]interface{}{LevelName(level), "GID",
-			gidStr + ","}, a...)
+//enabled returns whether the level is logged for the caller, and the module of the caller if it is needed
+func (l *Logger) enabled(level int) (string, bool) {
+	mls := loadModuleLevels()
+	if mls == nil {
+		if level < l.level {
+			return "", false
+		}
+		if !isJsonFormat() {
+			return "", true
+		}
+		return callerModule(), true
+	}
+	if level < l.level && level < mls.min {
+		return "", false
+	}
+	module := callerModule()
+	return module, level >= mls.levelOf(module, l.level)
+}
+
+func (l *Logger) write(level int, module string, fields Fields, msg string) error {
+	gid := GetGID()
+	if isJsonFormat() {
+		return l.raw.Output(CALL_DEPTH, jsonLine(level, gid, module, fields, msg))
+	}
+	return l.logger.Output(CALL_DEPTH, fmt.Sprintf("%s GID %d, %s%s\n", LevelName(level), gid, msg, textFields(fields)))
+}
 
-		return l.logger.Output(CALL_DEPTH, fmt.Sprintln(a...))
+func (l *Logger) Output(level int, a ...interface{}) error {
+	if module, ok := l.enabled(level); ok {
+		return l.write(level, module, nil, strings.TrimSuffix(fmt.Sprintln(a...), "\n"))
 	}
 	return nil
 }
 
 func (l *Logger) Outputf(level int, format string, v ...interface{}) error {
-	if level >= l.level {
-		gid := GetGID()
-		v = append([]interface{}{LevelName(level), "GID",
-			gid}, v...)
-
-		return l.logger.Output(CALL_DEPTH, fmt.Sprintf("%s %s %d, "+format+"\n", v...))
+	if module, ok := l.enabled(level); ok {
+		return l.write(level, module, nil, fmt.Sprintf(format, v...))
 	}
 	return nil
 }
@@ -208,7 +240,7 @@ func (l *Logger) Fatalf(format string, a ...interface{}) {
 }
 
 func Trace(a ...interface{}) {
-	if TraceLog < Log.level {
+	if !Log.mayLog(TraceLog) {
 		return
 	}
 
@@ -228,7 +260,7 @@ func Trace(a ...interface{}) {
 }
 
 func Tracef(format string, a ...interface{}) {
-	if TraceLog < Log.level {
+	if !Log.mayLog(TraceLog) {
 		return
 	}
 
@@ -248,7 +280,7 @@ func Tracef(format string, a ...interface{}) {
 }
 
 func Debug(a ...interface{}) {
-	if DebugLog < Log.level {
+	if !Log.mayLog(DebugLog) {
 		return
 	}
 
@@ -264,7 +296,7 @@ func Debug(a ...interface{}) {
 }
 
 func Debugf(format string, a ...interface{}) {
-	if DebugLog < Log.level {
+	if !Log.mayLog(DebugLog) {
 		return
 	}
 
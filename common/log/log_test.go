@@ -18,9 +18,12 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,3 +87,95 @@ func TestNewLogFile(t *testing.T) {
 	}
 	assert.Equal(t, len(logfileNum1), len(logfileNum2)-1)
 }
+
+func TestModuleLevels(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	old := Log
+	Log = New(buf, "", 0, WarnLog, nil)
+	defer func() {
+		Log = old
+		SetModuleLevels("")
+	}()
+
+	Info("hidden")
+	assert.Equal(t, 0, buf.Len())
+
+	assert.Nil(t, SetModuleLevels("common/log=debug, p2pserver=error"))
+	assert.Equal(t, "common/log=debug,p2pserver=error", GetModuleLevels())
+	Debugf("shown %d", 1)
+	Trace("hidden")
+	assert.Contains(t, buf.String(), "shown 1")
+	assert.NotContains(t, buf.String(), "hidden")
+
+	buf.Reset()
+	assert.Nil(t, SetModuleLevels("common=error,common/log=info"))
+	Debug("hidden")
+	Infof("shown %d", 2)
+	assert.Contains(t, buf.String(), "shown 2")
+	assert.NotContains(t, buf.String(), "hidden")
+
+	assert.NotNil(t, SetModuleLevels("p2pserver"))
+	assert.NotNil(t, SetModuleLevels("p2pserver=verbose"))
+	assert.Equal(t, "common/log=info,common=error", GetModuleLevels())
+
+	mls := loadModuleLevels()
+	assert.Equal(t, ErrorLog, mls.levelOf("common/config", WarnLog))
+	assert.Equal(t, WarnLog, mls.levelOf("commons", WarnLog))
+
+	buf.Reset()
+	assert.Nil(t, SetModuleLevels(""))
+	assert.Equal(t, "", GetModuleLevels())
+	Info("hidden")
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestJsonFormat(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	old := Log
+	Log = New(buf, "", 0, InfoLog, nil)
+	defer func() {
+		Log = old
+		SetFormat(TEXT_FORMAT)
+	}()
+
+	WithFields(Fields{FIELD_HEIGHT: 100, FIELD_TX_HASH: "ab"}).Infof("block %d", 100)
+	assert.True(t, strings.HasPrefix(buf.String(), LevelName(InfoLog)+" GID "))
+	assert.Contains(t, buf.String(), "block 100 height=100 txHash=ab\n")
+
+	assert.NotNil(t, SetFormat("xml"))
+	assert.Nil(t, SetFormat(JSON_FORMAT))
+	assert.Equal(t, JSON_FORMAT, GetFormat())
+	buf.Reset()
+	WithFields(Fields{FIELD_HEIGHT: 100, FIELD_PEER_ID: "1a", "msg": "overridden"}).Infof("block %d", 100)
+	Debug("hidden")
+	entry := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "common/log", entry["module"])
+	assert.Equal(t, "block 100", entry["msg"])
+	assert.Equal(t, float64(100), entry[FIELD_HEIGHT])
+	assert.Equal(t, "1a", entry[FIELD_PEER_ID])
+}
+
+func TestJsonFormatRotation(t *testing.T) {
+	defer func() {
+		SetFormat(TEXT_FORMAT)
+		os.RemoveAll("Log/")
+	}()
+	assert.Nil(t, SetFormat(JSON_FORMAT))
+	InitLog(InfoLog, PATH)
+	Warnf("warn %d", 1)
+	ClosePrintLog()
+	InitLog(InfoLog, PATH)
+	name := Log.logFile.Name()
+	Errorf("error %d", 2)
+	ClosePrintLog()
+
+	data, err := ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	entry := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(lines[len(lines)-1]), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, "error 2", entry["msg"])
+}
@@ -0,0 +1,315 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// The formats of the log lines
+const (
+	TEXT_FORMAT = "text"
+	JSON_FORMAT = "json"
+)
+
+// The names of the common fields of the structured logs
+const (
+	FIELD_HEIGHT  = "height"
+	FIELD_TX_HASH = "txHash"
+	FIELD_PEER_ID = "peerId"
+)
+
+const (
+	MODULE_PREFIX = "github.com/ontio/ontology/"
+	LOG_PACKAGE   = MODULE_PREFIX + "common/log"
+	MAX_CALLERS   = 16
+)
+
+var levelTexts = map[int]string{
+	TraceLog: "trace",
+	DebugLog: "debug",
+	InfoLog:  "info",
+	WarnLog:  "warn",
+	ErrorLog: "error",
+	FatalLog: "fatal",
+}
+
+// Fields are the key values attached to a structured log
+type Fields map[string]interface{}
+
+type moduleLevel struct {
+	module string
+	level  int
+}
+
+// moduleLevels are the levels set by modules, sorted by the module path,
+// the longest first, so that the most specific module matches.
+type moduleLevels struct {
+	levels []moduleLevel
+	min    int
+}
+
+var (
+	jsonFormat int32
+	modLevels  atomic.Value
+)
+
+// ParseLevel parses a level by its name, e.g. debug, or its number
+func ParseLevel(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for level, text := range levelTexts {
+		if text == name {
+			return level, nil
+		}
+	}
+	level, err := strconv.Atoi(name)
+	if err != nil || level < 0 || level > MaxLevelLog {
+		return 0, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+func levelText(level int) string {
+	if text, ok := levelTexts[level]; ok {
+		return text
+	}
+	return strconv.Itoa(level)
+}
+
+// SetFormat sets the format of the log lines, text or json
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", TEXT_FORMAT:
+		atomic.StoreInt32(&jsonFormat, 0)
+	case JSON_FORMAT:
+		atomic.StoreInt32(&jsonFormat, 1)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+	return nil
+}
+
+// GetFormat returns the format of the log lines
+func GetFormat() string {
+	if isJsonFormat() {
+		return JSON_FORMAT
+	}
+	return TEXT_FORMAT
+}
+
+func isJsonFormat() bool {
+	return atomic.LoadInt32(&jsonFormat) == 1
+}
+
+// SetModuleLevels sets the levels of the modules with the comma separated
+// module=level pairs, e.g. p2pserver=debug,consensus=info. A module is the
+// package path in the repository, and the level of a module applies to its
+// sub packages. The modules not set follow the level of the logger, and an
+// empty spec clears the module levels.
+func SetModuleLevels(spec string) error {
+	levels := make(map[string]int)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid module level %q, module=level expected", item)
+		}
+		module := strings.Trim(strings.TrimSpace(kv[0]), "/")
+		if module == "" {
+			return fmt.Errorf("invalid module level %q, empty module", item)
+		}
+		level, err := ParseLevel(kv[1])
+		if err != nil {
+			return err
+		}
+		levels[module] = level
+	}
+
+	mls := &moduleLevels{min: MaxLevelLog}
+	for module, level := range levels {
+		mls.levels = append(mls.levels, moduleLevel{module: module, level: level})
+		if level < mls.min {
+			mls.min = level
+		}
+	}
+	sort.Slice(mls.levels, func(i, j int) bool {
+		if len(mls.levels[i].module) != len(mls.levels[j].module) {
+			return len(mls.levels[i].module) > len(mls.levels[j].module)
+		}
+		return mls.levels[i].module < mls.levels[j].module
+	})
+	modLevels.Store(mls)
+	return nil
+}
+
+// GetModuleLevels returns the module levels in the format of SetModuleLevels
+func GetModuleLevels() string {
+	mls := loadModuleLevels()
+	if mls == nil {
+		return ""
+	}
+	items := make([]string, 0, len(mls.levels))
+	for _, ml := range mls.levels {
+		items = append(items, ml.module+"="+levelText(ml.level))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func loadModuleLevels() *moduleLevels {
+	mls, _ := modLevels.Load().(*moduleLevels)
+	if mls == nil || len(mls.levels) == 0 {
+		return nil
+	}
+	return mls
+}
+
+// levelOf returns the level of the module, or def if it is not set
+func (self *moduleLevels) levelOf(module string, def int) int {
+	for _, ml := range self.levels {
+		if module == ml.module || strings.HasPrefix(module, ml.module+"/") {
+			return ml.level
+		}
+	}
+	return def
+}
+
+// callerModule returns the package path of the first caller out of the log
+// package, relative to the repository.
+func callerModule() string {
+	pcs := make([]uintptr, MAX_CALLERS)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if pkg != LOG_PACKAGE || strings.HasSuffix(frame.File, "_test.go") {
+			return strings.TrimPrefix(pkg, MODULE_PREFIX)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// funcPackage returns the package path of a full function name
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// jsonLine formats a log line in json, the fields do not override the
+// builtin keys.
+func jsonLine(level int, gid uint64, module string, fields Fields, msg string) string {
+	entry := make(map[string]interface{}, len(fields)+5)
+	for k, v := range fields {
+		switch val := v.(type) {
+		case error:
+			entry[k] = val.Error()
+		case fmt.Stringer:
+			entry[k] = val.String()
+		default:
+			entry[k] = v
+		}
+	}
+	entry["time"] = time.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	entry["level"] = levelText(level)
+	entry["gid"] = gid
+	entry["module"] = module
+	entry["msg"] = strings.TrimRight(msg, "\n")
+	line, err := json.Marshal(entry)
+	if err != nil {
+		for k := range fields {
+			entry[k] = fmt.Sprint(fields[k])
+		}
+		line, _ = json.Marshal(entry)
+	}
+	return string(line)
+}
+
+// textFields formats the fields as the sorted key=value pairs
+func textFields(fields Fields) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%v", k, fields[k])
+	}
+	return sb.String()
+}
+
+// Entry is a log with the fields attached
+type Entry struct {
+	fields Fields
+}
+
+// WithFields returns a log entry with the fields, which are kept as json
+// keys in the json format, or appended as key=value in the text format.
+func WithFields(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+func (e *Entry) outputf(level int, format string, a ...interface{}) {
+	if module, ok := Log.enabled(level); ok {
+		Log.write(level, module, e.fields, fmt.Sprintf(format, a...))
+	}
+}
+
+func (e *Entry) Tracef(format string, a ...interface{}) {
+	e.outputf(TraceLog, format, a...)
+}
+
+func (e *Entry) Debugf(format string, a ...interface{}) {
+	e.outputf(DebugLog, format, a...)
+}
+
+func (e *Entry) Infof(format string, a ...interface{}) {
+	e.outputf(InfoLog, format, a...)
+}
+
+func (e *Entry) Warnf(format string, a ...interface{}) {
+	e.outputf(WarnLog, format, a...)
+}
+
+func (e *Entry) Errorf(format string, a ...interface{}) {
+	e.outputf(ErrorLog, format, a...)
+}
+
+func (e *Entry) Fatalf(format string, a ...interface{}) {
+	e.outputf(FatalLog, format, a...)
+}
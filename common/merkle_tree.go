@@ -57,3 +57,46 @@ func ComputeMerkleRoot(hashes []Uint256) Uint256 {
 
 	return hashes[0]
 }
+
+// ComputeMerkleProof return the sibling hashes from the leaf at index up to the root computed by
+// ComputeMerkleRoot, which proves the leaf is included in the root
+func ComputeMerkleProof(hashes []Uint256, index int) []Uint256 {
+	if index < 0 || index >= len(hashes) {
+		return nil
+	}
+	level := make([]Uint256, len(hashes))
+	copy(level, hashes)
+	proof := make([]Uint256, 0)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		proof = append(proof, level[index^1])
+		next := make([]Uint256, len(level)/2)
+		for i := range next {
+			next[i] = hashMerkleChildren(level[2*i], level[2*i+1])
+		}
+		level = next
+		index /= 2
+	}
+	return proof
+}
+
+// VerifyMerkleProof check the proof computed by ComputeMerkleProof for the leaf at index
+func VerifyMerkleProof(leaf Uint256, index int, proof []Uint256, root Uint256) bool {
+	hash := leaf
+	for _, sibling := range proof {
+		if index%2 == 0 {
+			hash = hashMerkleChildren(hash, sibling)
+		} else {
+			hash = hashMerkleChildren(sibling, hash)
+		}
+		index /= 2
+	}
+	return index == 0 && hash == root
+}
+
+func hashMerkleChildren(left, right Uint256) Uint256 {
+	temp := sha256.Sum256(append(left[:], right[:]...))
+	return sha256.Sum256(temp[:])
+}
@@ -183,3 +183,25 @@ func computeMerkleRootOld(hashes []Uint256) Uint256 {
 	tree, _ := newMerkleTree(hashes)
 	return tree.Root.Hash
 }
+
+func TestMerkleProof(t *testing.T) {
+	for n := 1; n < 40; n++ {
+		data := make([]Uint256, n)
+		for i := range data {
+			data[i] = Uint256(sha256.Sum256([]byte(fmt.Sprint(i))))
+		}
+		proofs := make([][]Uint256, n)
+		for i := range data {
+			proofs[i] = ComputeMerkleProof(data, i)
+		}
+		root := ComputeMerkleRoot(append([]Uint256{}, data...))
+		for i := range data {
+			assert.True(t, VerifyMerkleProof(data[i], i, proofs[i], root))
+			assert.False(t, VerifyMerkleProof(data[i], i+1<<uint(len(proofs[i])), proofs[i], root))
+			if n > 1 {
+				assert.False(t, VerifyMerkleProof(data[(i+1)%n], i, proofs[i], root))
+			}
+		}
+	}
+	assert.Nil(t, ComputeMerkleProof(nil, 0))
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package metrics defines the prometheus metrics collected by the modules of
+// the node, and serves them on the metrics endpoint.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const METRICS_PATH = "/metrics"
+
+// The kinds of the contracts consuming gas
+const (
+	VM_NEOVM  = "neovm"
+	VM_WASMVM = "wasmvm"
+	VM_NATIVE = "native"
+)
+
+// The directions of the p2p messages
+const (
+	P2P_SEND = "send"
+	P2P_RECV = "recv"
+)
+
+var vmTypes = []string{VM_NEOVM, VM_WASMVM, VM_NATIVE}
+
+var (
+	blockExecuteMetric = prom.NewHistogram(prom.HistogramOpts{
+		Name:    "ontology_ledger_block_execute_seconds",
+		Help:    "ontology time spent executing the transactions of a block",
+		Buckets: prom.ExponentialBuckets(0.001, 2, 15),
+	})
+
+	blockCommitMetric = prom.NewHistogram(prom.HistogramOpts{
+		Name:    "ontology_ledger_block_commit_seconds",
+		Help:    "ontology time spent committing a block to the stores",
+		Buckets: prom.ExponentialBuckets(0.001, 2, 15),
+	})
+
+	blockGasMetric = prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "ontology_ledger_block_gas",
+		Help: "ontology gas consumed by the latest committed block",
+	}, []string{"vm"})
+
+	gasConsumedMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_ledger_gas_consumed_total",
+		Help: "ontology gas consumed by the committed blocks",
+	}, []string{"vm"})
+
+	txRejectedMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_txpool_rejected_total",
+		Help: "ontology transactions rejected by the tx pool",
+	}, []string{"reason"})
+
+	vbftRoundMetric = prom.NewGauge(prom.GaugeOpts{
+		Name: "ontology_vbft_round",
+		Help: "ontology block number of the current vbft round",
+	})
+
+	vbftRoundsMetric = prom.NewCounter(prom.CounterOpts{
+		Name: "ontology_vbft_rounds_total",
+		Help: "ontology vbft rounds started",
+	})
+
+	vbftViewMetric = prom.NewGauge(prom.GaugeOpts{
+		Name: "ontology_vbft_view",
+		Help: "ontology view of the vbft chain config",
+	})
+
+	vbftViewChangesMetric = prom.NewCounter(prom.CounterOpts{
+		Name: "ontology_vbft_view_changes_total",
+		Help: "ontology vbft chain config updates",
+	})
+
+	vbftTimeoutsMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_vbft_timeouts_total",
+		Help: "ontology vbft round timeouts",
+	}, []string{"event"})
+
+	p2pBytesMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_p2p_message_bytes_total",
+		Help: "ontology p2p message bytes",
+	}, []string{"direction", "type"})
+
+	p2pMessagesMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_p2p_messages_total",
+		Help: "ontology p2p messages",
+	}, []string{"direction", "type"})
+
+	p2pRateLimitedMetric = prom.NewCounterVec(prom.CounterOpts{
+		Name: "ontology_p2p_rate_limited_total",
+		Help: "ontology p2p messages received over the rate limits",
+	}, []string{"limit", "type", "policy"})
+)
+
+func init() {
+	prom.MustRegister(blockExecuteMetric, blockCommitMetric, blockGasMetric, gasConsumedMetric, txRejectedMetric,
+		vbftRoundMetric, vbftRoundsMetric, vbftViewMetric, vbftViewChangesMetric, vbftTimeoutsMetric,
+		p2pBytesMetric, p2pMessagesMetric, p2pRateLimitedMetric)
+}
+
+// Register registers a collector whose metrics are gathered on scraping
+func Register(c prom.Collector) error {
+	return prom.Register(c)
+}
+
+// ObserveBlockExecute records the time spent executing a block since start
+func ObserveBlockExecute(start time.Time) {
+	blockExecuteMetric.Observe(time.Since(start).Seconds())
+}
+
+// ObserveBlockCommit records the time spent committing a block since start
+func ObserveBlockCommit(start time.Time) {
+	blockCommitMetric.Observe(time.Since(start).Seconds())
+}
+
+// AddBlockGas records the gas consumed by a committed block, keyed by the
+// kind of the contracts consuming it
+func AddBlockGas(gas map[string]uint64) {
+	for _, vm := range vmTypes {
+		blockGasMetric.WithLabelValues(vm).Set(float64(gas[vm]))
+		gasConsumedMetric.WithLabelValues(vm).Add(float64(gas[vm]))
+	}
+}
+
+// TxRejected records a transaction rejected by the tx pool
+func TxRejected(reason string) {
+	txRejectedMetric.WithLabelValues(reason).Inc()
+}
+
+// VbftNewRound records a vbft round started for the block
+func VbftNewRound(blockNum uint32) {
+	vbftRoundMetric.Set(float64(blockNum))
+	vbftRoundsMetric.Inc()
+}
+
+// SetVbftView records the view of the vbft chain config loaded
+func SetVbftView(view uint32) {
+	vbftViewMetric.Set(float64(view))
+}
+
+// VbftViewChange records the view of an updated vbft chain config
+func VbftViewChange(view uint32) {
+	SetVbftView(view)
+	vbftViewChangesMetric.Inc()
+}
+
+// VbftTimeout records a vbft round timeout
+func VbftTimeout(event string) {
+	vbftTimeoutsMetric.WithLabelValues(event).Inc()
+}
+
+// P2PMessage records a p2p message sent or received with its size in bytes
+func P2PMessage(direction, msgType string, size int) {
+	p2pBytesMetric.WithLabelValues(direction, msgType).Add(float64(size))
+	p2pMessagesMetric.WithLabelValues(direction, msgType).Inc()
+}
+
+// P2PRateLimited records a p2p message received over the limit of the peer or the
+// message type, and the policy applied to it
+func P2PRateLimited(limit, msgType, policy string) {
+	p2pRateLimitedMetric.WithLabelValues(limit, msgType, policy).Inc()
+}
+
+// StartServer serves the metrics on the port, it blocks until the server fails
+func StartServer(port uint) error {
+	mux := http.NewServeMux()
+	mux.Handle(METRICS_PATH, promhttp.Handler())
+	return http.ListenAndServe(":"+strconv.Itoa(int(port)), mux)
+}
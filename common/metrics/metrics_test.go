@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	ObserveBlockExecute(time.Now())
+	ObserveBlockCommit(time.Now())
+	AddBlockGas(map[string]uint64{VM_NEOVM: 100, VM_NATIVE: 20})
+	AddBlockGas(map[string]uint64{VM_NEOVM: 1})
+	TxRejected("transaction expired")
+	VbftNewRound(10)
+	VbftViewChange(3)
+	VbftTimeout("commit_block")
+	P2PMessage(P2P_SEND, "block", 100)
+	P2PMessage(P2P_SEND, "block", 50)
+	P2PRateLimited("type", "getdata", "drop")
+
+	server := httptest.NewServer(promhttp.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL + METRICS_PATH)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+
+	expected := []string{
+		"ontology_ledger_block_execute_seconds_count 1",
+		"ontology_ledger_block_commit_seconds_count 1",
+		`ontology_ledger_block_gas{vm="native"} 0`,
+		`ontology_ledger_block_gas{vm="neovm"} 1`,
+		`ontology_ledger_gas_consumed_total{vm="native"} 20`,
+		`ontology_ledger_gas_consumed_total{vm="neovm"} 101`,
+		`ontology_txpool_rejected_total{reason="transaction expired"} 1`,
+		"ontology_vbft_round 10",
+		"ontology_vbft_view 3",
+		"ontology_vbft_view_changes_total 1",
+		`ontology_vbft_timeouts_total{event="commit_block"} 1`,
+		`ontology_p2p_message_bytes_total{direction="send",type="block"} 150`,
+		`ontology_p2p_messages_total{direction="send",type="block"} 2`,
+		`ontology_p2p_rate_limited_total{limit="type",policy="drop",type="getdata"} 1`,
+	}
+	for _, line := range expected {
+		assert.True(t, strings.Contains(string(body), line), line)
+	}
+}
@@ -23,6 +23,21 @@ import "github.com/ontio/ontology/core/types"
 type StartConsensus struct{}
 type StopConsensus struct{}
 
+//GetRoundState request the state of the current consensus round, answered with RoundState
+type GetRoundState struct{}
+
+//RoundState is the state of the current vbft round
+type RoundState struct {
+	State             string
+	Index             uint32 // peer index of the node
+	CurrentBlockNum   uint32
+	CommittedBlockNum uint32
+	ChainConfigView   uint32
+	Proposers         []uint32
+	Endorsers         []uint32
+	Committers        []uint32
+}
+
 //internal Message
 type TimeOut struct{}
 type BlockCompleted struct {
@@ -60,13 +60,15 @@ func OpenBlockStore(db *ledger.Ledger, serverPid *actor.PID) (*ChainStore, error
 		log.Errorf("GetCrossStatesRoot blockNum:%d, error :%s", chainstore.chainedBlockNum, err)
 		return nil, fmt.Errorf("GetCrossStatesRoot blockNum:%d, error :%s", chainstore.chainedBlockNum, err)
 	}
+	// empty if the state trie is not enabled
+	stateTrieRoot, _ := db.GetStateTrieRoot(chainstore.chainedBlockNum)
 	writeSet := overlaydb.NewMemDB(1, 1)
 	block, err := chainstore.getBlock(chainstore.chainedBlockNum)
 	if err != nil {
 		return nil, err
 	}
 	log.Debugf("chainstore openblockstore pendingBlocks height:%d,", chainstore.chainedBlockNum)
-	chainstore.pendingBlocks[chainstore.chainedBlockNum] = &PendingBlock{block: block, execResult: &store.ExecuteResult{WriteSet: writeSet, MerkleRoot: merkleRoot, CrossStatesRoot: crossStatesRoot}, hasSubmitted: true}
+	chainstore.pendingBlocks[chainstore.chainedBlockNum] = &PendingBlock{block: block, execResult: &store.ExecuteResult{WriteSet: writeSet, MerkleRoot: merkleRoot, CrossStatesRoot: crossStatesRoot, StateTrieRoot: stateTrieRoot}, hasSubmitted: true}
 	return chainstore, nil
 }
 
@@ -104,6 +106,24 @@ func (self *ChainStore) getCrossStatesRoot(blkNum uint32) (common.Uint256, error
 	}
 }
 
+func (self *ChainStore) getExecStateTrieRoot(blkNum uint32) (common.Uint256, error) {
+	root := common.UINT256_EMPTY
+	if blk, present := self.pendingBlocks[blkNum]; blk != nil && present {
+		root = blk.execResult.StateTrieRoot
+	} else {
+		var err error
+		root, err = self.db.GetStateTrieRoot(blkNum)
+		if err != nil {
+			log.Infof("getExecStateTrieRoot blockNum:%d, error :%s", blkNum, err)
+			return common.UINT256_EMPTY, fmt.Errorf("getExecStateTrieRoot blockNum:%d, error :%s", blkNum, err)
+		}
+	}
+	if root == common.UINT256_EMPTY {
+		return common.UINT256_EMPTY, fmt.Errorf("getExecStateTrieRoot blockNum:%d, state trie is not enabled", blkNum)
+	}
+	return root, nil
+}
+
 func (self *ChainStore) getExecWriteSet(blkNum uint32) *overlaydb.MemDB {
 	if blk, present := self.pendingBlocks[blkNum]; blk != nil && present {
 		return blk.execResult.WriteSet
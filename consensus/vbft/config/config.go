@@ -60,6 +60,8 @@ type VbftBlockInfo struct {
 	VrfProof           []byte       `json:"vrf_proof"`
 	LastConfigBlockNum uint32       `json:"last_config_block_num"`
 	NewChainConfig     *ChainConfig `json:"new_chain_config"`
+	// state trie root at the end of previous block, committed since config.GetStateTrieRootHeight()
+	PrevStateTrieRoot []byte `json:"prev_state_trie_root,omitempty"`
 }
 
 const (
@@ -44,6 +44,17 @@ const (
 	EventMax
 )
 
+// timeoutEventNames are the metric labels of the round timeout events
+var timeoutEventNames = map[TimerEventType]string{
+	EventProposeBlockTimeout:      "propose_block",
+	EventRandomBackoff:            "random_backoff",
+	EventPropose2ndBlockTimeout:   "propose_2nd_block",
+	EventEndorseBlockTimeout:      "endorse_block",
+	EventEndorseEmptyBlockTimeout: "endorse_empty_block",
+	EventCommitBlockTimeout:       "commit_block",
+	EventTxBlockTimeout:           "tx_block",
+}
+
 var (
 	makeProposalTimeout    = 300 * time.Millisecond
 	make2ndProposalTimeout = 300 * time.Millisecond
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/ledger"
@@ -274,6 +275,13 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 		LastConfigBlockNum: lastConfigBlkNum,
 		NewChainConfig:     chainconfig,
 	}
+	if blkNum >= config.GetStateTrieRootHeight() {
+		stateTrieRoot, err := self.blockPool.getExecStateTrieRoot(blkNum - 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GetExecStateTrieRoot: %s,blkNum:%d", err, blkNum-1)
+		}
+		vbftBlkInfo.PrevStateTrieRoot = stateTrieRoot[:]
+	}
 	consensusPayload, err := json.Marshal(vbftBlkInfo)
 	if err != nil {
 		return nil, err
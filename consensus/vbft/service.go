@@ -31,13 +31,17 @@ import (
 	"github.com/ontio/ontology-eventbus/actor"
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	actorTypes "github.com/ontio/ontology/consensus/actor"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/store/ledgerstore"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/core/utils"
+	ontErrors "github.com/ontio/ontology/errors"
 	"github.com/ontio/ontology/events"
 	"github.com/ontio/ontology/events/message"
 	p2pmsg "github.com/ontio/ontology/p2pserver/message/types"
@@ -175,15 +179,21 @@ func (self *Server) Receive(context actor.Context) {
 	case *actorTypes.StopConsensus:
 		self.stop()
 	case *message.SaveBlockCompleteMsg:
-		log.Infof("vbft actor SaveBlockCompleteMsg receives block complete event. block height=%d, numtx=%d",
+		log.WithFields(log.Fields{log.FIELD_HEIGHT: msg.Block.Header.Height}).Infof(
+			"vbft actor SaveBlockCompleteMsg receives block complete event. block height=%d, numtx=%d",
 			msg.Block.Header.Height, len(msg.Block.Transactions))
 		self.handleBlockPersistCompleted(msg.Block)
 	case *message.BlockConsensusComplete:
-		log.Infof("vbft actor  BlockConsensusComplete receives block complete event. block height=%d, numtx=%d",
+		log.WithFields(log.Fields{log.FIELD_HEIGHT: msg.Block.Header.Height}).Infof(
+			"vbft actor  BlockConsensusComplete receives block complete event. block height=%d, numtx=%d",
 			msg.Block.Header.Height, len(msg.Block.Transactions))
 		self.handleBlockPersistCompleted(msg.Block)
 	case *p2pmsg.ConsensusPayload:
 		self.NewConsensusPayload(msg)
+	case *actorTypes.GetRoundState:
+		if context.Sender() != nil {
+			context.Respond(self.getRoundState())
+		}
 
 	default:
 		log.Info("vbft actor: Unknown msg ", msg, "type", reflect.TypeOf(msg))
@@ -204,10 +214,11 @@ func (self *Server) Halt() error {
 }
 
 func (self *Server) handleBlockPersistCompleted(block *types.Block) {
-	log.Infof("persist block: %d, %x", block.Header.Height, block.Hash())
+	logger := log.WithFields(log.Fields{log.FIELD_HEIGHT: block.Header.Height})
+	logger.Infof("persist block: %d, %x", block.Header.Height, block.Hash())
 
 	if block.Header.Height <= self.completedBlockNum {
-		log.Infof("server %d, persist block %d, vs completed %d",
+		logger.Infof("server %d, persist block %d, vs completed %d",
 			self.Index, block.Header.Height, self.completedBlockNum)
 		return
 	}
@@ -304,6 +315,7 @@ func (self *Server) LoadChainConfig(blkNum uint32) error {
 	self.metaLock.Lock()
 	self.config = &cfg
 	self.metaLock.Unlock()
+	metrics.SetVbftView(cfg.View)
 
 	self.metaLock.RLock()
 	defer self.metaLock.RUnlock()
@@ -357,6 +369,7 @@ func (self *Server) updateChainConfig() error {
 	self.config = block.Info.NewChainConfig
 	self.LastConfigBlockNum = block.getLastConfigBlockNum()
 	self.metaLock.Unlock()
+	metrics.VbftViewChange(block.Info.NewChainConfig.View)
 
 	self.metaLock.RLock()
 	defer self.metaLock.RUnlock()
@@ -640,6 +653,27 @@ func (self *Server) getState() ServerState {
 	return self.stateMgr.getState()
 }
 
+func (self *Server) getRoundState() *actorTypes.RoundState {
+	self.metaLock.RLock()
+	defer self.metaLock.RUnlock()
+
+	state := &actorTypes.RoundState{
+		State:             self.getState().String(),
+		Index:             self.Index,
+		CurrentBlockNum:   self.currentBlockNum,
+		CommittedBlockNum: self.GetCommittedBlockNo(),
+	}
+	if self.config != nil {
+		state.ChainConfigView = self.config.View
+	}
+	if cfg := self.currentParticipantConfig; cfg != nil {
+		state.Proposers = cfg.Proposers
+		state.Endorsers = cfg.Endorsers
+		state.Committers = cfg.Committers
+	}
+	return state
+}
+
 func (self *Server) updateParticipantConfig() error {
 	blkNum := self.GetCurrentBlockNo()
 	block, _ := self.blockPool.getSealedBlock(blkNum - 1)
@@ -665,9 +699,11 @@ func (self *Server) updateParticipantConfig() error {
 
 func (self *Server) startNewRound() error {
 	blkNum := self.GetCurrentBlockNo()
+	metrics.VbftNewRound(blkNum)
+	logger := log.WithFields(log.Fields{log.FIELD_HEIGHT: blkNum})
 
 	if err := self.updateParticipantConfig(); err != nil {
-		log.Errorf("startNewRound error:%s", err)
+		logger.Errorf("startNewRound error:%s", err)
 		return err
 	}
 	// check proposals in msgpool
@@ -684,7 +720,7 @@ func (self *Server) startNewRound() error {
 			} else {
 				// add other proposals to blockpool
 				if err := self.blockPool.newBlockProposal(msg); err != nil {
-					log.Errorf("starting new round, failed to add proposal from %d: %s",
+					logger.Errorf("starting new round, failed to add proposal from %d: %s",
 						msg.Block.getProposer(), err)
 				}
 			}
@@ -710,7 +746,7 @@ func (self *Server) startNewRound() error {
 				continue
 			}
 			if err := self.blockPool.newBlockCommitment(msg); err != nil {
-				log.Infof("start new round, failed to add commit, blk %d, commit for %d: %s",
+				logger.Infof("start new round, failed to add commit, blk %d, commit for %d: %s",
 					blkNum, msg.BlockProposer, err)
 			}
 		}
@@ -731,20 +767,21 @@ func (self *Server) startNewRound() error {
 		return nil
 	}
 	if err := self.timer.startTxTicker(blkNum); err != nil {
-		log.Errorf("startxticker blk:%d,err:%s", blkNum, err)
+		logger.Errorf("startxticker blk:%d,err:%s", blkNum, err)
 		return err
 	}
 	if err := self.timer.StartTxBlockTimeout(blkNum); err != nil {
-		log.Errorf("starttxblocktimeout blk:%d,err:%s", blkNum, err)
+		logger.Errorf("starttxblocktimeout blk:%d,err:%s", blkNum, err)
 		return err
 	}
 	return nil
 }
 
 func (self *Server) startNewProposal(blkNum uint32) {
+	logger := log.WithFields(log.Fields{log.FIELD_HEIGHT: blkNum})
 	// make proposal
 	if self.isProposer(blkNum, self.Index) {
-		log.Infof("server %d, proposer for block %d", self.Index, blkNum)
+		logger.Infof("server %d, proposer for block %d", self.Index, blkNum)
 		// FIXME: possible deadlock on channel
 		self.bftActionC <- &BftAction{
 			Type:     MakeProposal,
@@ -752,16 +789,16 @@ func (self *Server) startNewProposal(blkNum uint32) {
 			forEmpty: false,
 		}
 	} else if self.is2ndProposer(blkNum, self.Index) {
-		log.Infof("server %d, 2nd proposer for block %d", self.Index, blkNum)
+		logger.Infof("server %d, 2nd proposer for block %d", self.Index, blkNum)
 		if err := self.timer.StartProposalBackoffTimer(blkNum); err != nil {
-			log.Errorf("server %d, startproposalbackofftimer for block %d err:%s", self.Index, blkNum, err)
+			logger.Errorf("server %d, startproposalbackofftimer for block %d err:%s", self.Index, blkNum, err)
 		}
 	}
 
 	// TODO: if new round block proposal has received, go endorsing/committing directly
 
 	if err := self.timer.StartProposalTimer(blkNum); err != nil {
-		log.Errorf("server %d, startnewproposal for block %d err:%s", self.Index, blkNum, err)
+		logger.Errorf("server %d, startnewproposal for block %d err:%s", self.Index, blkNum, err)
 	}
 }
 
@@ -1127,6 +1164,18 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		log.Errorf("BlockPrposalMessage check MerkleRoot blocknum:%d,msg MerkleRoot:%s,self MerkleRoot:%s", msg.GetBlockNum(), msgMerkleRoot.ToHexString(), merkleRoot.ToHexString())
 		return
 	}
+	if msgBlkNum >= config.GetStateTrieRootHeight() {
+		stateTrieRoot, err := self.blockPool.getExecStateTrieRoot(msgBlkNum - 1)
+		if err != nil {
+			log.Errorf("failed to GetExecStateTrieRoot: %s,blkNum:%d", err, msgBlkNum-1)
+			return
+		}
+		if !bytes.Equal(msg.Block.Info.PrevStateTrieRoot, stateTrieRoot[:]) {
+			self.msgPool.DropMsg(msg)
+			log.Errorf("BlockPrposalMessage check StateTrieRoot blocknum:%d,msg StateTrieRoot:%x,self StateTrieRoot:%s", msg.GetBlockNum(), msg.Block.Info.PrevStateTrieRoot, stateTrieRoot.ToHexString())
+			return
+		}
+	}
 	cfg := vconfig.ChainConfig{}
 	if blk.getNewChainConfig() != nil {
 		cfg = *blk.getNewChainConfig()
@@ -1192,6 +1241,11 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 						self.Index, msg.Block.getProposer(), msgBlkNum, len(txs), err)
 					return
 				}
+				if errCode := ledgerstore.CheckValidityWindow(tx, msgBlkNum); errCode != ontErrors.ErrNoError {
+					log.Errorf("server %d verify proposal tx from %d failed, blk %d, tx %x, err: %s",
+						self.Index, msg.Block.getProposer(), msgBlkNum, tx.Hash(), errCode)
+					return
+				}
 			}
 			self.processConsensusMsg(msg)
 		}()
@@ -1666,6 +1720,9 @@ func (self *Server) timerLoop() {
 }
 
 func (self *Server) processTimerEvent(evt *TimerEvent) error {
+	if name, present := timeoutEventNames[evt.evtType]; present {
+		metrics.VbftTimeout(name)
+	}
 	switch evt.evtType {
 	case EventProposalBackoff:
 		// 1. if endorsed, return
@@ -2098,7 +2155,8 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 
 	_, h := self.blockPool.getSealedBlock(sealedBlkNum)
 	prevBlkHash := block.getPrevBlockHash()
-	log.Infof("server %d, sealed block %d, proposer %d, prevhash: %s, hash: %s", self.Index,
+	log.WithFields(log.Fields{log.FIELD_HEIGHT: sealedBlkNum}).Infof(
+		"server %d, sealed block %d, proposer %d, prevhash: %s, hash: %s", self.Index,
 		sealedBlkNum, block.getProposer(), prevBlkHash.ToHexString(), h.ToHexString())
 
 	// broadcast to other modules
@@ -19,6 +19,7 @@
 package vbft
 
 import (
+	"fmt"
 	"math"
 	"time"
 
@@ -44,6 +45,24 @@ const (
 	SyncingCheck     // potentially lost syncing
 )
 
+var serverStateNames = map[ServerState]string{
+	Init:             "Init",
+	LocalConfigured:  "LocalConfigured",
+	Configured:       "Configured",
+	Syncing:          "Syncing",
+	WaitNetworkReady: "WaitNetworkReady",
+	SyncReady:        "SyncReady",
+	Synced:           "Synced",
+	SyncingCheck:     "SyncingCheck",
+}
+
+func (state ServerState) String() string {
+	if name, ok := serverStateNames[state]; ok {
+		return name
+	}
+	return fmt.Sprintf("ServerState(%d)", int(state))
+}
+
 func isReady(state ServerState) bool {
 	return state >= SyncReady
 }
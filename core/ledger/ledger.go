@@ -20,6 +20,7 @@ package ledger
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
@@ -31,6 +32,7 @@ import (
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/smartcontract/event"
 	cstate "github.com/ontio/ontology/smartcontract/states"
+	"github.com/ontio/ontology/smartcontract/trace"
 )
 
 var DefLedger *Ledger
@@ -49,6 +51,17 @@ func NewLedger(dataDir string, stateHashHeight uint32) (*Ledger, error) {
 	}, nil
 }
 
+//NewLightLedger return the ledger of light node, which only syncs the headers and fetches the blocks on demand
+func NewLightLedger(dataDir string) (*Ledger, error) {
+	ldgStore, err := ledgerstore.NewLightLedgerStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("NewLightLedgerStore error %s", err)
+	}
+	return &Ledger{
+		ldgStore: ldgStore,
+	}, nil
+}
+
 func (self *Ledger) GetStore() store.LedgerStore {
 	return self.ldgStore
 }
@@ -68,7 +81,8 @@ func (self *Ledger) AddHeaders(headers []*types.Header) error {
 func (self *Ledger) AddBlock(block *types.Block, ccMsg *types.CrossChainMsg, stateMerkleRoot common.Uint256) error {
 	err := self.ldgStore.AddBlock(block, ccMsg, stateMerkleRoot)
 	if err != nil {
-		log.Errorf("Ledger AddBlock BlockHeight:%d BlockHash:%x error:%s", block.Header.Height, block.Hash(), err)
+		log.WithFields(log.Fields{log.FIELD_HEIGHT: block.Header.Height}).Errorf("Ledger AddBlock BlockHeight:%d BlockHash:%x error:%s",
+			block.Header.Height, block.Hash(), err)
 	}
 	return err
 }
@@ -85,6 +99,10 @@ func (self *Ledger) GetStateMerkleRoot(height uint32) (result common.Uint256, er
 	return self.ldgStore.GetStateMerkleRoot(height)
 }
 
+func (self *Ledger) GetStateTrieRoot(height uint32) (common.Uint256, error) {
+	return self.ldgStore.GetStateTrieRoot(height)
+}
+
 func (self *Ledger) GetCrossStatesRoot(height uint32) (common.Uint256, error) {
 	return self.ldgStore.GetCrossStatesRoot(height)
 }
@@ -172,6 +190,33 @@ func (self *Ledger) GetContractState(contractHash common.Address) (*payload.Depl
 	return self.ldgStore.GetContractState(contractHash)
 }
 
+func (self *Ledger) GetStorageItemByHeight(codeHash common.Address, key []byte, height uint32) ([]byte, error) {
+	storageKey := &states.StorageKey{
+		ContractAddress: codeHash,
+		Key:             key,
+	}
+	storageItem, err := self.ldgStore.GetStorageItemByHeight(storageKey, height)
+	if err != nil {
+		return nil, err
+	}
+	if storageItem == nil {
+		return nil, nil
+	}
+	return storageItem.Value, nil
+}
+
+func (self *Ledger) GetContractStateByHeight(contractHash common.Address, height uint32) (*payload.DeployCode, error) {
+	return self.ldgStore.GetContractStateByHeight(contractHash, height)
+}
+
+func (self *Ledger) GetStorageProof(codeHash common.Address, key []byte, height uint32) (*store.StorageProof, error) {
+	storageKey := &states.StorageKey{
+		ContractAddress: codeHash,
+		Key:             key,
+	}
+	return self.ldgStore.GetStorageProof(storageKey, height)
+}
+
 func (self *Ledger) GetMerkleProof(proofHeight, rootHeight uint32) ([]common.Uint256, error) {
 	return self.ldgStore.GetMerkleProof(proofHeight, rootHeight)
 }
@@ -184,6 +229,22 @@ func (self *Ledger) PreExecuteContractBatch(txes []*types.Transaction, atomic bo
 	return self.ldgStore.PreExecuteContractBatch(txes, atomic)
 }
 
+func (self *Ledger) PreExecuteContractByHeight(tx *types.Transaction, height uint32) (*cstate.PreExecResult, error) {
+	return self.ldgStore.PreExecuteContractByHeight(tx, height)
+}
+
+func (self *Ledger) TraceTransaction(txHash common.Uint256) (*trace.Trace, error) {
+	return self.ldgStore.TraceTransaction(txHash)
+}
+
+func (self *Ledger) TraceCall(tx *types.Transaction) (*trace.Trace, error) {
+	return self.ldgStore.TraceCall(tx)
+}
+
+func (self *Ledger) EstimateGas(tx *types.Transaction) (uint64, error) {
+	return self.ldgStore.EstimateGas(tx)
+}
+
 func (self *Ledger) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
 	return self.ldgStore.GetEventNotifyByTx(tx)
 }
@@ -192,6 +253,22 @@ func (self *Ledger) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify
 	return self.ldgStore.GetEventNotifyByBlock(height)
 }
 
+func (self *Ledger) GetTxsByAddress(addr common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	return self.ldgStore.GetTxsByAddress(addr, startHeight, endHeight, offset, limit)
+}
+
+func (self *Ledger) GetAddressIndexStartHeight() (uint32, error) {
+	return self.ldgStore.GetAddressIndexStartHeight()
+}
+
+func (self *Ledger) GetTxsByContract(contract common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	return self.ldgStore.GetTxsByContract(contract, startHeight, endHeight, offset, limit)
+}
+
+func (self *Ledger) GetContractIndexStartHeight() (uint32, error) {
+	return self.ldgStore.GetContractIndexStartHeight()
+}
+
 func (self *Ledger) GetCrossChainMsg(height uint32) (*types.CrossChainMsg, error) {
 	return self.ldgStore.GetCrossChainMsg(height)
 }
@@ -207,3 +284,19 @@ func (self *Ledger) Close() error {
 func (self *Ledger) EnableBlockPrune(numBeforeCurr uint32) {
 	self.ldgStore.EnableBlockPrune(numBeforeCurr)
 }
+
+func (self *Ledger) DisableBlockPrune() {
+	self.ldgStore.DisableBlockPrune()
+}
+
+func (self *Ledger) RollbackTo(height uint32) error {
+	return self.ldgStore.RollbackTo(height)
+}
+
+func (self *Ledger) CreateSnapshot(w io.Writer) error {
+	return self.ldgStore.CreateSnapshot(w)
+}
+
+func (self *Ledger) RestoreSnapshot(r io.Reader, genesisBlock *types.Block, trusted bool) (*store.SnapshotMetadata, error) {
+	return self.ldgStore.RestoreSnapshot(r, genesisBlock, trusted)
+}
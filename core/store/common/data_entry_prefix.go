@@ -32,6 +32,8 @@ const (
 	ST_BOOKKEEPER DataEntryPrefix = 0x03 //BookKeeper state key prefix
 	ST_CONTRACT   DataEntryPrefix = 0x04 //Smart contract state key prefix
 	ST_STORAGE    DataEntryPrefix = 0x05 //Smart contract storage key prefix
+	ST_ARCHIVE    DataEntryPrefix = 0x06 //Versioned contract & storage state key prefix, only written in archive mode
+	ST_STATE_TRIE DataEntryPrefix = 0x07 //Sparse merkle tree node of contract & storage states key prefix
 
 	IX_HEADER_HASH_LIST DataEntryPrefix = 0x09 //Block height => block hash key prefix
 
@@ -42,8 +44,18 @@ const (
 	SYS_BLOCK_MERKLE_TREE    DataEntryPrefix = 0x13 // Block merkle tree root key prefix
 	SYS_STATE_MERKLE_TREE    DataEntryPrefix = 0x20 // state merkle tree root key prefix
 	SYS_CROSS_CHAIN_MSG      DataEntryPrefix = 0x22 // state merkle tree root key prefix
-
-	EVENT_NOTIFY DataEntryPrefix = 0x14 //Event notify key prefix
+	SYS_ARCHIVE_START_HEIGHT DataEntryPrefix = 0x23 // first block height whose states are kept in archive mode
+	SYS_STATE_TRIE_ROOT      DataEntryPrefix = 0x24 // block height => state sparse merkle tree root key prefix
+	SYS_STATE_UNDO           DataEntryPrefix = 0x25 // block height => old values of the states changed by the block
+	SYS_ADDRESS_INDEX_START  DataEntryPrefix = 0x26 // first block height whose transactions are indexed by address
+	SYS_CONTRACT_INDEX_START DataEntryPrefix = 0x27 // first block height whose transactions are indexed by contract
+	SYS_SNAPSHOT_STATE_ROOT  DataEntryPrefix = 0x28 // height and state trie root of the restored snapshot, until checked with the next block
+
+	EVENT_NOTIFY         DataEntryPrefix = 0x14 //Event notify key prefix
+	EVENT_ADDRESS_TX     DataEntryPrefix = 0x15 //Address + block height + tx index => tx hash key prefix
+	EVENT_ADDRESS_BLOCK  DataEntryPrefix = 0x16 //Block height => addresses indexed in the block key prefix
+	EVENT_CONTRACT_TX    DataEntryPrefix = 0x17 //Contract address + block height + tx index => tx hash key prefix
+	EVENT_CONTRACT_BLOCK DataEntryPrefix = 0x18 //Block height => contracts indexed in the block key prefix
 
 	DATA_BLOCK_PRUNE_HEIGHT DataEntryPrefix = 0x80 //  last pruned block height, genesis block can not be pruned
 )
@@ -54,6 +54,13 @@ type PersistStore interface {
 	NewIterator(prefix []byte) StoreIterator //Return the iterator of store
 }
 
+//StoreSnapshot is a read only view of store at the time it's taken
+type StoreSnapshot interface {
+	Get(key []byte) ([]byte, error)          //Get the value if key in snapshot
+	NewIterator(prefix []byte) StoreIterator //Return the iterator of snapshot
+	Release()                                //Release the snapshot
+}
+
 //EventStore save event notify
 type EventStore interface {
 	//SaveEventNotifyByTx save event notify gen by smart contract execution
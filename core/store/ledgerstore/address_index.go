@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/store"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/ontio/ontology/smartcontract/service/native/ont"
+)
+
+var ErrAddressIndexDisabled = errors.New("address index is not enabled")
+
+//EnableAddressIndex turn on the address index. Transactions of the blocks before the start height are not indexed.
+func (this *EventStore) EnableAddressIndex() error {
+	return this.enableTxIndex(this.addressIndex)
+}
+
+//DisableAddressIndex drop the address index start height, so that a later EnableAddressIndex will not trust the stale index
+func (this *EventStore) DisableAddressIndex() error {
+	return this.addressIndex.disable()
+}
+
+//SaveAddressIndexStartHeight reset the first block height whose transactions are indexed
+func (this *EventStore) SaveAddressIndexStartHeight(height uint32) error {
+	return this.addressIndex.saveStartHeight(height)
+}
+
+//IsAddressIndex return whether the address index is enabled
+func (this *EventStore) IsAddressIndex() bool {
+	return this.addressIndex.enabled
+}
+
+//GetAddressIndexStartHeight return the first block height whose transactions are indexed
+func (this *EventStore) GetAddressIndexStartHeight() uint32 {
+	return this.addressIndex.startHeight
+}
+
+//SaveAddressIndex save the addresses touched by the transactions of block to batch.
+//notifies is the execute notify of the transactions, keyed by transaction hash.
+func (this *EventStore) SaveAddressIndex(block *types.Block, notifies map[common.Uint256]*event.ExecuteNotify) {
+	if !this.addressIndex.enabled {
+		return
+	}
+	txHashes := make([]common.Uint256, 0, len(block.Transactions))
+	entries := make([]txIndexEntry, 0)
+	for i, tx := range block.Transactions {
+		txHash := tx.Hash()
+		txHashes = append(txHashes, txHash)
+		for _, addr := range getTxAddresses(tx, notifies[txHash]) {
+			entries = append(entries, txIndexEntry{addr: addr, txIndex: uint32(i)})
+		}
+	}
+	this.addressIndex.save(block.Header.Height, txHashes, entries)
+}
+
+//GetTxsByAddress return the transactions touching addr in block height range [startHeight, endHeight],
+//skipping the first offset ones and returning at most limit ones.
+func (this *EventStore) GetTxsByAddress(addr common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	if !this.addressIndex.enabled {
+		return nil, ErrAddressIndexDisabled
+	}
+	return this.addressIndex.getTxs(addr, startHeight, endHeight, offset, limit)
+}
+
+//getTxAddresses return the payer of tx and the addresses in the transfer notifications of tx
+func getTxAddresses(tx *types.Transaction, notify *event.ExecuteNotify) []common.Address {
+	addrs := []common.Address{tx.Payer}
+	add := func(addr common.Address) {
+		for _, a := range addrs {
+			if a == addr {
+				return
+			}
+		}
+		addrs = append(addrs, addr)
+	}
+	if notify == nil {
+		return addrs
+	}
+	for _, n := range notify.Notify {
+		states, ok := n.States.([]interface{})
+		if !ok || len(states) < 3 || !isTransferName(states[0]) {
+			continue
+		}
+		for _, state := range states[1:3] {
+			if addr, ok := parseNotifyAddress(state); ok {
+				add(addr)
+			}
+		}
+	}
+	return addrs
+}
+
+// isTransferName return whether the name of notification is transfer, which is
+// plain text in native contracts and hex string in neovm contracts.
+func isTransferName(name interface{}) bool {
+	str, ok := name.(string)
+	if !ok {
+		return false
+	}
+	return str == ont.TRANSFER_NAME || str == hex.EncodeToString([]byte(ont.TRANSFER_NAME))
+}
+
+// parseNotifyAddress parse the address in notification, which is base58 in native
+// contracts and hex string of the address bytes in neovm contracts.
+func parseNotifyAddress(state interface{}) (common.Address, bool) {
+	str, ok := state.(string)
+	if !ok {
+		return common.ADDRESS_EMPTY, false
+	}
+	if addr, err := common.AddressFromBase58(str); err == nil {
+		return addr, true
+	}
+	data, err := hex.DecodeString(str)
+	if err != nil {
+		return common.ADDRESS_EMPTY, false
+	}
+	addr, err := common.AddressParseFromBytes(data)
+	if err != nil {
+		return common.ADDRESS_EMPTY, false
+	}
+	return addr, true
+}
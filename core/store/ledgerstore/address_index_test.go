@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/store"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressIndex(t *testing.T) {
+	eventStore, err := NewEventStore("test/address_index")
+	assert.Nil(t, err)
+	defer eventStore.Close()
+	assert.Nil(t, eventStore.EnableAddressIndex())
+	assert.Equal(t, uint32(0), eventStore.GetAddressIndexStartHeight())
+
+	addr1, addr2, addr3, addr4 := common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}
+	newTx := func(payer common.Address, amount uint64) *types.Transaction {
+		tx, err := transferTx(payer, common.ADDRESS_EMPTY, amount)
+		assert.Nil(t, err)
+		tx.Payer = payer
+		return tx
+	}
+	tx10, tx11, tx20 := newTx(addr1, 1), newTx(addr3, 2), newTx(addr2, 3)
+	notifies := map[common.Uint256]*event.ExecuteNotify{
+		tx10.Hash(): {Notify: []*event.NotifyEventInfo{
+			{States: []interface{}{"transfer", addr1.ToBase58(), addr2.ToBase58(), uint64(1)}},
+		}},
+		tx11.Hash(): {Notify: []*event.NotifyEventInfo{
+			{States: []interface{}{hex.EncodeToString([]byte("transfer")), hex.EncodeToString(addr3[:]),
+				hex.EncodeToString(addr4[:]), "01"}},
+			{States: []interface{}{"approve", addr1.ToBase58(), addr2.ToBase58(), uint64(1)}},
+		}},
+	}
+	blocks := []*types.Block{
+		{Header: &types.Header{Height: 1}, Transactions: []*types.Transaction{tx10, tx11}},
+		{Header: &types.Header{Height: 2}, Transactions: []*types.Transaction{tx20}},
+	}
+	for _, block := range blocks {
+		eventStore.NewBatch()
+		eventStore.SaveAddressIndex(block, notifies)
+		eventStore.SaveCurrentBlock(block.Header.Height, block.Hash())
+		assert.Nil(t, eventStore.CommitTo())
+	}
+
+	query := func(addr common.Address, start, end, offset, limit uint32) []store.AddressTx {
+		txs, err := eventStore.GetTxsByAddress(addr, start, end, offset, limit)
+		assert.Nil(t, err)
+		result := make([]store.AddressTx, 0, len(txs))
+		for _, tx := range txs {
+			result = append(result, *tx)
+		}
+		return result
+	}
+	entry10 := store.AddressTx{Height: 1, TxIndex: 0, TxHash: tx10.Hash()}
+	entry11 := store.AddressTx{Height: 1, TxIndex: 1, TxHash: tx11.Hash()}
+	entry20 := store.AddressTx{Height: 2, TxIndex: 0, TxHash: tx20.Hash()}
+	assert.Equal(t, []store.AddressTx{entry10}, query(addr1, 0, 10, 0, 10))
+	assert.Equal(t, []store.AddressTx{entry10, entry20}, query(addr2, 0, ^uint32(0), 0, 10))
+	assert.Equal(t, []store.AddressTx{entry11}, query(addr3, 0, 10, 0, 10))
+	assert.Equal(t, []store.AddressTx{entry11}, query(addr4, 0, 10, 0, 10))
+	assert.Equal(t, []store.AddressTx{entry20}, query(addr2, 2, 2, 0, 10))
+	assert.Equal(t, []store.AddressTx{entry10}, query(addr2, 0, 1, 0, 10))
+	assert.Equal(t, []store.AddressTx{entry10}, query(addr2, 0, 10, 0, 1))
+	assert.Equal(t, []store.AddressTx{entry20}, query(addr2, 0, 10, 1, 1))
+	assert.Equal(t, []store.AddressTx{}, query(addr2, 0, 10, 2, 10))
+
+	// pruned block is removed from the index
+	eventStore.NewBatch()
+	eventStore.PruneBlock(1, nil)
+	assert.Nil(t, eventStore.CommitTo())
+	assert.Equal(t, []store.AddressTx{}, query(addr1, 0, 10, 0, 10))
+	assert.Equal(t, []store.AddressTx{entry20}, query(addr2, 0, 10, 0, 10))
+
+	assert.Nil(t, eventStore.DisableAddressIndex())
+	_, err = eventStore.GetTxsByAddress(addr2, 0, 10, 0, 10)
+	assert.Equal(t, ErrAddressIndexDisabled, err)
+	assert.Nil(t, eventStore.EnableAddressIndex())
+	assert.Equal(t, uint32(3), eventStore.GetAddressIndexStartHeight())
+}
@@ -0,0 +1,375 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+)
+
+var ErrArchiveDisabled = errors.New("archive mode is not enabled")
+
+// archive mode keeps every version of the contract and storage states, keyed by
+// ST_ARCHIVE + raw state key + ^height(big endian), so the newest version sorts first.
+// An empty value means the state was deleted at that height.
+
+// isStateKey return whether key is a contract or storage state key
+func isStateKey(key []byte) bool {
+	return len(key) > 0 && (key[0] == byte(scom.ST_CONTRACT) || key[0] == byte(scom.ST_STORAGE))
+}
+
+func genArchiveKey(key []byte, height uint32) []byte {
+	buf := make([]byte, 1+len(key)+4)
+	buf[0] = byte(scom.ST_ARCHIVE)
+	copy(buf[1:], key)
+	binary.BigEndian.PutUint32(buf[1+len(key):], ^height)
+	return buf
+}
+
+func genArchivePrefix(prefix []byte) []byte {
+	return append([]byte{byte(scom.ST_ARCHIVE)}, prefix...)
+}
+
+// splitArchiveKey return the raw state key and the height of an archived entry
+func splitArchiveKey(key []byte) ([]byte, uint32) {
+	l := len(key) - 4
+	return key[1:l], ^binary.BigEndian.Uint32(key[l:])
+}
+
+func (self *StateStore) genArchiveStartHeightKey() []byte {
+	return []byte{byte(scom.SYS_ARCHIVE_START_HEIGHT)}
+}
+
+//EnableArchive turn on archive mode. States of the blocks before the start height can not be queried.
+func (self *StateStore) EnableArchive() error {
+	key := self.genArchiveStartHeightKey()
+	data, err := self.store.Get(key)
+	if err == nil && len(data) == 4 {
+		self.archive = true
+		self.archiveStartHeight = binary.LittleEndian.Uint32(data)
+		return nil
+	}
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	start := uint32(0)
+	_, height, err := self.GetCurrentBlock()
+	if err == nil {
+		start = height + 1
+	} else if err != scom.ErrNotFound {
+		return err
+	}
+	if err := self.saveArchiveStartHeight(start); err != nil {
+		return err
+	}
+	self.archive = true
+	log.Infof("archive mode enabled from block height %d", start)
+	return nil
+}
+
+//DisableArchive turn off archive mode and drop the archive start height. The archived versions are kept
+//on disk, a later EnableArchive starts a new window and ignores the versions before it.
+func (self *StateStore) DisableArchive() error {
+	self.archive = false
+	key := self.genArchiveStartHeightKey()
+	has, err := self.store.Has(key)
+	if err != nil || !has {
+		return err
+	}
+	log.Warnf("archive mode is disabled, historical states will not be available any more")
+	return self.store.Delete(key)
+}
+
+func (self *StateStore) saveArchiveStartHeight(height uint32) error {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, height)
+	self.archiveStartHeight = height
+	return self.store.Put(self.genArchiveStartHeightKey(), value)
+}
+
+//IsArchive return whether archive mode is enabled
+func (self *StateStore) IsArchive() bool {
+	return self.archive
+}
+
+//GetArchiveStartHeight return the first block height that can be queried in archive mode
+func (self *StateStore) GetArchiveStartHeight() uint32 {
+	return self.archiveStartHeight
+}
+
+//AddArchiveWriteSet save the versions of the states changed by the block to batch
+func (self *StateStore) AddArchiveWriteSet(height uint32, writeSet *overlaydb.MemDB) error {
+	if !self.archive {
+		return nil
+	}
+	var err error
+	writeSet.ForEach(func(key, val []byte) {
+		if err != nil || !isStateKey(key) {
+			return
+		}
+		// record the value before the first change since archive start, otherwise the
+		// state can not be restored for the heights between archive start and this change.
+		if self.archiveStartHeight > 0 {
+			baseKey := genArchiveKey(key, self.archiveStartHeight-1)
+			has, e := self.store.Has(baseKey)
+			if e != nil {
+				err = e
+				return
+			}
+			if !has {
+				origin, e := self.store.Get(key)
+				if e != nil && e != scom.ErrNotFound {
+					err = e
+					return
+				}
+				self.store.BatchPut(baseKey, origin)
+			}
+		}
+		self.store.BatchPut(genArchiveKey(key, height), val)
+	})
+	return err
+}
+
+// inArchiveWindow return whether the version archived at height belongs to the current archive window.
+// The version at the height before archive start is the base value recorded by AddArchiveWriteSet, the
+// older ones are left by a previous window and the states may have changed since then.
+func (self *StateStore) inArchiveWindow(height uint32) bool {
+	return self.archiveStartHeight == 0 || height+1 >= self.archiveStartHeight
+}
+
+// getArchivedValue return the newest version of key no higher than height read from db.
+// found is false if the key has not been changed since archive start, in that case the current value is valid.
+func (self *StateStore) getArchivedValue(db stateReader, key []byte, height uint32) (value []byte, found bool, err error) {
+	prefix := genArchivePrefix(key)
+	iter := db.NewIterator(prefix)
+	defer iter.Release()
+	changed := false
+	for has := iter.First(); has; has = iter.Next() {
+		k := iter.Key()
+		if len(k) != len(prefix)+4 {
+			continue
+		}
+		_, h := splitArchiveKey(k)
+		if !self.inArchiveWindow(h) {
+			continue
+		}
+		changed = true
+		if h <= height {
+			value = append([]byte{}, iter.Value()...)
+			return value, true, iter.Error()
+		}
+	}
+	if err = iter.Error(); err != nil {
+		return nil, false, err
+	}
+	if changed {
+		// the key is created after the height
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+func (self *StateStore) checkArchiveHeight(height uint32) error {
+	if !self.archive {
+		return ErrArchiveDisabled
+	}
+	if height < self.archiveStartHeight {
+		return fmt.Errorf("states before height %d are not archived", self.archiveStartHeight)
+	}
+	_, curr, err := self.GetCurrentBlock()
+	if err != nil {
+		return err
+	}
+	if height > curr {
+		return fmt.Errorf("height %d is higher than current block height %d", height, curr)
+	}
+	return nil
+}
+
+//NewArchivedOverlayDB return an overlay db reading the states at the end of block height
+func (self *StateStore) NewArchivedOverlayDB(height uint32) (*overlaydb.OverlayDB, error) {
+	if err := self.checkArchiveHeight(height); err != nil {
+		return nil, err
+	}
+	return overlaydb.NewOverlayDB(&archivedStore{stateStore: self, db: self.store, height: height}), nil
+}
+
+//GetContractStateByHeight return contract by contract address at the end of block height
+func (self *StateStore) GetContractStateByHeight(contractHash common.Address, height uint32) (*payload.DeployCode, error) {
+	if err := self.checkArchiveHeight(height); err != nil {
+		return nil, err
+	}
+	key, err := self.getContractStateKey(contractHash)
+	if err != nil {
+		return nil, err
+	}
+	store := &archivedStore{stateStore: self, db: self.store, height: height}
+	value, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	contractState := new(payload.DeployCode)
+	err = contractState.Deserialization(common.NewZeroCopySource(value))
+	if err != nil {
+		return nil, err
+	}
+	return contractState, nil
+}
+
+//GetStorageStateByHeight return the storage value of the key at the end of block height
+func (self *StateStore) GetStorageStateByHeight(key *states.StorageKey, height uint32) (*states.StorageItem, error) {
+	if err := self.checkArchiveHeight(height); err != nil {
+		return nil, err
+	}
+	storeKey, err := self.getStorageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	store := &archivedStore{stateStore: self, db: self.store, height: height}
+	data, err := store.Get(storeKey)
+	if err != nil {
+		return nil, err
+	}
+	storageState := new(states.StorageItem)
+	err = storageState.Deserialization(common.NewZeroCopySource(data))
+	if err != nil {
+		return nil, err
+	}
+	return storageState, nil
+}
+
+// stateReader is the read access to the state store db, or to a snapshot of it
+type stateReader interface {
+	Get(key []byte) ([]byte, error)
+	NewIterator(prefix []byte) scom.StoreIterator
+}
+
+// archivedStore is a read only view of the state store at the end of a given block
+type archivedStore struct {
+	stateStore *StateStore
+	db         stateReader
+	height     uint32
+}
+
+var errArchiveReadOnly = errors.New("archived store is read only")
+
+func (self *archivedStore) Get(key []byte) ([]byte, error) {
+	if !isStateKey(key) {
+		return self.db.Get(key)
+	}
+	value, found, err := self.stateStore.getArchivedValue(self.db, key, self.height)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return self.db.Get(key)
+	}
+	if len(value) == 0 {
+		return nil, scom.ErrNotFound
+	}
+	return value, nil
+}
+
+func (self *archivedStore) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	if err == scom.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (self *archivedStore) NewIterator(prefix []byte) scom.StoreIterator {
+	if !isStateKey(prefix) {
+		return self.db.NewIterator(prefix)
+	}
+	// the versions of one key are not contiguous when other keys share its prefix,
+	// so collect the states into a memdb to get them ordered.
+	states := overlaydb.NewMemDB(0, 0)
+	changed := make(map[string]bool)
+	iter := self.db.NewIterator(genArchivePrefix(prefix))
+	for has := iter.First(); has; has = iter.Next() {
+		k := iter.Key()
+		if len(k) < 1+len(prefix)+4 {
+			continue
+		}
+		key, h := splitArchiveKey(k)
+		if !self.stateStore.inArchiveWindow(h) {
+			continue
+		}
+		changed[string(key)] = true
+		if h > self.height {
+			continue
+		}
+		if _, unknown := states.Get(key); !unknown {
+			continue
+		}
+		if len(iter.Value()) == 0 {
+			states.Delete(key)
+		} else {
+			states.Put(key, iter.Value())
+		}
+	}
+	iter.Release()
+
+	iter = self.db.NewIterator(prefix)
+	for has := iter.First(); has; has = iter.Next() {
+		if !changed[string(iter.Key())] {
+			states.Put(iter.Key(), iter.Value())
+		}
+	}
+	iter.Release()
+
+	result := overlaydb.NewMemDB(0, 0)
+	states.ForEach(func(key, val []byte) {
+		if len(val) != 0 {
+			result.Put(key, val)
+		}
+	})
+	return result.NewIterator(nil)
+}
+
+func (self *archivedStore) Put(key []byte, value []byte) error {
+	return errArchiveReadOnly
+}
+
+func (self *archivedStore) Delete(key []byte) error {
+	return errArchiveReadOnly
+}
+
+func (self *archivedStore) NewBatch() {}
+
+func (self *archivedStore) BatchPut(key []byte, value []byte) {}
+
+func (self *archivedStore) BatchDelete(key []byte) {}
+
+func (self *archivedStore) BatchCommit() error {
+	return errArchiveReadOnly
+}
+
+func (self *archivedStore) Close() error {
+	return nil
+}
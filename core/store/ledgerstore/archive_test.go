@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchivedStates(t *testing.T) {
+	db := NewMemStateStore(0)
+	contract := common.Address{1, 2, 3}
+	storageKey := func(key string) []byte {
+		k, _ := db.getStorageKey(&states.StorageKey{ContractAddress: contract, Key: []byte(key)})
+		return k
+	}
+	storageValue := func(value string) []byte {
+		item := &states.StorageItem{Value: []byte(value)}
+		return item.ToArray()
+	}
+	// key -> value, empty value means delete
+	submit := func(height uint32, changes map[string]string) {
+		writeSet := overlaydb.NewMemDB(0, 0)
+		for k, v := range changes {
+			if v == "" {
+				writeSet.Delete(storageKey(k))
+			} else {
+				writeSet.Put(storageKey(k), storageValue(v))
+			}
+		}
+		db.NewBatch()
+		assert.Nil(t, db.SaveCurrentBlock(height, common.Uint256{byte(height)}))
+		assert.Nil(t, db.AddArchiveWriteSet(height, writeSet))
+		writeSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+	}
+	get := func(key string, height uint32) (string, error) {
+		item, err := db.GetStorageStateByHeight(&states.StorageKey{ContractAddress: contract, Key: []byte(key)}, height)
+		if err != nil {
+			return "", err
+		}
+		return string(item.Value), nil
+	}
+
+	submit(0, map[string]string{"a": "a0"})
+	submit(1, map[string]string{"b": "b1"})
+	_, err := get("a", 1)
+	assert.Equal(t, ErrArchiveDisabled, err)
+
+	assert.Nil(t, db.EnableArchive())
+	assert.Equal(t, uint32(2), db.GetArchiveStartHeight())
+	submit(2, map[string]string{})
+	submit(3, map[string]string{"a": "a3", "c": "c3"})
+	submit(4, map[string]string{"b": ""})
+
+	_, err = get("a", 1)
+	assert.NotNil(t, err)
+	_, err = get("a", 5)
+	assert.NotNil(t, err)
+
+	expected := []map[string]string{
+		2: {"a": "a0", "b": "b1"},
+		3: {"a": "a3", "b": "b1", "c": "c3"},
+		4: {"a": "a3", "c": "c3"},
+	}
+	for height := uint32(2); height <= 4; height++ {
+		for _, key := range []string{"a", "b", "c"} {
+			value, err := get(key, height)
+			if expect, ok := expected[height][key]; ok {
+				assert.Nil(t, err)
+				assert.Equal(t, expect, value)
+			} else {
+				assert.Equal(t, scom.ErrNotFound, err)
+			}
+		}
+
+		overlay, err := db.NewArchivedOverlayDB(height)
+		assert.Nil(t, err)
+		iter := overlay.NewIterator(storageKey(""))
+		found := make(map[string]string)
+		for has := iter.First(); has; has = iter.Next() {
+			key := string(iter.Key()[len(storageKey("")):])
+			item, err := states.GetValueFromRawStorageItem(iter.Value())
+			assert.Nil(t, err)
+			found[key] = string(item)
+		}
+		iter.Release()
+		assert.Equal(t, expected[height], found)
+	}
+
+	// the versions of the previous window are ignored after the archive is re-enabled
+	assert.Nil(t, db.DisableArchive())
+	submit(5, map[string]string{"a": "a5"})
+	assert.Nil(t, db.EnableArchive())
+	assert.Equal(t, uint32(6), db.GetArchiveStartHeight())
+	submit(6, map[string]string{})
+	submit(7, map[string]string{"c": "c7"})
+
+	expected = []map[string]string{
+		6: {"a": "a5", "c": "c3"},
+		7: {"a": "a5", "c": "c7"},
+	}
+	for height := uint32(6); height <= 7; height++ {
+		for _, key := range []string{"a", "b", "c"} {
+			value, err := get(key, height)
+			if expect, ok := expected[height][key]; ok {
+				assert.Nil(t, err)
+				assert.Equal(t, expect, value)
+			} else {
+				assert.Equal(t, scom.ErrNotFound, err)
+			}
+		}
+		overlay, err := db.NewArchivedOverlayDB(height)
+		assert.Nil(t, err)
+		iter := overlay.NewIterator(storageKey(""))
+		found := make(map[string]string)
+		for has := iter.First(); has; has = iter.Next() {
+			item, err := states.GetValueFromRawStorageItem(iter.Value())
+			assert.Nil(t, err)
+			found[string(iter.Key()[len(storageKey("")):])] = string(item)
+		}
+		iter.Release()
+		assert.Equal(t, expected[height], found)
+	}
+}
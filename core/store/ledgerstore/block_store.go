@@ -135,14 +135,27 @@ func (this *BlockStore) GetBlock(blockHash common.Uint256) (*types.Block, error)
 }
 
 func (this *BlockStore) loadHeaderWithTx(blockHash common.Uint256) (*types.Header, []common.Uint256, error) {
-	key := genHeaderKey(blockHash)
-	value, err := this.store.Get(key)
+	value, err := this.loadHeaderData(blockHash)
 	if err != nil {
 		return nil, nil, err
 	}
+	return deserializeHeaderWithTx(value)
+}
+
+//loadHeaderData return the raw data of sys fee, header and transaction hashes saved in store
+func (this *BlockStore) loadHeaderData(blockHash common.Uint256) ([]byte, error) {
+	return this.store.Get(genHeaderKey(blockHash))
+}
+
+//putHeaderData save the raw data of sys fee, header and transaction hashes to batch
+func (this *BlockStore) putHeaderData(blockHash common.Uint256, data []byte) {
+	this.store.BatchPut(genHeaderKey(blockHash), data)
+}
+
+func deserializeHeaderWithTx(value []byte) (*types.Header, []common.Uint256, error) {
 	source := common.NewZeroCopySource(value)
 	sysFee := new(common.Fixed64)
-	err = sysFee.Deserialization(source)
+	err := sysFee.Deserialization(source)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -328,6 +341,43 @@ func (this *BlockStore) SaveHeaderIndexList(startIndex uint32, indexList []commo
 	this.store.BatchPut(indexKey, value.Bytes())
 }
 
+//SaveBlockMerkleTree persist the merkle tree of block roots, which is only kept by the light node in block store
+func (this *BlockStore) SaveBlockMerkleTree(treeSize uint32, hashes []common.Uint256) {
+	value := common.NewZeroCopySink(make([]byte, 0, 4+len(hashes)*common.UINT256_SIZE))
+	value.WriteUint32(treeSize)
+	for _, hash := range hashes {
+		value.WriteHash(hash)
+	}
+	this.store.BatchPut(genBlockMerkleTreeKey(), value.Bytes())
+}
+
+//GetBlockMerkleTree return the merkle tree of block roots saved by SaveBlockMerkleTree
+func (this *BlockStore) GetBlockMerkleTree() (uint32, []common.Uint256, error) {
+	data, err := this.store.Get(genBlockMerkleTreeKey())
+	if err != nil {
+		return 0, nil, err
+	}
+	source := common.NewZeroCopySource(data)
+	treeSize, eof := source.NextUint32()
+	if eof {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	hashes := make([]common.Uint256, 0, source.Len()/common.UINT256_SIZE)
+	for source.Len() > 0 {
+		hash, eof := source.NextHash()
+		if eof {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		hashes = append(hashes, hash)
+	}
+	return treeSize, hashes, nil
+}
+
+//DeleteHeaderIndexList delete the header index list start from startIndex
+func (this *BlockStore) DeleteHeaderIndexList(startIndex uint32) {
+	this.store.BatchDelete(genHeaderIndexListKey(startIndex))
+}
+
 //GetBlockHash return block hash by block height
 func (this *BlockStore) GetBlockHash(height uint32) (common.Uint256, error) {
 	key := genBlockHashKey(height)
@@ -496,6 +546,10 @@ func genVersionKey() []byte {
 	return []byte{byte(scom.SYS_VERSION)}
 }
 
+func genBlockMerkleTreeKey() []byte {
+	return []byte{byte(scom.SYS_BLOCK_MERKLE_TREE)}
+}
+
 func genHeaderIndexListKey(startHeight uint32) []byte {
 	sink := common.NewZeroCopySink(nil)
 	sink.WriteByte(byte(scom.IX_HEADER_HASH_LIST))
@@ -554,3 +608,16 @@ func (this *BlockStore) PruneBlock(hash common.Uint256) []common.Uint256 {
 	this.store.BatchDelete(key)
 	return txHashes
 }
+
+//RollbackBlock delete the block at height and its transactions, return the transaction hashes of the block
+func (this *BlockStore) RollbackBlock(height uint32, blockHash common.Uint256) []common.Uint256 {
+	txHashes := this.PruneBlock(blockHash)
+	this.store.BatchDelete(genBlockHashKey(height))
+	if this.enableCache {
+		this.cache.RemoveBlock(blockHash)
+		for _, txHash := range txHashes {
+			this.cache.RemoveTransaction(txHash)
+		}
+	}
+	return txHashes
+}
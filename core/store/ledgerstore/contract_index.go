@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"errors"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/store"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+)
+
+var ErrContractIndexDisabled = errors.New("contract index is not enabled")
+
+//EnableContractIndex turn on the contract index. Transactions of the blocks before the start height are not indexed.
+func (this *EventStore) EnableContractIndex() error {
+	return this.enableTxIndex(this.contractIndex)
+}
+
+//DisableContractIndex drop the contract index start height, so that a later EnableContractIndex will not trust the stale index
+func (this *EventStore) DisableContractIndex() error {
+	return this.contractIndex.disable()
+}
+
+//SaveContractIndexStartHeight reset the first block height whose transactions are indexed by contract
+func (this *EventStore) SaveContractIndexStartHeight(height uint32) error {
+	return this.contractIndex.saveStartHeight(height)
+}
+
+//IsContractIndex return whether the contract index is enabled
+func (this *EventStore) IsContractIndex() bool {
+	return this.contractIndex.enabled
+}
+
+//GetContractIndexStartHeight return the first block height whose transactions are indexed by contract
+func (this *EventStore) GetContractIndexStartHeight() uint32 {
+	return this.contractIndex.startHeight
+}
+
+//SaveContractIndex save the contracts which notify events in the transactions of block to batch.
+//notifies is the execute notify of the transactions, keyed by transaction hash.
+func (this *EventStore) SaveContractIndex(block *types.Block, notifies map[common.Uint256]*event.ExecuteNotify) {
+	if !this.contractIndex.enabled {
+		return
+	}
+	txHashes := make([]common.Uint256, 0, len(block.Transactions))
+	entries := make([]txIndexEntry, 0)
+	for i, tx := range block.Transactions {
+		txHash := tx.Hash()
+		txHashes = append(txHashes, txHash)
+		notify := notifies[txHash]
+		if notify == nil {
+			continue
+		}
+		contracts := make(map[common.Address]bool)
+		for _, n := range notify.Notify {
+			if contracts[n.ContractAddress] {
+				continue
+			}
+			contracts[n.ContractAddress] = true
+			entries = append(entries, txIndexEntry{addr: n.ContractAddress, txIndex: uint32(i)})
+		}
+	}
+	this.contractIndex.save(block.Header.Height, txHashes, entries)
+}
+
+//GetTxsByContract return the transactions with event notify of contract in block height range [startHeight, endHeight],
+//skipping the first offset ones and returning at most limit ones.
+func (this *EventStore) GetTxsByContract(contract common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	if !this.contractIndex.enabled {
+		return nil, ErrContractIndexDisabled
+	}
+	return this.contractIndex.getTxs(contract, startHeight, endHeight, offset, limit)
+}
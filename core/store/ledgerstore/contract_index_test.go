@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractIndex(t *testing.T) {
+	eventStore, err := NewEventStore("test/contract_index")
+	assert.Nil(t, err)
+	defer eventStore.Close()
+	assert.Nil(t, eventStore.EnableContractIndex())
+
+	contract1, contract2 := common.Address{1}, common.Address{2}
+	tx0, err := transferTx(common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 1)
+	assert.Nil(t, err)
+	tx1, err := transferTx(common.ADDRESS_EMPTY, common.ADDRESS_EMPTY, 2)
+	assert.Nil(t, err)
+	notifies := map[common.Uint256]*event.ExecuteNotify{
+		tx0.Hash(): {Notify: []*event.NotifyEventInfo{
+			{ContractAddress: contract1, States: []interface{}{"a"}},
+			{ContractAddress: contract1, States: []interface{}{"b"}},
+			{ContractAddress: contract2, States: []interface{}{"c"}},
+		}},
+		tx1.Hash(): {Notify: []*event.NotifyEventInfo{
+			{ContractAddress: contract2, States: []interface{}{"d"}},
+		}},
+	}
+	block := &types.Block{Header: &types.Header{Height: 1}, Transactions: []*types.Transaction{tx0, tx1}}
+	eventStore.NewBatch()
+	eventStore.SaveContractIndex(block, notifies)
+	assert.Nil(t, eventStore.CommitTo())
+
+	txs, err := eventStore.GetTxsByContract(contract1, 0, 1, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, tx0.Hash(), txs[0].TxHash)
+	txs, err = eventStore.GetTxsByContract(contract2, 0, 1, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+	assert.Equal(t, uint32(1), txs[1].TxIndex)
+	assert.Equal(t, tx1.Hash(), txs[1].TxHash)
+	txs, err = eventStore.GetTxsByContract(contract2, 2, 10, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(txs))
+
+	eventStore.NewBatch()
+	eventStore.PruneBlock(1, nil)
+	assert.Nil(t, eventStore.CommitTo())
+	txs, err = eventStore.GetTxsByContract(contract2, 0, 1, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(txs))
+
+	assert.Nil(t, eventStore.DisableContractIndex())
+	_, err = eventStore.GetTxsByContract(contract1, 0, 1, 0, 10)
+	assert.Equal(t, ErrContractIndexDisabled, err)
+}
@@ -78,6 +78,11 @@ func (this *CrossChainStore) GetCrossChainMsg(height uint32) (*types.CrossChainM
 	return msg, nil
 }
 
+//DeleteCrossChainMsg delete the cross chain msg of height
+func (this *CrossChainStore) DeleteCrossChainMsg(height uint32) error {
+	return this.store.Delete(this.genCrossChainMsgKey(height))
+}
+
 func (this *CrossChainStore) genCrossChainMsgKey(height uint32) []byte {
 	temp := make([]byte, 5)
 	temp[0] = byte(scom.SYS_CROSS_CHAIN_MSG)
@@ -34,8 +34,10 @@ import (
 
 //Saving event notifies gen by smart contract execution
 type EventStore struct {
-	dbDir string                     //Store path
-	store *leveldbstore.LevelDBStore //Store handler
+	dbDir         string                     //Store path
+	store         *leveldbstore.LevelDBStore //Store handler
+	addressIndex  *txIndex                   //Transactions indexed by payer and transfer addresses
+	contractIndex *txIndex                   //Transactions indexed by the contracts of event notify
 }
 
 //NewEventStore return event store instance
@@ -47,9 +49,31 @@ func NewEventStore(dbDir string) (*EventStore, error) {
 	return &EventStore{
 		dbDir: dbDir,
 		store: store,
+		addressIndex: &txIndex{
+			name:        "address",
+			store:       store,
+			txPrefix:    scom.EVENT_ADDRESS_TX,
+			blockPrefix: scom.EVENT_ADDRESS_BLOCK,
+			startPrefix: scom.SYS_ADDRESS_INDEX_START,
+		},
+		contractIndex: &txIndex{
+			name:        "contract",
+			store:       store,
+			txPrefix:    scom.EVENT_CONTRACT_TX,
+			blockPrefix: scom.EVENT_CONTRACT_BLOCK,
+			startPrefix: scom.SYS_CONTRACT_INDEX_START,
+		},
 	}, nil
 }
 
+func (this *EventStore) enableTxIndex(index *txIndex) error {
+	_, height, err := this.GetCurrentBlock()
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	return index.enable(height, err == nil)
+}
+
 //NewBatch start event commit batch
 func (this *EventStore) NewBatch() {
 	this.store.NewBatch()
@@ -123,6 +147,8 @@ func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNo
 func (this *EventStore) PruneBlock(height uint32, hashes []common.Uint256) {
 	key := genEventNotifyByBlockKey(height)
 	this.store.BatchDelete(key)
+	this.addressIndex.prune(height)
+	this.contractIndex.prune(height)
 	for _, hash := range hashes {
 		this.store.BatchDelete(genEventNotifyByTxKey(hash))
 	}
@@ -34,6 +34,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	vconfig "github.com/ontio/ontology/consensus/vbft/config"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/signature"
@@ -53,6 +54,8 @@ import (
 	"github.com/ontio/ontology/smartcontract/service/wasmvm"
 	sstate "github.com/ontio/ontology/smartcontract/states"
 	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/ontio/ontology/smartcontract/trace"
+	vm "github.com/ontio/ontology/vm/neovm"
 	types2 "github.com/ontio/ontology/vm/neovm/types"
 )
 
@@ -73,6 +76,8 @@ type PrexecuteParam struct {
 	JitMode    bool
 	WasmFactor uint64
 	MinGas     bool
+	GasLimit   uint64        // the gas limit of the execution, 0 means unlimited
+	Tracer     *trace.Tracer // trace the execution if not nil
 }
 
 //LedgerStoreImp is main store struct fo ledger
@@ -94,6 +99,10 @@ type LedgerStoreImp struct {
 	savingBlockSemaphore       chan bool
 	closing                    bool
 	preserveBlockHistoryLength uint32 // block could be pruned if blockHeight + preserveBlockHistoryLength < currHeight , disable prune if equals 0
+
+	lightMode       bool                      //Only keep the headers and the fetched blocks, without states and events
+	blockMerkleTree *merkle.CompactMerkleTree //Merkle tree of block root kept by light node
+	merkleHashStore merkle.HashStore
 }
 
 //NewLedgerStore return LedgerStoreImp instance
@@ -125,12 +134,49 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, er
 	if err != nil {
 		return nil, fmt.Errorf("NewStateStore error %s", err)
 	}
+	if config.DefConfig.Common.EnableArchive {
+		err = stateStore.EnableArchive()
+	} else {
+		err = stateStore.DisableArchive()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init archive mode error %s", err)
+	}
+	if config.DefConfig.Common.EnableStateProof {
+		err = stateStore.EnableStateProof()
+		if err != nil {
+			return nil, fmt.Errorf("init state trie error %s", err)
+		}
+	} else if config.DefConfig.Consensus.EnableConsensus && isVbft() && config.GetStateTrieRootHeight() != math.MaxUint32 {
+		// the consensus nodes propose and check the state trie root committed in the block header
+		return nil, fmt.Errorf("state trie root is committed in blocks since height %d, state proof must be enabled on consensus node",
+			config.GetStateTrieRootHeight())
+	}
+	if err = stateStore.EnableUndo(config.DefConfig.Common.UndoBlockNum); err != nil {
+		return nil, fmt.Errorf("init undo data error %s", err)
+	}
 	ledgerStore.stateStore = stateStore
 
 	eventState, err := NewEventStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirEvent))
 	if err != nil {
 		return nil, fmt.Errorf("NewEventStore error %s", err)
 	}
+	if config.DefConfig.Common.EnableAddressIndex {
+		err = eventState.EnableAddressIndex()
+	} else {
+		err = eventState.DisableAddressIndex()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init address index error %s", err)
+	}
+	if config.DefConfig.Common.EnableContractIndex {
+		err = eventState.EnableContractIndex()
+	} else {
+		err = eventState.DisableContractIndex()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init contract index error %s", err)
+	}
 	ledgerStore.eventStore = eventState
 
 	return ledgerStore, nil
@@ -138,6 +184,9 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, er
 
 //InitLedgerStoreWithGenesisBlock init the ledger store with genesis block. It's the first operation after NewLedgerStore.
 func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.Block, defaultBookkeeper []keypair.PublicKey) error {
+	if this.lightMode {
+		return this.initLightStore(genesisBlock)
+	}
 	hasInit, err := this.hasAlreadyInitGenesisBlock()
 	if err != nil {
 		return fmt.Errorf("hasAlreadyInit error %s", err)
@@ -193,7 +242,17 @@ func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.
 			return fmt.Errorf("init error %s", err)
 		}
 	}
-	//load vbft peerInfo
+	err = this.loadVbftPeerInfo()
+	if err != nil {
+		return err
+	}
+	// check and fix imcompatible states
+	err = this.stateStore.CheckStorage()
+	return err
+}
+
+//loadVbftPeerInfo load vbft peerInfo of current block
+func (this *LedgerStoreImp) loadVbftPeerInfo() error {
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
 	if consensusType == "vbft" {
 		header, err := this.GetHeaderByHash(this.currBlockHash)
@@ -230,9 +289,7 @@ func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.
 		}
 		this.lock.Unlock()
 	}
-	// check and fix imcompatible states
-	err = this.stateStore.CheckStorage()
-	return err
+	return nil
 }
 
 func (this *LedgerStoreImp) hasAlreadyInitGenesisBlock() (bool, error) {
@@ -324,7 +381,7 @@ func (this *LedgerStoreImp) recoverStore() error {
 		if err != nil {
 			return fmt.Errorf("save to state store height:%d error:%s", i, err)
 		}
-		this.saveBlockToEventStore(block)
+		this.saveBlockToEventStore(block, result)
 		err = this.eventStore.CommitTo()
 		if err != nil {
 			return fmt.Errorf("eventStore.CommitTo height:%d error %s", i, err)
@@ -337,6 +394,125 @@ func (this *LedgerStoreImp) recoverStore() error {
 	return nil
 }
 
+//RollbackTo revert the ledger to the end of block height. Only the latest blocks whose undo data is kept can be rolled back.
+func (this *LedgerStoreImp) RollbackTo(height uint32) error {
+	if this.lightMode {
+		return ErrLightMode
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	if this.closing {
+		return errors.NewErr("rollback error: ledger is closing")
+	}
+	currHeight := this.GetCurrentBlockHeight()
+	if height >= currHeight {
+		return fmt.Errorf("rollback height %d should be lower than current block height %d", height, currHeight)
+	}
+	pruned, err := this.blockStore.GetBlockPrunedHeight()
+	if err != nil {
+		return fmt.Errorf("GetBlockPrunedHeight error %s", err)
+	}
+	if height < pruned {
+		return fmt.Errorf("blocks before height %d have been pruned", pruned)
+	}
+	_, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	for i := height + 1; i <= stateHeight; i++ {
+		has, err := this.stateStore.HasUndoLog(i)
+		if err != nil {
+			return fmt.Errorf("stateStore.HasUndoLog height:%d error %s", i, err)
+		}
+		if !has {
+			return fmt.Errorf("undo data of block %d is not kept, can not rollback to height %d", i, height)
+		}
+	}
+
+	// header index list is rebuilt from block hashes when loading
+	this.blockStore.NewBatch()
+	for start := (height + 1) / HEADER_INDEX_BATCH_SIZE * HEADER_INDEX_BATCH_SIZE; start < this.storedIndexCount; start += HEADER_INDEX_BATCH_SIZE {
+		this.blockStore.DeleteHeaderIndexList(start)
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	// undo the stores block by block, the state store is never ahead of the block store,
+	// so that an interrupted rollback is recovered by recoverStore
+	for i := currHeight; i > height; i-- {
+		blockHash, err := this.blockStore.GetBlockHash(i)
+		if err != nil {
+			return fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", i, err)
+		}
+		prevHash, err := this.blockStore.GetBlockHash(i - 1)
+		if err != nil {
+			return fmt.Errorf("blockStore.GetBlockHash height:%d error:%s", i-1, err)
+		}
+		if i <= stateHeight {
+			err = this.stateStore.UndoBlock()
+			if err != nil {
+				return fmt.Errorf("stateStore.UndoBlock height:%d error %s", i, err)
+			}
+		}
+		err = this.crossChainStore.DeleteCrossChainMsg(i - 1)
+		if err != nil {
+			return fmt.Errorf("crossChainStore.DeleteCrossChainMsg height:%d error %s", i-1, err)
+		}
+		this.blockStore.NewBatch()
+		this.eventStore.NewBatch()
+		txHashes := this.blockStore.RollbackBlock(i, blockHash)
+		err = this.blockStore.SaveCurrentBlock(i-1, prevHash)
+		if err != nil {
+			return fmt.Errorf("blockStore.SaveCurrentBlock height:%d error %s", i-1, err)
+		}
+		this.eventStore.PruneBlock(i, txHashes)
+		this.eventStore.SaveCurrentBlock(i-1, prevHash)
+		err = this.eventStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("eventStore.CommitTo height:%d error %s", i, err)
+		}
+		err = this.blockStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("blockStore.CommitTo height:%d error %s", i, err)
+		}
+	}
+
+	err = this.stateStore.ReloadAfterUndo()
+	if err != nil {
+		return fmt.Errorf("stateStore.ReloadAfterUndo error %s", err)
+	}
+	if this.eventStore.IsAddressIndex() && this.eventStore.GetAddressIndexStartHeight() > height+1 {
+		err = this.eventStore.SaveAddressIndexStartHeight(height + 1)
+		if err != nil {
+			return fmt.Errorf("eventStore.SaveAddressIndexStartHeight error %s", err)
+		}
+	}
+	if this.eventStore.IsContractIndex() && this.eventStore.GetContractIndexStartHeight() > height+1 {
+		err = this.eventStore.SaveContractIndexStartHeight(height + 1)
+		if err != nil {
+			return fmt.Errorf("eventStore.SaveContractIndexStartHeight error %s", err)
+		}
+	}
+	err = this.loadCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("loadCurrentBlock error %s", err)
+	}
+	err = this.loadHeaderIndexList()
+	if err != nil {
+		return fmt.Errorf("loadHeaderIndexList error %s", err)
+	}
+	this.lock.Lock()
+	this.headerCache = make(map[common.Uint256]*types.Header)
+	this.lock.Unlock()
+	err = this.loadVbftPeerInfo()
+	if err != nil {
+		return fmt.Errorf("loadVbftPeerInfo error %s", err)
+	}
+	log.WithFields(log.Fields{log.FIELD_HEIGHT: height}).Infof("ledger is rolled back to block height %d", height)
+	return nil
+}
+
 func (this *LedgerStoreImp) setHeaderIndex(height uint32, blockHash common.Uint256) {
 	this.lock.Lock()
 	defer this.lock.Unlock()
@@ -441,7 +617,13 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[
 	if prevHeader == nil {
 		return vbftPeerInfo, fmt.Errorf("cannot find pre header by blockHash %s", prevHeaderHash.ToHexString())
 	}
+	return verifyHeaderWithPrev(header, prevHeader, vbftPeerInfo)
+}
 
+//verifyHeaderWithPrev check the header is linked to prevHeader and signed by the bookkeepers,
+//return the vbft peers of the next header
+func verifyHeaderWithPrev(header, prevHeader *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	var err error
 	if prevHeader.Height+1 != header.Height {
 		return vbftPeerInfo, fmt.Errorf("block height is incorrect")
 	}
@@ -460,14 +642,16 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[
 			pubkey := vconfig.PubkeyID(bookkeeper)
 			_, present := vbftPeerInfo[pubkey]
 			if !present {
-				log.Errorf("invalid pubkey :%v,height:%d", pubkey, header.Height)
+				log.WithFields(log.Fields{log.FIELD_HEIGHT: header.Height}).Errorf("invalid pubkey :%v,height:%d",
+					pubkey, header.Height)
 				return vbftPeerInfo, fmt.Errorf("invalid pubkey :%v", pubkey)
 			}
 		}
 		hash := header.Hash()
 		err = signature.VerifyMultiSignature(hash[:], header.Bookkeepers, m, header.SigData)
 		if err != nil {
-			log.Errorf("VerifyMultiSignature:%s,Bookkeepers:%d,pubkey:%d,heigh:%d", err, len(header.Bookkeepers), len(vbftPeerInfo), header.Height)
+			log.WithFields(log.Fields{log.FIELD_HEIGHT: header.Height}).Errorf("VerifyMultiSignature:%s,Bookkeepers:%d,pubkey:%d,heigh:%d",
+				err, len(header.Bookkeepers), len(vbftPeerInfo), header.Height)
 			return vbftPeerInfo, err
 		}
 		blkInfo, err := vconfig.VbftBlock(header)
@@ -523,14 +707,17 @@ func (this *LedgerStoreImp) verifyCrossChainMsg(crossChainMsg *types.CrossChainM
 
 //AddHeader add header to cache, and add the mapping of block height to block hash. Using in block sync
 func (this *LedgerStoreImp) AddHeader(header *types.Header) error {
+	if this.lightMode {
+		return this.addLightHeaders([]*types.Header{header})
+	}
 	nextHeaderHeight := this.GetCurrentHeaderHeight() + 1
 	if header.Height != nextHeaderHeight {
-		return fmt.Errorf("header height %d not equal next header height %d", header.Height, nextHeaderHeight)
+		return invalidBlockErrorf("header height %d not equal next header height %d", header.Height, nextHeaderHeight)
 	}
 	var err error
 	this.vbftPeerInfoheader, err = this.verifyHeader(header, this.vbftPeerInfoheader)
 	if err != nil {
-		return fmt.Errorf("verifyHeader error %s", err)
+		return invalidBlockErrorf("verifyHeader error %s", err)
 	}
 	this.addHeaderCache(header)
 	this.setHeaderIndex(header.Height, header.Hash())
@@ -542,6 +729,9 @@ func (this *LedgerStoreImp) AddHeaders(headers []*types.Header) error {
 	sort.Slice(headers, func(i, j int) bool {
 		return headers[i].Height < headers[j].Height
 	})
+	if this.lightMode {
+		return this.addLightHeaders(headers)
+	}
 	var err error
 	for _, header := range headers {
 		err = this.AddHeader(header)
@@ -553,10 +743,16 @@ func (this *LedgerStoreImp) AddHeaders(headers []*types.Header) error {
 }
 
 func (this *LedgerStoreImp) GetStateMerkleRoot(height uint32) (common.Uint256, error) {
+	if this.lightMode {
+		return common.UINT256_EMPTY, ErrLightMode
+	}
 	return this.stateStore.GetStateMerkleRoot(height)
 }
 
 func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.ExecuteResult, err error) {
+	if this.lightMode {
+		return result, ErrLightMode
+	}
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
 	currBlockHeight := this.GetCurrentBlockHeight()
@@ -576,6 +772,9 @@ func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.Execu
 }
 
 func (this *LedgerStoreImp) SubmitBlock(block *types.Block, ccMsg *types.CrossChainMsg, result store.ExecuteResult) error {
+	if this.lightMode {
+		return ErrLightMode
+	}
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
 	if this.closing {
@@ -626,6 +825,9 @@ func (this *LedgerStoreImp) SubmitBlock(block *types.Block, ccMsg *types.CrossCh
 //AddBlock add the block to store.
 //When the block is not the next block, it will be cache. until the missing block arrived
 func (this *LedgerStoreImp) AddBlock(block *types.Block, ccMsg *types.CrossChainMsg, stateMerkleRoot common.Uint256) error {
+	if this.lightMode {
+		return this.addLightBlock(block)
+	}
 	currBlockHeight := this.GetCurrentBlockHeight()
 	blockHeight := block.Header.Height
 	if blockHeight <= currBlockHeight {
@@ -633,19 +835,19 @@ func (this *LedgerStoreImp) AddBlock(block *types.Block, ccMsg *types.CrossChain
 	}
 	nextBlockHeight := currBlockHeight + 1
 	if blockHeight != nextBlockHeight {
-		return fmt.Errorf("block height %d not equal next block height %d", blockHeight, nextBlockHeight)
+		return invalidBlockErrorf("block height %d not equal next block height %d", blockHeight, nextBlockHeight)
 	}
 	var err error
 	this.vbftPeerInfoblock, err = this.verifyHeader(block.Header, this.vbftPeerInfoblock)
 	if err != nil {
-		return fmt.Errorf("verifyHeader error %s", err)
+		return invalidBlockErrorf("verifyHeader error %s", err)
 	}
 	if ccMsg != nil {
 		if ccMsg.Height != currBlockHeight {
-			return fmt.Errorf("cross chain msg height %d not equal next block height %d", blockHeight, ccMsg.Height)
+			return invalidBlockErrorf("cross chain msg height %d not equal next block height %d", blockHeight, ccMsg.Height)
 		}
 		if ccMsg.Version != types.CURR_CROSS_STATES_VERSION {
-			return fmt.Errorf("error cross chain msg version excepted:%d actual:%d", types.CURR_CROSS_STATES_VERSION, ccMsg.Version)
+			return invalidBlockErrorf("error cross chain msg version excepted:%d actual:%d", types.CURR_CROSS_STATES_VERSION, ccMsg.Version)
 		}
 		root, err := this.stateStore.GetCrossStatesRoot(ccMsg.Height)
 		if err != nil {
@@ -655,7 +857,7 @@ func (this *LedgerStoreImp) AddBlock(block *types.Block, ccMsg *types.CrossChain
 			return fmt.Errorf("cross state root compare fail, expected:%x actual:%x", ccMsg.StatesRoot, root)
 		}
 		if err := this.verifyCrossChainMsg(ccMsg, block.Header.Bookkeepers); err != nil {
-			return fmt.Errorf("verifyCrossChainMsg error: %s", err)
+			return invalidBlockErrorf("verifyCrossChainMsg error: %s", err)
 		}
 	}
 	err = this.saveBlock(block, ccMsg, stateMerkleRoot)
@@ -688,6 +890,7 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 }
 
 func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.ExecuteResult, err error) {
+	defer metrics.ObserveBlockExecute(time.Now())
 	overlay := this.stateStore.NewOverlayDB()
 	if block.Header.Height != 0 {
 		config := &smartcontract.Config{
@@ -710,6 +913,16 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 		return true
 	})
 
+	if err = this.checkStateTrieRoot(block.Header); err != nil {
+		return
+	}
+	for _, tx := range block.Transactions {
+		if errCode := CheckValidityWindow(tx, block.Header.Height); errCode != errors.ErrNoError {
+			err = fmt.Errorf("block height %d tx %x: %s", block.Header.Height, tx.Hash(), errCode)
+			return
+		}
+	}
+
 	cache := storage.NewCacheDB(overlay)
 	for _, tx := range block.Transactions {
 		cache.Reset()
@@ -723,8 +936,15 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 	}
 	result.Hash = overlay.ChangeHash()
 	result.WriteSet = overlay.GetWriteSet()
+	if this.stateStore.IsStateProof() {
+		result.StateTrieRoot, err = this.stateStore.GetStateTrieRootWithWriteSet(result.WriteSet)
+		if err != nil {
+			return
+		}
+	}
 	if len(result.CrossStates) != 0 {
-		log.Infof("executeBlock: %d cross states generated at block height:%d", len(result.CrossStates), block.Header.Height)
+		log.WithFields(log.Fields{log.FIELD_HEIGHT: block.Header.Height}).Infof("executeBlock: %d cross states generated at block height:%d",
+			len(result.CrossStates), block.Header.Height)
 		result.CrossStatesRoot = merkle.TreeHasher{}.HashFullTreeWithLeafHash(result.CrossStates)
 	} else {
 		result.CrossStatesRoot = common.UINT256_EMPTY
@@ -747,6 +967,60 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 	return
 }
 
+func isVbft() bool {
+	return strings.ToLower(config.DefConfig.Genesis.ConsensusType) == "vbft"
+}
+
+//checkStateTrieRoot check the state trie root of current block committed in the consensus payload of the next block.
+//The states restored from a snapshot are always checked with the next block.
+func (this *LedgerStoreImp) checkStateTrieRoot(header *types.Header) error {
+	if header.Height == 0 || !isVbft() {
+		return nil
+	}
+	expected := this.stateStore.stateTrieRoot
+	snapshotHeight, snapshotRoot, err := this.stateStore.getSnapshotStateRoot()
+	if err == nil && snapshotHeight+1 == header.Height {
+		expected = snapshotRoot
+	} else if err != nil && err != scom.ErrNotFound {
+		return err
+	} else if header.Height < config.GetStateTrieRootHeight() || !this.stateStore.IsStateProof() {
+		return nil
+	}
+	root, err := GetCommittedStateTrieRoot(header)
+	if err != nil {
+		return fmt.Errorf("block height %d: %s", header.Height, err)
+	}
+	if root != expected {
+		return fmt.Errorf("state trie root mismatch at block height %d. expected: %s, got: %s", header.Height-1,
+			expected.ToHexString(), root.ToHexString())
+	}
+	return nil
+}
+
+//invalidBlockErrorf return the error of a header or block rejected for its own content
+func invalidBlockErrorf(format string, a ...interface{}) error {
+	return &store.InvalidBlockError{Msg: fmt.Sprintf(format, a...)}
+}
+
+//CheckValidityWindow checks the transaction can be packed in the block of height. The transactions with attributes
+//are rejected before config.GetTxValidityWindowHeight()
+func CheckValidityWindow(tx *types.Transaction, height uint32) errors.ErrCode {
+	if len(tx.Attributes) == 0 {
+		return errors.ErrNoError
+	}
+	if height < config.GetTxValidityWindowHeight() {
+		return errors.ErrTxAttribute
+	}
+	notBefore, _ := tx.GetValidityWindow()
+	if height < notBefore {
+		return errors.ErrTxNotValidYet
+	}
+	if tx.IsExpiredAt(height) {
+		return errors.ErrTxExpired
+	}
+	return errors.ErrNoError
+}
+
 func calculateTotalStateHash(overlay *overlaydb.OverlayDB) (result common.Uint256, err error) {
 	stateDiff := sha256.New()
 	iter := overlay.NewIterator([]byte{byte(scom.ST_CONTRACT)})
@@ -805,7 +1079,22 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 		return fmt.Errorf("SaveCrossStates error %s", err)
 	}
 
-	log.Debugf("the state transition hash of block %d is:%s", blockHeight, result.Hash.ToHexString())
+	err = this.stateStore.AddArchiveWriteSet(blockHeight, result.WriteSet)
+	if err != nil {
+		return fmt.Errorf("AddArchiveWriteSet error %s", err)
+	}
+
+	err = this.stateStore.AddStateTrieWriteSet(blockHeight, result.WriteSet)
+	if err != nil {
+		return fmt.Errorf("AddStateTrieWriteSet error %s", err)
+	}
+	err = this.stateStore.clearSnapshotStateRoot(blockHeight)
+	if err != nil {
+		return fmt.Errorf("clearSnapshotStateRoot error %s", err)
+	}
+
+	log.WithFields(log.Fields{log.FIELD_HEIGHT: blockHeight}).Debugf("the state transition hash of block %d is:%s",
+		blockHeight, result.Hash.ToHexString())
 
 	result.WriteSet.ForEach(func(key, val []byte) {
 		if len(val) == 0 {
@@ -815,10 +1104,15 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 		}
 	})
 
+	err = this.stateStore.SaveUndoLog(blockHeight)
+	if err != nil {
+		return fmt.Errorf("SaveUndoLog error %s", err)
+	}
+
 	return nil
 }
 
-func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block) {
+func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block, result store.ExecuteResult) {
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
 	txs := make([]common.Uint256, 0)
@@ -829,6 +1123,14 @@ func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block) {
 	if len(txs) > 0 {
 		this.eventStore.SaveEventNotifyByBlock(block.Header.Height, txs)
 	}
+	if this.eventStore.IsAddressIndex() || this.eventStore.IsContractIndex() {
+		notifies := make(map[common.Uint256]*event.ExecuteNotify, len(result.Notify))
+		for _, notify := range result.Notify {
+			notifies[notify.TxHash] = notify
+		}
+		this.eventStore.SaveAddressIndex(block, notifies)
+		this.eventStore.SaveContractIndex(block, notifies)
+	}
 	this.eventStore.SaveCurrentBlock(blockHeight, blockHash)
 }
 
@@ -884,6 +1186,7 @@ func (this *LedgerStoreImp) tryPruneBlock(header *types.Header) bool {
 
 //saveBlock do the job of execution samrt contract and commit block to store.
 func (this *LedgerStoreImp) submitBlock(block *types.Block, crossChainMsg *types.CrossChainMsg, result store.ExecuteResult) error {
+	defer metrics.ObserveBlockCommit(time.Now())
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
 	blockRoot := this.GetBlockRootWithNewTxRoots(block.Header.Height, []common.Uint256{block.Header.TransactionsRoot})
@@ -908,7 +1211,7 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, crossChainMsg *types
 	if err != nil {
 		return fmt.Errorf("save to state store height:%d error:%s", blockHeight, err)
 	}
-	this.saveBlockToEventStore(block)
+	this.saveBlockToEventStore(block, result)
 	err = this.blockStore.CommitTo()
 	if err != nil {
 		return fmt.Errorf("blockStore.CommitTo height:%d error %s", blockHeight, err)
@@ -923,6 +1226,9 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, crossChainMsg *types
 		return fmt.Errorf("stateStore.CommitTo height:%d error %s", blockHeight, err)
 	}
 	this.setCurrentBlock(blockHeight, blockHash)
+	metrics.AddBlockGas(blockGasConsumed(block, result.Notify))
+	log.WithFields(log.Fields{log.FIELD_HEIGHT: blockHeight}).Debugf("block %s saved with %d transactions",
+		blockHash.ToHexString(), len(block.Transactions))
 
 	if events.DefActorPublisher != nil {
 		events.DefActorPublisher.Publish(
@@ -976,7 +1282,8 @@ func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cach
 			return nil, nil, fmt.Errorf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
 		if err != nil {
-			log.Debugf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), err)
+			log.WithFields(log.Fields{log.FIELD_HEIGHT: block.Header.Height, log.FIELD_TX_HASH: txHash.ToHexString()}).Debugf(
+				"HandleDeployTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
 	case types.InvokeNeo, types.InvokeWasm:
 		crossStateHashes, err = this.stateStore.HandleInvokeTransaction(this, overlay, gasTable, cache, tx, block, notify)
@@ -984,12 +1291,47 @@ func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cach
 			return nil, nil, fmt.Errorf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
 		if err != nil {
-			log.Debugf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), err)
+			log.WithFields(log.Fields{log.FIELD_HEIGHT: block.Header.Height, log.FIELD_TX_HASH: txHash.ToHexString()}).Debugf(
+				"HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
 	}
 	return notify, crossStateHashes, nil
 }
 
+//blockGasConsumed sums the gas consumed by the transactions of the block by the kind of the contracts invoked
+func blockGasConsumed(block *types.Block, notifies []*event.ExecuteNotify) map[string]uint64 {
+	gas := make(map[string]uint64)
+	for i, notify := range notifies {
+		if i >= len(block.Transactions) {
+			break
+		}
+		gas[txVmType(block.Transactions[i])] += notify.GasConsumed
+	}
+	return gas
+}
+
+var nativeInvokeSuffix = append([]byte{byte(vm.SYSCALL), byte(len(neovm.NATIVE_INVOKE_NAME))},
+	neovm.NATIVE_INVOKE_NAME...)
+
+//txVmType return the kind of the contract invoked by the transaction. The neovm code ending with the native invoke
+//syscall is counted as native.
+func txVmType(tx *types.Transaction) string {
+	switch pl := tx.Payload.(type) {
+	case *payload.DeployCode:
+		if pl.VmType() == payload.WASMVM_TYPE {
+			return metrics.VM_WASMVM
+		}
+	case *payload.InvokeCode:
+		if tx.TxType == types.InvokeWasm {
+			return metrics.VM_WASMVM
+		}
+		if bytes.HasSuffix(pl.Code, nativeInvokeSuffix) {
+			return metrics.VM_NATIVE
+		}
+	}
+	return metrics.VM_NEOVM
+}
+
 func (this *LedgerStoreImp) saveHeaderIndexList() error {
 	this.lock.RLock()
 	storeCount := this.storedIndexCount
@@ -1041,18 +1383,30 @@ func (this *LedgerStoreImp) GetBlockRootWithNewTxRoots(startHeight uint32, txRoo
 	}
 
 	needs := txRoots[this.currBlockHeight+1-startHeight:]
+	if this.lightMode {
+		return this.blockMerkleTree.GetRootWithNewLeaves(needs)
+	}
 	return this.stateStore.GetBlockRootWithNewTxRoots(needs)
 }
 
 func (this *LedgerStoreImp) GetCrossStatesRoot(height uint32) (common.Uint256, error) {
+	if this.lightMode {
+		return common.UINT256_EMPTY, ErrLightMode
+	}
 	return this.stateStore.GetCrossStatesRoot(height)
 }
 
 func (this *LedgerStoreImp) GetCrossChainMsg(height uint32) (*types.CrossChainMsg, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	return this.crossChainStore.GetCrossChainMsg(height)
 }
 
 func (this *LedgerStoreImp) GetCrossStatesProof(height uint32, key []byte) ([]byte, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	hashes, err := this.stateStore.GetCrossStates(height)
 	if err != nil {
 		return nil, fmt.Errorf("GetCrossStates:%s", err)
@@ -1107,8 +1461,16 @@ func (this *LedgerStoreImp) GetTransaction(txHash common.Uint256) (*types.Transa
 }
 
 //GetBlockByHash return block by block hash. Wrap function of BlockStore.GetBlockByHash
+//The light node only has the blocks fetched from the full nodes.
 func (this *LedgerStoreImp) GetBlockByHash(blockHash common.Uint256) (*types.Block, error) {
-	return this.blockStore.GetBlock(blockHash)
+	block, err := this.blockStore.GetBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if this.lightMode && len(block.Transactions) == 0 && block.Header.TransactionsRoot != common.UINT256_EMPTY {
+		return nil, scom.ErrNotFound
+	}
+	return block, nil
 }
 
 //GetBlockByHeight return block by height.
@@ -1123,34 +1485,140 @@ func (this *LedgerStoreImp) GetBlockByHeight(height uint32) (*types.Block, error
 
 //GetBookkeeperState return the bookkeeper state. Wrap function of StateStore.GetBookkeeperState
 func (this *LedgerStoreImp) GetBookkeeperState() (*states.BookkeeperState, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	return this.stateStore.GetBookkeeperState()
 }
 
 //GetMerkleProof return the block merkle proof. Wrap function of StateStore.GetMerkleProof
 func (this *LedgerStoreImp) GetMerkleProof(proofHeight, rootHeight uint32) ([]common.Uint256, error) {
+	if this.lightMode {
+		return this.blockMerkleTree.InclusionProof(proofHeight, rootHeight+1)
+	}
 	return this.stateStore.GetMerkleProof(proofHeight, rootHeight)
 }
 
 //GetContractState return contract by contract address. Wrap function of StateStore.GetContractState
 func (this *LedgerStoreImp) GetContractState(contractHash common.Address) (*payload.DeployCode, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	return this.stateStore.GetContractState(contractHash)
 }
 
 //GetStorageItem return the storage value of the key in smart contract. Wrap function of StateStore.GetStorageState
 func (this *LedgerStoreImp) GetStorageItem(key *states.StorageKey) (*states.StorageItem, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	return this.stateStore.GetStorageState(key)
 }
 
+//GetContractStateByHeight return contract by contract address at the end of block height. It's only available in archive mode
+func (this *LedgerStoreImp) GetContractStateByHeight(contractHash common.Address, height uint32) (*payload.DeployCode, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	return this.stateStore.GetContractStateByHeight(contractHash, height)
+}
+
+//GetStorageItemByHeight return the storage value of the key at the end of block height. It's only available in archive mode
+func (this *LedgerStoreImp) GetStorageItemByHeight(key *states.StorageKey, height uint32) (*states.StorageItem, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	return this.stateStore.GetStorageStateByHeight(key, height)
+}
+
+//GetStateTrieRoot return the state trie root at the end of block height. Wrap function of StateStore.GetStateTrieRoot
+func (this *LedgerStoreImp) GetStateTrieRoot(height uint32) (common.Uint256, error) {
+	if this.lightMode {
+		return common.UINT256_EMPTY, ErrLightMode
+	}
+	return this.stateStore.GetStateTrieRoot(height)
+}
+
+//GetStorageProof return the storage value at the end of block height with its state trie proof, and the header of
+//block height+1 which commits the state trie root. The proof is not available until the next block is saved.
+func (this *LedgerStoreImp) GetStorageProof(key *states.StorageKey, height uint32) (*store.StorageProof, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	if height+1 < config.GetStateTrieRootHeight() {
+		return nil, ErrStateTrieRootNotCommitted
+	}
+	proof, err := this.stateStore.GetStorageProof(key, height)
+	if err != nil {
+		return nil, err
+	}
+	header, err := this.GetHeaderByHeight(height + 1)
+	if err == scom.ErrNotFound {
+		return nil, fmt.Errorf("state trie root at height %d is not committed yet", height)
+	} else if err != nil {
+		return nil, err
+	}
+	if err = VerifyStorageProof(header, proof); err != nil {
+		return nil, err
+	}
+	proof.Header = header
+	return proof, nil
+}
+
 //GetEventNotifyByTx return the events notify gen by executing of smart contract.  Wrap function of EventStore.GetEventNotifyByTx
 func (this *LedgerStoreImp) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	return this.eventStore.GetEventNotifyByTx(tx)
 }
 
 //GetEventNotifyByBlock return the transaction hash which have event notice after execution of smart contract. Wrap function of EventStore.GetEventNotifyByBlock
 func (this *LedgerStoreImp) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
 	return this.eventStore.GetEventNotifyByBlock(height)
 }
 
+//GetTxsByAddress return the transactions touching addr in block height range [startHeight, endHeight]. Wrap function of EventStore.GetTxsByAddress
+func (this *LedgerStoreImp) GetTxsByAddress(addr common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	return this.eventStore.GetTxsByAddress(addr, startHeight, endHeight, offset, limit)
+}
+
+//GetAddressIndexStartHeight return the first block height whose transactions are indexed by address
+func (this *LedgerStoreImp) GetAddressIndexStartHeight() (uint32, error) {
+	if this.lightMode {
+		return 0, ErrLightMode
+	}
+	if !this.eventStore.IsAddressIndex() {
+		return 0, ErrAddressIndexDisabled
+	}
+	return this.eventStore.GetAddressIndexStartHeight(), nil
+}
+
+//GetTxsByContract return the transactions with event notify of contract in block height range [startHeight, endHeight]. Wrap function of EventStore.GetTxsByContract
+func (this *LedgerStoreImp) GetTxsByContract(contract common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	return this.eventStore.GetTxsByContract(contract, startHeight, endHeight, offset, limit)
+}
+
+//GetContractIndexStartHeight return the first block height whose transactions are indexed by contract
+func (this *LedgerStoreImp) GetContractIndexStartHeight() (uint32, error) {
+	if this.lightMode {
+		return 0, ErrLightMode
+	}
+	if !this.eventStore.IsContractIndex() {
+		return 0, ErrContractIndexDisabled
+	}
+	return this.eventStore.GetContractIndexStartHeight(), nil
+}
+
 //PreExecuteContract return the result of smart contract execution without commit to store
 func (this *LedgerStoreImp) PreExecuteContractBatch(txes []*types.Transaction, atomic bool) ([]*sstate.PreExecResult, uint32, error) {
 	if atomic {
@@ -1173,12 +1641,44 @@ func (this *LedgerStoreImp) PreExecuteContractBatch(txes []*types.Transaction, a
 
 //PreExecuteContract return the result of smart contract execution without commit to store
 func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, preParam PrexecuteParam) (*sstate.PreExecResult, error) {
+	if this.lightMode {
+		return &sstate.PreExecResult{State: event.CONTRACT_STATE_FAIL, Gas: neovm.MIN_TRANSACTION_GAS}, ErrLightMode
+	}
 	height := this.GetCurrentBlockHeight()
 	// use previous block time to make it predictable for easy test
 	blockTime := uint32(time.Now().Unix())
 	if header, err := this.GetHeaderByHeight(height); err == nil {
 		blockTime = header.Timestamp + 1
 	}
+
+	return this.preExecuteContract(tx, preParam, height, blockTime, this.stateStore.NewOverlayDB())
+}
+
+//PreExecuteContractByHeight return the result of smart contract execution on the states at the end of block height.
+//It's only available in archive mode.
+func (this *LedgerStoreImp) PreExecuteContractByHeight(tx *types.Transaction, height uint32) (*sstate.PreExecResult, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	overlay, err := this.stateStore.NewArchivedOverlayDB(height)
+	if err != nil {
+		return nil, err
+	}
+	header, err := this.GetHeaderByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	param := PrexecuteParam{
+		JitMode:    false,
+		WasmFactor: 0,
+		MinGas:     true,
+	}
+
+	return this.preExecuteContract(tx, param, height, header.Timestamp+1, overlay)
+}
+
+func (this *LedgerStoreImp) preExecuteContract(tx *types.Transaction, preParam PrexecuteParam, height, blockTime uint32,
+	overlay *overlaydb.OverlayDB) (*sstate.PreExecResult, error) {
 	stf := &sstate.PreExecResult{State: event.CONTRACT_STATE_FAIL, Gas: neovm.MIN_TRANSACTION_GAS, Result: nil}
 
 	sconfig := &smartcontract.Config{
@@ -1188,7 +1688,6 @@ func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, p
 		BlockHash: this.GetBlockHash(height),
 	}
 
-	overlay := this.stateStore.NewOverlayDB()
 	cache := storage.NewCacheDB(overlay)
 	gasTable := make(map[string]uint64)
 	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
@@ -1206,24 +1705,39 @@ func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, p
 	if tx.TxType == types.InvokeNeo || tx.TxType == types.InvokeWasm {
 		invoke := tx.Payload.(*payload.InvokeCode)
 
+		gasLimit := uint64(math.MaxUint64)
+		if preParam.GasLimit != 0 {
+			gasLimit = preParam.GasLimit
+		}
+		codeLenGasLimit := calcGasByCodeLen(len(invoke.Code), gasTable[neovm.UINT_INVOKE_CODE_LEN_NAME])
+		if gasLimit < codeLenGasLimit {
+			return stf, fmt.Errorf("gasLimit insufficient: need %d actual %d", codeLenGasLimit, gasLimit)
+		}
+
 		sc := smartcontract.SmartContract{
 			Config:       sconfig,
 			Store:        this,
 			CacheDB:      cache,
 			GasTable:     gasTable,
-			Gas:          math.MaxUint64 - calcGasByCodeLen(len(invoke.Code), gasTable[neovm.UINT_INVOKE_CODE_LEN_NAME]),
+			Gas:          gasLimit - codeLenGasLimit,
 			WasmExecStep: config.DEFAULT_WASM_MAX_STEPCOUNT,
 			JitMode:      preParam.JitMode,
 			PreExec:      true,
 		}
+		if preParam.Tracer != nil {
+			sc.SetTracer(preParam.Tracer)
+		}
 		//start the smart contract executive function
 		engine, _ := sc.NewExecuteEngine(invoke.Code, tx.TxType)
 
 		result, err := engine.Invoke()
+		if preParam.Tracer != nil {
+			preParam.Tracer.Finish(err)
+		}
 		if err != nil {
 			return stf, err
 		}
-		gasCost := math.MaxUint64 - sc.Gas
+		gasCost := gasLimit - sc.Gas
 
 		if preParam.MinGas {
 			mixGas := neovm.MIN_TRANSACTION_GAS
@@ -1246,7 +1760,13 @@ func (this *LedgerStoreImp) PreExecuteContractWithParam(tx *types.Transaction, p
 			cv = common.ToHexString(result.([]byte))
 		}
 
-		return &sstate.PreExecResult{State: event.CONTRACT_STATE_SUCCESS, Gas: gasCost, Result: cv, Notify: sc.Notifications}, nil
+		writeSet, err := cache.GetStorageWriteSet()
+		if err != nil {
+			return stf, err
+		}
+
+		return &sstate.PreExecResult{State: event.CONTRACT_STATE_SUCCESS, Gas: gasCost, Result: cv, Notify: sc.Notifications,
+			WriteSet: writeSet}, nil
 	} else if tx.TxType == types.Deploy {
 		deploy := tx.Payload.(*payload.DeployCode)
 
@@ -1283,6 +1803,141 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 	return this.PreExecuteContractWithParam(tx, param)
 }
 
+//TraceTransaction re-executes an invoke transaction on the states before its block, and return the execution trace.
+//It's only available in archive mode.
+func (this *LedgerStoreImp) TraceTransaction(txHash common.Uint256) (*trace.Trace, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	tx, height, err := this.GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if tx.TxType != types.InvokeNeo && tx.TxType != types.InvokeWasm {
+		return nil, fmt.Errorf("transaction %s is not an invoke transaction", txHash.ToHexString())
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("can not trace the transactions in the genesis block")
+	}
+	block, err := this.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := this.stateStore.NewArchivedOverlayDB(height - 1)
+	if err != nil {
+		return nil, err
+	}
+	config := &smartcontract.Config{
+		Time:   block.Header.Timestamp,
+		Height: block.Header.Height,
+		Tx:     &types.Transaction{},
+	}
+	gasTable, err := loadGasTable(config, storage.NewCacheDB(overlay), this)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := storage.NewCacheDB(overlay)
+	for _, t := range block.Transactions {
+		cache.Reset()
+		if t.Hash() != txHash {
+			// replay the transactions before it in the block
+			if _, _, err := this.handleTransaction(overlay, cache, gasTable, block, t); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		tracer := trace.NewTracer()
+		notify := &event.ExecuteNotify{TxHash: txHash, State: event.CONTRACT_STATE_FAIL}
+		_, err := this.stateStore.handleInvokeTransaction(this, overlay, gasTable, cache, t, block, notify, tracer)
+		if overlay.Error() != nil {
+			return nil, fmt.Errorf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
+		}
+		result := tracer.Trace()
+		result.State = notify.State
+		result.GasConsumed = notify.GasConsumed
+		result.Notify = notify.Notify
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("transaction %s not found in block %d", txHash.ToHexString(), height)
+}
+
+//TraceCall pre-executes an invoke transaction on the current states, and return the execution trace.
+func (this *LedgerStoreImp) TraceCall(tx *types.Transaction) (*trace.Trace, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	if tx.TxType != types.InvokeNeo && tx.TxType != types.InvokeWasm {
+		return nil, fmt.Errorf("transaction type %d can not be traced", tx.TxType)
+	}
+	tracer := trace.NewTracer()
+	param := PrexecuteParam{
+		JitMode:    false,
+		WasmFactor: 0,
+		MinGas:     true,
+		Tracer:     tracer,
+	}
+	res, err := this.PreExecuteContractWithParam(tx, param)
+	result := tracer.Trace()
+	result.State = res.State
+	result.GasConsumed = res.Gas
+	result.Result = res.Result
+	result.Notify = res.Notify
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+//EstimateGas return the minimal gas limit for the transaction to be executed successfully on the current states.
+//The gas limit of an invoke transaction is searched by pre-executing it with different gas limits.
+func (this *LedgerStoreImp) EstimateGas(tx *types.Transaction) (uint64, error) {
+	param := PrexecuteParam{
+		JitMode:    false,
+		WasmFactor: 0,
+		MinGas:     true,
+	}
+	// the gas consumed with the transaction gas floor and rounding
+	res, err := this.PreExecuteContractWithParam(tx, param)
+	if err != nil {
+		return 0, err
+	}
+	if tx.TxType != types.InvokeNeo && tx.TxType != types.InvokeWasm {
+		return res.Gas, nil
+	}
+
+	execute := func(gasLimit uint64) bool {
+		param.GasLimit = gasLimit
+		_, err := this.PreExecuteContractWithParam(tx, param)
+		return err == nil
+	}
+	// the gas is checked before consumed, so the execution may need more gas limit than the gas consumed
+	lo, hi := uint64(0), res.Gas
+	for !execute(hi) {
+		lo = hi
+		if hi > math.MaxUint64/2 {
+			hi = math.MaxUint64
+			break
+		}
+		hi *= 2
+	}
+	if lo == 0 {
+		return hi, nil
+	}
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		if execute(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
 //Close ledger store.
 func (this *LedgerStoreImp) Close() error {
 	// wait block saving complete, and get the lock to avoid subsequent block saving
@@ -1295,6 +1950,12 @@ func (this *LedgerStoreImp) Close() error {
 	if err != nil {
 		return fmt.Errorf("blockStore close error %s", err)
 	}
+	if this.lightMode {
+		if this.merkleHashStore != nil {
+			this.merkleHashStore.Close()
+		}
+		return nil
+	}
 	err = this.eventStore.Close()
 	if err != nil {
 		return fmt.Errorf("eventStore close error %s", err)
@@ -1318,6 +1979,13 @@ func (this *LedgerStoreImp) EnableBlockPrune(numBeforeCurr uint32) {
 	this.preserveBlockHistoryLength = numBeforeCurr
 }
 
+func (this *LedgerStoreImp) DisableBlockPrune() {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+
+	this.preserveBlockHistoryLength = 0
+}
+
 func (this *LedgerStoreImp) maxAllowedPruneHeight(currHeader *types.Header) uint32 {
 	if currHeader.Height <= config.GetContractApiDeprecateHeight() {
 		return 0
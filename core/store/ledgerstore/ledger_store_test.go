@@ -25,9 +25,18 @@ import (
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/core/genesis"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/core/utils"
+	"github.com/ontio/ontology/errors"
+	"github.com/ontio/ontology/smartcontract/event"
+	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
 )
 
 var testBlockStore *BlockStore
@@ -138,3 +147,140 @@ func TestInitLedgerStoreWithGenesisBlock(t *testing.T) {
 		return
 	}
 }
+
+//newTestLedger init a ledger with genesis block in dir, whose only bookkeeper is the returned account
+func newTestLedger(t *testing.T, dir string) (*LedgerStoreImp, *account.Account, *types.Block) {
+	ledger, err := NewLedgerStore(dir, 0)
+	assert.Nil(t, err)
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	assert.Nil(t, ledger.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers))
+	return ledger, acc, genesisBlock
+}
+
+//newTestBlock build the next block of ledger with a transfer transaction
+func newTestBlock(t *testing.T, ledger *LedgerStoreImp, from *account.Account) *types.Block {
+	height, prevHash := ledger.GetCurrentBlock()
+	prev, err := ledger.GetHeaderByHash(prevHash)
+	assert.Nil(t, err)
+	tx, err := transferTx(from.Address, common.Address{1}, uint64(height+1))
+	assert.Nil(t, err)
+	block := &types.Block{
+		Header: &types.Header{
+			PrevBlockHash: prevHash,
+			Timestamp:     prev.Timestamp + 1,
+			Height:        height + 1,
+		},
+		Transactions: []*types.Transaction{tx},
+	}
+	block.RebuildMerkleRoot()
+	block.Header.BlockRoot = ledger.GetBlockRootWithNewTxRoots(height+1, []common.Uint256{block.Header.TransactionsRoot})
+	return block
+}
+
+func submitTestBlock(t *testing.T, ledger *LedgerStoreImp, block *types.Block) {
+	result, err := ledger.executeBlock(block)
+	assert.Nil(t, err)
+	assert.Nil(t, ledger.submitBlock(block, nil, result))
+}
+
+func TestRollbackTo(t *testing.T) {
+	ledger, acc, genesisBlock := newTestLedger(t, "test/rollback")
+	defer ledger.Close()
+	assert.Nil(t, ledger.stateStore.EnableUndo(100))
+
+	blocks := make([]*types.Block, 0)
+	for i := 0; i < 3; i++ {
+		block := newTestBlock(t, ledger, acc)
+		submitTestBlock(t, ledger, block)
+		blocks = append(blocks, block)
+	}
+	stateRoots := func() []common.Uint256 {
+		roots := make([]common.Uint256, 0)
+		for _, block := range blocks {
+			root, err := ledger.GetStateMerkleRoot(block.Header.Height)
+			assert.Nil(t, err)
+			roots = append(roots, root)
+		}
+		return roots
+	}
+	roots := stateRoots()
+	assert.Equal(t, uint32(3), ledger.GetCurrentBlockHeight())
+
+	assert.NotNil(t, ledger.RollbackTo(3))
+	assert.Nil(t, ledger.RollbackTo(0))
+	assert.Equal(t, uint32(0), ledger.GetCurrentBlockHeight())
+	assert.Equal(t, uint32(0), ledger.GetCurrentHeaderHeight())
+	assert.Equal(t, genesisBlock.Hash(), ledger.GetCurrentBlockHash())
+	_, stateHeight, err := ledger.stateStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), stateHeight)
+	_, eventHeight, err := ledger.eventStore.GetCurrentBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), eventHeight)
+	for _, block := range blocks {
+		exist, err := ledger.IsContainBlock(block.Hash())
+		assert.Nil(t, err)
+		assert.False(t, exist)
+		exist, err = ledger.IsContainTransaction(block.Transactions[0].Hash())
+		assert.Nil(t, err)
+		assert.False(t, exist)
+		notify, _ := ledger.GetEventNotifyByTx(block.Transactions[0].Hash())
+		assert.Nil(t, notify)
+	}
+
+	// the same blocks can be submitted again, with the same block and state roots
+	for _, block := range blocks {
+		submitTestBlock(t, ledger, block)
+	}
+	assert.Equal(t, roots, stateRoots())
+}
+
+func TestBlockGasConsumed(t *testing.T) {
+	nativeCode, err := utils.BuildNativeInvokeCode(nutils.OntContractAddress, 0, "name", []interface{}{""})
+	assert.Nil(t, err)
+	newTx := func(txType types.TransactionType, pl types.Payload) *types.Transaction {
+		return &types.Transaction{TxType: txType, Payload: pl}
+	}
+	block := &types.Block{
+		Header: &types.Header{},
+		Transactions: []*types.Transaction{
+			newTx(types.InvokeNeo, &payload.InvokeCode{Code: []byte{0x51}}),
+			newTx(types.InvokeNeo, &payload.InvokeCode{Code: nativeCode}),
+			newTx(types.InvokeWasm, &payload.InvokeCode{Code: []byte{0x00}}),
+			newTx(types.Deploy, &payload.DeployCode{}),
+		},
+	}
+	notifies := []*event.ExecuteNotify{{GasConsumed: 1}, {GasConsumed: 10}, {GasConsumed: 100}, {GasConsumed: 1000}}
+	gas := blockGasConsumed(block, notifies)
+	assert.Equal(t, uint64(1001), gas[metrics.VM_NEOVM])
+	assert.Equal(t, uint64(10), gas[metrics.VM_NATIVE])
+	assert.Equal(t, uint64(100), gas[metrics.VM_WASMVM])
+}
+
+func TestCheckValidityWindow(t *testing.T) {
+	networkId, windowHeight := config.DefConfig.P2PNode.NetworkId, config.DefConfig.Genesis.TxValidityWindowHeight
+	defer func() {
+		config.DefConfig.P2PNode.NetworkId, config.DefConfig.Genesis.TxValidityWindowHeight = networkId, windowHeight
+	}()
+	config.DefConfig.P2PNode.NetworkId = config.NETWORK_ID_SOLO_NET
+	config.DefConfig.Genesis.TxValidityWindowHeight = 100
+
+	mutable := &types.MutableTransaction{
+		TxType:  types.InvokeNeo,
+		Payload: &payload.InvokeCode{Code: []byte("window")},
+	}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	assert.Equal(t, errors.ErrNoError, CheckValidityWindow(tx, 0))
+
+	mutable.SetValidityWindow(150, 200)
+	tx, err = mutable.IntoImmutable()
+	assert.Nil(t, err)
+	assert.Equal(t, errors.ErrTxAttribute, CheckValidityWindow(tx, 99))
+	assert.Equal(t, errors.ErrTxNotValidYet, CheckValidityWindow(tx, 149))
+	assert.Equal(t, errors.ErrNoError, CheckValidityWindow(tx, 200))
+	assert.Equal(t, errors.ErrTxExpired, CheckValidityWindow(tx, 201))
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/events"
+	"github.com/ontio/ontology/events/message"
+	"github.com/ontio/ontology/merkle"
+)
+
+//DBDirLight is the store path of the light node
+var DBDirLight = "light"
+
+//ErrLightMode is returned by the functions relying on the states, which are not kept by the light node
+var ErrLightMode = errors.New("not supported by light node")
+
+//NewLightLedgerStore return the ledger store of the light node. It only keeps the verified headers and the merkle
+//tree of block roots, the blocks are saved when they are fetched from the full nodes. The states are never executed.
+func NewLightLedgerStore(dataDir string) (*LedgerStoreImp, error) {
+	ledgerStore := &LedgerStoreImp{
+		headerIndex:          make(map[uint32]common.Uint256),
+		headerCache:          make(map[common.Uint256]*types.Header, 0),
+		vbftPeerInfoheader:   make(map[string]uint32),
+		vbftPeerInfoblock:    make(map[string]uint32),
+		savingBlockSemaphore: make(chan bool, 1),
+		lightMode:            true,
+	}
+
+	lightDir := fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirLight)
+	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", lightDir, string(os.PathSeparator), DBDirBlock), true)
+	if err != nil {
+		return nil, fmt.Errorf("NewBlockStore error %s", err)
+	}
+	ledgerStore.blockStore = blockStore
+
+	treeSize, hashes, err := blockStore.GetBlockMerkleTree()
+	if err != nil && err != scom.ErrNotFound {
+		return nil, fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	merklePath := fmt.Sprintf("%s%s%s", lightDir, string(os.PathSeparator), MerkleTreeStorePath)
+	ledgerStore.merkleHashStore, err = merkle.NewFileHashStore(merklePath, treeSize)
+	if err != nil {
+		log.Warn("merkle store is inconsistent with ChainStore. persistence will be disabled")
+	}
+	ledgerStore.blockMerkleTree = merkle.NewTree(treeSize, hashes, ledgerStore.merkleHashStore)
+	return ledgerStore, nil
+}
+
+//initLightStore init the light ledger store with genesis block
+func (this *LedgerStoreImp) initLightStore(genesisBlock *types.Block) error {
+	hasInit, err := this.hasAlreadyInitGenesisBlock()
+	if err != nil {
+		return fmt.Errorf("hasAlreadyInit error %s", err)
+	}
+	if !hasInit {
+		err = this.blockStore.ClearAll()
+		if err != nil {
+			return fmt.Errorf("blockStore.ClearAll error %s", err)
+		}
+		this.getSavingBlockLock()
+		err = this.saveLightBlock(genesisBlock)
+		this.releaseSavingBlockLock()
+		if err != nil {
+			return fmt.Errorf("save genesis block error %s", err)
+		}
+		err = this.initGenesisBlock()
+		if err != nil {
+			return fmt.Errorf("init error %s", err)
+		}
+		genHash := genesisBlock.Hash()
+		log.Infof("GenesisBlock init success. GenesisBlock hash:%s\n", genHash.ToHexString())
+	} else {
+		genesisHash := genesisBlock.Hash()
+		exist, err := this.blockStore.ContainBlock(genesisHash)
+		if err != nil {
+			return fmt.Errorf("HashBlockExist error %s", err)
+		}
+		if !exist {
+			return fmt.Errorf("GenesisBlock arenot init correctly")
+		}
+		err = this.loadCurrentBlock()
+		if err != nil {
+			return fmt.Errorf("loadCurrentBlock error %s", err)
+		}
+		err = this.loadHeaderIndexList()
+		if err != nil {
+			return fmt.Errorf("loadHeaderIndexList error %s", err)
+		}
+		if treeSize := this.blockMerkleTree.TreeSize(); treeSize != this.GetCurrentBlockHeight()+1 {
+			return fmt.Errorf("merkle tree size %d is inconsistent with blockheight: %d", treeSize,
+				this.GetCurrentBlockHeight()+1)
+		}
+	}
+	return this.loadVbftPeerInfo()
+}
+
+//addLightHeaders verify the headers and save them one by one. The headers saved are kept even if a later one
+//fails the verification.
+func (this *LedgerStoreImp) addLightHeaders(headers []*types.Header) error {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	for _, header := range headers {
+		if header.Height <= this.GetCurrentBlockHeight() {
+			continue
+		}
+		err := this.saveLightBlock(&types.Block{Header: header})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//addLightBlock save the block of the light node. The block following the current one is verified like a header, and
+//the transactions of an earlier block are saved if the block is the one in the header index.
+func (this *LedgerStoreImp) addLightBlock(block *types.Block) error {
+	if err := verifyTransactionsRoot(block); err != nil {
+		return err
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+
+	blockHeight := block.Header.Height
+	nextBlockHeight := this.GetCurrentBlockHeight() + 1
+	if blockHeight == nextBlockHeight {
+		return this.saveLightBlock(block)
+	}
+	if blockHeight > nextBlockHeight {
+		return invalidBlockErrorf("block height %d not equal next block height %d", blockHeight, nextBlockHeight)
+	}
+	blockHash := block.Hash()
+	if this.GetBlockHash(blockHeight) != blockHash {
+		return invalidBlockErrorf("block hash %s is not the one at height %d", blockHash.ToHexString(), blockHeight)
+	}
+	this.blockStore.NewBatch()
+	err := this.blockStore.SaveBlock(block)
+	if err != nil {
+		return fmt.Errorf("SaveBlock height %d hash %s error %s", blockHeight, blockHash.ToHexString(), err)
+	}
+	return this.blockStore.CommitTo()
+}
+
+//saveLightBlock verify the header of the block following the current one, and save it as the current block.
+//The caller should hold the saving block lock.
+func (this *LedgerStoreImp) saveLightBlock(block *types.Block) error {
+	header := block.Header
+	blockHash := block.Hash()
+	vbftPeerInfo, err := this.verifyHeader(header, this.vbftPeerInfoheader)
+	if err != nil {
+		return invalidBlockErrorf("verifyHeader error %s", err)
+	}
+	blockRoot := this.blockMerkleTree.GetRootWithNewLeaf(header.TransactionsRoot)
+	if header.Height != 0 && blockRoot != header.BlockRoot {
+		return invalidBlockErrorf("wrong block root at height:%d, expected:%s, got:%s",
+			header.Height, blockRoot.ToHexString(), header.BlockRoot.ToHexString())
+	}
+
+	this.blockStore.NewBatch()
+	this.setHeaderIndex(header.Height, blockHash)
+	err = this.saveHeaderIndexList()
+	if err != nil {
+		return fmt.Errorf("saveHeaderIndexList error %s", err)
+	}
+	err = this.blockStore.SaveCurrentBlock(header.Height, blockHash)
+	if err != nil {
+		return fmt.Errorf("SaveCurrentBlock error %s", err)
+	}
+	this.blockStore.SaveBlockHash(header.Height, blockHash)
+	if len(block.Transactions) == 0 {
+		err = this.blockStore.SaveHeader(block, 0)
+	} else {
+		err = this.blockStore.SaveBlock(block)
+	}
+	if err != nil {
+		return fmt.Errorf("SaveBlock height %d hash %s error %s", header.Height, blockHash.ToHexString(), err)
+	}
+	this.blockMerkleTree.AppendHash(header.TransactionsRoot)
+	this.blockStore.SaveBlockMerkleTree(this.blockMerkleTree.TreeSize(), this.blockMerkleTree.Hashes())
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo height:%d error %s", header.Height, err)
+	}
+	this.setCurrentBlock(header.Height, blockHash)
+	this.vbftPeerInfoheader = vbftPeerInfo
+	this.vbftPeerInfoblock = vbftPeerInfo
+
+	if events.DefActorPublisher != nil {
+		events.DefActorPublisher.Publish(
+			message.TOPIC_SAVE_BLOCK_COMPLETE,
+			&message.SaveBlockCompleteMsg{
+				Block: block,
+			})
+	}
+	return nil
+}
+
+//verifyTransactionsRoot check the transactions of the block match the transactions root in header
+func verifyTransactionsRoot(block *types.Block) error {
+	hashes := make([]common.Uint256, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		hashes = append(hashes, tx.Hash())
+	}
+	if root := common.ComputeMerkleRoot(hashes); root != block.Header.TransactionsRoot {
+		return invalidBlockErrorf("wrong transactions root at height:%d, expected:%s, got:%s", block.Header.Height,
+			root.ToHexString(), block.Header.TransactionsRoot.ToHexString())
+	}
+	return nil
+}
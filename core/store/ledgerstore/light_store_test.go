@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/core/signature"
+	"github.com/ontio/ontology/core/states"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+//signTestBlock sign the block by the only bookkeeper acc, which is also the next bookkeeper
+func signTestBlock(t *testing.T, block *types.Block, acc *account.Account) {
+	bookkeeper, err := types.AddressFromBookkeepers([]keypair.PublicKey{acc.PublicKey})
+	assert.Nil(t, err)
+	block.Header.NextBookkeeper = bookkeeper
+	hash := block.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	assert.Nil(t, err)
+	block.Header.Bookkeepers = []keypair.PublicKey{acc.PublicKey}
+	block.Header.SigData = [][]byte{sig}
+}
+
+func TestLightLedgerStore(t *testing.T) {
+	ledger, acc, genesisBlock := newTestLedger(t, "test/light_full")
+	defer ledger.Close()
+	// the headers are verified against the bookkeepers instead of the vbft peers
+	consensusType := config.DefConfig.Genesis.ConsensusType
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SOLO
+	defer func() { config.DefConfig.Genesis.ConsensusType = consensusType }()
+
+	blocks := make([]*types.Block, 0)
+	headers := make([]*types.Header, 0)
+	for i := 0; i < 3; i++ {
+		block := newTestBlock(t, ledger, acc)
+		signTestBlock(t, block, acc)
+		submitTestBlock(t, ledger, block)
+		blocks = append(blocks, block)
+		headers = append(headers, block.Header)
+	}
+
+	light, err := NewLightLedgerStore("test/light")
+	assert.Nil(t, err)
+	defer light.Close()
+	assert.Nil(t, light.InitLedgerStoreWithGenesisBlock(genesisBlock, []keypair.PublicKey{acc.PublicKey}))
+
+	// the header signed by others is rejected
+	forged := *headers[0]
+	signTestBlock(t, &types.Block{Header: &forged}, account.NewAccount(""))
+	assert.NotNil(t, light.AddHeaders([]*types.Header{&forged}))
+	assert.Equal(t, uint32(0), light.GetCurrentBlockHeight())
+
+	assert.Nil(t, light.AddHeaders(headers))
+	assert.Equal(t, uint32(3), light.GetCurrentBlockHeight())
+	assert.Equal(t, uint32(3), light.GetCurrentHeaderHeight())
+	assert.Equal(t, ledger.GetCurrentBlockHash(), light.GetCurrentBlockHash())
+
+	// the block bodies are not kept until they are fetched
+	_, err = light.GetBlockByHash(blocks[0].Hash())
+	assert.Equal(t, scom.ErrNotFound, err)
+	tampered := &types.Block{Header: blocks[0].Header, Transactions: blocks[1].Transactions}
+	assert.NotNil(t, light.AddBlock(tampered, nil, common.UINT256_EMPTY))
+	assert.Nil(t, light.AddBlock(blocks[0], nil, common.UINT256_EMPTY))
+	block, err := light.GetBlockByHash(blocks[0].Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, blocks[0].Hash(), block.Hash())
+	tx, height, err := light.GetTransaction(blocks[0].Transactions[0].Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), height)
+	assert.Equal(t, blocks[0].Transactions[0].Hash(), tx.Hash())
+
+	// the block proof is the same as the one of the full node
+	expected, err := ledger.GetMerkleProof(1, 3)
+	assert.Nil(t, err)
+	proof, err := light.GetMerkleProof(1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, proof)
+
+	_, err = light.GetStorageItem(&states.StorageKey{})
+	assert.Equal(t, ErrLightMode, err)
+}
@@ -0,0 +1,516 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/serialization"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/store"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+)
+
+const (
+	snapshotBatchSize   = 10000 //Number of entries written to db in one batch while restoring
+	snapshotStateRecord = byte(1)
+	snapshotStateEnd    = byte(0)
+)
+
+//isSnapshotStateKey return whether the state store key is part of a snapshot.
+//Archived states, state trie and undo data are local to a node and not captured.
+func isSnapshotStateKey(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	switch scom.DataEntryPrefix(key[0]) {
+	case scom.ST_ARCHIVE, scom.SYS_ARCHIVE_START_HEIGHT, scom.ST_STATE_TRIE, scom.SYS_STATE_TRIE_ROOT, scom.SYS_STATE_UNDO,
+		scom.SYS_SNAPSHOT_STATE_ROOT:
+		return false
+	}
+	return true
+}
+
+func (self *StateStore) genSnapshotStateRootKey() []byte {
+	return []byte{byte(scom.SYS_SNAPSHOT_STATE_ROOT)}
+}
+
+//saveSnapshotStateRoot save the state trie root of the states restored from the snapshot at block height,
+//which is checked with the root committed in the header of the next block
+func (self *StateStore) saveSnapshotStateRoot(height uint32, root common.Uint256) error {
+	value := make([]byte, 4+common.UINT256_SIZE)
+	binary.LittleEndian.PutUint32(value, height)
+	copy(value[4:], root[:])
+	return self.store.Put(self.genSnapshotStateRootKey(), value)
+}
+
+//getSnapshotStateRoot return the height and state trie root of the restored snapshot which is not checked yet
+func (self *StateStore) getSnapshotStateRoot() (uint32, common.Uint256, error) {
+	value, err := self.store.Get(self.genSnapshotStateRootKey())
+	if err != nil {
+		return 0, common.UINT256_EMPTY, err
+	}
+	if len(value) != 4+common.UINT256_SIZE {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("invalid snapshot state root %x", value)
+	}
+	root, err := common.Uint256ParseFromBytes(value[4:])
+	return binary.LittleEndian.Uint32(value), root, err
+}
+
+//clearSnapshotStateRoot drop the state trie root of the restored snapshot once the next block is saved to batch
+func (self *StateStore) clearSnapshotStateRoot(height uint32) error {
+	snapshotHeight, _, err := self.getSnapshotStateRoot()
+	if err == scom.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if height > snapshotHeight {
+		self.store.BatchDelete(self.genSnapshotStateRootKey())
+	}
+	return nil
+}
+
+func writeSnapshotState(w io.Writer, key, value []byte) error {
+	if err := serialization.WriteByte(w, snapshotStateRecord); err != nil {
+		return err
+	}
+	if err := serialization.WriteVarBytes(w, key); err != nil {
+		return err
+	}
+	return serialization.WriteVarBytes(w, value)
+}
+
+//newSnapshot return a read only snapshot of the states, which should be released after use
+func (self *StateStore) newSnapshot() (scom.StoreSnapshot, error) {
+	db, ok := self.undo.PersistStore.(*leveldbstore.LevelDBStore)
+	if !ok {
+		return nil, fmt.Errorf("state store does not support snapshot")
+	}
+	return db.NewSnapshot()
+}
+
+//snapshotCurrentBlock return the snapshot metadata of current block, along with the db snapshots of block store and
+//state store at current block. The saving block lock is only held while the db snapshots are taken.
+func (this *LedgerStoreImp) snapshotCurrentBlock() (*store.SnapshotMetadata, scom.StoreSnapshot, scom.StoreSnapshot, error) {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	height, blockHash := this.GetCurrentBlock()
+	stateHash, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if stateHeight != height || stateHash != blockHash {
+		return nil, nil, nil, fmt.Errorf("state store at height %d is inconsistent with block height %d", stateHeight, height)
+	}
+	metadata := &store.SnapshotMetadata{
+		Version:     store.SNAPSHOT_VERSION,
+		GenesisHash: this.GetBlockHash(0),
+		Height:      height,
+		BlockHash:   blockHash,
+	}
+	metadata.StateMerkleRoot, err = this.stateStore.GetStateMerkleRoot(height)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("GetStateMerkleRoot error %s", err)
+	}
+	metadata.BlockTreeSize, metadata.BlockTreeHashes, err = this.stateStore.GetBlockMerkleTree()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	blockSnapshot, err := this.blockStore.store.NewSnapshot()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("snapshot block store error %s", err)
+	}
+	stateSnapshot, err := this.stateStore.newSnapshot()
+	if err != nil {
+		blockSnapshot.Release()
+		return nil, nil, nil, fmt.Errorf("snapshot state store error %s", err)
+	}
+	return metadata, blockSnapshot, stateSnapshot, nil
+}
+
+//CreateSnapshot write the snapshot of current block to w. The blocks can still be saved while the snapshot is
+//written, since it's read from the db snapshots taken at current block.
+func (this *LedgerStoreImp) CreateSnapshot(w io.Writer) error {
+	if this.lightMode {
+		return ErrLightMode
+	}
+	metadata, blockSnapshot, stateSnapshot, err := this.snapshotCurrentBlock()
+	if err != nil {
+		return err
+	}
+	defer blockSnapshot.Release()
+	defer stateSnapshot.Release()
+	if err = metadata.Serialize(w); err != nil {
+		return err
+	}
+
+	height := metadata.Height
+	for i := uint32(0); i <= height; i++ {
+		hash := this.GetBlockHash(i)
+		data, err := blockSnapshot.Get(genHeaderKey(hash))
+		if err != nil {
+			return fmt.Errorf("load header of height %d error %s", i, err)
+		}
+		if err = serialization.WriteVarBytes(w, data); err != nil {
+			return err
+		}
+	}
+
+	hasher := sha256.New()
+	count := uint64(0)
+	iter := stateSnapshot.NewIterator(nil)
+	for iter.Next() {
+		if !isSnapshotStateKey(iter.Key()) {
+			continue
+		}
+		if err = writeSnapshotState(w, iter.Key(), iter.Value()); err != nil {
+			break
+		}
+		writeSnapshotState(hasher, iter.Key(), iter.Value())
+		count++
+	}
+	iter.Release()
+	if err == nil {
+		err = iter.Error()
+	}
+	if err != nil {
+		return err
+	}
+	if err = serialization.WriteByte(w, snapshotStateEnd); err != nil {
+		return err
+	}
+	if err = serialization.WriteUint64(w, count); err != nil {
+		return err
+	}
+	var digest common.Uint256
+	hasher.Sum(digest[:0])
+	if err = digest.Serialize(w); err != nil {
+		return err
+	}
+	log.Infof("snapshot of block height %d created with %d states", height, count)
+	return nil
+}
+
+//RestoreSnapshot init an empty ledger with the snapshot read from r. The restored ledger has no transactions and
+//events before the snapshot height, except the genesis block. The headers are verified with their signatures.
+//If the vbft block headers commit the state trie root, the state trie root of the restored states is checked with
+//the header of the next block when it is saved. Otherwise the restored states can only be checked with the
+//digest and the state merkle root carried by the snapshot itself, so the snapshot must come from a trusted source,
+//and trusted is required.
+func (this *LedgerStoreImp) RestoreSnapshot(r io.Reader, genesisBlock *types.Block, trusted bool) (*store.SnapshotMetadata, error) {
+	if this.lightMode {
+		return nil, ErrLightMode
+	}
+	hasInit, err := this.hasAlreadyInitGenesisBlock()
+	if err != nil {
+		return nil, err
+	}
+	if hasInit {
+		return nil, fmt.Errorf("ledger has already been initialized")
+	}
+	metadata := &store.SnapshotMetadata{}
+	if err = metadata.Deserialize(r); err != nil {
+		return nil, fmt.Errorf("read snapshot metadata error %s", err)
+	}
+	genesisHash := genesisBlock.Hash()
+	if metadata.GenesisHash != genesisHash {
+		return nil, fmt.Errorf("genesis block hash of snapshot %s mismatch with %s",
+			metadata.GenesisHash.ToHexString(), genesisHash.ToHexString())
+	}
+	committed := isVbft() && metadata.Height+1 >= config.GetStateTrieRootHeight()
+	if !committed && !trusted {
+		return nil, fmt.Errorf("states at height %d are not committed in block headers and can not be verified, "+
+			"the snapshot must be trusted", metadata.Height)
+	}
+
+	if err = this.blockStore.ClearAll(); err != nil {
+		return nil, fmt.Errorf("blockStore.ClearAll error %s", err)
+	}
+	if err = this.stateStore.ClearAll(); err != nil {
+		return nil, fmt.Errorf("stateStore.ClearAll error %s", err)
+	}
+	if err = this.eventStore.ClearAll(); err != nil {
+		return nil, fmt.Errorf("eventStore.ClearAll error %s", err)
+	}
+	if err = this.restoreSnapshotHeaders(r, metadata, genesisBlock); err != nil {
+		return nil, err
+	}
+	if err = this.restoreSnapshotStates(r); err != nil {
+		return nil, err
+	}
+
+	// the restored states should be at the end of the snapshot height, and consistent with the metadata
+	stateStore := this.stateStore
+	stateHash, stateHeight, err := stateStore.GetCurrentBlock()
+	if err != nil {
+		return nil, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if stateHeight != metadata.Height || stateHash != metadata.BlockHash {
+		return nil, fmt.Errorf("snapshot states are at block %d, not %d", stateHeight, metadata.Height)
+	}
+	stateRoot, err := stateStore.GetStateMerkleRoot(metadata.Height)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateMerkleRoot error %s", err)
+	}
+	if stateRoot != metadata.StateMerkleRoot {
+		return nil, fmt.Errorf("state merkle root %s mismatch with snapshot %s",
+			stateRoot.ToHexString(), metadata.StateMerkleRoot.ToHexString())
+	}
+	if metadata.Height >= stateStore.stateHashCheckHeight {
+		treeSize, hashes, err := stateStore.GetStateMerkleTree()
+		if err != nil {
+			return nil, fmt.Errorf("GetStateMerkleTree error %s", err)
+		}
+		deltaMerkleTree := merkle.NewTree(treeSize, hashes, nil)
+		if deltaMerkleTree.Root() != stateRoot {
+			return nil, fmt.Errorf("state merkle tree is inconsistent with state merkle root %s", stateRoot.ToHexString())
+		}
+		stateStore.deltaMerkleTree = deltaMerkleTree
+	}
+	treeSize, hashes, err := stateStore.GetBlockMerkleTree()
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	if treeSize != metadata.BlockTreeSize || !hashesEqual(hashes, metadata.BlockTreeHashes) {
+		return nil, fmt.Errorf("block merkle tree of states is inconsistent with snapshot")
+	}
+	if stateStore.archive {
+		if err = stateStore.saveArchiveStartHeight(metadata.Height + 1); err != nil {
+			return nil, err
+		}
+	}
+	if stateStore.stateProof || committed {
+		if err = stateStore.EnableStateProof(); err != nil {
+			return nil, fmt.Errorf("EnableStateProof error %s", err)
+		}
+	}
+	if committed {
+		if err = stateStore.saveSnapshotStateRoot(metadata.Height, stateStore.stateTrieRoot); err != nil {
+			return nil, err
+		}
+	}
+
+	if this.eventStore.IsAddressIndex() {
+		if err = this.eventStore.SaveAddressIndexStartHeight(metadata.Height + 1); err != nil {
+			return nil, err
+		}
+	}
+	if this.eventStore.IsContractIndex() {
+		if err = this.eventStore.SaveContractIndexStartHeight(metadata.Height + 1); err != nil {
+			return nil, err
+		}
+	}
+	this.eventStore.NewBatch()
+	this.eventStore.SaveCurrentBlock(metadata.Height, metadata.BlockHash)
+	if err = this.eventStore.CommitTo(); err != nil {
+		return nil, fmt.Errorf("eventStore.CommitTo error %s", err)
+	}
+	this.blockStore.NewBatch()
+	if err = this.blockStore.SaveCurrentBlock(metadata.Height, metadata.BlockHash); err != nil {
+		return nil, err
+	}
+	if err = this.blockStore.CommitTo(); err != nil {
+		return nil, fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	// the ledger is regarded as initialized only after everything is restored
+	if err = this.initGenesisBlock(); err != nil {
+		return nil, fmt.Errorf("init error %s", err)
+	}
+	log.Infof("snapshot of block height %d restored", metadata.Height)
+	return metadata, nil
+}
+
+//restoreSnapshotHeaders save the headers to block store, and rebuild the block merkle tree along with its hash store
+func (this *LedgerStoreImp) restoreSnapshotHeaders(r io.Reader, metadata *store.SnapshotMetadata, genesisBlock *types.Block) error {
+	stateStore := this.stateStore
+	if stateStore.merkleHashStore != nil {
+		stateStore.merkleHashStore.Close()
+		stateStore.merkleHashStore = nil
+	}
+	if stateStore.merklePath != "" {
+		if err := merkle.TruncateFileHashStore(stateStore.merklePath, 0); err != nil {
+			return err
+		}
+	}
+	if err := stateStore.init(0); err != nil {
+		return fmt.Errorf("stateStore.init error %s", err)
+	}
+	tree := stateStore.merkleTree
+
+	var peerInfo map[string]uint32
+	if isVbft() {
+		info, err := vconfig.VbftBlock(genesisBlock.Header)
+		if err != nil {
+			return err
+		}
+		if info.NewChainConfig == nil {
+			return fmt.Errorf("no chain config in genesis block")
+		}
+		peerInfo = make(map[string]uint32)
+		for _, p := range info.NewChainConfig.Peers {
+			peerInfo[p.ID] = p.Index
+		}
+	}
+
+	this.blockStore.NewBatch()
+	indexList := make([]common.Uint256, 0, HEADER_INDEX_BATCH_SIZE)
+	prevHash := common.UINT256_EMPTY
+	prevHeader := genesisBlock.Header
+	for height := uint32(0); height <= metadata.Height; height++ {
+		data, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("read header of height %d error %s", height, err)
+		}
+		header, _, err := deserializeHeaderWithTx(data)
+		if err != nil {
+			return fmt.Errorf("deserialize header of height %d error %s", height, err)
+		}
+		hash := header.Hash()
+		if header.Height != height || header.PrevBlockHash != prevHash {
+			return fmt.Errorf("header %s is not linked at height %d", hash.ToHexString(), height)
+		}
+		if height == 0 {
+			if hash != genesisBlock.Hash() {
+				return fmt.Errorf("genesis block hash mismatch")
+			}
+			if err = this.blockStore.SaveBlock(genesisBlock); err != nil {
+				return err
+			}
+		} else {
+			peerInfo, err = verifyHeaderWithPrev(header, prevHeader, peerInfo)
+			if err != nil {
+				return fmt.Errorf("verify header of height %d error %s", height, err)
+			}
+			blockRoot := tree.GetRootWithNewLeaves([]common.Uint256{header.TransactionsRoot})
+			if blockRoot != header.BlockRoot {
+				return fmt.Errorf("wrong block root at height:%d, expected:%s, got:%s",
+					height, blockRoot.ToHexString(), header.BlockRoot.ToHexString())
+			}
+			this.blockStore.putHeaderData(hash, data)
+		}
+		tree.AppendHash(header.TransactionsRoot)
+		this.blockStore.SaveBlockHash(height, hash)
+		indexList = append(indexList, hash)
+		if uint32(len(indexList)) == HEADER_INDEX_BATCH_SIZE && height < metadata.Height {
+			this.blockStore.SaveHeaderIndexList(height+1-HEADER_INDEX_BATCH_SIZE, indexList)
+			indexList = make([]common.Uint256, 0, HEADER_INDEX_BATCH_SIZE)
+		}
+		if (height+1)%snapshotBatchSize == 0 {
+			if err = this.blockStore.CommitTo(); err != nil {
+				return fmt.Errorf("blockStore.CommitTo error %s", err)
+			}
+			this.blockStore.NewBatch()
+		}
+		prevHash = hash
+		prevHeader = header
+	}
+	if prevHash != metadata.BlockHash {
+		return fmt.Errorf("block hash of height %d mismatch with snapshot", metadata.Height)
+	}
+	if tree.TreeSize() != metadata.BlockTreeSize || !hashesEqual(tree.Hashes(), metadata.BlockTreeHashes) {
+		return fmt.Errorf("block merkle tree rebuilt from headers is inconsistent with snapshot")
+	}
+	if stateStore.merkleHashStore != nil {
+		if err := stateStore.merkleHashStore.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := this.blockStore.CommitTo(); err != nil {
+		return fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	return nil
+}
+
+//restoreSnapshotStates save the state entries to state store, and check them with the trailer
+func (this *LedgerStoreImp) restoreSnapshotStates(r io.Reader) error {
+	db := this.stateStore.store
+	hasher := sha256.New()
+	count := uint64(0)
+	db.NewBatch()
+	for {
+		flag, err := serialization.ReadByte(r)
+		if err != nil {
+			return fmt.Errorf("read state error %s", err)
+		}
+		if flag == snapshotStateEnd {
+			break
+		} else if flag != snapshotStateRecord {
+			return fmt.Errorf("invalid state record flag %d", flag)
+		}
+		key, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("read state key error %s", err)
+		}
+		value, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("read state value error %s", err)
+		}
+		if !isSnapshotStateKey(key) {
+			return fmt.Errorf("unexpected state key %x", key)
+		}
+		writeSnapshotState(hasher, key, value)
+		db.BatchPut(key, value)
+		count++
+		if count%snapshotBatchSize == 0 {
+			if err = db.BatchCommit(); err != nil {
+				return err
+			}
+			db.NewBatch()
+		}
+	}
+	if err := db.BatchCommit(); err != nil {
+		return err
+	}
+	expectedCount, err := serialization.ReadUint64(r)
+	if err != nil {
+		return fmt.Errorf("read state count error %s", err)
+	}
+	var expectedDigest, digest common.Uint256
+	if err = expectedDigest.Deserialize(r); err != nil {
+		return fmt.Errorf("read state digest error %s", err)
+	}
+	hasher.Sum(digest[:0])
+	if count != expectedCount || digest != expectedDigest {
+		return fmt.Errorf("snapshot states are corrupted, %d states with digest %s, expected %d states with digest %s",
+			count, digest.ToHexString(), expectedCount, expectedDigest.ToHexString())
+	}
+	return nil
+}
+
+func hashesEqual(a, b []common.Uint256) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
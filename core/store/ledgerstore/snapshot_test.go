@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	ledger, acc, genesisBlock := newTestLedger(t, "test/snapshot")
+	defer ledger.Close()
+	// the test blocks have no vbft consensus payload, the headers are verified against the bookkeepers
+	consensusType := config.DefConfig.Genesis.ConsensusType
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SOLO
+	defer func() { config.DefConfig.Genesis.ConsensusType = consensusType }()
+	for i := 0; i < 3; i++ {
+		block := newTestBlock(t, ledger, acc)
+		signTestBlock(t, block, acc)
+		submitTestBlock(t, ledger, block)
+	}
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(t, ledger.CreateSnapshot(buf))
+	snapshot := buf.Bytes()
+	height, blockHash := ledger.GetCurrentBlock()
+	stateRoot, err := ledger.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+
+	restored, err := NewLedgerStore("test/snapshot_restore", 0)
+	assert.Nil(t, err)
+	defer restored.Close()
+
+	// corrupted snapshot is rejected, and the states not committed in headers must be trusted
+	corrupted := append([]byte{}, snapshot...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	_, err = restored.RestoreSnapshot(bytes.NewReader(corrupted), genesisBlock, true)
+	assert.NotNil(t, err)
+	_, err = restored.RestoreSnapshot(bytes.NewReader(snapshot), genesisBlock, false)
+	assert.NotNil(t, err)
+
+	metadata, err := restored.RestoreSnapshot(bytes.NewReader(snapshot), genesisBlock, true)
+	assert.Nil(t, err)
+	assert.Equal(t, height, metadata.Height)
+	assert.Equal(t, blockHash, metadata.BlockHash)
+	assert.Nil(t, restored.InitLedgerStoreWithGenesisBlock(genesisBlock, nil))
+
+	restoredHeight, restoredHash := restored.GetCurrentBlock()
+	assert.Equal(t, height, restoredHeight)
+	assert.Equal(t, blockHash, restoredHash)
+	restoredRoot, err := restored.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+	assert.Equal(t, stateRoot, restoredRoot)
+	for i := uint32(0); i <= height; i++ {
+		assert.Equal(t, ledger.GetBlockHash(i), restored.GetBlockHash(i))
+	}
+
+	// both ledgers go on with the same block
+	block := newTestBlock(t, ledger, acc)
+	signTestBlock(t, block, acc)
+	submitTestBlock(t, ledger, block)
+	submitTestBlock(t, restored, block)
+	stateRoot, err = ledger.GetStateMerkleRoot(height + 1)
+	assert.Nil(t, err)
+	restoredRoot, err = restored.GetStateMerkleRoot(height + 1)
+	assert.Nil(t, err)
+	assert.Equal(t, stateRoot, restoredRoot)
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/states"
+	"github.com/ontio/ontology/core/store"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+)
+
+var ErrStateProofDisabled = errors.New("state proof is not enabled")
+var ErrStateTrieRootNotCommitted = errors.New("state trie root is not committed in block header")
+
+// nodes are flushed to db every stateTrieRebuildBatch keys while building the trie from current states
+const stateTrieRebuildBatch = 10000
+
+func (self *StateStore) genStateTrieNodeKey(hash common.Uint256) []byte {
+	return append([]byte{byte(scom.ST_STATE_TRIE)}, hash[:]...)
+}
+
+func (self *StateStore) genStateTrieRootKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_STATE_TRIE_ROOT)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//GetSparseMerkleNode return the state trie node by its hash
+func (self *StateStore) GetSparseMerkleNode(hash common.Uint256) ([]byte, error) {
+	return self.store.Get(self.genStateTrieNodeKey(hash))
+}
+
+//EnableStateProof turn on the state trie. The trie is built from current states if it's missing at current block.
+func (self *StateStore) EnableStateProof() error {
+	self.stateTrieRoot = common.UINT256_EMPTY
+	_, height, err := self.GetCurrentBlock()
+	if err == scom.ErrNotFound {
+		self.stateProof = true
+		return nil
+	} else if err != nil {
+		return err
+	}
+	root, err := self.GetStateTrieRoot(height)
+	if err == nil {
+		self.stateTrieRoot = root
+		self.stateProof = true
+		return nil
+	} else if err != scom.ErrNotFound {
+		return err
+	}
+
+	log.Infof("building state trie at block height %d", height)
+	err = nil
+	tree := merkle.NewSparseMerkleTree(common.UINT256_EMPTY, self)
+	flush := func() error {
+		self.store.NewBatch()
+		tree.Commit(func(hash common.Uint256, node []byte) {
+			self.store.BatchPut(self.genStateTrieNodeKey(hash), node)
+		})
+		return self.store.BatchCommit()
+	}
+	count := 0
+	for _, prefix := range []scom.DataEntryPrefix{scom.ST_CONTRACT, scom.ST_STORAGE} {
+		iter := self.store.NewIterator([]byte{byte(prefix)})
+		for has := iter.First(); has; has = iter.Next() {
+			if err = tree.Update(iter.Key(), iter.Value()); err != nil {
+				break
+			}
+			count++
+			if count%stateTrieRebuildBatch == 0 {
+				if err = flush(); err != nil {
+					break
+				}
+				log.Infof("building state trie: %d states added", count)
+			}
+		}
+		iter.Release()
+		if err == nil {
+			err = iter.Error()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err = flush(); err != nil {
+		return err
+	}
+	root = tree.Root()
+	if err = self.store.Put(self.genStateTrieRootKey(height), root[:]); err != nil {
+		return err
+	}
+	log.Infof("state trie built with %d states, root %s", count, root.ToHexString())
+	self.stateTrieRoot = root
+	self.stateProof = true
+	return nil
+}
+
+//IsStateProof return whether state trie is enabled
+func (self *StateStore) IsStateProof() bool {
+	return self.stateProof
+}
+
+//updateStateTrie apply the states changed by the block to the state trie at current block
+func (self *StateStore) updateStateTrie(writeSet *overlaydb.MemDB) (*merkle.SparseMerkleTree, error) {
+	tree := merkle.NewSparseMerkleTree(self.stateTrieRoot, self)
+	var err error
+	writeSet.ForEach(func(key, val []byte) {
+		if err != nil || !isStateKey(key) {
+			return
+		}
+		err = tree.Update(key, val)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+//GetStateTrieRootWithWriteSet return the state trie root after applying the states changed by the next block,
+//without saving the new nodes
+func (self *StateStore) GetStateTrieRootWithWriteSet(writeSet *overlaydb.MemDB) (common.Uint256, error) {
+	if !self.stateProof {
+		return common.UINT256_EMPTY, ErrStateProofDisabled
+	}
+	tree, err := self.updateStateTrie(writeSet)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return tree.Root(), nil
+}
+
+//AddStateTrieWriteSet apply the states changed by the block to state trie, and save the new nodes and root to batch
+func (self *StateStore) AddStateTrieWriteSet(height uint32, writeSet *overlaydb.MemDB) error {
+	if !self.stateProof {
+		return nil
+	}
+	tree, err := self.updateStateTrie(writeSet)
+	if err != nil {
+		return err
+	}
+	tree.Commit(func(hash common.Uint256, node []byte) {
+		self.store.BatchPut(self.genStateTrieNodeKey(hash), node)
+	})
+	root := tree.Root()
+	self.store.BatchPut(self.genStateTrieRootKey(height), root[:])
+	self.stateTrieRoot = root
+	return nil
+}
+
+//GetStateTrieRoot return the state trie root at the end of block height
+func (self *StateStore) GetStateTrieRoot(height uint32) (common.Uint256, error) {
+	value, err := self.store.Get(self.genStateTrieRootKey(height))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return common.Uint256ParseFromBytes(value)
+}
+
+//GetStateProof return the state trie root at the end of block height and the proof of the raw state key
+func (self *StateStore) GetStateProof(key []byte, height uint32) (common.Uint256, *merkle.SparseMerkleProof, error) {
+	if !self.stateProof {
+		return common.UINT256_EMPTY, nil, ErrStateProofDisabled
+	}
+	root, err := self.GetStateTrieRoot(height)
+	if err != nil {
+		return common.UINT256_EMPTY, nil, err
+	}
+	proof, err := merkle.NewSparseMerkleTree(root, self).Prove(key)
+	if err != nil {
+		return common.UINT256_EMPTY, nil, err
+	}
+	return root, proof, nil
+}
+
+//GetStorageProof return the storage value at the end of block height with its proof.
+//The states before current block can only be proved in archive mode.
+//The value is read from a db snapshot, so that it stays consistent with the proof while blocks are saved.
+func (self *StateStore) GetStorageProof(key *states.StorageKey, height uint32) (*store.StorageProof, error) {
+	storeKey, err := self.getStorageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := self.newSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+	_, curr, err := self.getCurrentBlock(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	root, proof, err := self.GetStateProof(storeKey, height)
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	if height == curr {
+		value, err = snapshot.Get(storeKey)
+	} else {
+		if err = self.checkArchiveHeight(height); err != nil {
+			return nil, err
+		}
+		value, err = (&archivedStore{stateStore: self, db: snapshot, height: height}).Get(storeKey)
+	}
+	if err != nil && err != scom.ErrNotFound {
+		return nil, err
+	}
+	return &store.StorageProof{
+		Height: height,
+		Root:   root,
+		Key:    storeKey,
+		Value:  value,
+		Proof:  proof,
+	}, nil
+}
+
+//GetCommittedStateTrieRoot return the state trie root of the previous block committed in the vbft consensus payload
+//of header
+func GetCommittedStateTrieRoot(header *types.Header) (common.Uint256, error) {
+	info, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if len(info.PrevStateTrieRoot) == 0 {
+		return common.UINT256_EMPTY, ErrStateTrieRootNotCommitted
+	}
+	return common.Uint256ParseFromBytes(info.PrevStateTrieRoot)
+}
+
+//VerifyStorageProof check the storage proof against the state trie root committed in the header of the next block.
+//The signatures of the header should be verified by the caller.
+func VerifyStorageProof(header *types.Header, proof *store.StorageProof) error {
+	if header.Height != proof.Height+1 {
+		return fmt.Errorf("header height %d does not commit the state at height %d", header.Height, proof.Height)
+	}
+	root, err := GetCommittedStateTrieRoot(header)
+	if err != nil {
+		return err
+	}
+	if root != proof.Root {
+		return fmt.Errorf("state trie root mismatch. committed: %s, got: %s", root.ToHexString(), proof.Root.ToHexString())
+	}
+	return merkle.VerifySparseMerkleProof(root, proof.Key, proof.Value, proof.Proof)
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	vconfig "github.com/ontio/ontology/consensus/vbft/config"
+	"github.com/ontio/ontology/core/states"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageProof(t *testing.T) {
+	contract := common.Address{1, 2, 3}
+	submit := func(db *StateStore, height uint32, changes map[string]string) {
+		writeSet := overlaydb.NewMemDB(0, 0)
+		for k, v := range changes {
+			key, _ := db.getStorageKey(&states.StorageKey{ContractAddress: contract, Key: []byte(k)})
+			if v == "" {
+				writeSet.Delete(key)
+			} else {
+				writeSet.Put(key, states.GenRawStorageItem([]byte(v)))
+			}
+		}
+		db.NewBatch()
+		assert.Nil(t, db.SaveCurrentBlock(height, common.Uint256{byte(height)}))
+		assert.Nil(t, db.AddStateTrieWriteSet(height, writeSet))
+		writeSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.CommitTo())
+	}
+	blocks := []map[string]string{
+		{"a": "a0", "b": "b0"},
+		{"c": "c1"},
+		{"a": "a2", "b": ""},
+	}
+
+	db := NewMemStateStore(0)
+	assert.Nil(t, db.EnableStateProof())
+	for height, changes := range blocks {
+		submit(db, uint32(height), changes)
+	}
+	root, err := db.GetStateTrieRoot(2)
+	assert.Nil(t, err)
+	for key, expect := range map[string]string{"a": "a2", "b": "", "c": "c1", "d": ""} {
+		proof, err := db.GetStorageProof(&states.StorageKey{ContractAddress: contract, Key: []byte(key)}, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, root, proof.Root)
+		assert.Nil(t, merkle.VerifySparseMerkleProof(proof.Root, proof.Key, proof.Value, proof.Proof))
+		if expect == "" {
+			assert.Nil(t, proof.Value)
+		} else {
+			value, err := states.GetValueFromRawStorageItem(proof.Value)
+			assert.Nil(t, err)
+			assert.Equal(t, expect, string(value))
+		}
+	}
+	_, err = db.GetStorageProof(&states.StorageKey{ContractAddress: contract, Key: []byte("a")}, 1)
+	assert.Equal(t, ErrArchiveDisabled, err)
+
+	// the trie built from the states of an existing ledger has the same root
+	other := NewMemStateStore(0)
+	for height, changes := range blocks {
+		submit(other, uint32(height), changes)
+	}
+	assert.Nil(t, other.EnableStateProof())
+	otherRoot, err := other.GetStateTrieRoot(2)
+	assert.Nil(t, err)
+	assert.Equal(t, root, otherRoot)
+
+	// the root computed on execution is the one saved on submission
+	writeSet := overlaydb.NewMemDB(0, 0)
+	key, _ := db.getStorageKey(&states.StorageKey{ContractAddress: contract, Key: []byte("d")})
+	writeSet.Put(key, states.GenRawStorageItem([]byte("d3")))
+	execRoot, err := db.GetStateTrieRootWithWriteSet(writeSet)
+	assert.Nil(t, err)
+	assert.Equal(t, root, db.stateTrieRoot, "the trie is not changed on execution")
+	submit(db, 3, map[string]string{"d": "d3"})
+	root, err = db.GetStateTrieRoot(3)
+	assert.Nil(t, err)
+	assert.Equal(t, execRoot, root)
+}
+
+func TestVerifyStorageProof(t *testing.T) {
+	db := NewMemStateStore(0)
+	assert.Nil(t, db.EnableStateProof())
+	key := &states.StorageKey{ContractAddress: common.Address{1}, Key: []byte("a")}
+	storeKey, _ := db.getStorageKey(key)
+	writeSet := overlaydb.NewMemDB(0, 0)
+	writeSet.Put(storeKey, states.GenRawStorageItem([]byte("a0")))
+	db.NewBatch()
+	assert.Nil(t, db.SaveCurrentBlock(0, common.Uint256{}))
+	assert.Nil(t, db.AddStateTrieWriteSet(0, writeSet))
+	db.BatchPutRawKeyVal(storeKey, states.GenRawStorageItem([]byte("a0")))
+	assert.Nil(t, db.CommitTo())
+
+	proof, err := db.GetStorageProof(key, 0)
+	assert.Nil(t, err)
+	newHeader := func(height uint32, root []byte) *types.Header {
+		payload, _ := json.Marshal(&vconfig.VbftBlockInfo{PrevStateTrieRoot: root})
+		return &types.Header{Height: height, ConsensusPayload: payload}
+	}
+	assert.Nil(t, VerifyStorageProof(newHeader(1, proof.Root[:]), proof))
+	assert.NotNil(t, VerifyStorageProof(newHeader(2, proof.Root[:]), proof))
+	assert.NotNil(t, VerifyStorageProof(newHeader(1, common.UINT256_EMPTY[:]), proof))
+	assert.Equal(t, ErrStateTrieRootNotCommitted, VerifyStorageProof(newHeader(1, nil), proof))
+
+	proof.Value = states.GenRawStorageItem([]byte("a1"))
+	assert.NotNil(t, VerifyStorageProof(newHeader(1, proof.Root[:]), proof))
+}
@@ -51,6 +51,12 @@ type StateStore struct {
 	deltaMerkleTree      *merkle.CompactMerkleTree //Merkle tree of delta state root
 	merkleHashStore      merkle.HashStore
 	stateHashCheckHeight uint32
+	archive              bool   //Whether keep the historical versions of states
+	archiveStartHeight   uint32 //First block height whose states are archived
+	stateProof           bool           //Whether maintain the state trie
+	stateTrieRoot        common.Uint256 //State trie root of current block
+	undo                 *undoJournal   //Record the old values of the states written in current batch
+	undoBlockNum         uint32         //Number of latest blocks whose undo data is kept
 }
 
 //NewStateStore return state store instance
@@ -60,9 +66,11 @@ func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*Stat
 	if err != nil {
 		return nil, err
 	}
+	undo := newUndoJournal(store)
 	stateStore := &StateStore{
 		dbDir:                dbDir,
-		store:                store,
+		store:                undo,
+		undo:                 undo,
 		merklePath:           merklePath,
 		stateHashCheckHeight: stateHashCheckHeight,
 	}
@@ -80,8 +88,10 @@ func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*Stat
 // for test
 func NewMemStateStore(stateHashHeight uint32) *StateStore {
 	store, _ := leveldbstore.NewMemLevelDBStore()
+	undo := newUndoJournal(store)
 	stateStore := &StateStore{
-		store:                store,
+		store:                undo,
+		undo:                 undo,
 		merkleTree:           merkle.NewTree(0, nil, nil),
 		deltaMerkleTree:      merkle.NewTree(0, nil, nil),
 		stateHashCheckHeight: stateHashHeight,
@@ -93,6 +103,9 @@ func NewMemStateStore(stateHashHeight uint32) *StateStore {
 //NewBatch start new commit batch
 func (self *StateStore) NewBatch() {
 	self.store.NewBatch()
+	if self.undoBlockNum > 0 {
+		self.undo.begin()
+	}
 }
 
 func (self *StateStore) BatchPutRawKeyVal(key, val []byte) {
@@ -314,8 +327,13 @@ func (self *StateStore) GetStorageState(key *states.StorageKey) (*states.Storage
 
 //GetCurrentBlock return current block height and current hash in state store
 func (self *StateStore) GetCurrentBlock() (common.Uint256, uint32, error) {
+	return self.getCurrentBlock(self.store)
+}
+
+//getCurrentBlock return the current block hash and height read from db
+func (self *StateStore) getCurrentBlock(db stateReader) (common.Uint256, uint32, error) {
 	key := self.getCurrentBlockKey()
-	data, err := self.store.Get(key)
+	data, err := db.Get(key)
 	if err != nil {
 		return common.Uint256{}, 0, err
 	}
@@ -455,12 +473,21 @@ func (self *StateStore) ClearAll() error {
 		self.store.NewBatch() // reset the batch
 		return err
 	}
-	return self.store.BatchCommit()
+	if err := self.store.BatchCommit(); err != nil {
+		return err
+	}
+	self.stateTrieRoot = common.UINT256_EMPTY
+	if self.archive {
+		return self.saveArchiveStartHeight(0)
+	}
+	return nil
 }
 
 //Close state store
 func (self *StateStore) Close() error {
-	self.merkleHashStore.Close()
+	if self.merkleHashStore != nil {
+		self.merkleHashStore.Close()
+	}
 	return self.store.Close()
 }
 
@@ -0,0 +1,251 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/merkle"
+)
+
+//undoJournal record the old value of every key written to the batch, so that the block can be undone later
+type undoJournal struct {
+	scom.PersistStore
+	changes map[string][]byte //key => old value, nil if the key did not exist
+	err     error
+}
+
+func newUndoJournal(store scom.PersistStore) *undoJournal {
+	return &undoJournal{PersistStore: store}
+}
+
+func (self *undoJournal) begin() {
+	self.changes = make(map[string][]byte)
+	self.err = nil
+}
+
+func (self *undoJournal) end() (map[string][]byte, error) {
+	changes, err := self.changes, self.err
+	self.changes, self.err = nil, nil
+	return changes, err
+}
+
+func (self *undoJournal) record(key []byte) {
+	if self.changes == nil {
+		return
+	}
+	if _, ok := self.changes[string(key)]; ok {
+		return
+	}
+	old, err := self.PersistStore.Get(key)
+	if err == scom.ErrNotFound {
+		old = nil
+	} else if err != nil {
+		if self.err == nil {
+			self.err = err
+		}
+		return
+	} else if old == nil {
+		old = []byte{}
+	}
+	self.changes[string(key)] = old
+}
+
+func (self *undoJournal) BatchPut(key []byte, value []byte) {
+	self.record(key)
+	self.PersistStore.BatchPut(key, value)
+}
+
+func (self *undoJournal) BatchDelete(key []byte) {
+	self.record(key)
+	self.PersistStore.BatchDelete(key)
+}
+
+func (self *StateStore) genUndoKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.SYS_STATE_UNDO)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+//EnableUndo keep the undo data of the latest blockNum blocks, so that the states can be rolled back to any of them.
+//Undo data is not recorded if blockNum is 0, and the undo data kept by a previous run is left untouched, so that
+//it's still available to the offline commands which don't keep undo data. Otherwise the undo data kept by a previous
+//run out of the new range is dropped.
+func (self *StateStore) EnableUndo(blockNum uint32) error {
+	self.undoBlockNum = blockNum
+	if blockNum == 0 {
+		return nil
+	}
+	_, height, err := self.GetCurrentBlock()
+	if err == scom.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return self.pruneUndoLog(height)
+}
+
+//pruneUndoLog delete the undo data of the blocks before the latest undoBlockNum blocks at current block height
+func (self *StateStore) pruneUndoLog(height uint32) error {
+	prefix := []byte{byte(scom.SYS_STATE_UNDO)}
+	iter := self.store.NewIterator(prefix)
+	self.store.NewBatch()
+	count := 0
+	for has := iter.First(); has; has = iter.Next() {
+		key := iter.Key()
+		if len(key) != 5 {
+			continue
+		}
+		undoHeight := binary.LittleEndian.Uint32(key[1:])
+		if uint64(undoHeight)+uint64(self.undoBlockNum) > uint64(height) {
+			continue
+		}
+		self.store.BatchDelete(self.genUndoKey(undoHeight))
+		count++
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		self.store.NewBatch() // reset the batch
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	log.Infof("pruned the undo data of %d blocks", count)
+	return self.store.BatchCommit()
+}
+
+//SaveUndoLog save the old values of the states changed in current batch as the undo data of block height,
+//and drop the undo data which is out of the keeping range.
+func (self *StateStore) SaveUndoLog(height uint32) error {
+	changes, err := self.undo.end()
+	if err != nil {
+		return err
+	}
+	if self.undoBlockNum == 0 || changes == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarUint(uint64(len(keys)))
+	for _, key := range keys {
+		old := changes[key]
+		sink.WriteVarBytes([]byte(key))
+		sink.WriteBool(old != nil)
+		if old != nil {
+			sink.WriteVarBytes(old)
+		}
+	}
+	self.store.BatchPut(self.genUndoKey(height), sink.Bytes())
+	if height >= self.undoBlockNum {
+		self.store.BatchDelete(self.genUndoKey(height - self.undoBlockNum))
+	}
+	return nil
+}
+
+//HasUndoLog return whether the undo data of block height is kept
+func (self *StateStore) HasUndoLog(height uint32) (bool, error) {
+	return self.store.Has(self.genUndoKey(height))
+}
+
+//UndoBlock revert the states to the end of the previous block of current block
+func (self *StateStore) UndoBlock() error {
+	_, height, err := self.GetCurrentBlock()
+	if err != nil {
+		return err
+	}
+	if height == 0 {
+		return fmt.Errorf("genesis block can not be undone")
+	}
+	key := self.genUndoKey(height)
+	data, err := self.store.Get(key)
+	if err == scom.ErrNotFound {
+		return fmt.Errorf("undo data of block %d not found", height)
+	} else if err != nil {
+		return err
+	}
+	source := common.NewZeroCopySource(data)
+	count, _, irregular, eof := source.NextVarUint()
+	if irregular || eof {
+		return fmt.Errorf("invalid undo data of block %d", height)
+	}
+	self.store.NewBatch()
+	for i := uint64(0); i < count; i++ {
+		k, _, irr, eof := source.NextVarBytes()
+		exist, irr2, eof2 := source.NextBool()
+		if irr || eof || irr2 || eof2 {
+			self.store.NewBatch() // reset the batch
+			return fmt.Errorf("invalid undo data of block %d: %s", height, io.ErrUnexpectedEOF)
+		}
+		if !exist {
+			self.store.BatchDelete(k)
+			continue
+		}
+		old, _, irr, eof := source.NextVarBytes()
+		if irr || eof {
+			self.store.NewBatch() // reset the batch
+			return fmt.Errorf("invalid undo data of block %d: %s", height, io.ErrUnexpectedEOF)
+		}
+		self.store.BatchPut(k, old)
+	}
+	self.store.BatchDelete(key)
+	return self.store.BatchCommit()
+}
+
+//ReloadAfterUndo reset the merkle trees, archive start height and state trie to the current block after UndoBlock
+func (self *StateStore) ReloadAfterUndo() error {
+	_, height, err := self.GetCurrentBlock()
+	if err != nil {
+		return err
+	}
+	if self.merkleHashStore != nil {
+		self.merkleHashStore.Close()
+		self.merkleHashStore = nil
+	}
+	if self.merklePath != "" {
+		if err := merkle.TruncateFileHashStore(self.merklePath, height+1); err != nil {
+			return err
+		}
+	}
+	self.deltaMerkleTree = merkle.NewTree(0, nil, nil)
+	if err := self.init(height); err != nil {
+		return err
+	}
+	if self.archive && self.archiveStartHeight > height+1 {
+		if err := self.saveArchiveStartHeight(height + 1); err != nil {
+			return err
+		}
+	}
+	log.Infof("state store is rolled back to block height %d", height)
+	if self.stateProof {
+		return self.EnableStateProof()
+	}
+	return nil
+}
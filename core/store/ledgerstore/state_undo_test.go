@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableUndoPrune(t *testing.T) {
+	db := NewMemStateStore(0)
+	assert.Nil(t, db.EnableUndo(3), "no block yet")
+	db.NewBatch()
+	for height := uint32(0); height < 10; height++ {
+		db.store.BatchPut(db.genUndoKey(height), []byte{0})
+	}
+	assert.Nil(t, db.SaveCurrentBlock(9, common.Uint256{9}))
+	assert.Nil(t, db.CommitTo())
+
+	kept := func() []uint32 {
+		heights := make([]uint32, 0)
+		for height := uint32(0); height < 10; height++ {
+			has, err := db.HasUndoLog(height)
+			assert.Nil(t, err)
+			if has {
+				heights = append(heights, height)
+			}
+		}
+		return heights
+	}
+	assert.Nil(t, db.EnableUndo(3))
+	assert.Equal(t, []uint32{7, 8, 9}, kept())
+	assert.Nil(t, db.EnableUndo(5))
+	assert.Equal(t, []uint32{7, 8, 9}, kept())
+	assert.Nil(t, db.EnableUndo(0), "undo data is kept when disabled")
+	assert.Equal(t, []uint32{7, 8, 9}, kept())
+	assert.Nil(t, db.EnableUndo(1))
+	assert.Equal(t, []uint32{9}, kept())
+}
@@ -42,6 +42,7 @@ import (
 	"github.com/ontio/ontology/smartcontract/service/neovm"
 	"github.com/ontio/ontology/smartcontract/service/wasmvm"
 	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/ontio/ontology/smartcontract/trace"
 )
 
 func tuneGasFeeByHeight(height uint32, gas uint64, gasRound uint64, curBalance uint64) uint64 {
@@ -145,6 +146,12 @@ func (self *StateStore) HandleDeployTransaction(store store.LedgerStore, overlay
 //HandleInvokeTransaction deal with smart contract invoke transaction
 func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay *overlaydb.OverlayDB, gasTable map[string]uint64, cache *storage.CacheDB,
 	tx *types.Transaction, block *types.Block, notify *event.ExecuteNotify) ([]common.Uint256, error) {
+	return self.handleInvokeTransaction(store, overlay, gasTable, cache, tx, block, notify, nil)
+}
+
+//handleInvokeTransaction deal with smart contract invoke transaction, the execution is traced if tracer is not nil
+func (self *StateStore) handleInvokeTransaction(store store.LedgerStore, overlay *overlaydb.OverlayDB, gasTable map[string]uint64, cache *storage.CacheDB,
+	tx *types.Transaction, block *types.Block, notify *event.ExecuteNotify, tracer *trace.Tracer) ([]common.Uint256, error) {
 	invoke := tx.Payload.(*payload.InvokeCode)
 	code := invoke.Code
 	sysTransFlag := bytes.Compare(code, ninit.COMMIT_DPOS_BYTES) == 0 || block.Header.Height == 0
@@ -226,10 +233,17 @@ func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay
 		PreExec:      false,
 	}
 
+	if tracer != nil {
+		sc.SetTracer(tracer)
+	}
+
 	//start the smart contract executive function
 	engine, _ := sc.NewExecuteEngine(invoke.Code, tx.TxType)
 
 	_, err = engine.Invoke()
+	if tracer != nil {
+		tracer.Finish(err)
+	}
 	if sc.IsInternalErr() {
 		overlay.SetError(fmt.Errorf("[HandleInvokeTransaction] %s", err))
 		return nil, nil
@@ -330,6 +344,18 @@ func chargeCostGas(payer common.Address, gas uint64, config *smartcontract.Confi
 }
 
 func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) error {
+	gasTable, err := loadGasTable(config, cache, store)
+	if err != nil {
+		return err
+	}
+	for key, value := range gasTable {
+		neovm.GAS_TABLE.Store(key, value)
+	}
+	return nil
+}
+
+//loadGasTable return the gas table with the global params stored in cache, the global gas table is not changed
+func loadGasTable(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) (map[string]uint64, error) {
 	sink := common.NewZeroCopySink(nil)
 	utils.EncodeVarUint(sink, uint64(len(neovm.GAS_TABLE_KEYS)))
 	for _, value := range neovm.GAS_TABLE_KEYS {
@@ -346,25 +372,27 @@ func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, st
 	service, _ := sc.NewNativeService()
 	result, err := service.NativeCall(utils.ParamContractAddress, "getGlobalParam", sink.Bytes())
 	if err != nil {
-		return err
+		return nil, err
 	}
 	params := new(global_params.Params)
 	if err := params.Deserialization(common.NewZeroCopySource(result)); err != nil {
-		return fmt.Errorf("deserialize global params error:%s", err)
+		return nil, fmt.Errorf("deserialize global params error:%s", err)
 	}
+	gasTable := make(map[string]uint64)
 	neovm.GAS_TABLE.Range(func(key, value interface{}) bool {
+		gasTable[key.(string)] = value.(uint64)
 		n, ps := params.GetParam(key.(string))
 		if n != -1 && ps.Value != "" {
 			pu, err := strconv.ParseUint(ps.Value, 10, 64)
 			if err != nil {
 				log.Errorf("[refreshGlobalParam] failed to parse uint %v\n", ps.Value)
 			} else {
-				neovm.GAS_TABLE.Store(key, pu)
+				gasTable[key.(string)] = pu
 			}
 		}
 		return true
 	})
-	return nil
+	return gasTable, nil
 }
 
 func getBalanceFromNative(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore, address common.Address) (uint64, error) {
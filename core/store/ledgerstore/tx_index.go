@@ -0,0 +1,195 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/store"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+)
+
+// txIndex keeps the transactions related to an address, keyed by
+// txPrefix + address + height(big endian) + tx index(big endian), so that the
+// transactions of an address sort by block height. The entries of a block are
+// also kept by blockPrefix + height, so that they can be deleted with the block.
+type txIndex struct {
+	name        string
+	store       *leveldbstore.LevelDBStore
+	txPrefix    scom.DataEntryPrefix
+	blockPrefix scom.DataEntryPrefix
+	startPrefix scom.DataEntryPrefix
+	enabled     bool
+	startHeight uint32 //first block height whose transactions are indexed
+}
+
+type txIndexEntry struct {
+	addr    common.Address
+	txIndex uint32
+}
+
+func (self *txIndex) genTxKey(addr common.Address, height uint32, txIndex uint32) []byte {
+	key := make([]byte, 1+common.ADDR_LEN+8)
+	key[0] = byte(self.txPrefix)
+	copy(key[1:], addr[:])
+	binary.BigEndian.PutUint32(key[1+common.ADDR_LEN:], height)
+	binary.BigEndian.PutUint32(key[1+common.ADDR_LEN+4:], txIndex)
+	return key
+}
+
+// splitTxKey return the height and tx index of an index entry
+func splitTxKey(key []byte) (uint32, uint32, error) {
+	if len(key) != 1+common.ADDR_LEN+8 {
+		return 0, 0, fmt.Errorf("invalid tx index key")
+	}
+	return binary.BigEndian.Uint32(key[1+common.ADDR_LEN:]), binary.BigEndian.Uint32(key[1+common.ADDR_LEN+4:]), nil
+}
+
+func (self *txIndex) genBlockKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(self.blockPrefix)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
+func (self *txIndex) genStartHeightKey() []byte {
+	return []byte{byte(self.startPrefix)}
+}
+
+//enable turn on the index from the block after currentHeight, or from the start height saved before
+func (self *txIndex) enable(currentHeight uint32, hasCurrent bool) error {
+	data, err := self.store.Get(self.genStartHeightKey())
+	if err == nil && len(data) == 4 {
+		self.enabled = true
+		self.startHeight = binary.LittleEndian.Uint32(data)
+		return nil
+	}
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	start := uint32(0)
+	if hasCurrent {
+		start = currentHeight + 1
+	}
+	if err := self.saveStartHeight(start); err != nil {
+		return err
+	}
+	self.enabled = true
+	log.Infof("%s index enabled from block height %d", self.name, start)
+	return nil
+}
+
+//disable drop the start height, so that a later enable will not trust the stale index
+func (self *txIndex) disable() error {
+	self.enabled = false
+	key := self.genStartHeightKey()
+	has, err := self.store.Has(key)
+	if err != nil || !has {
+		return err
+	}
+	log.Warnf("%s index is disabled, transactions by %s will not be available any more", self.name, self.name)
+	return self.store.Delete(key)
+}
+
+func (self *txIndex) saveStartHeight(height uint32) error {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, height)
+	self.startHeight = height
+	return self.store.Put(self.genStartHeightKey(), value)
+}
+
+//save put the index entries of block height to batch
+func (self *txIndex) save(height uint32, txHashes []common.Uint256, entries []txIndexEntry) {
+	if !self.enabled || len(entries) == 0 {
+		return
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(uint32(len(entries)))
+	for _, e := range entries {
+		self.store.BatchPut(self.genTxKey(e.addr, height, e.txIndex), txHashes[e.txIndex].ToArray())
+		sink.WriteAddress(e.addr)
+		sink.WriteUint32(e.txIndex)
+	}
+	self.store.BatchPut(self.genBlockKey(height), sink.Bytes())
+}
+
+//prune delete the index entries of block height to batch
+func (self *txIndex) prune(height uint32) {
+	key := self.genBlockKey(height)
+	data, err := self.store.Get(key)
+	if err != nil {
+		if err != scom.ErrNotFound {
+			log.Errorf("prune %s index of block %d error %s", self.name, height, err)
+		}
+		return
+	}
+	source := common.NewZeroCopySource(data)
+	count, eof := source.NextUint32()
+	for i := uint32(0); i < count && !eof; i++ {
+		var addr common.Address
+		var txIndex uint32
+		addr, eof = source.NextAddress()
+		txIndex, eof = source.NextUint32()
+		if eof {
+			break
+		}
+		self.store.BatchDelete(self.genTxKey(addr, height, txIndex))
+	}
+	if eof {
+		log.Errorf("invalid %s index of block %d", self.name, height)
+	}
+	self.store.BatchDelete(key)
+}
+
+//getTxs return the transactions indexed by addr in block height range [startHeight, endHeight],
+//skipping the first offset ones and returning at most limit ones.
+func (self *txIndex) getTxs(addr common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	txs := make([]*store.AddressTx, 0)
+	if startHeight > endHeight || limit == 0 {
+		return txs, nil
+	}
+	start := self.genTxKey(addr, startHeight, 0)
+	// the limit is exclusive, so append a byte to include the last entry of end height
+	limitKey := append(self.genTxKey(addr, endHeight, ^uint32(0)), 0)
+	iter := self.store.NewRangeIterator(start, limitKey)
+	defer iter.Release()
+	for iter.Next() {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		height, txIndex, err := splitTxKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		txHash, err := common.Uint256ParseFromBytes(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, &store.AddressTx{Height: height, TxIndex: txIndex, TxHash: txHash})
+		if uint32(len(txs)) >= limit {
+			break
+		}
+	}
+	return txs, iter.Error()
+}
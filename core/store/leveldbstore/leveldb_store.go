@@ -157,3 +157,43 @@ func (self *LevelDBStore) NewIterator(prefix []byte) common.StoreIterator {
 
 	return iter
 }
+
+//NewRangeIterator return a iterator of leveldb with the keys in range [start, limit)
+func (self *LevelDBStore) NewRangeIterator(start, limit []byte) common.StoreIterator {
+	return self.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+}
+
+//NewSnapshot return a read only snapshot of leveldb at current time, which should be released after use
+func (self *LevelDBStore) NewSnapshot() (common.StoreSnapshot, error) {
+	snapshot, err := self.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &levelDBSnapshot{snapshot: snapshot}, nil
+}
+
+type levelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+//Get the value of a key from leveldb snapshot
+func (self *levelDBSnapshot) Get(key []byte) ([]byte, error) {
+	dat, err := self.snapshot.Get(key, nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return dat, nil
+}
+
+//NewIterator return a iterator of leveldb snapshot with the key prefix
+func (self *levelDBSnapshot) NewIterator(prefix []byte) common.StoreIterator {
+	return self.snapshot.NewIterator(util.BytesPrefix(prefix), nil)
+}
+
+//Release the leveldb snapshot
+func (self *levelDBSnapshot) Release() {
+	self.snapshot.Release()
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"testing"
+
+	"github.com/ontio/ontology/core/store/common"
 )
 
 var testLevelDB *LevelDBStore
@@ -140,3 +142,33 @@ func TestIterator(t *testing.T) {
 	}
 
 }
+
+func TestSnapshot(t *testing.T) {
+	key := []byte("snapshot")
+	if err := testLevelDB.Put(key, []byte("old")); err != nil {
+		t.Errorf("Put error:%s", err)
+		return
+	}
+	snapshot, err := testLevelDB.NewSnapshot()
+	if err != nil {
+		t.Errorf("NewSnapshot error:%s", err)
+		return
+	}
+	defer snapshot.Release()
+	if err = testLevelDB.Put(key, []byte("new")); err != nil {
+		t.Errorf("Put error:%s", err)
+		return
+	}
+	v, err := snapshot.Get(key)
+	if err != nil {
+		t.Errorf("Get error:%s", err)
+		return
+	}
+	if string(v) != "old" {
+		t.Errorf("Get error %s != old", v)
+		return
+	}
+	if _, err = snapshot.Get([]byte("missing")); err != common.ErrNotFound {
+		t.Errorf("Get missing key error:%v", err)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package store
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/serialization"
+)
+
+const SNAPSHOT_VERSION = byte(1)
+
+//SnapshotMetadata describe the ledger captured by a snapshot.
+//A snapshot is made of the metadata, the raw headers from genesis block to Height, the state entries and a trailer
+//with the count and digest of the state entries.
+type SnapshotMetadata struct {
+	Version         byte
+	GenesisHash     common.Uint256
+	Height          uint32
+	BlockHash       common.Uint256
+	StateMerkleRoot common.Uint256   //GetStateMerkleRoot at Height, empty before state hash check height
+	BlockTreeSize   uint32           //Size of block merkle tree
+	BlockTreeHashes []common.Uint256 //Compact hashes of block merkle tree
+}
+
+func (this *SnapshotMetadata) Serialize(w io.Writer) error {
+	if err := serialization.WriteByte(w, this.Version); err != nil {
+		return err
+	}
+	if err := this.GenesisHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, this.Height); err != nil {
+		return err
+	}
+	if err := this.BlockHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := this.StateMerkleRoot.Serialize(w); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, this.BlockTreeSize); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, uint32(len(this.BlockTreeHashes))); err != nil {
+		return err
+	}
+	for _, hash := range this.BlockTreeHashes {
+		if err := hash.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SnapshotMetadata) Deserialize(r io.Reader) error {
+	var err error
+	this.Version, err = serialization.ReadByte(r)
+	if err != nil {
+		return err
+	}
+	if this.Version != SNAPSHOT_VERSION {
+		return fmt.Errorf("unsupported snapshot version %d", this.Version)
+	}
+	if err = this.GenesisHash.Deserialize(r); err != nil {
+		return err
+	}
+	if this.Height, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	if err = this.BlockHash.Deserialize(r); err != nil {
+		return err
+	}
+	if err = this.StateMerkleRoot.Deserialize(r); err != nil {
+		return err
+	}
+	if this.BlockTreeSize, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	count, err := serialization.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	// a compact merkle tree has at most 32 hashes
+	if count > 32 {
+		return fmt.Errorf("invalid block merkle tree hash count %d", count)
+	}
+	this.BlockTreeHashes = make([]common.Uint256, count)
+	for i := range this.BlockTreeHashes {
+		if err = this.BlockTreeHashes[i].Deserialize(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -19,25 +19,65 @@
 package store
 
 import (
+	"io"
+
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/states"
 	"github.com/ontio/ontology/core/store/overlaydb"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/merkle"
 	"github.com/ontio/ontology/smartcontract/event"
 	cstates "github.com/ontio/ontology/smartcontract/states"
+	"github.com/ontio/ontology/smartcontract/trace"
 )
 
+//InvalidBlockError is the error of a header or block rejected for its own content, like a discontinuous height or
+//invalid signatures, which is the fault of its sender rather than the local node
+type InvalidBlockError struct {
+	Msg string
+}
+
+func (self *InvalidBlockError) Error() string {
+	return self.Msg
+}
+
+//IsInvalidBlock return whether the header or block is rejected for its own content
+func IsInvalidBlock(err error) bool {
+	_, ok := err.(*InvalidBlockError)
+	return ok
+}
+
 type ExecuteResult struct {
 	WriteSet        *overlaydb.MemDB
 	Hash            common.Uint256
 	MerkleRoot      common.Uint256
 	CrossStates     []common.Uint256
 	CrossStatesRoot common.Uint256
+	StateTrieRoot   common.Uint256 // empty if the state trie is not enabled
 	Notify          []*event.ExecuteNotify
 }
 
+// AddressTx is a transaction indexed by an account or contract address, located by block height and the index in block
+type AddressTx struct {
+	Height  uint32
+	TxIndex uint32
+	TxHash  common.Uint256
+}
+
+// StorageProof is the raw storage state at the end of block Height, proved by the state trie Root.
+// Value is nil if the key does not exist. Root is committed in the consensus payload of the signed Header
+// of block Height+1.
+type StorageProof struct {
+	Height uint32
+	Root   common.Uint256
+	Key    []byte
+	Value  []byte
+	Proof  *merkle.SparseMerkleProof
+	Header *types.Header
+}
+
 // LedgerStore provides func with store package.
 type LedgerStore interface {
 	InitLedgerStoreWithGenesisBlock(genesisblock *types.Block, defaultBookkeeper []keypair.PublicKey) error
@@ -70,9 +110,36 @@ type LedgerStore interface {
 	GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error)
 	GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error)
 
+	//transactions by address, only available when address index is enabled
+	GetTxsByAddress(addr common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*AddressTx, error)
+	GetAddressIndexStartHeight() (uint32, error)
+	//transactions by the contracts of event notify, only available when event log is enabled
+	GetTxsByContract(contract common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*AddressTx, error)
+	GetContractIndexStartHeight() (uint32, error)
+
+	//historical states, only available in archive mode
+	GetContractStateByHeight(contractHash common.Address, height uint32) (*payload.DeployCode, error)
+	GetStorageItemByHeight(key *states.StorageKey, height uint32) (*states.StorageItem, error)
+	PreExecuteContractByHeight(tx *types.Transaction, height uint32) (*cstates.PreExecResult, error)
+
+	//execution traces, tracing the transactions in blocks is only available in archive mode
+	TraceTransaction(txHash common.Uint256) (*trace.Trace, error)
+	TraceCall(tx *types.Transaction) (*trace.Trace, error)
+	EstimateGas(tx *types.Transaction) (uint64, error)
+
+	//state trie
+	GetStateTrieRoot(height uint32) (common.Uint256, error)
+	GetStorageProof(key *states.StorageKey, height uint32) (*StorageProof, error)
+
 	//cross chain states root
 	GetCrossStatesRoot(height uint32) (common.Uint256, error)
 	GetCrossChainMsg(height uint32) (*types.CrossChainMsg, error)
 	GetCrossStatesProof(height uint32, key []byte) ([]byte, error)
 	EnableBlockPrune(numBeforeCurr uint32)
+	DisableBlockPrune()
+	RollbackTo(height uint32) error
+
+	//snapshot of ledger
+	CreateSnapshot(w io.Writer) error
+	RestoreSnapshot(r io.Reader, genesisBlock *types.Block, trusted bool) (*SnapshotMetadata, error)
 }
@@ -33,11 +33,27 @@ type MutableTransaction struct {
 	GasLimit uint64
 	Payer    common.Address
 	Payload  Payload
-	//Attributes []*TxAttribute
-	attributes byte //this must be 0 now, Attribute Array length use VarUint encoding, so byte is enough for extension
+	// Attributes only the ValidUntilHeight and NotBeforeHeight attributes are supported, each at most once
+	Attributes []*TxAttribute
 	Sigs       []Sig
 }
 
+// SetValidityWindow set the first and the last block height the transaction can be packed in, zero means no bound
+func (self *MutableTransaction) SetValidityWindow(notBefore, validUntil uint32) {
+	self.Attributes = nil
+	if notBefore != 0 {
+		self.Attributes = append(self.Attributes, NewHeightAttribute(NotBeforeHeight, notBefore))
+	}
+	if validUntil != 0 {
+		self.Attributes = append(self.Attributes, NewHeightAttribute(ValidUntilHeight, validUntil))
+	}
+}
+
+// GetValidityWindow return the first and the last block height the transaction can be packed in, zero means no bound
+func (self *MutableTransaction) GetValidityWindow() (notBefore, validUntil uint32) {
+	return getValidityWindow(self.Attributes)
+}
+
 // output has no reference to self
 func (self *MutableTransaction) IntoImmutable() (*Transaction, error) {
 	sink := common.NewZeroCopySink(nil)
@@ -114,7 +130,13 @@ func (tx *MutableTransaction) serializeUnsigned(sink *common.ZeroCopySink) error
 	default:
 		return errors.New("wrong transaction payload type")
 	}
-	sink.WriteVarUint(uint64(tx.attributes))
+	sink.WriteVarUint(uint64(len(tx.Attributes)))
+	for _, attr := range tx.Attributes {
+		err := attr.Serialization(sink)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -32,6 +32,7 @@ import (
 )
 
 const MAX_TX_SIZE = 1024 * 1024 // The max size of a transaction to prevent DOS attacks
+const MAX_TX_ATTRIBUTES = 2     // The max number of transaction attributes, only the validity window is supported
 
 type Transaction struct {
 	Version  byte
@@ -41,8 +42,8 @@ type Transaction struct {
 	GasLimit uint64
 	Payer    common.Address
 	Payload  Payload
-	//Attributes []*TxAttribute
-	attributes byte //this must be 0 now, Attribute Array length use VarUint encoding, so byte is enough for extension
+	// Attributes only the ValidUntilHeight and NotBeforeHeight attributes are supported, each at most once
+	Attributes []*TxAttribute
 	Sigs       []RawSig
 
 	Raw []byte // raw transaction data
@@ -127,6 +128,9 @@ func (tx *Transaction) IntoMutable() (*MutableTransaction, error) {
 		Payer:    tx.Payer,
 		Payload:  tx.Payload,
 	}
+	if len(tx.Attributes) != 0 {
+		mutable.Attributes = append(mutable.Attributes, tx.Attributes...)
+	}
 
 	for _, raw := range tx.Sigs {
 		sig, err := raw.GetSig()
@@ -183,14 +187,74 @@ func (tx *Transaction) deserializationUnsigned(source *common.ZeroCopySource) er
 		return io.ErrUnexpectedEOF
 	}
 
-	if length != 0 {
-		return fmt.Errorf("transaction attribute must be 0, got %d", length)
+	if length > MAX_TX_ATTRIBUTES {
+		return fmt.Errorf("transaction attribute number %d execced %d", length, MAX_TX_ATTRIBUTES)
+	}
+	tx.Attributes = nil
+	for i := 0; i < int(length); i++ {
+		attr := new(TxAttribute)
+		err := attr.Deserialization(source)
+		if err != nil {
+			return err
+		}
+		tx.Attributes = append(tx.Attributes, attr)
 	}
-	tx.attributes = 0
 
+	return checkAttributes(tx.Attributes)
+}
+
+// checkAttributes check the attributes are the bounds of validity window, and each bound is set at most once
+func checkAttributes(attrs []*TxAttribute) error {
+	usages := make(map[TransactionAttributeUsage]bool, len(attrs))
+	for _, attr := range attrs {
+		if !IsHeightAttributeType(attr.Usage) {
+			return fmt.Errorf("unsupported transaction attribute usage 0x%x", byte(attr.Usage))
+		}
+		if usages[attr.Usage] {
+			return fmt.Errorf("duplicated transaction attribute usage 0x%x", byte(attr.Usage))
+		}
+		usages[attr.Usage] = true
+		if _, err := attr.GetHeight(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// getValidityWindow return the bounds of validity window in attributes, zero means no bound
+func getValidityWindow(attrs []*TxAttribute) (notBefore, validUntil uint32) {
+	for _, attr := range attrs {
+		height, err := attr.GetHeight()
+		if err != nil {
+			continue
+		}
+		switch attr.Usage {
+		case NotBeforeHeight:
+			notBefore = height
+		case ValidUntilHeight:
+			validUntil = height
+		}
+	}
+	return notBefore, validUntil
+}
+
+// GetValidityWindow return the first and the last block height the transaction can be packed in, zero means no bound
+func (tx *Transaction) GetValidityWindow() (notBefore, validUntil uint32) {
+	return getValidityWindow(tx.Attributes)
+}
+
+// IsExpiredAt return whether the transaction can not be packed in the block of height and later ones
+func (tx *Transaction) IsExpiredAt(height uint32) bool {
+	_, validUntil := tx.GetValidityWindow()
+	return validUntil != 0 && height > validUntil
+}
+
+// IsValidAt return whether the transaction can be packed in the block of height
+func (tx *Transaction) IsValidAt(height uint32) bool {
+	notBefore, _ := tx.GetValidityWindow()
+	return height >= notBefore && !tx.IsExpiredAt(height)
+}
+
 type RawSig struct {
 	Invoke []byte
 	Verify []byte
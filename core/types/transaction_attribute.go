@@ -19,6 +19,7 @@
 package types
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -29,15 +30,23 @@ import (
 type TransactionAttributeUsage byte
 
 const (
-	Nonce          TransactionAttributeUsage = 0x00
-	Script         TransactionAttributeUsage = 0x20
-	DescriptionUrl TransactionAttributeUsage = 0x81
-	Description    TransactionAttributeUsage = 0x90
+	Nonce            TransactionAttributeUsage = 0x00
+	Script           TransactionAttributeUsage = 0x20
+	ValidUntilHeight TransactionAttributeUsage = 0x40 // The last block height the transaction can be packed in
+	NotBeforeHeight  TransactionAttributeUsage = 0x41 // The first block height the transaction can be packed in
+	DescriptionUrl   TransactionAttributeUsage = 0x81
+	Description      TransactionAttributeUsage = 0x90
 )
 
 func IsValidAttributeType(usage TransactionAttributeUsage) bool {
 	return usage == Nonce || usage == Script ||
-		usage == DescriptionUrl || usage == Description
+		usage == DescriptionUrl || usage == Description ||
+		usage == ValidUntilHeight || usage == NotBeforeHeight
+}
+
+// IsHeightAttributeType return whether the attribute is a bound of the validity window of transaction
+func IsHeightAttributeType(usage TransactionAttributeUsage) bool {
+	return usage == ValidUntilHeight || usage == NotBeforeHeight
 }
 
 type TxAttribute struct {
@@ -52,6 +61,25 @@ func NewTxAttribute(u TransactionAttributeUsage, d []byte) TxAttribute {
 	return tx
 }
 
+// NewHeightAttribute return the attribute bounding the validity window of transaction with the block height
+func NewHeightAttribute(usage TransactionAttributeUsage, height uint32) *TxAttribute {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, height)
+	attr := NewTxAttribute(usage, data)
+	return &attr
+}
+
+// GetHeight return the block height of the attribute bounding the validity window
+func (u *TxAttribute) GetHeight() (uint32, error) {
+	if !IsHeightAttributeType(u.Usage) {
+		return 0, fmt.Errorf("attribute usage 0x%x is not a block height", byte(u.Usage))
+	}
+	if len(u.Data) != 4 {
+		return 0, fmt.Errorf("invalid block height length %d of attribute usage 0x%x", len(u.Data), byte(u.Usage))
+	}
+	return binary.LittleEndian.Uint32(u.Data), nil
+}
+
 func (u *TxAttribute) GetSize() uint32 {
 	if u.Usage == DescriptionUrl {
 		return uint32(len([]byte{byte(0xff)}) + len([]byte{byte(0xff)}) + len(u.Data))
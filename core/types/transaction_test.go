@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAttributeTx(attrs ...*TxAttribute) *MutableTransaction {
+	return &MutableTransaction{
+		TxType:     InvokeNeo,
+		Nonce:      1,
+		Payload:    &payload.InvokeCode{Code: []byte{1}},
+		Attributes: attrs,
+	}
+}
+
+func TestTransactionValidityWindow(t *testing.T) {
+	mutable := newAttributeTx()
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Attributes)
+	assert.True(t, tx.IsValidAt(0))
+	assert.False(t, tx.IsExpiredAt(1000000))
+	hash := tx.Hash()
+
+	mutable.SetValidityWindow(100, 200)
+	tx, err = mutable.IntoImmutable()
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, tx.Hash())
+	notBefore, validUntil := tx.GetValidityWindow()
+	assert.Equal(t, uint32(100), notBefore)
+	assert.Equal(t, uint32(200), validUntil)
+	assert.False(t, tx.IsValidAt(99))
+	assert.True(t, tx.IsValidAt(100))
+	assert.True(t, tx.IsValidAt(200))
+	assert.False(t, tx.IsValidAt(201))
+	assert.True(t, tx.IsExpiredAt(201))
+
+	copied, err := tx.IntoMutable()
+	assert.Nil(t, err)
+	assert.Equal(t, tx.Hash(), copied.Hash())
+
+	mutable.SetValidityWindow(0, 200)
+	assert.Equal(t, 1, len(mutable.Attributes))
+	tx, err = mutable.IntoImmutable()
+	assert.Nil(t, err)
+	assert.True(t, tx.IsValidAt(0))
+}
+
+func TestTransactionInvalidAttributes(t *testing.T) {
+	invalids := []*MutableTransaction{
+		newAttributeTx(&TxAttribute{Usage: Description, Data: []byte("desc")}),
+		newAttributeTx(NewHeightAttribute(ValidUntilHeight, 1), NewHeightAttribute(ValidUntilHeight, 2)),
+		newAttributeTx(&TxAttribute{Usage: NotBeforeHeight, Data: []byte{1}}),
+		newAttributeTx(NewHeightAttribute(NotBeforeHeight, 1), NewHeightAttribute(ValidUntilHeight, 2),
+			NewHeightAttribute(ValidUntilHeight, 3)),
+	}
+	for _, mutable := range invalids {
+		sink := common.NewZeroCopySink(nil)
+		assert.Nil(t, mutable.serialize(sink))
+		_, err := TransactionFromRawBytes(sink.Bytes())
+		assert.NotNil(t, err)
+	}
+}
@@ -52,6 +52,11 @@ const (
 	ErrNetVerifyFail        ErrCode = 45019
 	ErrGasPrice             ErrCode = 45020
 	ErrVerifySignature      ErrCode = 45021
+	ErrReplaceUnderpriced   ErrCode = 45022
+	ErrPayerTxsLimit        ErrCode = 45023
+	ErrTxExpired            ErrCode = 45024
+	ErrTxNotValidYet        ErrCode = 45025
+	ErrTxAttribute          ErrCode = 45026
 )
 
 func (err ErrCode) Error() string {
@@ -100,6 +105,16 @@ func (err ErrCode) Error() string {
 		return "invalid gas price"
 	case ErrVerifySignature:
 		return "transaction verify signature fail"
+	case ErrReplaceUnderpriced:
+		return "replacement transaction underpriced"
+	case ErrPayerTxsLimit:
+		return "too many pending transactions of the payer"
+	case ErrTxExpired:
+		return "transaction expired"
+	case ErrTxNotValidYet:
+		return "transaction not valid yet"
+	case ErrTxAttribute:
+		return "transaction attributes not enabled"
 
 	}
 
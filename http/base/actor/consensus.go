@@ -19,7 +19,11 @@
 package actor
 
 import (
+	"errors"
+	"time"
+
 	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology/common/log"
 	cactor "github.com/ontio/ontology/consensus/actor"
 )
 
@@ -44,3 +48,21 @@ func ConsensusSrvHalt() error {
 	}
 	return nil
 }
+
+//GetRoundState from consensus actor, only the vbft consensus answers it
+func GetRoundState() (*cactor.RoundState, error) {
+	if consensusSrvPid == nil {
+		return nil, errors.New("consensus is not started")
+	}
+	future := consensusSrvPid.RequestFuture(&cactor.GetRoundState{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	state, ok := result.(*cactor.RoundState)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return state, nil
+}
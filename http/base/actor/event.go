@@ -27,6 +27,7 @@ import (
 type EventActor struct {
 	blockPersistCompleted func(v interface{})
 	smartCodeEvt          func(v interface{})
+	pendingTx             func(v interface{})
 }
 
 //receive from subscribed actor
@@ -36,17 +37,21 @@ func (t *EventActor) Receive(c actor.Context) {
 		t.blockPersistCompleted(*msg.Block)
 	case *message.SmartCodeEventMsg:
 		t.smartCodeEvt(*msg.Event)
+	case *message.PendingTxMsg:
+		t.pendingTx(msg.Tx)
 	default:
 	}
 }
 
-//Subscribe save block complete and smartcontract Event
+//Subscribe save block complete, smartcontract Event and pending transaction
 func SubscribeEvent(topic string, handler func(v interface{})) {
 	var props = actor.FromProducer(func() actor.Actor {
 		if topic == message.TOPIC_SAVE_BLOCK_COMPLETE {
 			return &EventActor{blockPersistCompleted: handler}
 		} else if topic == message.TOPIC_SMART_CODE_EVENT {
 			return &EventActor{smartCodeEvt: handler}
+		} else if topic == message.TOPIC_PENDING_TX {
+			return &EventActor{pendingTx: handler}
 		} else {
 			return &EventActor{}
 		}
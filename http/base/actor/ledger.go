@@ -22,9 +22,11 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/ledger"
 	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/store"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/smartcontract/event"
 	cstate "github.com/ontio/ontology/smartcontract/states"
+	"github.com/ontio/ontology/smartcontract/trace"
 )
 
 const (
@@ -78,6 +80,42 @@ func GetContractStateFromStore(hash common.Address) (*payload.DeployCode, error)
 	return ledger.DefLedger.GetContractState(hash)
 }
 
+//GetStorageItemByHeight from ledger, archive mode only
+func GetStorageItemByHeight(address common.Address, key []byte, height uint32) ([]byte, error) {
+	return ledger.DefLedger.GetStorageItemByHeight(address, key, height)
+}
+
+//GetContractStateByHeight from ledger, archive mode only
+func GetContractStateByHeight(hash common.Address, height uint32) (*payload.DeployCode, error) {
+	hash = updateNativeSCAddr(hash)
+	return ledger.DefLedger.GetContractStateByHeight(hash, height)
+}
+
+//GetStorageProof from ledger
+func GetStorageProof(address common.Address, key []byte, height uint32) (*store.StorageProof, error) {
+	return ledger.DefLedger.GetStorageProof(address, key, height)
+}
+
+//GetTxsByAddress from ledger, address index only
+func GetTxsByAddress(address common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	return ledger.DefLedger.GetTxsByAddress(address, startHeight, endHeight, offset, limit)
+}
+
+//GetAddressIndexStartHeight from ledger, address index only
+func GetAddressIndexStartHeight() (uint32, error) {
+	return ledger.DefLedger.GetAddressIndexStartHeight()
+}
+
+//GetTxsByContract from ledger, event log only
+func GetTxsByContract(contract common.Address, startHeight, endHeight uint32, offset, limit uint32) ([]*store.AddressTx, error) {
+	return ledger.DefLedger.GetTxsByContract(contract, startHeight, endHeight, offset, limit)
+}
+
+//GetContractIndexStartHeight from ledger, event log only
+func GetContractIndexStartHeight() (uint32, error) {
+	return ledger.DefLedger.GetContractIndexStartHeight()
+}
+
 //GetTxnWithHeightByTxHash from ledger
 func GetTxnWithHeightByTxHash(hash common.Uint256) (uint32, *types.Transaction, error) {
 	tx, height, err := ledger.DefLedger.GetTransactionWithHeight(hash)
@@ -93,6 +131,26 @@ func PreExecuteContractBatch(tx []*types.Transaction, atomic bool) ([]*cstate.Pr
 	return ledger.DefLedger.PreExecuteContractBatch(tx, atomic)
 }
 
+//PreExecuteContractByHeight from ledger, archive mode only
+func PreExecuteContractByHeight(tx *types.Transaction, height uint32) (*cstate.PreExecResult, error) {
+	return ledger.DefLedger.PreExecuteContractByHeight(tx, height)
+}
+
+//TraceTransaction from ledger, archive mode only
+func TraceTransaction(txHash common.Uint256) (*trace.Trace, error) {
+	return ledger.DefLedger.TraceTransaction(txHash)
+}
+
+//TraceCall from ledger
+func TraceCall(tx *types.Transaction) (*trace.Trace, error) {
+	return ledger.DefLedger.TraceCall(tx)
+}
+
+//EstimateGas from ledger
+func EstimateGas(tx *types.Transaction) (uint64, error) {
+	return ledger.DefLedger.EstimateGas(tx)
+}
+
 //GetEventNotifyByTxHash from ledger
 func GetEventNotifyByTxHash(txHash common.Uint256) (*event.ExecuteNotify, error) {
 	return ledger.DefLedger.GetEventNotifyByTx(txHash)
@@ -115,3 +173,13 @@ func GetCrossChainMsg(height uint32) (*types.CrossChainMsg, error) {
 func GetCrossStatesProof(height uint32, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetCrossStatesProof(height, key)
 }
+
+//EnableBlockPrune of ledger, the blocks before the latest numBeforeCurr ones are pruned
+func EnableBlockPrune(numBeforeCurr uint32) {
+	ledger.DefLedger.EnableBlockPrune(numBeforeCurr)
+}
+
+//DisableBlockPrune of ledger
+func DisableBlockPrune() {
+	ledger.DefLedger.DisableBlockPrune()
+}
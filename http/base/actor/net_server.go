@@ -19,8 +19,15 @@
 package actor
 
 import (
+	"errors"
+	"time"
+
+	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/p2pserver/common"
+	"github.com/ontio/ontology/p2pserver/connect_controller"
+	"github.com/ontio/ontology/p2pserver/net/netserver"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer_score"
 )
 
 var netServer p2p.P2P
@@ -29,6 +36,25 @@ func SetNetServer(p2p p2p.P2P) {
 	netServer = p2p
 }
 
+//BlockFetcher fetch the block from the full nodes, used by the light node
+type BlockFetcher interface {
+	FetchBlock(height uint32) (*types.Block, error)
+}
+
+var blockFetcher BlockFetcher
+
+func SetBlockFetcher(fetcher BlockFetcher) {
+	blockFetcher = fetcher
+}
+
+//FetchBlockByHeight fetch the block from the full nodes and save it to ledger
+func FetchBlockByHeight(height uint32) (*types.Block, error) {
+	if blockFetcher == nil {
+		return nil, errors.New("block fetcher is not set")
+	}
+	return blockFetcher.FetchBlock(height)
+}
+
 //GetConnectionCnt from netSever actor
 func GetConnectionCnt() uint32 {
 	if netServer == nil {
@@ -93,3 +119,75 @@ func GetNodeType() uint64 {
 	}
 	return netServer.GetHostInfo().Services
 }
+
+//getNetServer return the net server to be administrated
+func getNetServer() (*netserver.NetServer, error) {
+	ns, ok := netServer.(*netserver.NetServer)
+	if !ok {
+		return nil, errors.New("net server is not available")
+	}
+	return ns, nil
+}
+
+//GetReservedPeers from netSever actor
+func GetReservedPeers() ([]string, error) {
+	ns, err := getNetServer()
+	if err != nil {
+		return nil, err
+	}
+	return ns.ConnectController().GetReservedPeers(), nil
+}
+
+//AddReservedPeer to netSever actor
+func AddReservedPeer(ipOrName string) error {
+	ns, err := getNetServer()
+	if err != nil {
+		return err
+	}
+	return ns.ConnectController().AddReservedPeer(ipOrName)
+}
+
+//RemoveReservedPeer from netSever actor
+func RemoveReservedPeer(ipOrName string) error {
+	ns, err := getNetServer()
+	if err != nil {
+		return err
+	}
+	return ns.ConnectController().RemoveReservedPeer(ipOrName)
+}
+
+//BanPeer to netSever actor for duration, 0 means forever, return the number of the peers disconnected
+func BanPeer(ip string, duration time.Duration) (int, error) {
+	ns, err := getNetServer()
+	if err != nil {
+		return 0, err
+	}
+	return ns.BanPeer(ip, duration), nil
+}
+
+//UnbanPeer from netSever actor, return false if the ip is not banned
+func UnbanPeer(ip string) (bool, error) {
+	ns, err := getNetServer()
+	if err != nil {
+		return false, err
+	}
+	return ns.ConnectController().UnbanPeer(ip), nil
+}
+
+//GetBannedPeers from netSever actor
+func GetBannedPeers() ([]connect_controller.BannedPeer, error) {
+	ns, err := getNetServer()
+	if err != nil {
+		return nil, err
+	}
+	return ns.ConnectController().GetBannedPeers(), nil
+}
+
+//GetPeerScores from netSever actor
+func GetPeerScores() ([]peer_score.PeerScore, error) {
+	ns, err := getNetServer()
+	if err != nil {
+		return nil, err
+	}
+	return ns.GetPeerScores(), nil
+}
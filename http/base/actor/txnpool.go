@@ -45,7 +45,7 @@ func SetTxnPoolPid(actr *actor.PID) {
 //append transaction to pool to txpool actor
 func AppendTxToPool(txn *types.Transaction) (ontErrors.ErrCode, string) {
 	if DisableSyncVerifyTx {
-		txReq := &tcomn.TxReq{txn, tcomn.HttpSender, nil}
+		txReq := &tcomn.TxReq{Tx: txn, Sender: tcomn.HttpSender}
 		txnPid.Tell(txReq)
 		return ontErrors.ErrNoError, ""
 	}
@@ -55,7 +55,7 @@ func AppendTxToPool(txn *types.Transaction) (ontErrors.ErrCode, string) {
 		return ontErrors.ErrUnknown, err.Error()
 	}
 	ch := make(chan *tcomn.TxResult, 1)
-	txReq := &tcomn.TxReq{txn, tcomn.HttpSender, ch}
+	txReq := &tcomn.TxReq{Tx: txn, Sender: tcomn.HttpSender, TxResultCh: ch}
 	txnPid.Tell(txReq)
 	if msg, ok := <-ch; ok {
 		return msg.Err, msg.Desc
@@ -100,18 +100,27 @@ func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
 		return tcomn.TXEntry{}, errors.New("fail")
 	}
 
-	future = txnPid.RequestFuture(&tcomn.GetTxnStatusReq{hash}, REQ_TIMEOUT*time.Second)
-	result, err = future.Result()
+	txStatus, err := GetTxStatusFromPool(hash)
 	if err != nil {
-		log.Errorf(ERR_ACTOR_COMM, err)
 		return tcomn.TXEntry{}, err
 	}
+	txnEntry := tcomn.TXEntry{rsp.Txn, txStatus.TxStatus}
+	return txnEntry, nil
+}
+
+//GetTxStatusFromPool from txpool actor
+func GetTxStatusFromPool(hash common.Uint256) (*tcomn.GetTxnStatusRsp, error) {
+	future := txnPid.RequestFuture(&tcomn.GetTxnStatusReq{hash}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
 	txStatus, ok := result.(*tcomn.GetTxnStatusRsp)
 	if !ok {
-		return tcomn.TXEntry{}, errors.New("fail")
+		return nil, errors.New("fail")
 	}
-	txnEntry := tcomn.TXEntry{rsp.Txn, txStatus.TxStatus}
-	return txnEntry, nil
+	return txStatus, nil
 }
 
 //GetTxnCount from txpool actor
@@ -129,6 +138,21 @@ func GetTxnCount() ([]uint32, error) {
 	return txnCnt.Count, nil
 }
 
+//GetTxnGasPrice from txpool actor
+func GetTxnGasPrice(txn *types.Transaction) (uint64, error) {
+	future := txnPid.RequestFuture(&tcomn.GetTxnGasPriceReq{Tx: txn}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return 0, err
+	}
+	rsp, ok := result.(*tcomn.GetTxnGasPriceRsp)
+	if !ok {
+		return 0, errors.New("fail")
+	}
+	return rsp.GasPrice, nil
+}
+
 //GetTxnHashList from txpool actor
 func GetTxnHashList() ([]common.Uint256, error) {
 	future := txnPid.RequestFuture(&tcomn.GetPendingTxnHashReq{}, REQ_TIMEOUT*time.Second)
@@ -143,3 +167,33 @@ func GetTxnHashList() ([]common.Uint256, error) {
 	}
 	return txnHashList.TxHashs, nil
 }
+
+//FlushTxPool from txpool actor, return the hashes of the txs dropped
+func FlushTxPool() ([]common.Uint256, error) {
+	future := txnPid.RequestFuture(&tcomn.FlushTxnPoolReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	rsp, ok := result.(*tcomn.FlushTxnPoolRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return rsp.TxHashs, nil
+}
+
+//DumpTxPool from txpool actor, return the verified txs and the txs being verified
+func DumpTxPool() ([]*types.Transaction, []*types.Transaction, error) {
+	future := txnPid.RequestFuture(&tcomn.DumpTxnPoolReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, nil, err
+	}
+	rsp, ok := result.(*tcomn.DumpTxnPoolRsp)
+	if !ok {
+		return nil, nil, errors.New("fail")
+	}
+	return rsp.Txs, rsp.Pending, nil
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/constants"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/ledger"
@@ -42,10 +43,12 @@ import (
 	"github.com/ontio/ontology/smartcontract/service/native/ont"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 	cstate "github.com/ontio/ontology/smartcontract/states"
+	"github.com/ontio/ontology/smartcontract/trace"
 	"github.com/ontio/ontology/vm/neovm"
 )
 
 const MAX_SEARCH_HEIGHT uint32 = 100
+const MAX_ADDRESS_TXS_LIMIT uint32 = 100
 const MAX_REQUEST_BODY_SIZE = 1 << 20
 
 type BalanceOfRsp struct {
@@ -61,6 +64,8 @@ type MerkleProof struct {
 	CurBlockRoot     string
 	CurBlockHeight   uint32
 	TargetHashes     []string
+	TxIndex          uint32
+	TxMerkleProof    []string
 }
 
 type LogEventArgs struct {
@@ -77,10 +82,23 @@ type ExecuteNotify struct {
 }
 
 type PreExecuteResult struct {
-	State  byte
-	Gas    uint64
-	Result interface{}
-	Notify []NotifyEventInfo
+	State    byte
+	Gas      uint64
+	Result   interface{}
+	Notify   []NotifyEventInfo
+	WriteSet []*ContractWriteSet `json:",omitempty"`
+}
+
+type ContractWriteSet struct {
+	Contract string
+	Changes  []*StorageChange
+}
+
+type StorageChange struct {
+	Key      string
+	OldValue string
+	NewValue string
+	Deleted  bool
 }
 
 type NotifyEventInfo struct {
@@ -88,6 +106,18 @@ type NotifyEventInfo struct {
 	States          interface{}
 }
 
+type ExecuteTrace struct {
+	State       byte
+	GasConsumed uint64
+	Result      interface{} `json:",omitempty"`
+	Notify      []NotifyEventInfo
+	Error       string `json:",omitempty"`
+	Truncated   bool
+	Steps       []*trace.Step
+	Calls       []*trace.Call
+	Storage     []*trace.StorageAccess
+}
+
 type TxAttributeInfo struct {
 	Usage types.TransactionAttributeUsage
 	Data  string
@@ -114,6 +144,28 @@ type CrossStatesProof struct {
 	AuditPath string
 }
 
+type StorageProof struct {
+	Type   string
+	Height uint32
+	Root   string
+	Key    string
+	Value  string
+	Proof  string
+	Header string
+}
+
+type AddressTx struct {
+	TxHash  string
+	Height  uint32
+	TxIndex uint32
+}
+
+type AddressTxs struct {
+	Address          string
+	IndexStartHeight uint32
+	Txs              []AddressTx
+}
+
 type Transactions struct {
 	Version    byte
 	Nonce      uint32
@@ -164,6 +216,21 @@ type NodeInfo struct {
 	//RxTxnCnt uint64 // The transaction received by this node
 }
 
+type LogLevelsInfo struct {
+	Level   int    // The level of the modules not set
+	Modules string // The levels of the modules, e.g. p2pserver=debug,consensus=info
+	Format  string // The format of the log lines
+}
+
+type TxPoolEntryInfo struct {
+	Hash     string
+	Payer    string
+	Nonce    uint32
+	GasPrice uint64
+	GasLimit uint64
+	Verified bool // false if the transaction is being verified
+}
+
 type ConsensusInfo struct {
 	// TODO
 }
@@ -175,7 +242,9 @@ type TXNAttrInfo struct {
 }
 
 type TXNEntryInfo struct {
-	State []TXNAttrInfo // the result from each validator
+	State      []TXNAttrInfo // the result from each validator
+	Evicted    bool          `json:",omitempty"` // evicted by a tx with higher gas price
+	ReplacedBy string        `json:",omitempty"` // hash of the tx taking its place, empty if evicted as the pool is full
 }
 
 func GetLogEvent(obj *event.LogEventArgs) (map[string]bool, LogEventArgs) {
@@ -201,7 +270,46 @@ func ConvertPreExecuteResult(obj *cstate.PreExecResult) PreExecuteResult {
 	for _, v := range obj.Notify {
 		evts = append(evts, NotifyEventInfo{v.ContractAddress.ToHexString(), v.States})
 	}
-	return PreExecuteResult{obj.State, obj.Gas, obj.Result, evts}
+	return PreExecuteResult{obj.State, obj.Gas, obj.Result, evts, nil}
+}
+
+//ConvertWriteSet groups the storage changes by contract address
+func ConvertWriteSet(changes []*cstate.StorageChange) []*ContractWriteSet {
+	writeSet := make([]*ContractWriteSet, 0)
+	contracts := make(map[common.Address]*ContractWriteSet)
+	for _, change := range changes {
+		set, ok := contracts[change.Contract]
+		if !ok {
+			set = &ContractWriteSet{Contract: change.Contract.ToHexString()}
+			contracts[change.Contract] = set
+			writeSet = append(writeSet, set)
+		}
+		set.Changes = append(set.Changes, &StorageChange{
+			Key:      common.ToHexString(change.Key),
+			OldValue: common.ToHexString(change.OldValue),
+			NewValue: common.ToHexString(change.NewValue),
+			Deleted:  change.Deleted,
+		})
+	}
+	return writeSet
+}
+
+func ConvertExecuteTrace(obj *trace.Trace) *ExecuteTrace {
+	evts := []NotifyEventInfo{}
+	for _, v := range obj.Notify {
+		evts = append(evts, NotifyEventInfo{v.ContractAddress.ToHexString(), v.States})
+	}
+	return &ExecuteTrace{
+		State:       obj.State,
+		GasConsumed: obj.GasConsumed,
+		Result:      obj.Result,
+		Notify:      evts,
+		Error:       obj.Error,
+		Truncated:   obj.Truncated,
+		Steps:       obj.Steps,
+		Calls:       obj.Calls,
+		Storage:     obj.Storage,
+	}
 }
 
 func TransArryByteToHexString(ptx *types.Transaction) *Transactions {
@@ -213,7 +321,13 @@ func TransArryByteToHexString(ptx *types.Transaction) *Transactions {
 	trans.Payer = ptx.Payer.ToBase58()
 	trans.Payload = TransPayloadToHex(ptx.Payload)
 
-	trans.Attributes = make([]TxAttributeInfo, 0)
+	trans.Attributes = make([]TxAttributeInfo, 0, len(ptx.Attributes))
+	for _, attr := range ptx.Attributes {
+		trans.Attributes = append(trans.Attributes, TxAttributeInfo{
+			Usage: attr.Usage,
+			Data:  common.ToHexString(attr.Data),
+		})
+	}
 	trans.Sigs = []Sig{}
 	for _, sigdata := range ptx.Sigs {
 		sig, _ := sigdata.GetSig()
@@ -247,6 +361,145 @@ func TransferCrossChainMsg(msg *types.CrossChainMsg, pks []keypair.PublicKey) st
 	return common.ToHexString(sink.Bytes())
 }
 
+//GetStorageProof return the raw storage state at the end of block height, with the state trie proof and the signed
+//header of block height+1 which commits the state trie root
+func GetStorageProof(address common.Address, key []byte, height uint32) (*StorageProof, error) {
+	proof, err := bactor.GetStorageProof(address, key, height)
+	if err != nil {
+		return nil, err
+	}
+	sink := common.NewZeroCopySink(nil)
+	proof.Proof.Serialization(sink)
+	return &StorageProof{
+		Type:   "StorageProof",
+		Height: proof.Height,
+		Root:   proof.Root.ToHexString(),
+		Key:    common.ToHexString(proof.Key),
+		Value:  common.ToHexString(proof.Value),
+		Proof:  common.ToHexString(sink.Bytes()),
+		Header: common.ToHexString(common.SerializeToBytes(proof.Header)),
+	}, nil
+}
+
+//GetMerkleProof return the proof of the transaction in the transactions root of its block, and the proof of the
+//block in the block root of current block. The light node fetches the block at height from the full nodes if the
+//transaction is not found locally, height 0 means the block height is unknown.
+func GetMerkleProof(hash common.Uint256, height uint32) (*MerkleProof, error) {
+	txHeight, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if (err != nil || tx == nil) && height != 0 && config.DefConfig.Common.LightMode {
+		if _, err = bactor.FetchBlockByHeight(height); err != nil {
+			return nil, fmt.Errorf("fetch block %d error: %s", height, err)
+		}
+		txHeight, tx, err = bactor.GetTxnWithHeightByTxHash(hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %s not found", hash.ToHexString())
+	}
+	block, err := bactor.GetBlockByHeight(txHeight)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	txHashes := make([]common.Uint256, 0, len(block.Transactions))
+	for i, t := range block.Transactions {
+		if t.Hash() == hash {
+			index = i
+		}
+		txHashes = append(txHashes, t.Hash())
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("transaction %s not in block %d", hash.ToHexString(), txHeight)
+	}
+	var txProof []string
+	for _, v := range common.ComputeMerkleProof(txHashes, index) {
+		txProof = append(txProof, v.ToHexString())
+	}
+
+	curHeight := bactor.GetCurrentBlockHeight()
+	curHeader, err := bactor.GetHeaderByHeight(curHeight)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := bactor.GetMerkleProof(txHeight, curHeight)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, v := range proof {
+		hashes = append(hashes, v.ToHexString())
+	}
+	return &MerkleProof{
+		Type:             "MerkleProof",
+		TransactionsRoot: block.Header.TransactionsRoot.ToHexString(),
+		BlockHeight:      txHeight,
+		CurBlockRoot:     curHeader.BlockRoot.ToHexString(),
+		CurBlockHeight:   curHeight,
+		TargetHashes:     hashes,
+		TxIndex:          uint32(index),
+		TxMerkleProof:    txProof,
+	}, nil
+}
+
+//GetTxsByAddress return the transactions touching address in block height range [startHeight, endHeight].
+//limit is the max number of transactions returned, 0 means MAX_ADDRESS_TXS_LIMIT.
+func GetTxsByAddress(address common.Address, startHeight, endHeight uint32, offset, limit uint32) (*AddressTxs, error) {
+	if limit == 0 {
+		limit = MAX_ADDRESS_TXS_LIMIT
+	}
+	if limit > MAX_ADDRESS_TXS_LIMIT {
+		return nil, fmt.Errorf("limit should not be greater than %d", MAX_ADDRESS_TXS_LIMIT)
+	}
+	indexStart, err := bactor.GetAddressIndexStartHeight()
+	if err != nil {
+		return nil, err
+	}
+	txs, err := bactor.GetTxsByAddress(address, startHeight, endHeight, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &AddressTxs{
+		Address:          address.ToBase58(),
+		IndexStartHeight: indexStart,
+		Txs:              make([]AddressTx, 0, len(txs)),
+	}
+	for _, tx := range txs {
+		result.Txs = append(result.Txs, AddressTx{
+			TxHash:  tx.TxHash.ToHexString(),
+			Height:  tx.Height,
+			TxIndex: tx.TxIndex,
+		})
+	}
+	return result, nil
+}
+
+//GetMemPoolTxState return the verified result of a transaction in the tx pool,
+//or the transaction replacing it if it has been evicted.
+func GetMemPoolTxState(hash common.Uint256) (*TXNEntryInfo, error) {
+	txStatus, err := bactor.GetTxStatusFromPool(hash)
+	if err != nil {
+		return nil, err
+	}
+	if txStatus.Evicted {
+		info := &TXNEntryInfo{State: []TXNAttrInfo{}, Evicted: true}
+		if txStatus.ReplacedBy != common.UINT256_EMPTY {
+			info.ReplacedBy = txStatus.ReplacedBy.ToHexString()
+		}
+		return info, nil
+	}
+	txEntry, err := bactor.GetTxFromPool(hash)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []TXNAttrInfo{}
+	for _, t := range txEntry.Attrs {
+		attrs = append(attrs, TXNAttrInfo{t.Height, int(t.Type), int(t.ErrCode)})
+	}
+	return &TXNEntryInfo{State: attrs}, nil
+}
+
 func SendTxToPool(txn *types.Transaction) (ontErrors.ErrCode, string) {
 	if errCode, desc := bactor.AppendTxToPool(txn); errCode != ontErrors.ErrNoError {
 		log.Warn("TxnPool verify error:", errCode.Error())
@@ -312,6 +565,19 @@ func GetBalance(address common.Address) (*BalanceOfRsp, error) {
 	}, nil
 }
 
+//GetBalanceByHeight return the ont and ong balance at the end of block height, archive mode only
+func GetBalanceByHeight(address common.Address, height uint32) (*BalanceOfRsp, error) {
+	balances, err := GetContractBalanceByHeight(0, []common.Address{utils.OntContractAddress, utils.OngContractAddress}, address, height)
+	if err != nil {
+		return nil, fmt.Errorf("get ont balance error:%s", err)
+	}
+	return &BalanceOfRsp{
+		Ont:    fmt.Sprintf("%d", balances[0]),
+		Ong:    fmt.Sprintf("%d", balances[1]),
+		Height: fmt.Sprintf("%d", height),
+	}, nil
+}
+
 func GetGrantOng(addr common.Address) (string, error) {
 	key := append([]byte(ont.UNBOUND_TIME_OFFSET), addr[:]...)
 	value, err := ledger.DefLedger.GetStorageItem(utils.OntContractAddress, key)
@@ -384,6 +650,36 @@ func GetContractBalance(cVersion byte, contractAddres []common.Address, accAddr
 	return balances, height, nil
 }
 
+func GetContractBalanceByHeight(cVersion byte, contractAddres []common.Address, accAddr common.Address, height uint32) ([]uint64, error) {
+	balances := make([]uint64, 0, len(contractAddres))
+	for _, contractAddr := range contractAddres {
+		mutable, err := NewNativeInvokeTransaction(0, 0, contractAddr, cVersion, "balanceOf", []interface{}{accAddr[:]})
+		if err != nil {
+			return nil, fmt.Errorf("NewNativeInvokeTransaction error:%s", err)
+		}
+		tx, err := mutable.IntoImmutable()
+		if err != nil {
+			return nil, err
+		}
+		result, err := bactor.PreExecuteContractByHeight(tx, height)
+		if err != nil {
+			return nil, fmt.Errorf("PrepareInvokeContract error:%s", err)
+		}
+		if result.State == 0 {
+			return nil, fmt.Errorf("prepare invoke failed")
+		}
+		data, err := hex.DecodeString(result.Result.(string))
+		if err != nil {
+			return nil, fmt.Errorf("hex.DecodeString error:%s", err)
+		}
+
+		balance := common.BigIntFromNeoBytes(data)
+		balances = append(balances, balance.Uint64())
+	}
+
+	return balances, nil
+}
+
 func GetContractAllowance(cVersion byte, contractAddr, fromAddr, toAddr common.Address) (uint64, error) {
 	type allowanceStruct struct {
 		From common.Address
@@ -445,6 +741,28 @@ func GetGasPrice() (map[string]interface{}, error) {
 	return result, nil
 }
 
+type GasEstimate struct {
+	GasLimit uint64
+	GasPrice uint64
+}
+
+//EstimateGas returns the minimal gas limit for the transaction to be executed on the current states,
+//with the gas price for it to be accepted by the tx pool
+func EstimateGas(txn *types.Transaction) (*GasEstimate, error) {
+	gasLimit, err := bactor.EstimateGas(txn)
+	if err != nil {
+		return nil, err
+	}
+	if gasLimit < config.DefConfig.Common.GasLimit {
+		gasLimit = config.DefConfig.Common.GasLimit
+	}
+	gasPrice, err := bactor.GetTxnGasPrice(txn)
+	if err != nil {
+		return nil, err
+	}
+	return &GasEstimate{GasLimit: gasLimit, GasPrice: gasPrice}, nil
+}
+
 func GetBlockTransactions(block *types.Block) interface{} {
 	trans := make([]string, len(block.Transactions))
 	for i := 0; i < len(block.Transactions); i++ {
@@ -0,0 +1,293 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/store"
+	scom "github.com/ontio/ontology/core/store/common"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	"github.com/ontio/ontology/smartcontract/event"
+)
+
+const MAX_LOGS_LIMIT = 1000
+
+//LogFilter select the event notify in block height range [FromHeight, ToHeight]
+type LogFilter struct {
+	FromHeight uint32
+	ToHeight   uint32
+	Contracts  []common.Address //contracts of the notify, empty to match any contract
+	States     []string         //prefix of the notify states, empty string matches any state
+	Limit      uint32           //max number of logs returned, 0 for MAX_LOGS_LIMIT
+	Index      uint32           //number of matched logs at FromHeight to skip, taken from the cursor of previous page
+}
+
+type Log struct {
+	TxHash          string
+	Height          uint32
+	ContractAddress string
+	States          interface{}
+}
+
+//LogCursor is the position of the next matched log, the Index-th one matched at block Height
+type LogCursor struct {
+	Height uint32
+	Index  uint32
+}
+
+//Logs is a page of matched logs. Next is nil if no more logs are in the height range, otherwise
+//the following page is queried with FromHeight and Index of Next.
+type Logs struct {
+	Logs []*Log
+	Next *LogCursor
+}
+
+//GetLogs return a page of the event notify matching filter. The blocks indexed by contract are looked up by the
+//contract index, others are scanned block by block, at most MAX_SEARCH_HEIGHT blocks for a page.
+func GetLogs(filter *LogFilter) (*Logs, error) {
+	if filter.FromHeight > filter.ToHeight {
+		return nil, fmt.Errorf("from height %d is greater than to height %d", filter.FromHeight, filter.ToHeight)
+	}
+	if filter.ToHeight > bactor.GetCurrentBlockHeight() {
+		return nil, fmt.Errorf("to height %d is greater than current block height", filter.ToHeight)
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = MAX_LOGS_LIMIT
+	}
+	if limit > MAX_LOGS_LIMIT {
+		return nil, fmt.Errorf("limit should not be greater than %d", MAX_LOGS_LIMIT)
+	}
+	contracts := make(map[common.Address]bool, len(filter.Contracts))
+	for _, contract := range filter.Contracts {
+		contracts[contract] = true
+	}
+	// the first block height looked up by the contract index
+	indexStart := filter.ToHeight + 1
+	if len(contracts) > 0 {
+		if start, err := bactor.GetContractIndexStartHeight(); err == nil && start <= filter.ToHeight {
+			indexStart = start
+			if indexStart < filter.FromHeight {
+				indexStart = filter.FromHeight
+			}
+		}
+	}
+
+	collector := &logCollector{
+		filter:    filter,
+		contracts: contracts,
+		limit:     limit,
+		logs:      make([]*Log, 0),
+		height:    filter.FromHeight,
+	}
+	height := filter.FromHeight
+	if indexStart > height {
+		scanEnd := indexStart
+		if scanEnd-height > MAX_SEARCH_HEIGHT {
+			scanEnd = height + MAX_SEARCH_HEIGHT
+		}
+		for ; height < scanEnd; height++ {
+			notifies, err := bactor.GetEventNotifyByHeight(height)
+			if err != nil {
+				if err == scom.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			for _, notify := range notifies {
+				if collector.add(height, notify) {
+					return collector.result(), nil
+				}
+			}
+		}
+		if height < indexStart {
+			collector.next = &LogCursor{Height: height}
+			return collector.result(), nil
+		}
+	}
+
+	for height <= filter.ToHeight {
+		txs, next, err := getTxsByContracts(contracts, height, filter.ToHeight)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range txs {
+			notify, err := bactor.GetEventNotifyByTxHash(tx.TxHash)
+			if err != nil {
+				if err == scom.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			if collector.add(tx.Height, notify) {
+				return collector.result(), nil
+			}
+		}
+		height = next
+	}
+	return collector.result(), nil
+}
+
+//getTxsByContracts return the transactions of any contract from the contract index in block height range
+//[startHeight, next), sorted by height and tx index. next is the first height not returned yet.
+func getTxsByContracts(contracts map[common.Address]bool, startHeight, endHeight uint32) ([]*store.AddressTx, uint32, error) {
+	// the transactions of all contracts are complete below the horizon
+	horizon := endHeight + 1
+	txs := make(map[common.Uint256]*store.AddressTx)
+	for contract := range contracts {
+		list, err := bactor.GetTxsByContract(contract, startHeight, endHeight, 0, MAX_LOGS_LIMIT)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, tx := range list {
+			txs[tx.TxHash] = tx
+		}
+		if len(list) == MAX_LOGS_LIMIT && list[len(list)-1].Height < horizon {
+			horizon = list[len(list)-1].Height
+		}
+	}
+	if horizon == startHeight {
+		// too many transactions in a single block, read all of them
+		for contract := range contracts {
+			for offset := uint32(0); ; offset += MAX_LOGS_LIMIT {
+				list, err := bactor.GetTxsByContract(contract, startHeight, startHeight, offset, MAX_LOGS_LIMIT)
+				if err != nil {
+					return nil, 0, err
+				}
+				for _, tx := range list {
+					txs[tx.TxHash] = tx
+				}
+				if len(list) < MAX_LOGS_LIMIT {
+					break
+				}
+			}
+		}
+		horizon = startHeight + 1
+	}
+	sorted := make([]*store.AddressTx, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Height < horizon {
+			sorted = append(sorted, tx)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Height != sorted[j].Height {
+			return sorted[i].Height < sorted[j].Height
+		}
+		return sorted[i].TxIndex < sorted[j].TxIndex
+	})
+	return sorted, horizon, nil
+}
+
+//logCollector collect the matched logs into a page, skipping the ones before the cursor
+type logCollector struct {
+	filter    *LogFilter
+	contracts map[common.Address]bool
+	limit     uint32
+	logs      []*Log
+	next      *LogCursor
+	height    uint32 //block height of the last matched log
+	index     uint32 //number of logs matched at height
+}
+
+//add the logs of notify matching the filter, return true if the page is full and next cursor is set
+func (self *logCollector) add(height uint32, notify *event.ExecuteNotify) bool {
+	if height != self.height {
+		self.height = height
+		self.index = 0
+	}
+	for _, n := range notify.Notify {
+		if len(self.contracts) > 0 && !self.contracts[n.ContractAddress] {
+			continue
+		}
+		if !MatchNotifyStates(n.States, self.filter.States) {
+			continue
+		}
+		index := self.index
+		self.index++
+		if height == self.filter.FromHeight && index < self.filter.Index {
+			continue
+		}
+		if uint32(len(self.logs)) >= self.limit {
+			self.next = &LogCursor{Height: height, Index: index}
+			return true
+		}
+		self.logs = append(self.logs, &Log{
+			TxHash:          notify.TxHash.ToHexString(),
+			Height:          height,
+			ContractAddress: n.ContractAddress.ToHexString(),
+			States:          n.States,
+		})
+	}
+	return false
+}
+
+func (self *logCollector) result() *Logs {
+	return &Logs{Logs: self.logs, Next: self.next}
+}
+
+//MatchNotifyStates return whether the notify states start with prefix. A state matches the prefix element
+//if it equals the element, or the hex string of the element as notified by neovm contracts.
+func MatchNotifyStates(states interface{}, prefix []string) bool {
+	if len(prefix) == 0 {
+		return true
+	}
+	list, ok := states.([]interface{})
+	if !ok || len(list) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if p == "" {
+			continue
+		}
+		s, ok := list[i].(string)
+		if !ok || (s != p && s != hex.EncodeToString([]byte(p))) {
+			return false
+		}
+	}
+	return true
+}
+
+//MatchNotifyAddresses return whether any of the notify states is one of the addresses, in base58 or the hex string
+//of the address bytes as notified by neovm contracts.
+func MatchNotifyAddresses(states interface{}, addresses []common.Address) bool {
+	if len(addresses) == 0 {
+		return true
+	}
+	list, ok := states.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, state := range list {
+		s, ok := state.(string)
+		if !ok {
+			continue
+		}
+		for _, addr := range addresses {
+			if s == addr.ToBase58() || s == hex.EncodeToString(addr[:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogCollector(t *testing.T) {
+	contract := common.Address{1}
+	notify := func(txHash byte, states ...string) *event.ExecuteNotify {
+		n := &event.ExecuteNotify{TxHash: common.Uint256{txHash}}
+		for _, s := range states {
+			n.Notify = append(n.Notify, &event.NotifyEventInfo{ContractAddress: contract, States: []interface{}{s}})
+			n.Notify = append(n.Notify, &event.NotifyEventInfo{ContractAddress: common.Address{2}, States: []interface{}{s}})
+		}
+		return n
+	}
+	blocks := map[uint32][]*event.ExecuteNotify{
+		5: {notify(1, "transfer", "approve", "transfer"), notify(2, "transfer")},
+		6: {notify(3, "transfer", "transfer")},
+	}
+	// page through the logs with the returned cursor until all are collected
+	filter := &LogFilter{FromHeight: 5, ToHeight: 6, Contracts: []common.Address{contract}, States: []string{"transfer"}}
+	collect := func() *Logs {
+		collector := &logCollector{filter: filter, contracts: map[common.Address]bool{contract: true}, limit: 2,
+			logs: make([]*Log, 0), height: filter.FromHeight}
+		for height := filter.FromHeight; height <= filter.ToHeight; height++ {
+			for _, n := range blocks[height] {
+				if collector.add(height, n) {
+					return collector.result()
+				}
+			}
+		}
+		return collector.result()
+	}
+	pages := make([]*Logs, 0)
+	for {
+		page := collect()
+		pages = append(pages, page)
+		if page.Next == nil {
+			break
+		}
+		filter.FromHeight, filter.Index = page.Next.Height, page.Next.Index
+	}
+	assert.Equal(t, 3, len(pages))
+	assert.Equal(t, &LogCursor{Height: 5, Index: 2}, pages[0].Next)
+	assert.Equal(t, &LogCursor{Height: 6, Index: 1}, pages[1].Next)
+	txHashes := make([]string, 0)
+	for _, page := range pages {
+		for _, l := range page.Logs {
+			assert.Equal(t, contract.ToHexString(), l.ContractAddress)
+			txHashes = append(txHashes, l.TxHash)
+		}
+	}
+	h := func(b byte) string {
+		hash := common.Uint256{b}
+		return hash.ToHexString()
+	}
+	assert.Equal(t, []string{h(1), h(1), h(2), h(3), h(3)}, txHashes)
+}
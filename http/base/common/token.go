@@ -0,0 +1,309 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/types"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+)
+
+const MAX_TOKEN_BATCH = 50 //max tokens queried in a request
+
+const (
+	TOKEN_TYPE_NATIVE = "native"
+	TOKEN_TYPE_OEP4   = "oep4"
+)
+
+var nativeTokens = map[common.Address]bool{
+	utils.OntContractAddress: true,
+	utils.OngContractAddress: true,
+}
+
+type TokenInfo struct {
+	Contract string
+	Type     string
+	Name     string
+	Symbol   string
+	Decimals uint64
+}
+
+type TokenBalance struct {
+	Contract string
+	Symbol   string
+	Decimals uint64
+	Balance  string
+}
+
+type TokenBalances struct {
+	Address  string
+	Height   uint32
+	Balances []*TokenBalance
+}
+
+type balanceKey struct {
+	contract common.Address
+	account  common.Address
+}
+
+//tokenCache keep the token infos and balances pre-executed at the block height, it's reset once the block height
+//moves
+type tokenCache struct {
+	sync.Mutex
+	height   uint32
+	infos    map[common.Address]*TokenInfo
+	balances map[balanceKey]string
+}
+
+var tokens = &tokenCache{
+	infos:    make(map[common.Address]*TokenInfo),
+	balances: make(map[balanceKey]string),
+}
+
+//reset clear the cache if it's not at height. The caller should hold the lock.
+func (self *tokenCache) reset(height uint32) {
+	if self.height == height {
+		return
+	}
+	self.height = height
+	self.infos = make(map[common.Address]*TokenInfo)
+	self.balances = make(map[balanceKey]string)
+}
+
+func (self *tokenCache) getInfo(contract common.Address) (*TokenInfo, bool) {
+	self.Lock()
+	defer self.Unlock()
+	self.reset(bactor.GetCurrentBlockHeight())
+	info, ok := self.infos[contract]
+	return info, ok
+}
+
+func (self *tokenCache) addInfo(height uint32, contract common.Address, info *TokenInfo) {
+	self.Lock()
+	defer self.Unlock()
+	self.reset(height)
+	self.infos[contract] = info
+}
+
+func (self *tokenCache) getBalance(contract, account common.Address) (string, uint32, bool) {
+	self.Lock()
+	defer self.Unlock()
+	self.reset(bactor.GetCurrentBlockHeight())
+	balance, ok := self.balances[balanceKey{contract, account}]
+	return balance, self.height, ok
+}
+
+func (self *tokenCache) addBalance(height uint32, contract, account common.Address, balance string) {
+	self.Lock()
+	defer self.Unlock()
+	self.reset(height)
+	self.balances[balanceKey{contract, account}] = balance
+}
+
+//GetTokenInfos return the name, symbol and decimals of the native assets and OEP-4 tokens
+func GetTokenInfos(contracts []common.Address) ([]*TokenInfo, error) {
+	if len(contracts) > MAX_TOKEN_BATCH {
+		return nil, fmt.Errorf("more than %d tokens in a request", MAX_TOKEN_BATCH)
+	}
+	infos := make([]*TokenInfo, 0, len(contracts))
+	for _, contract := range contracts {
+		info, ok := tokens.getInfo(contract)
+		if !ok {
+			var height uint32
+			var err error
+			info, height, err = getTokenInfo(contract)
+			if err != nil {
+				return nil, err
+			}
+			tokens.addInfo(height, contract, info)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+//GetTokenBalances return the balances of account in the native assets and OEP-4 tokens
+func GetTokenBalances(account common.Address, contracts []common.Address) (*TokenBalances, error) {
+	infos, err := GetTokenInfos(contracts)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &TokenBalances{
+		Address:  account.ToBase58(),
+		Balances: make([]*TokenBalance, len(contracts)),
+	}
+	txes := make([]*types.Transaction, 0, len(contracts))
+	missing := make([]int, 0, len(contracts))
+	for i, contract := range contracts {
+		rsp.Balances[i] = &TokenBalance{
+			Contract: infos[i].Contract,
+			Symbol:   infos[i].Symbol,
+			Decimals: infos[i].Decimals,
+		}
+		if balance, height, ok := tokens.getBalance(contract, account); ok {
+			rsp.Balances[i].Balance, rsp.Height = balance, height
+			continue
+		}
+		tx, err := newTokenInvokeTransaction(infos[i].Type, contract, "balanceOf", account[:])
+		if err != nil {
+			return nil, err
+		}
+		txes = append(txes, tx)
+		missing = append(missing, i)
+	}
+	if len(txes) == 0 {
+		return rsp, nil
+	}
+	results, height, err := bactor.PreExecuteContractBatch(txes, true)
+	if err != nil {
+		return nil, fmt.Errorf("PrepareInvokeContract error:%s", err)
+	}
+	for j, i := range missing {
+		data, err := getPreExecBytes(results[j].State, results[j].Result)
+		if err != nil {
+			return nil, fmt.Errorf("balanceOf of token %s error: %s", infos[i].Contract, err)
+		}
+		balance := common.BigIntFromNeoBytes(data).String()
+		tokens.addBalance(height, contracts[i], account, balance)
+		rsp.Balances[i].Balance = balance
+	}
+	rsp.Height = height
+	return rsp, nil
+}
+
+//getTokenInfo detect the token type of contract, and pre-execute its name, symbol and decimals methods
+func getTokenInfo(contract common.Address) (*TokenInfo, uint32, error) {
+	tokenType := TOKEN_TYPE_NATIVE
+	if !nativeTokens[contract] {
+		dc, err := bactor.GetContractStateFromStore(contract)
+		if err != nil || dc == nil {
+			return nil, 0, fmt.Errorf("contract %s not found", contract.ToHexString())
+		}
+		if dc.VmType() != payload.NEOVM_TYPE {
+			return nil, 0, fmt.Errorf("contract %s is not a neovm contract", contract.ToHexString())
+		}
+		tokenType = TOKEN_TYPE_OEP4
+	}
+	methods := []string{"name", "symbol", "decimals"}
+	txes := make([]*types.Transaction, 0, len(methods))
+	for _, method := range methods {
+		tx, err := newTokenInvokeTransaction(tokenType, contract, method)
+		if err != nil {
+			return nil, 0, err
+		}
+		txes = append(txes, tx)
+	}
+	results, height, err := bactor.PreExecuteContractBatch(txes, true)
+	if err != nil {
+		return nil, 0, fmt.Errorf("contract %s is not a token: %s", contract.ToHexString(), err)
+	}
+	values := make([][]byte, 0, len(results))
+	for i, result := range results {
+		data, err := getPreExecBytes(result.State, result.Result)
+		if err != nil {
+			return nil, 0, fmt.Errorf("contract %s is not a token, %s error: %s", contract.ToHexString(),
+				methods[i], err)
+		}
+		values = append(values, data)
+	}
+	decimals := common.BigIntFromNeoBytes(values[2])
+	if !decimals.IsUint64() {
+		return nil, 0, fmt.Errorf("contract %s is not a token, invalid decimals", contract.ToHexString())
+	}
+	return &TokenInfo{
+		Contract: contract.ToHexString(),
+		Type:     tokenType,
+		Name:     string(values[0]),
+		Symbol:   string(values[1]),
+		Decimals: decimals.Uint64(),
+	}, height, nil
+}
+
+//newTokenInvokeTransaction build the transaction invoking the token method, the native assets are invoked by
+//native invoke code, and the OEP-4 tokens by neovm invoke code
+func newTokenInvokeTransaction(tokenType string, contract common.Address, method string,
+	args ...interface{}) (*types.Transaction, error) {
+	var mutable *types.MutableTransaction
+	var err error
+	if tokenType == TOKEN_TYPE_NATIVE {
+		// the native invoke code takes exactly one argument
+		if len(args) == 0 {
+			args = []interface{}{""}
+		}
+		mutable, err = NewNativeInvokeTransaction(0, 0, contract, 0, method, args)
+	} else {
+		mutable, err = NewNeovmInvokeTransaction(0, 0, contract, []interface{}{method, args})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("build %s invoke transaction error:%s", method, err)
+	}
+	return mutable.IntoImmutable()
+}
+
+//getPreExecBytes return the bytes returned by the pre-executed transaction
+func getPreExecBytes(state byte, result interface{}) ([]byte, error) {
+	if state == 0 {
+		return nil, fmt.Errorf("prepare invoke failed")
+	}
+	str, ok := result.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result %v", result)
+	}
+	return hex.DecodeString(str)
+}
+
+//ParseTokenContracts parse the token contract addresses, in a list of string or a string separated by comma
+func ParseTokenContracts(param interface{}) ([]common.Address, error) {
+	var list []string
+	switch v := param.(type) {
+	case string:
+		if v != "" {
+			list = strings.Split(v, ",")
+		}
+	case []interface{}:
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid token contract %v", item)
+			}
+			list = append(list, str)
+		}
+	default:
+		return nil, fmt.Errorf("invalid token contracts %v", param)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no token contract")
+	}
+	contracts := make([]common.Address, 0, len(list))
+	for _, str := range list {
+		contract, err := GetAddress(strings.TrimSpace(str))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token contract %s", str)
+		}
+		contracts = append(contracts, contract)
+	}
+	return contracts, nil
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokenContracts(t *testing.T) {
+	ont, ong := utils.OntContractAddress, utils.OngContractAddress
+	contracts, err := ParseTokenContracts(ont.ToHexString() + ", " + ong.ToBase58())
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{ont, ong}, contracts)
+	contracts, err = ParseTokenContracts([]interface{}{ong.ToHexString()})
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{ong}, contracts)
+
+	for _, param := range []interface{}{"", []interface{}{}, []interface{}{1}, "ont", nil} {
+		_, err = ParseTokenContracts(param)
+		assert.NotNil(t, err)
+	}
+}
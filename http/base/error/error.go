@@ -27,6 +27,7 @@ const (
 	SERVICE_CEILING    int64 = 41002
 	ILLEGAL_DATAFORMAT int64 = 41003
 	INVALID_VERSION    int64 = 41004
+	UNAUTHORIZED       int64 = 41005
 
 	INVALID_METHOD int64 = 42001
 	INVALID_PARAMS int64 = 42002
@@ -51,6 +52,7 @@ var ErrMap = map[int64]string{
 	SERVICE_CEILING:    "SERVICE CEILING",
 	ILLEGAL_DATAFORMAT: "ILLEGAL DATAFORMAT",
 	INVALID_VERSION:    "INVALID VERSION",
+	UNAUTHORIZED:       "UNAUTHORIZED",
 
 	INVALID_METHOD: "INVALID METHOD",
 	INVALID_PARAMS: "INVALID PARAMS",
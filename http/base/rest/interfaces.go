@@ -19,11 +19,14 @@
 package rest
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/payload"
 	scom "github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/types"
 	ontErrors "github.com/ontio/ontology/errors"
@@ -31,6 +34,7 @@ import (
 	bcomn "github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	cstate "github.com/ontio/ontology/smartcontract/states"
 )
 
 const TLS_PORT int = 443
@@ -40,6 +44,22 @@ type ApiServer interface {
 	Stop()
 }
 
+// getStateHeight return the optional block height of historical state query
+func getStateHeight(cmd map[string]interface{}) (uint32, bool, error) {
+	param, ok := cmd["Height"].(string)
+	if !ok || len(param) == 0 {
+		return 0, false, nil
+	}
+	height, err := strconv.ParseUint(param, 10, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid height param")
+	}
+	if !config.DefConfig.Common.EnableArchive {
+		return 0, false, fmt.Errorf("query by height is only supported in archive mode")
+	}
+	return uint32(height), true, nil
+}
+
 // get node verison
 func GetNodeVersion(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -268,15 +288,31 @@ func SendRawTransaction(cmd map[string]interface{}) map[string]interface{} {
 	hash = txn.Hash()
 	log.Debugf("SendRawTransaction recv %s", hash.ToHexString())
 	if txn.TxType == types.InvokeNeo || txn.TxType == types.InvokeWasm || txn.TxType == types.Deploy {
-		if preExec, ok := cmd["PreExec"].(string); ok && preExec == "1" {
-			rst, err := bactor.PreExecuteContract(txn)
+		// the storage write set is also returned if PreExec is 2
+		if preExec, ok := cmd["PreExec"].(string); ok && (preExec == "1" || preExec == "2") {
+			height, hasHeight, err := getStateHeight(cmd)
+			if err != nil {
+				resp = ResponsePack(berr.INVALID_PARAMS)
+				resp["Result"] = err.Error()
+				return resp
+			}
+			var rst *cstate.PreExecResult
+			if hasHeight {
+				rst, err = bactor.PreExecuteContractByHeight(txn, height)
+			} else {
+				rst, err = bactor.PreExecuteContract(txn)
+			}
 			if err != nil {
-				log.Infof("PreExec: ", err)
+				log.Infof("PreExec: %s", err)
 				resp = ResponsePack(berr.SMARTCODE_ERROR)
 				resp["Result"] = err.Error()
 				return resp
 			}
-			resp["Result"] = bcomn.ConvertPreExecuteResult(rst)
+			result := bcomn.ConvertPreExecuteResult(rst)
+			if preExec == "2" {
+				result.WriteSet = bcomn.ConvertWriteSet(rst.WriteSet)
+			}
+			resp["Result"] = result
 			return resp
 		}
 	}
@@ -355,6 +391,133 @@ func GetSmartCodeEventByTxHash(cmd map[string]interface{}) map[string]interface{
 	return resp
 }
 
+//get smartcontract event matching the filter
+func GetLogs(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+	resp := ResponsePack(berr.SUCCESS)
+	from, ok := cmd["FromHeight"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	fromHeight, err := strconv.ParseUint(from, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	to, ok := cmd["ToHeight"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	toHeight, err := strconv.ParseUint(to, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	filter := &bcomn.LogFilter{FromHeight: uint32(fromHeight), ToHeight: uint32(toHeight)}
+	if contracts, ok := cmd["Contracts"].(string); ok && len(contracts) > 0 {
+		for _, str := range strings.Split(contracts, ",") {
+			address, err := bcomn.GetAddress(str)
+			if err != nil {
+				return ResponsePack(berr.INVALID_PARAMS)
+			}
+			filter.Contracts = append(filter.Contracts, address)
+		}
+	}
+	if states, ok := cmd["States"].(string); ok && len(states) > 0 {
+		filter.States = strings.Split(states, ",")
+	}
+	for name, arg := range map[string]*uint32{"Limit": &filter.Limit, "Index": &filter.Index} {
+		param, ok := cmd[name].(string)
+		if !ok || len(param) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		*arg = uint32(v)
+	}
+	logs, err := bcomn.GetLogs(filter)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = logs
+	return resp
+}
+
+//re-execute a transaction on the states before its block and get the execution trace, only available in archive mode
+func TraceTransaction(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	result, err := bactor.TraceTransaction(hash)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = bcomn.ConvertExecuteTrace(result)
+	return resp
+}
+
+//pre-execute a transaction on the current states and get the execution trace
+func TraceCall(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Data"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	bys, err := common.HexToBytes(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	txn, err := types.TransactionFromRawBytes(bys)
+	if err != nil {
+		return ResponsePack(berr.INVALID_TRANSACTION)
+	}
+	result, err := bactor.TraceCall(txn)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = bcomn.ConvertExecuteTrace(result)
+	return resp
+}
+
+//estimate the gas limit and gas price of a raw transaction
+func EstimateGas(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Data"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	bys, err := common.HexToBytes(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	txn, err := types.TransactionFromRawBytes(bys)
+	if err != nil {
+		return ResponsePack(berr.INVALID_TRANSACTION)
+	}
+	result, err := bcomn.EstimateGas(txn)
+	if err != nil {
+		resp = ResponsePack(berr.SMARTCODE_ERROR)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = result
+	return resp
+}
+
 //get contract state
 func GetContractState(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -366,7 +529,18 @@ func GetContractState(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	contract, err := bactor.GetContractStateFromStore(address)
+	height, hasHeight, err := getStateHeight(cmd)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	var contract *payload.DeployCode
+	if hasHeight {
+		contract, err = bactor.GetContractStateByHeight(address, height)
+	} else {
+		contract, err = bactor.GetContractStateFromStore(address)
+	}
 	if err != nil {
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
@@ -399,7 +573,18 @@ func GetStorage(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	value, err := bactor.GetStorageItem(address, item)
+	height, hasHeight, err := getStateHeight(cmd)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	var value []byte
+	if hasHeight {
+		value, err = bactor.GetStorageItemByHeight(address, item, height)
+	} else {
+		value, err = bactor.GetStorageItem(address, item)
+	}
 	if err != nil {
 		if err == scom.ErrNotFound {
 			return ResponsePack(berr.SUCCESS)
@@ -410,8 +595,45 @@ func GetStorage(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
-//get balance of address
-func GetBalance(cmd map[string]interface{}) map[string]interface{} {
+//get storage with state trie proof
+func GetStorageProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	str, ok = cmd["Key"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	key, err := common.HexToBytes(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	height := bactor.GetCurrentBlockHeight()
+	if param, ok := cmd["Height"].(string); ok && len(param) > 0 {
+		h, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		height = uint32(h)
+	}
+	proof, err := bcomn.GetStorageProof(address, key, height)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = proof
+	return resp
+}
+
+//get transactions touching the address, only available when address index is enabled
+func GetTxsByAddress(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
 	addrBase58, ok := cmd["Addr"].(string)
 	if !ok {
@@ -421,52 +643,130 @@ func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	balance, err := bcomn.GetBalance(address)
+	// startHeight, endHeight, offset, limit
+	args := []uint32{0, bactor.GetCurrentBlockHeight(), 0, 0}
+	for i, name := range []string{"StartHeight", "EndHeight", "Offset", "Limit"} {
+		param, ok := cmd[name].(string)
+		if !ok || len(param) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		args[i] = uint32(v)
+	}
+	txs, err := bcomn.GetTxsByAddress(address, args[0], args[1], args[2], args[3])
 	if err != nil {
-		return ResponsePack(berr.INVALID_PARAMS)
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
 	}
-	resp["Result"] = balance
+	resp["Result"] = txs
 	return resp
 }
 
-//get merkle proof by transaction hash
-func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
+//get balance of address
+func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
-	str, ok := cmd["Hash"].(string)
+	addrBase58, ok := cmd["Addr"].(string)
 	if !ok {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	hash, err := common.Uint256FromHexString(str)
+	address, err := common.AddressFromBase58(addrBase58)
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	height, hasHeight, err := getStateHeight(cmd)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	var balance *bcomn.BalanceOfRsp
+	if hasHeight {
+		balance, err = bcomn.GetBalanceByHeight(address, height)
+	} else {
+		balance, err = bcomn.GetBalance(address)
+	}
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	if tx == nil {
+	resp["Result"] = balance
+	return resp
+}
+
+//get balances of address in the native assets and OEP-4 tokens
+func GetTokenBalance(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	addrStr, ok := cmd["Addr"].(string)
+	if !ok {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	header, err := bactor.GetHeaderByHeight(height)
+	address, err := bcomn.GetAddress(addrStr)
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
+	contracts, err := bcomn.ParseTokenContracts(cmd["Tokens"])
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	balances, err := bcomn.GetTokenBalances(address, contracts)
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = balances
+	return resp
+}
 
-	curHeight := bactor.GetCurrentBlockHeight()
-	curHeader, err := bactor.GetHeaderByHeight(curHeight)
+//get name, symbol and decimals of the native assets and OEP-4 tokens
+func GetTokenInfo(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	contracts, err := bcomn.ParseTokenContracts(cmd["Tokens"])
+	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	infos, err := bcomn.GetTokenInfos(contracts)
 	if err != nil {
+		resp = ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = infos
+	return resp
+}
+
+//get merkle proof by transaction hash
+func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	proof, err := bactor.GetMerkleProof(uint32(height), uint32(curHeight))
+	hash, err := common.Uint256FromHexString(str)
 	if err != nil {
-		return ResponsePack(berr.INTERNAL_ERROR)
+		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	var hashes []string
-	for _, v := range proof {
-		hashes = append(hashes, v.ToHexString())
+	//the light node fetches the block of the transaction at the height given
+	var height uint32
+	if heightStr, ok := cmd["Height"].(string); ok && heightStr != "" {
+		h, err := strconv.ParseUint(heightStr, 10, 32)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		height = uint32(h)
 	}
-	resp["Result"] = bcomn.MerkleProof{"MerkleProof", header.TransactionsRoot.ToHexString(), height,
-		curHeader.BlockRoot.ToHexString(), curHeight, hashes}
+	proof, err := bcomn.GetMerkleProof(hash, height)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	resp["Result"] = proof
 	return resp
 }
 
@@ -585,14 +885,10 @@ func GetMemPoolTxState(cmd map[string]interface{}) map[string]interface{} {
 	if err != nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	txEntry, err := bactor.GetTxFromPool(hash)
+	info, err := bcomn.GetMemPoolTxState(hash)
 	if err != nil {
 		return ResponsePack(berr.UNKNOWN_TRANSACTION)
 	}
-	attrs := []bcomn.TXNAttrInfo{}
-	for _, t := range txEntry.Attrs {
-		attrs = append(attrs, bcomn.TXNAttrInfo{t.Height, int(t.Type), int(t.ErrCode)})
-	}
-	resp["Result"] = bcomn.TXNEntryInfo{attrs}
+	resp["Result"] = info
 	return resp
 }
@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rpc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	berr "github.com/ontio/ontology/http/base/error"
+)
+
+//ADMIN_TOKEN_SCHEME is the scheme of the Authorization header carrying the admin token
+const ADMIN_TOKEN_SCHEME = "Bearer "
+
+//rpcCaller is the caller of the local rpc server, which is allowed to call the admin methods
+type rpcCaller struct {
+	remoteAddr string
+	authorized bool //whether the admin token is verified
+}
+
+//auditEntry is a line of the audit log file
+type auditEntry struct {
+	Time   string        `json:"time"`
+	Remote string        `json:"remote"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	Error  interface{}   `json:"error"`
+	Desc   interface{}   `json:"desc"`
+}
+
+//auditLog is the file the audit entries are appended to, besides the node log
+var auditLog struct {
+	sync.Mutex
+	file *os.File
+}
+
+//HandleAdminFunc register an admin method, which is only served by the local rpc server. The admin methods require
+//the token if it is configured, and each call is audited.
+func HandleAdminFunc(pattern string, handler func([]interface{}) map[string]interface{}) {
+	mainMux.Lock()
+	defer mainMux.Unlock()
+	mainMux.admin[pattern] = handler
+}
+
+//LocalHandle answers the rpc call of the local rpc server, which also serves the admin methods
+func LocalHandle(w http.ResponseWriter, r *http.Request) {
+	handle(w, r, &rpcCaller{remoteAddr: r.RemoteAddr, authorized: checkAdminToken(r)})
+}
+
+//checkAdminToken return whether the request carries the configured admin token, always true if no token configured
+func checkAdminToken(r *http.Request) bool {
+	token := config.DefConfig.Rpc.LocalAdminToken
+	if token == "" {
+		return true
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, ADMIN_TOKEN_SCHEME) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(ADMIN_TOKEN_SCHEME):]), []byte(token)) == 1
+}
+
+//callAdmin call the admin method if the caller is authorized, and audit the call
+func (self *rpcCaller) callAdmin(method string, params []interface{},
+	function func([]interface{}) map[string]interface{}) map[string]interface{} {
+	var resp map[string]interface{}
+	if self.authorized {
+		resp = function(params)
+	} else {
+		resp = responsePack(berr.UNAUTHORIZED, "")
+	}
+	audit(self.remoteAddr, method, params, resp)
+	return resp
+}
+
+//OpenAuditLog open the file the audit log of the admin methods is appended to
+func OpenAuditLog(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log %s error:%s", path, err)
+	}
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file != nil {
+		auditLog.file.Close()
+	}
+	auditLog.file = file
+	return nil
+}
+
+//audit record the call of an admin method in the node log and the audit log file
+func audit(remoteAddr, method string, params []interface{}, resp map[string]interface{}) {
+	log.WithFields(log.Fields{
+		"remote": remoteAddr,
+		"params": params,
+		"error":  resp["error"],
+	}).Infof("[admin] %s called", method)
+
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+	line, err := json.Marshal(&auditEntry{
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Remote: remoteAddr,
+		Method: method,
+		Params: params,
+		Error:  resp["error"],
+		Desc:   resp["desc"],
+	})
+	if err != nil {
+		log.Errorf("[admin] marshal audit entry of %s error:%s", method, err)
+		return
+	}
+	if _, err := auditLog.file.Write(append(line, '\n')); err != nil {
+		log.Errorf("[admin] write audit entry of %s error:%s", method, err)
+	}
+}
@@ -20,6 +20,8 @@ package rpc
 
 import (
 	"encoding/hex"
+	"fmt"
+	"math"
 
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
@@ -32,8 +34,24 @@ import (
 	bcomn "github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
+	cstate "github.com/ontio/ontology/smartcontract/states"
 )
 
+// getStateHeight return the optional block height of historical state query at params[index]
+func getStateHeight(params []interface{}, index int) (uint32, bool, error) {
+	if len(params) <= index {
+		return 0, false, nil
+	}
+	height, ok := params[index].(float64)
+	if !ok || height < 0 || height > math.MaxUint32 {
+		return 0, false, fmt.Errorf("invalid height param")
+	}
+	if !config.DefConfig.Common.EnableArchive {
+		return 0, false, fmt.Errorf("query by height is only supported in archive mode")
+	}
+	return uint32(height), true, nil
+}
+
 //get best block hash
 func GetBestBlockHash(params []interface{}) map[string]interface{} {
 	hash := bactor.CurrentBlockHash()
@@ -171,15 +189,10 @@ func GetMemPoolTxState(params []interface{}) map[string]interface{} {
 		if err != nil {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
-		txEntry, err := bactor.GetTxFromPool(hash)
+		info, err := bcomn.GetMemPoolTxState(hash)
 		if err != nil {
 			return responsePack(berr.UNKNOWN_TRANSACTION, "unknown transaction")
 		}
-		attrs := []bcomn.TXNAttrInfo{}
-		for _, t := range txEntry.Attrs {
-			attrs = append(attrs, bcomn.TXNAttrInfo{t.Height, int(t.Type), int(t.ErrCode)})
-		}
-		info := bcomn.TXNEntryInfo{attrs}
 		return responseSuccess(info)
 	default:
 		return responsePack(berr.INVALID_PARAMS, "")
@@ -261,7 +274,16 @@ func GetStorage(params []interface{}) map[string]interface{} {
 	default:
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
-	value, err := bactor.GetStorageItem(address, key)
+	height, hasHeight, err := getStateHeight(params, 2)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	var value []byte
+	if hasHeight {
+		value, err = bactor.GetStorageItemByHeight(address, key, height)
+	} else {
+		value, err = bactor.GetStorageItem(address, key)
+	}
 	if err != nil {
 		if err == scom.ErrNotFound {
 			return responseSuccess(nil)
@@ -271,9 +293,81 @@ func GetStorage(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(value))
 }
 
+//get storage with state trie proof
+// A JSON example for getstorageproof method as following:
+//   {"jsonrpc": "2.0", "method": "getstorageproof", "params": ["code hash", "key", height], "id": 0}
+// height is optional, default to current block height
+func GetStorageProof(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok = params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height := bactor.GetCurrentBlockHeight()
+	if len(params) > 2 {
+		h, ok := params[2].(float64)
+		if !ok || h < 0 || h > math.MaxUint32 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		height = uint32(h)
+	}
+	proof, err := bcomn.GetStorageProof(address, key, height)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(proof)
+}
+
+//get transactions touching the address, only available when address index is enabled
+// A JSON example for gettxsbyaddress method as following:
+//   {"jsonrpc": "2.0", "method": "gettxsbyaddress", "params": ["address", startHeight, endHeight, offset, limit], "id": 0}
+// all params but address are optional, the height range defaults to [0, current block height]
+func GetTxsByAddress(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := common.AddressFromBase58(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	// startHeight, endHeight, offset, limit
+	args := []uint32{0, bactor.GetCurrentBlockHeight(), 0, 0}
+	for i := 1; i < len(params) && i <= len(args); i++ {
+		v, ok := params[i].(float64)
+		if !ok || v < 0 || v > math.MaxUint32 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		args[i-1] = uint32(v)
+	}
+	txs, err := bcomn.GetTxsByAddress(address, args[0], args[1], args[2], args[3])
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(txs)
+}
+
 //send raw transaction
 // A JSON example for sendrawtransaction method as following:
 //   {"jsonrpc": "2.0", "method": "sendrawtransaction", "params": ["raw transactioin in hex"], "id": 0}
+// the transaction is pre-executed if the second param is 1, and the storage write set is also returned if it is 2
 func SendRawTransaction(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return responsePack(berr.INVALID_PARAMS, nil)
@@ -295,13 +389,26 @@ func SendRawTransaction(params []interface{}) map[string]interface{} {
 		if txn.TxType == types.InvokeNeo || txn.TxType == types.Deploy || txn.TxType == types.InvokeWasm {
 			if len(params) > 1 {
 				preExec, ok := params[1].(float64)
-				if ok && preExec == 1 {
-					result, err := bactor.PreExecuteContract(txn)
+				if ok && (preExec == 1 || preExec == 2) {
+					height, hasHeight, err := getStateHeight(params, 2)
+					if err != nil {
+						return responsePack(berr.INVALID_PARAMS, err.Error())
+					}
+					var result *cstate.PreExecResult
+					if hasHeight {
+						result, err = bactor.PreExecuteContractByHeight(txn, height)
+					} else {
+						result, err = bactor.PreExecuteContract(txn)
+					}
 					if err != nil {
-						log.Infof("PreExec: ", err)
+						log.Infof("PreExec: %s", err)
 						return responsePack(berr.SMARTCODE_ERROR, err.Error())
 					}
-					return responseSuccess(bcomn.ConvertPreExecuteResult(result))
+					rst := bcomn.ConvertPreExecuteResult(result)
+					if preExec == 2 {
+						rst.WriteSet = bcomn.ConvertWriteSet(result.WriteSet)
+					}
+					return responseSuccess(rst)
 				}
 			}
 		}
@@ -333,6 +440,10 @@ func GetContractState(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return responsePack(berr.INVALID_PARAMS, nil)
 	}
+	height, hasHeight, err := getStateHeight(params, 2)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
 	var contract *payload.DeployCode
 	switch params[0].(type) {
 	case string:
@@ -341,7 +452,12 @@ func GetContractState(params []interface{}) map[string]interface{} {
 		if err != nil {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
-		c, err := bactor.GetContractStateFromStore(address)
+		var c *payload.DeployCode
+		if hasHeight {
+			c, err = bactor.GetContractStateByHeight(address, height)
+		} else {
+			c, err = bactor.GetContractStateFromStore(address)
+		}
 		if err != nil {
 			return responsePack(berr.UNKNOWN_CONTRACT, berr.ErrMap[berr.UNKNOWN_CONTRACT])
 		}
@@ -413,6 +529,146 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 	return responsePack(berr.INVALID_PARAMS, "")
 }
 
+//get smartconstract event matching the filter
+// A JSON example for getlogs method as following:
+//   {"jsonrpc": "2.0", "method": "getlogs", "params": [fromHeight, toHeight, ["contract address"], ["transfer"], limit, index], "id": 0}
+// all params but the height range are optional. The result is a page of at most limit logs, the next page is queried
+// with the height and index of the returned Next cursor as fromHeight and index
+func GetLogs(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return responsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	from, ok := params[0].(float64)
+	if !ok || from < 0 || from > math.MaxUint32 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	to, ok := params[1].(float64)
+	if !ok || to < 0 || to > math.MaxUint32 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	filter := &bcomn.LogFilter{FromHeight: uint32(from), ToHeight: uint32(to)}
+	if len(params) > 2 {
+		contracts, ok := params[2].([]interface{})
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		for _, c := range contracts {
+			str, ok := c.(string)
+			if !ok {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			address, err := bcomn.GetAddress(str)
+			if err != nil {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			filter.Contracts = append(filter.Contracts, address)
+		}
+	}
+	if len(params) > 3 {
+		states, ok := params[3].([]interface{})
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		for _, s := range states {
+			str, ok := s.(string)
+			if !ok {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			filter.States = append(filter.States, str)
+		}
+	}
+	for i, arg := range []*uint32{&filter.Limit, &filter.Index} {
+		if len(params) <= 4+i {
+			break
+		}
+		v, ok := params[4+i].(float64)
+		if !ok || v < 0 || v > math.MaxUint32 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		*arg = uint32(v)
+	}
+	logs, err := bcomn.GetLogs(filter)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(logs)
+}
+
+//re-execute a transaction on the states before its block and get the execution trace, only available in archive mode
+// A JSON example for tracetransaction method as following:
+//   {"jsonrpc": "2.0", "method": "tracetransaction", "params": ["transaction hash in hex"], "id": 0}
+func TraceTransaction(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	result, err := bactor.TraceTransaction(hash)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(bcomn.ConvertExecuteTrace(result))
+}
+
+//pre-execute a transaction on the current states and get the execution trace
+// A JSON example for tracecall method as following:
+//   {"jsonrpc": "2.0", "method": "tracecall", "params": ["raw transactioin in hex"], "id": 0}
+func TraceCall(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	raw, err := common.HexToBytes(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	txn, err := types.TransactionFromRawBytes(raw)
+	if err != nil {
+		return responsePack(berr.INVALID_TRANSACTION, "")
+	}
+	result, err := bactor.TraceCall(txn)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(bcomn.ConvertExecuteTrace(result))
+}
+
+//estimate the gas limit and gas price of a raw transaction
+func EstimateGas(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	raw, err := common.HexToBytes(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	txn, err := types.TransactionFromRawBytes(raw)
+	if err != nil {
+		return responsePack(berr.INVALID_TRANSACTION, "")
+	}
+	result, err := bcomn.EstimateGas(txn)
+	if err != nil {
+		return responsePack(berr.SMARTCODE_ERROR, err.Error())
+	}
+	return responseSuccess(result)
+}
+
 //get block height by transaction hash
 func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -451,13 +707,62 @@ func GetBalance(params []interface{}) map[string]interface{} {
 	if err != nil {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
-	rsp, err := bcomn.GetBalance(address)
+	height, hasHeight, err := getStateHeight(params, 1)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	var rsp *bcomn.BalanceOfRsp
+	if hasHeight {
+		rsp, err = bcomn.GetBalanceByHeight(address, height)
+	} else {
+		rsp, err = bcomn.GetBalance(address)
+	}
 	if err != nil {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	return responseSuccess(rsp)
 }
 
+//get balances of address in the native assets and OEP-4 tokens
+func GetTokenBalance(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	addrStr, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(addrStr)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	contracts, err := bcomn.ParseTokenContracts(params[1])
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	rsp, err := bcomn.GetTokenBalances(address, contracts)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(rsp)
+}
+
+//get name, symbol and decimals of the native assets and OEP-4 tokens
+func GetTokenInfo(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	contracts, err := bcomn.ParseTokenContracts(params[0])
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	rsp, err := bcomn.GetTokenInfos(contracts)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(rsp)
+}
+
 //get allowance
 func GetAllowance(params []interface{}) map[string]interface{} {
 	if len(params) < 3 {
@@ -503,30 +808,20 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 	if err != nil {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
-	height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
-		return responsePack(berr.INVALID_PARAMS, "")
-	}
-	header, err := bactor.GetHeaderByHeight(height)
-	if err != nil {
-		return responsePack(berr.INVALID_PARAMS, "")
-	}
-
-	curHeight := bactor.GetCurrentBlockHeight()
-	curHeader, err := bactor.GetHeaderByHeight(curHeight)
-	if err != nil {
-		return responsePack(berr.INVALID_PARAMS, "")
+	//the light node fetches the block of the transaction at the height given
+	var height uint32
+	if len(params) >= 2 {
+		h, ok := params[1].(float64)
+		if !ok || h < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		height = uint32(h)
 	}
-	proof, err := bactor.GetMerkleProof(uint32(height), uint32(curHeight))
+	proof, err := bcomn.GetMerkleProof(hash, height)
 	if err != nil {
-		return responsePack(berr.INTERNAL_ERROR, "")
-	}
-	var hashes []string
-	for _, v := range proof {
-		hashes = append(hashes, v.ToHexString())
+		return responsePack(berr.INVALID_PARAMS, err.Error())
 	}
-	return responseSuccess(bcomn.MerkleProof{"MerkleProof", header.TransactionsRoot.ToHexString(), height,
-		curHeader.BlockRoot.ToHexString(), curHeight, hashes})
+	return responseSuccess(proof)
 }
 
 //get block transactions by height
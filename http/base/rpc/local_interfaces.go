@@ -19,11 +19,13 @@
 package rpc
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/types"
 	bactor "github.com/ontio/ontology/http/base/actor"
 	"github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
@@ -100,3 +102,244 @@ func SetDebugInfo(params []interface{}) map[string]interface{} {
 	}
 	return responsePack(berr.SUCCESS, true)
 }
+
+//SetLogLevels sets the log levels of the modules, e.g. "p2pserver=debug,consensus=info". An empty string clears the
+//module levels.
+func SetLogLevels(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	spec, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if err := log.SetModuleLevels(spec); err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//GetLogLevels returns the log level, the levels of the modules and the log format
+func GetLogLevels(params []interface{}) map[string]interface{} {
+	return responseSuccess(common.LogLevelsInfo{
+		Level:   log.Log.GetDebugLevel(),
+		Modules: log.GetModuleLevels(),
+		Format:  log.GetFormat(),
+	})
+}
+
+//AddReservedPeer adds an ip or domain to the reserved peers, which can only be updated with the reserved peers enabled
+func AddReservedPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	peer, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if err := bactor.AddReservedPeer(peer); err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return GetReservedPeers(nil)
+}
+
+//RemoveReservedPeer removes an ip or domain from the reserved peers, the last one can not be removed
+func RemoveReservedPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	peer, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if err := bactor.RemoveReservedPeer(peer); err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return GetReservedPeers(nil)
+}
+
+//GetReservedPeers returns the reserved peers, empty if the reserved peers are not enabled
+func GetReservedPeers(params []interface{}) map[string]interface{} {
+	peers, err := bactor.GetReservedPeers()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return responseSuccess(peers)
+}
+
+//parsePeerIp parses the ip of a peer, given as ip or ip:port
+func parsePeerIp(param interface{}) (string, bool) {
+	addr, ok := param.(string)
+	if !ok {
+		return "", false
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", false
+	}
+	return ip.String(), true
+}
+
+//BanPeer bans the ip of a peer for the optional duration in seconds, forever by default, and disconnects it,
+//returns the number of the peers disconnected
+func BanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	ip, ok := parsePeerIp(params[0])
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	var duration time.Duration
+	if len(params) >= 2 {
+		seconds, ok := params[1].(float64)
+		if !ok || seconds < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	closed, err := bactor.BanPeer(ip, duration)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return responseSuccess(closed)
+}
+
+//UnbanPeer removes the ip of a peer from the ban list
+func UnbanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	ip, ok := parsePeerIp(params[0])
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	unbanned, err := bactor.UnbanPeer(ip)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	if !unbanned {
+		return responsePack(berr.INVALID_PARAMS, "peer not banned")
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//GetBannedPeers returns the banned ip list with the unix time each ban expires at, 0 means never
+func GetBannedPeers(params []interface{}) map[string]interface{} {
+	peers, err := bactor.GetBannedPeers()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return responseSuccess(peers)
+}
+
+//GetPeerScores returns the misbehaviour scores of the peers
+func GetPeerScores(params []interface{}) map[string]interface{} {
+	scores, err := bactor.GetPeerScores()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return responseSuccess(scores)
+}
+
+//SetLogLevel sets the log level by its name, e.g. "debug", or its number
+func SetLogLevel(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	var level int
+	switch val := params[0].(type) {
+	case float64:
+		level = int(val)
+	case string:
+		var err error
+		if level, err = log.ParseLevel(val); err != nil {
+			return responsePack(berr.INVALID_PARAMS, err.Error())
+		}
+	default:
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if err := log.Log.SetDebugLevel(level); err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//SetBlockPrune enables or disables the block prune. The blocks before the latest ones of the number in the second
+//param are pruned if enabled.
+func SetBlockPrune(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	enable, ok := params[0].(bool)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if !enable {
+		bactor.DisableBlockPrune()
+		return responsePack(berr.SUCCESS, true)
+	}
+	var numBeforeCurr uint32
+	if len(params) >= 2 {
+		num, ok := params[1].(float64)
+		if !ok || num < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		numBeforeCurr = uint32(num)
+	}
+	bactor.EnableBlockPrune(numBeforeCurr)
+	return responsePack(berr.SUCCESS, true)
+}
+
+//FlushTxPool drops the verified transactions in the tx pool, returns the hashes of them
+func FlushTxPool(params []interface{}) map[string]interface{} {
+	hashes, err := bactor.FlushTxPool()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	result := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		result = append(result, hash.ToHexString())
+	}
+	return responseSuccess(result)
+}
+
+//DumpTxPool returns the transactions in the tx pool, including the ones being verified
+func DumpTxPool(params []interface{}) map[string]interface{} {
+	txs, pending, err := bactor.DumpTxPool()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	result := make([]common.TxPoolEntryInfo, 0, len(txs)+len(pending))
+	for _, tx := range txs {
+		result = append(result, txPoolEntryInfo(tx, true))
+	}
+	for _, tx := range pending {
+		result = append(result, txPoolEntryInfo(tx, false))
+	}
+	return responseSuccess(result)
+}
+
+func txPoolEntryInfo(tx *types.Transaction, verified bool) common.TxPoolEntryInfo {
+	hash := tx.Hash()
+	return common.TxPoolEntryInfo{
+		Hash:     hash.ToHexString(),
+		Payer:    tx.Payer.ToBase58(),
+		Nonce:    tx.Nonce,
+		GasPrice: tx.GasPrice,
+		GasLimit: tx.GasLimit,
+		Verified: verified,
+	}
+}
+
+//GetRoundState returns the state of the current vbft round
+func GetRoundState(params []interface{}) map[string]interface{} {
+	state, err := bactor.GetRoundState()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	return responseSuccess(state)
+}
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,6 +30,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/http/base/common"
 	berr "github.com/ontio/ontology/http/base/error"
@@ -36,6 +38,7 @@ import (
 
 func init() {
 	mainMux.m = make(map[string]func([]interface{}) map[string]interface{})
+	mainMux.admin = make(map[string]func([]interface{}) map[string]interface{})
 }
 
 //an instance of the multiplexer
@@ -45,6 +48,7 @@ var mainMux ServeMux
 type ServeMux struct {
 	sync.RWMutex
 	m               map[string]func([]interface{}) map[string]interface{}
+	admin           map[string]func([]interface{}) map[string]interface{} // only served by the local rpc server
 	defaultFunction func(http.ResponseWriter, *http.Request)
 }
 
@@ -60,9 +64,168 @@ func SetDefaultFunc(def func(http.ResponseWriter, *http.Request)) {
 	mainMux.defaultFunction = def
 }
 
+// standard error codes of JSON-RPC 2.0
+const (
+	JSONRPC_PARSE_ERROR      int64 = -32700
+	JSONRPC_INVALID_REQUEST  int64 = -32600
+	JSONRPC_METHOD_NOT_FOUND int64 = -32601
+	JSONRPC_INVALID_PARAMS   int64 = -32602
+)
+
+var jsonRpcErrMap = map[int64]string{
+	JSONRPC_PARSE_ERROR:      "Parse error",
+	JSONRPC_INVALID_REQUEST:  "Invalid Request",
+	JSONRPC_METHOD_NOT_FOUND: "Method not found",
+	JSONRPC_INVALID_PARAMS:   "Invalid params",
+}
+
+// the ontology error code of the legacy response for the JSON-RPC errors
+var jsonRpcLegacyErrMap = map[int64]int64{
+	JSONRPC_PARSE_ERROR:      berr.ILLEGAL_DATAFORMAT,
+	JSONRPC_INVALID_REQUEST:  berr.INVALID_PARAMS,
+	JSONRPC_METHOD_NOT_FOUND: berr.INVALID_METHOD,
+	JSONRPC_INVALID_PARAMS:   berr.INVALID_PARAMS,
+}
+
+//result of a single rpc call
+type rpcResult struct {
+	id        interface{}
+	hasId     bool
+	jsonRpcV2 bool
+	errCode   int64 //JSON-RPC error code, 0 if the request is handled by the method
+	errData   interface{}
+	response  map[string]interface{} //response of the method
+}
+
+//isNotification return whether the request is a JSON-RPC 2.0 notification, which needs no response
+func (self *rpcResult) isNotification() bool {
+	return self.jsonRpcV2 && !self.hasId
+}
+
+//legacy return the response compatible with the existing clients, whose error is ontology error code
+func (self *rpcResult) legacy() map[string]interface{} {
+	if self.errCode != 0 {
+		return map[string]interface{}{
+			"jsonrpc": "2.0",
+			"error":   jsonRpcLegacyErrMap[self.errCode],
+			"desc":    berr.ErrMap[jsonRpcLegacyErrMap[self.errCode]],
+			"result":  self.errorObject(),
+			"id":      self.id,
+		}
+	}
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"error":   self.response["error"],
+		"desc":    self.response["desc"],
+		"result":  self.response["result"],
+		"id":      self.id,
+	}
+}
+
+//errorObject return the JSON-RPC 2.0 error object
+func (self *rpcResult) errorObject() map[string]interface{} {
+	if self.errCode != 0 {
+		return map[string]interface{}{
+			"code":    self.errCode,
+			"message": jsonRpcErrMap[self.errCode],
+			"data":    self.errData,
+		}
+	}
+	code, _ := self.response["error"].(int64)
+	return map[string]interface{}{
+		"code":    code,
+		"message": self.response["desc"],
+		"data":    self.response["result"],
+	}
+}
+
+//standard return the JSON-RPC 2.0 response
+func (self *rpcResult) standard() map[string]interface{} {
+	resp := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      self.id,
+	}
+	if self.errCode == 0 && self.response["error"] == berr.SUCCESS {
+		resp["result"] = self.response["result"]
+	} else {
+		resp["error"] = self.errorObject()
+	}
+	return resp
+}
+
+//call decode the request and call the corresponding function, the admin methods are only called by the local
+//caller, which is nil for the other servers
+func call(data json.RawMessage, caller *rpcCaller) *rpcResult {
+	request := make(map[string]interface{})
+	if err := json.Unmarshal(data, &request); err != nil {
+		log.Error("HTTP JSON RPC Handle - json.Unmarshal: ", err)
+		return &rpcResult{errCode: JSONRPC_INVALID_REQUEST, errData: err.Error()}
+	}
+	result := &rpcResult{}
+	result.id, result.hasId = request["id"]
+	result.jsonRpcV2 = request["jsonrpc"] == "2.0"
+	method, ok := request["method"].(string)
+	if !ok {
+		log.Error("HTTP JSON RPC Handle - method is not string: ")
+		result.errCode, result.errData = JSONRPC_INVALID_REQUEST, "method should be string"
+		return result
+	}
+	//get the corresponding function
+	function, ok := mainMux.m[method]
+	admin := false
+	if !ok && caller != nil {
+		function, ok = mainMux.admin[method]
+		admin = ok
+	}
+	if !ok {
+		log.Warn("HTTP JSON RPC Handle - No function to call for ", method)
+		result.errCode, result.errData = JSONRPC_METHOD_NOT_FOUND, "The called method was not found on the server"
+		return result
+	}
+	param := []interface{}{}
+	if request["params"] != nil {
+		param, ok = request["params"].([]interface{})
+		if !ok {
+			log.Error("HTTP JSON RPC Handle - parameter should be array")
+			result.errCode, result.errData = JSONRPC_INVALID_PARAMS, "params should be array"
+			return result
+		}
+	}
+	if admin {
+		result.response = caller.callAdmin(method, param, function)
+	} else {
+		result.response = function(param)
+	}
+	return result
+}
+
+func writeResponse(w http.ResponseWriter, resp interface{}) {
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("content-type", "application/json;charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Error("HTTP JSON RPC Handle - json.Marshal: ", err)
+		return
+	}
+	w.Write(data)
+}
+
 // this is the function that should be called in order to answer an rpc call
 // should be registered like "http.HandleFunc("/", httpjsonrpc.Handle)"
+//
+// A single request is answered with the legacy response, whose error is the ontology error code.
+// A batch request is answered with an array of JSON-RPC 2.0 responses, whose error is the error object.
+// JSON-RPC 2.0 notifications, which have no id, are not answered.
 func Handle(w http.ResponseWriter, r *http.Request) {
+	handle(w, r, nil)
+}
+
+func handle(w http.ResponseWriter, r *http.Request, caller *rpcCaller) {
 	mainMux.RLock()
 	defer mainMux.RUnlock()
 	if r.Method == "OPTIONS" {
@@ -93,68 +256,58 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	request := make(map[string]interface{})
 	defer r.Body.Close()
+	var request json.RawMessage
 	decoder := json.NewDecoder(io.LimitReader(r.Body, common.MAX_REQUEST_BODY_SIZE))
 	err := decoder.Decode(&request)
 	if err != nil {
 		log.Error("HTTP JSON RPC Handle - json.Unmarshal: ", err)
+		result := &rpcResult{errCode: JSONRPC_PARSE_ERROR, errData: err.Error()}
+		writeResponse(w, result.legacy())
 		return
 	}
-	if request["method"] == nil {
-		log.Error("HTTP JSON RPC Handle - method not found: ")
+	request = bytes.TrimSpace(request)
+	if len(request) == 0 || request[0] != '[' {
+		result := call(request, caller)
+		if result.isNotification() {
+			writeResponse(w, nil)
+			return
+		}
+		writeResponse(w, result.legacy())
 		return
 	}
-	method, ok := request["method"].(string)
-	if !ok {
-		log.Error("HTTP JSON RPC Handle - method is not string: ")
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(request, &batch); err != nil {
+		result := &rpcResult{errCode: JSONRPC_PARSE_ERROR, errData: err.Error()}
+		writeResponse(w, result.standard())
 		return
 	}
-	//get the corresponding function
-	function, ok := mainMux.m[method]
-	if ok {
-		param, ok := request["params"].([]interface{})
-		if !ok {
-			log.Error("HTTP JSON RPC Handle - parameter should be array")
-			return
-		}
-		response := function(param)
-		data, err := json.Marshal(map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error":   response["error"],
-			"desc":    response["desc"],
-			"result":  response["result"],
-			"id":      request["id"],
-		})
-		if err != nil {
-			log.Error("HTTP JSON RPC Handle - json.Marshal: ", err)
-			return
-		}
-		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("content-type", "application/json;charset=utf-8")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Write(data)
-	} else {
-		//if the function does not exist
-		log.Warn("HTTP JSON RPC Handle - No function to call for ", request["method"])
-		data, err := json.Marshal(map[string]interface{}{
-			"error": berr.INVALID_METHOD,
-			"result": map[string]interface{}{
-				"code":    -32601,
-				"message": "Method not found",
-				"data":    "The called method was not found on the server",
-			},
-			"id": request["id"],
-		})
-		if err != nil {
-			log.Error("HTTP JSON RPC Handle - json.Marshal: ", err)
-			return
+	if len(batch) == 0 {
+		result := &rpcResult{errCode: JSONRPC_INVALID_REQUEST, errData: "empty batch"}
+		writeResponse(w, result.standard())
+		return
+	}
+	maxBatchSize := config.DefConfig.Rpc.MaxBatchSize
+	if maxBatchSize > 0 && uint(len(batch)) > maxBatchSize {
+		result := &rpcResult{errCode: JSONRPC_INVALID_REQUEST,
+			errData: fmt.Sprintf("batch size %d exceeds the limit %d", len(batch), maxBatchSize)}
+		writeResponse(w, result.standard())
+		return
+	}
+	responses := make([]map[string]interface{}, 0, len(batch))
+	for _, req := range batch {
+		result := call(req, caller)
+		if result.isNotification() {
+			continue
 		}
-		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("content-type", "application/json;charset=utf-8")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Write(data)
+		responses = append(responses, result.standard())
+	}
+	if len(responses) == 0 {
+		writeResponse(w, nil)
+		return
 	}
+	writeResponse(w, responses)
 }
 
 // Call sends RPC request to server
@@ -0,0 +1,204 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/http/base/common"
+	berr "github.com/ontio/ontology/http/base/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	HandleFunc("echo", func(params []interface{}) map[string]interface{} {
+		if len(params) == 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		return responseSuccess(params[0])
+	})
+	HandleAdminFunc("adminecho", func(params []interface{}) map[string]interface{} {
+		return responseSuccess(params)
+	})
+}
+
+func post(t *testing.T, body string) (int, []byte) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	Handle(w, req)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestHandleSingle(t *testing.T) {
+	_, data := post(t, `{"jsonrpc":"2.0","method":"echo","params":["a"],"id":1}`)
+	resp := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(data, &resp))
+	assert.Equal(t, float64(berr.SUCCESS), resp["error"])
+	assert.Equal(t, "a", resp["result"])
+	assert.Equal(t, float64(1), resp["id"])
+
+	_, data = post(t, `{"jsonrpc":"2.0","method":"unknown","params":[],"id":2}`)
+	resp = make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(data, &resp))
+	assert.Equal(t, float64(berr.INVALID_METHOD), resp["error"])
+	assert.Equal(t, float64(JSONRPC_METHOD_NOT_FOUND), resp["result"].(map[string]interface{})["code"])
+
+	code, data := post(t, `{"jsonrpc":"2.0","method":"echo","params":["a"]}`)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, 0, len(data))
+}
+
+func TestHandleBatch(t *testing.T) {
+	_, data := post(t, `[
+		{"jsonrpc":"2.0","method":"echo","params":["a"],"id":1},
+		{"jsonrpc":"2.0","method":"echo","params":["b"]},
+		{"jsonrpc":"2.0","method":"echo","params":[],"id":"x"},
+		{"jsonrpc":"2.0","method":"unknown","id":3},
+		1
+	]`)
+	var resps []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &resps))
+	assert.Equal(t, 4, len(resps))
+	assert.Equal(t, "a", resps[0]["result"])
+	assert.Nil(t, resps[0]["error"])
+	assert.Equal(t, float64(berr.INVALID_PARAMS), resps[1]["error"].(map[string]interface{})["code"])
+	assert.Equal(t, "x", resps[1]["id"])
+	assert.Equal(t, float64(JSONRPC_METHOD_NOT_FOUND), resps[2]["error"].(map[string]interface{})["code"])
+	assert.Equal(t, float64(JSONRPC_INVALID_REQUEST), resps[3]["error"].(map[string]interface{})["code"])
+	assert.Nil(t, resps[3]["id"])
+
+	code, _ := post(t, `[{"jsonrpc":"2.0","method":"echo","params":["a"]}]`)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	resp := make(map[string]interface{})
+	_, data = post(t, `[]`)
+	assert.Nil(t, json.Unmarshal(data, &resp))
+	assert.Equal(t, float64(JSONRPC_INVALID_REQUEST), resp["error"].(map[string]interface{})["code"])
+
+	maxBatchSize := config.DefConfig.Rpc.MaxBatchSize
+	config.DefConfig.Rpc.MaxBatchSize = 1
+	defer func() { config.DefConfig.Rpc.MaxBatchSize = maxBatchSize }()
+	resp = make(map[string]interface{})
+	_, data = post(t, `[{"jsonrpc":"2.0","method":"echo","params":["a"],"id":1},{"jsonrpc":"2.0","method":"echo","params":["a"],"id":2}]`)
+	assert.Nil(t, json.Unmarshal(data, &resp))
+	assert.Equal(t, float64(JSONRPC_INVALID_REQUEST), resp["error"].(map[string]interface{})["code"])
+}
+
+func TestSetLogLevels(t *testing.T) {
+	defer log.SetModuleLevels("")
+
+	resp := SetLogLevels([]interface{}{"p2pserver=debug,consensus=info"})
+	assert.Equal(t, berr.SUCCESS, resp["error"])
+	resp = GetLogLevels(nil)
+	info := resp["result"].(common.LogLevelsInfo)
+	assert.Equal(t, "consensus=info,p2pserver=debug", info.Modules)
+	assert.Equal(t, log.TEXT_FORMAT, info.Format)
+
+	resp = SetLogLevels([]interface{}{"p2pserver=verbose"})
+	assert.Equal(t, berr.INVALID_PARAMS, resp["error"])
+	resp = SetLogLevels([]interface{}{1.0})
+	assert.Equal(t, berr.INVALID_PARAMS, resp["error"])
+	assert.Equal(t, "consensus=info,p2pserver=debug", log.GetModuleLevels())
+}
+
+func postLocal(t *testing.T, body, token string) map[string]interface{} {
+	req := httptest.NewRequest("POST", "/local", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", ADMIN_TOKEN_SCHEME+token)
+	}
+	w := httptest.NewRecorder()
+	LocalHandle(w, req)
+	resp := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestAdminMethods(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	auditPath := filepath.Join(dir, "audit.log")
+	assert.Nil(t, OpenAuditLog(auditPath))
+	defer func() {
+		auditLog.file.Close()
+		auditLog.file = nil
+		config.DefConfig.Rpc.LocalAdminToken = ""
+	}()
+
+	// the admin methods are not served by the other servers
+	_, data := post(t, `{"jsonrpc":"2.0","method":"adminecho","params":["a"],"id":1}`)
+	resp := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(data, &resp))
+	assert.Equal(t, float64(berr.INVALID_METHOD), resp["error"])
+
+	resp = postLocal(t, `{"jsonrpc":"2.0","method":"adminecho","params":["a"],"id":1}`, "")
+	assert.Equal(t, float64(berr.SUCCESS), resp["error"])
+	assert.Equal(t, []interface{}{"a"}, resp["result"])
+
+	config.DefConfig.Rpc.LocalAdminToken = "secret"
+	resp = postLocal(t, `{"jsonrpc":"2.0","method":"adminecho","params":["b"],"id":2}`, "")
+	assert.Equal(t, float64(berr.UNAUTHORIZED), resp["error"])
+	resp = postLocal(t, `{"jsonrpc":"2.0","method":"adminecho","params":["c"],"id":3}`, "wrong")
+	assert.Equal(t, float64(berr.UNAUTHORIZED), resp["error"])
+	resp = postLocal(t, `{"jsonrpc":"2.0","method":"adminecho","params":["d"],"id":4}`, "secret")
+	assert.Equal(t, float64(berr.SUCCESS), resp["error"])
+	// the other methods need no token
+	resp = postLocal(t, `{"jsonrpc":"2.0","method":"echo","params":["e"],"id":5}`, "")
+	assert.Equal(t, float64(berr.SUCCESS), resp["error"])
+
+	data, err = ioutil.ReadFile(auditPath)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, 4, len(lines))
+	expected := []struct {
+		param string
+		err   int64
+	}{{"a", berr.SUCCESS}, {"b", berr.UNAUTHORIZED}, {"c", berr.UNAUTHORIZED}, {"d", berr.SUCCESS}}
+	for i, line := range lines {
+		entry := &auditEntry{}
+		assert.Nil(t, json.Unmarshal([]byte(line), entry))
+		assert.Equal(t, "adminecho", entry.Method)
+		assert.Equal(t, []interface{}{expected[i].param}, entry.Params)
+		assert.Equal(t, float64(expected[i].err), entry.Error)
+	}
+}
+
+func TestSetBlockPruneParams(t *testing.T) {
+	resp := SetBlockPrune([]interface{}{"yes"})
+	assert.Equal(t, berr.INVALID_PARAMS, resp["error"])
+	resp = SetBlockPrune([]interface{}{true, -1.0})
+	assert.Equal(t, berr.INVALID_PARAMS, resp["error"])
+
+	_, ok := parsePeerIp("1.2.3.4:20338")
+	assert.True(t, ok)
+	ip, ok := parsePeerIp("::1")
+	assert.True(t, ok)
+	assert.Equal(t, "::1", ip)
+	_, ok = parsePeerIp("www.example.com")
+	assert.False(t, ok)
+}
@@ -44,6 +44,7 @@ func StartRPCServer() error {
 	rpc.HandleFunc("getrawtransaction", rpc.GetRawTransaction)
 	rpc.HandleFunc("sendrawtransaction", rpc.SendRawTransaction)
 	rpc.HandleFunc("getstorage", rpc.GetStorage)
+	rpc.HandleFunc("getstorageproof", rpc.GetStorageProof)
 	rpc.HandleFunc("getversion", rpc.GetNodeVersion)
 	rpc.HandleFunc("getnetworkid", rpc.GetNetworkId)
 
@@ -52,9 +53,16 @@ func StartRPCServer() error {
 	rpc.HandleFunc("getmempooltxstate", rpc.GetMemPoolTxState)
 	rpc.HandleFunc("getmempooltxhashlist", rpc.GetMemPoolTxHashList)
 	rpc.HandleFunc("getsmartcodeevent", rpc.GetSmartCodeEvent)
+	rpc.HandleFunc("getlogs", rpc.GetLogs)
+	rpc.HandleFunc("tracetransaction", rpc.TraceTransaction)
+	rpc.HandleFunc("tracecall", rpc.TraceCall)
+	rpc.HandleFunc("estimategas", rpc.EstimateGas)
 	rpc.HandleFunc("getblockheightbytxhash", rpc.GetBlockHeightByTxHash)
+	rpc.HandleFunc("gettxsbyaddress", rpc.GetTxsByAddress)
 
 	rpc.HandleFunc("getbalance", rpc.GetBalance)
+	rpc.HandleFunc("gettokenbalance", rpc.GetTokenBalance)
+	rpc.HandleFunc("gettokeninfo", rpc.GetTokenInfo)
 	rpc.HandleFunc("getallowance", rpc.GetAllowance)
 	rpc.HandleFunc("getmerkleproof", rpc.GetMerkleProof)
 	rpc.HandleFunc("getblocktxsbyheight", rpc.GetBlockTxsByHeight)
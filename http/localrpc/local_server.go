@@ -36,16 +36,46 @@ const (
 
 func StartLocalServer() error {
 	log.Debug()
-	http.HandleFunc(LOCAL_DIR, rpc.Handle)
+	if path := cfg.DefConfig.Rpc.LocalAuditLog; path != "" {
+		if err := rpc.OpenAuditLog(path); err != nil {
+			return err
+		}
+	}
+	// the local server has its own mux, so that the admin methods are not served by the other servers
+	mux := http.NewServeMux()
+	mux.HandleFunc(LOCAL_DIR, rpc.LocalHandle)
 
 	rpc.HandleFunc("getneighbor", rpc.GetNeighbor)
 	rpc.HandleFunc("getnodestate", rpc.GetNodeState)
 	rpc.HandleFunc("startconsensus", rpc.StartConsensus)
 	rpc.HandleFunc("stopconsensus", rpc.StopConsensus)
 	rpc.HandleFunc("setdebuginfo", rpc.SetDebugInfo)
+	rpc.HandleFunc("getloglevels", rpc.GetLogLevels)
+
+	// used by the console attached to the node
+	rpc.HandleFunc("getblockcount", rpc.GetBlockCount)
+	rpc.HandleFunc("getnetworkid", rpc.GetNetworkId)
+	rpc.HandleFunc("getbalance", rpc.GetBalance)
+	rpc.HandleFunc("getrawtransaction", rpc.GetRawTransaction)
+	rpc.HandleFunc("sendrawtransaction", rpc.SendRawTransaction)
+	rpc.HandleFunc("getsmartcodeevent", rpc.GetSmartCodeEvent)
+
+	// admin methods, which require the admin token if configured and are audited
+	rpc.HandleAdminFunc("addreservedpeer", rpc.AddReservedPeer)
+	rpc.HandleAdminFunc("removereservedpeer", rpc.RemoveReservedPeer)
+	rpc.HandleAdminFunc("getreservedpeers", rpc.GetReservedPeers)
+	rpc.HandleAdminFunc("banpeer", rpc.BanPeer)
+	rpc.HandleAdminFunc("unbanpeer", rpc.UnbanPeer)
+	rpc.HandleAdminFunc("getbannedpeers", rpc.GetBannedPeers)
+	rpc.HandleAdminFunc("getpeerscores", rpc.GetPeerScores)
+	rpc.HandleAdminFunc("setloglevel", rpc.SetLogLevel)
+	rpc.HandleAdminFunc("setloglevels", rpc.SetLogLevels)
+	rpc.HandleAdminFunc("setblockprune", rpc.SetBlockPrune)
+	rpc.HandleAdminFunc("flushtxpool", rpc.FlushTxPool)
+	rpc.HandleAdminFunc("dumptxpool", rpc.DumpTxPool)
+	rpc.HandleAdminFunc("getroundstate", rpc.GetRoundState)
 
-	// TODO: only listen to local host
-	err := http.ListenAndServe(LOCAL_HOST+":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpLocalPort)), nil)
+	err := http.ListenAndServe(LOCAL_HOST+":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpLocalPort)), mux)
 	if err != nil {
 		return fmt.Errorf("ListenAndServe error:%s", err)
 	}
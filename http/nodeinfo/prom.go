@@ -65,10 +65,21 @@ var (
 		Name: "ontology_p2p_reconnect_count",
 		Help: "ontology p2p reconnect count",
 	})
+
+	peerScoreMetric = prom.NewGaugeVec(prom.GaugeOpts{
+		Name: "ontology_p2p_peer_score",
+		Help: "ontology p2p peer misbehaviour score",
+	}, []string{"ip"})
+
+	bannedCountMetric = prom.NewGauge(prom.GaugeOpts{
+		Name: "ontology_p2p_banned_count",
+		Help: "ontology p2p banned peer count",
+	})
 )
 
 var (
-	metrics = []prom.Collector{nodePortMetric, blockHeightMetric, inboundsCountMetric, outboundsCountMetric, peerStatusMetric, reserveCountMetric, reconnectCountMetric}
+	metrics = []prom.Collector{nodePortMetric, blockHeightMetric, inboundsCountMetric, outboundsCountMetric, peerStatusMetric, reserveCountMetric, reconnectCountMetric,
+		peerScoreMetric, bannedCountMetric}
 )
 
 func initMetric() error {
@@ -102,7 +113,14 @@ func metricUpdate(n p2p.P2P) {
 		peerStatusMetric.WithLabelValues(curPeer.GetAddr(), id.ToHexString()).Set(float64(curPeer.GetHeight()))
 	}
 
-	reserveCountMetric.WithLabelValues(strings.Join(ns.ConnectController().ReservedPeers, " ")).Set(float64(len(ns.ConnectController().ReservedPeers)))
+	reservedPeers := ns.ConnectController().GetReservedPeers()
+	reserveCountMetric.WithLabelValues(strings.Join(reservedPeers, " ")).Set(float64(len(reservedPeers)))
+
+	peerScoreMetric.Reset()
+	for _, score := range ns.GetPeerScores() {
+		peerScoreMetric.WithLabelValues(score.Ip).Set(float64(score.Score))
+	}
+	bannedCountMetric.Set(float64(len(ns.ConnectController().GetBannedPeers())))
 
 	pt := ns.Protocol()
 	mh, ok := pt.(*protocols.MsgHandler)
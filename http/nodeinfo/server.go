@@ -28,7 +28,9 @@ import (
 
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/p2pserver/net/netserver"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer_score"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -44,6 +46,8 @@ type Info struct {
 	NodePort      uint16
 	NodeId        string
 	NodeType      string
+	BannedCnt     int
+	PeerScores    []peer_score.PeerScore
 }
 
 const (
@@ -104,6 +108,10 @@ func viewHandler(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/info", http.StatusFound)
 		return
 	}
+	if ns, ok := node.(*netserver.NetServer); ok {
+		pageInfo.BannedCnt = len(ns.ConnectController().GetBannedPeers())
+		pageInfo.PeerScores = ns.GetPeerScores()
+	}
 
 	err = templates.ExecuteTemplate(w, "info", pageInfo)
 	if err != nil {
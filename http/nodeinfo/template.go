@@ -90,6 +90,31 @@ const TEMPLATE_PAGE = `
 </td>
 </tr>
 </table>
+<br><br><br><br>
+
+<table class="bt" width="80%">
+	<tr><th>Peer Scores</th></tr>
+</table>
+<br>
+
+<table class="bd" width="80%">
+<tr>
+<td width="20%" >
+	<table class="font" width="100%">
+	<tr><th>Banned Count</th></tr>
+	<tr><td align="center"><b><font size="40px">{{.BannedCnt}}</font></b></td></tr>
+	</table>
+</td>
+<td width="80%">
+	<table class="font" width="100%">
+	<tr><th>Peer IP</th><th>Last Peer Id</th><th>Score</th><th>Misbehaviours</th></tr>
+	{{range .PeerScores}}
+	<tr><td align="center">{{.Ip}}</td><td align="center">{{.LastPeerId}}</td><td align="center">{{.Score}}</td><td align="center">{{range $name, $cnt := .Misbehaviours}}{{$name}}:{{$cnt}} {{end}}</td></tr>
+	{{end}}
+	</table>
+</td>
+</tr>
+</table>
 <br><br><br><br><br><br>
 
 <table class="font" border="0" width="80%">
@@ -63,10 +63,16 @@ const (
 	GET_BLK_HASH          = "/api/v1/block/hash/:height"
 	GET_TX                = "/api/v1/transaction/:hash"
 	GET_STORAGE           = "/api/v1/storage/:hash/:key"
+	GET_STORAGE_PROOF     = "/api/v1/storageproof/:hash/:key"
 	GET_BALANCE           = "/api/v1/balance/:addr"
+	GET_TOKEN_BALANCE     = "/api/v1/token/balance/:addr"
+	GET_TOKEN_INFO        = "/api/v1/token/info"
+	GET_ADDRESS_TXS       = "/api/v1/address/:addr/txs"
 	GET_CONTRACT_STATE    = "/api/v1/contract/:hash"
 	GET_SMTCOCE_EVT_TXS   = "/api/v1/smartcode/event/transactions/:height"
 	GET_SMTCOCE_EVTS      = "/api/v1/smartcode/event/txhash/:hash"
+	GET_LOGS              = "/api/v1/logs"
+	GET_TX_TRACE          = "/api/v1/trace/transaction/:hash"
 	GET_BLK_HGT_BY_TXHASH = "/api/v1/block/height/txhash/:hash"
 	GET_MERKLE_PROOF      = "/api/v1/merkleproof/:hash"
 	GET_GAS_PRICE         = "/api/v1/gasprice"
@@ -79,7 +85,9 @@ const (
 	GET_VERSION           = "/api/v1/version"
 	GET_NETWORKID         = "/api/v1/networkid"
 
-	POST_RAW_TX = "/api/v1/transaction"
+	POST_RAW_TX       = "/api/v1/transaction"
+	POST_TRACE_CALL   = "/api/v1/trace/call"
+	POST_ESTIMATE_GAS = "/api/v1/estimategas"
 )
 
 //init restful server
@@ -147,9 +155,15 @@ func (this *restServer) registryMethod() {
 		GET_CONTRACT_STATE:    {name: "getcontract", handler: rest.GetContractState},
 		GET_SMTCOCE_EVT_TXS:   {name: "getsmartcodeeventbyheight", handler: rest.GetSmartCodeEventTxsByHeight},
 		GET_SMTCOCE_EVTS:      {name: "getsmartcodeeventbyhash", handler: rest.GetSmartCodeEventByTxHash},
+		GET_LOGS:              {name: "getlogs", handler: rest.GetLogs},
+		GET_TX_TRACE:          {name: "tracetransaction", handler: rest.TraceTransaction},
 		GET_BLK_HGT_BY_TXHASH: {name: "getblockheightbytxhash", handler: rest.GetBlockHeightByTxHash},
 		GET_STORAGE:           {name: "getstorage", handler: rest.GetStorage},
+		GET_STORAGE_PROOF:     {name: "getstorageproof", handler: rest.GetStorageProof},
 		GET_BALANCE:           {name: "getbalance", handler: rest.GetBalance},
+		GET_TOKEN_BALANCE:     {name: "gettokenbalance", handler: rest.GetTokenBalance},
+		GET_TOKEN_INFO:        {name: "gettokeninfo", handler: rest.GetTokenInfo},
+		GET_ADDRESS_TXS:       {name: "gettxsbyaddress", handler: rest.GetTxsByAddress},
 		GET_ALLOWANCE:         {name: "getallowance", handler: rest.GetAllowance},
 		GET_MERKLE_PROOF:      {name: "getmerkleproof", handler: rest.GetMerkleProof},
 		GET_GAS_PRICE:         {name: "getgasprice", handler: rest.GetGasPrice},
@@ -163,7 +177,9 @@ func (this *restServer) registryMethod() {
 	}
 
 	postMethodMap := map[string]Action{
-		POST_RAW_TX: {name: "sendrawtransaction", handler: rest.SendRawTransaction},
+		POST_RAW_TX:       {name: "sendrawtransaction", handler: rest.SendRawTransaction},
+		POST_TRACE_CALL:   {name: "tracecall", handler: rest.TraceCall},
+		POST_ESTIMATE_GAS: {name: "estimategas", handler: rest.EstimateGas},
 	}
 	this.postMap = postMethodMap
 	this.getMap = getMethodMap
@@ -186,10 +202,18 @@ func (this *restServer) getPath(url string) string {
 		return GET_SMTCOCE_EVT_TXS
 	} else if strings.Contains(url, strings.TrimRight(GET_SMTCOCE_EVTS, ":hash")) {
 		return GET_SMTCOCE_EVTS
+	} else if strings.Contains(url, strings.TrimRight(GET_TX_TRACE, ":hash")) {
+		return GET_TX_TRACE
 	} else if strings.Contains(url, strings.TrimRight(GET_BLK_HGT_BY_TXHASH, ":hash")) {
 		return GET_BLK_HGT_BY_TXHASH
+	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE_PROOF, ":hash/:key")) {
+		return GET_STORAGE_PROOF
 	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE, ":hash/:key")) {
 		return GET_STORAGE
+	} else if strings.HasPrefix(url, strings.TrimSuffix(GET_ADDRESS_TXS, ":addr/txs")) && strings.HasSuffix(url, "/txs") {
+		return GET_ADDRESS_TXS
+	} else if strings.Contains(url, strings.TrimRight(GET_TOKEN_BALANCE, ":addr")) {
+		return GET_TOKEN_BALANCE
 	} else if strings.Contains(url, strings.TrimRight(GET_BALANCE, ":addr")) {
 		return GET_BALANCE
 	} else if strings.Contains(url, strings.TrimRight(GET_MERKLE_PROOF, ":hash")) {
@@ -223,20 +247,39 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 		req["Hash"], req["Raw"] = getParam(r, "hash"), r.FormValue("raw")
 	case GET_CONTRACT_STATE:
 		req["Hash"], req["Raw"] = getParam(r, "hash"), r.FormValue("raw")
+		req["Height"] = r.FormValue("height")
 	case POST_RAW_TX:
-		req["PreExec"] = r.FormValue("preExec")
+		req["PreExec"], req["Height"] = r.FormValue("preExec"), r.FormValue("height")
 	case GET_STORAGE:
 		req["Hash"], req["Key"] = getParam(r, "hash"), getParam(r, "key")
+		req["Height"] = r.FormValue("height")
+	case GET_STORAGE_PROOF:
+		req["Hash"], req["Key"] = getParam(r, "hash"), getParam(r, "key")
+		req["Height"] = r.FormValue("height")
 	case GET_SMTCOCE_EVT_TXS:
 		req["Height"] = getParam(r, "height")
 	case GET_SMTCOCE_EVTS:
 		req["Hash"] = getParam(r, "hash")
+	case GET_TX_TRACE:
+		req["Hash"] = getParam(r, "hash")
+	case GET_LOGS:
+		req["FromHeight"], req["ToHeight"] = r.FormValue("from"), r.FormValue("to")
+		req["Contracts"], req["States"] = r.FormValue("contracts"), r.FormValue("states")
+		req["Limit"], req["Index"] = r.FormValue("limit"), r.FormValue("index")
 	case GET_BLK_HGT_BY_TXHASH:
 		req["Hash"] = getParam(r, "hash")
 	case GET_BALANCE:
+		req["Addr"], req["Height"] = getParam(r, "addr"), r.FormValue("height")
+	case GET_TOKEN_BALANCE:
+		req["Addr"], req["Tokens"] = getParam(r, "addr"), r.FormValue("tokens")
+	case GET_TOKEN_INFO:
+		req["Tokens"] = r.FormValue("tokens")
+	case GET_ADDRESS_TXS:
 		req["Addr"] = getParam(r, "addr")
+		req["StartHeight"], req["EndHeight"] = r.FormValue("start"), r.FormValue("end")
+		req["Offset"], req["Limit"] = r.FormValue("offset"), r.FormValue("limit")
 	case GET_MERKLE_PROOF:
-		req["Hash"] = getParam(r, "hash")
+		req["Hash"], req["Height"] = getParam(r, "hash"), r.FormValue("height")
 	case GET_ALLOWANCE:
 		req["Asset"] = getParam(r, "asset")
 		req["From"], req["To"] = getParam(r, "from"), getParam(r, "to")
@@ -318,7 +361,7 @@ func (this *restServer) response(w http.ResponseWriter, resp map[string]interfac
 	resp["Desc"] = berr.ErrMap[resp["Error"].(int64)]
 	data, err := json.Marshal(resp)
 	if err != nil {
-		log.Fatal("HTTP Handle - json.Marshal: %v", err)
+		log.Fatalf("HTTP Handle - json.Marshal: %v", err)
 		return
 	}
 	this.write(w, data)
@@ -38,6 +38,7 @@ var ws *websocket.WsServer
 func StartServer() {
 	bactor.SubscribeEvent(message.TOPIC_SAVE_BLOCK_COMPLETE, sendBlock2WSclient)
 	bactor.SubscribeEvent(message.TOPIC_SMART_CODE_EVENT, pushSmartCodeEvent)
+	bactor.SubscribeEvent(message.TOPIC_PENDING_TX, pushPendingTx)
 	go func() {
 		ws = websocket.InitWsServer()
 		ws.Start()
@@ -48,6 +49,8 @@ func sendBlock2WSclient(v interface{}) {
 		go func() {
 			pushBlock(v)
 			pushBlockTransactions(v)
+			pushTxConfirmation(v)
+			pushBlockEvents(v)
 		}()
 	}
 }
@@ -72,7 +75,7 @@ func pushSmartCodeEvent(v interface{}) {
 	}
 	rs, ok := v.(types.SmartCodeEvent)
 	if !ok {
-		log.Errorf("[PushSmartCodeEvent]", "SmartCodeEvent err")
+		log.Errorf("[PushSmartCodeEvent] SmartCodeEvent err")
 		return
 	}
 	go func() {
@@ -110,9 +113,14 @@ func pushBlock(v interface{}) {
 		resp["Result"] = common.ToHexString(block.ToArray())
 		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_RAW_BLOCK, resp)
 
+		blockInfo := bcomn.GetBlockInfo(&block)
 		resp["Action"] = "sendjsonblock"
-		resp["Result"] = bcomn.GetBlockInfo(&block)
+		resp["Result"] = blockInfo
 		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_JSON_BLOCK, resp)
+
+		resp["Action"] = "sendheader"
+		resp["Result"] = blockInfo.Header
+		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_HEADER, resp)
 	}
 }
 func pushBlockTransactions(v interface{}) {
@@ -126,3 +134,37 @@ func pushBlockTransactions(v interface{}) {
 		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_TXHASHS, resp)
 	}
 }
+
+func pushTxConfirmation(v interface{}) {
+	if ws == nil {
+		return
+	}
+	if block, ok := v.(types.Block); ok {
+		ws.PushTxConfirmation(&block)
+	}
+}
+
+func pushBlockEvents(v interface{}) {
+	if ws == nil {
+		return
+	}
+	if block, ok := v.(types.Block); ok {
+		ws.PushBlockEvents(block.Header.Height)
+	}
+}
+
+func pushPendingTx(v interface{}) {
+	if ws == nil {
+		return
+	}
+	tx, ok := v.(*types.Transaction)
+	if !ok {
+		return
+	}
+	go func() {
+		resp := rest.ResponsePack(Err.SUCCESS)
+		resp["Action"] = "sendpendingtx"
+		resp["Result"] = bcomn.TransArryByteToHexString(tx)
+		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_PENDING_TX, resp)
+	}()
+}
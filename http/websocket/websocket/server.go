@@ -23,6 +23,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"strconv"
@@ -33,9 +34,14 @@ import (
 	"github.com/ontio/ontology/common"
 	cfg "github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	scom "github.com/ontio/ontology/core/store/common"
+	"github.com/ontio/ontology/core/types"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	bcomn "github.com/ontio/ontology/http/base/common"
 	Err "github.com/ontio/ontology/http/base/error"
 	"github.com/ontio/ontology/http/base/rest"
 	"github.com/ontio/ontology/http/websocket/session"
+	"github.com/ontio/ontology/smartcontract/event"
 )
 
 const (
@@ -43,6 +49,9 @@ const (
 	WSTOPIC_JSON_BLOCK = 2
 	WSTOPIC_RAW_BLOCK  = 3
 	WSTOPIC_TXHASHS    = 4
+	WSTOPIC_PENDING_TX = 5
+	WSTOPIC_TX_CONFIRM = 6
+	WSTOPIC_HEADER     = 7
 )
 
 type handler func(map[string]interface{}) map[string]interface{}
@@ -68,6 +77,9 @@ type WsServer struct {
 	ActionMap    map[string]Handler   //handler functions
 	TxHashMap    map[string]string    //key: txHash   value:sessionid
 	SubscribeMap map[string]subscribe //key: sessionId   value:subscribeInfo
+
+	Subscriptions  map[string]map[string]*Subscription //key: sessionId   value:subscriptions by id
+	subscriptionId uint64                              //the last subscription id assigned
 }
 
 //init websocket server
@@ -77,6 +89,8 @@ func InitWsServer() *WsServer {
 		SessionList:  session.NewSessionList(),
 		TxHashMap:    make(map[string]string),
 		SubscribeMap: make(map[string]subscribe),
+
+		Subscriptions: make(map[string]map[string]*Subscription),
 	}
 	return ws
 }
@@ -139,6 +153,9 @@ func (self *WsServer) registryMethod() {
 		return resp
 	}
 	subscribe := func(cmd map[string]interface{}) map[string]interface{} {
+		if cmd["Type"] != nil {
+			return self.addSubscription(cmd)
+		}
 		resp := rest.ResponsePack(Err.SUCCESS)
 		self.Lock()
 		defer self.Unlock()
@@ -171,6 +188,22 @@ func (self *WsServer) registryMethod() {
 		resp["Result"] = sub
 		return resp
 	}
+	unsubscribe := func(cmd map[string]interface{}) map[string]interface{} {
+		self.Lock()
+		defer self.Unlock()
+
+		sessionId, _ := cmd["SessionId"].(string)
+		id, _ := cmd["SubscriptionId"].(string)
+		if _, ok := self.Subscriptions[sessionId][id]; !ok {
+			return rest.ResponsePack(Err.INVALID_PARAMS)
+		}
+		delete(self.Subscriptions[sessionId], id)
+
+		resp := rest.ResponsePack(Err.SUCCESS)
+		resp["Action"] = "unsubscribe"
+		resp["Result"] = id
+		return resp
+	}
 	getsessioncount := func(cmd map[string]interface{}) map[string]interface{} {
 		resp := rest.ResponsePack(Err.SUCCESS)
 		resp["Action"] = "getsessioncount"
@@ -181,8 +214,14 @@ func (self *WsServer) registryMethod() {
 		"getblockheightbytxhash":    {handler: rest.GetBlockHeightByTxHash},
 		"getsmartcodeeventbyhash":   {handler: rest.GetSmartCodeEventByTxHash},
 		"getsmartcodeeventbyheight": {handler: rest.GetSmartCodeEventTxsByHeight},
+		"getlogs":                   {handler: rest.GetLogs},
+		"tracetransaction":          {handler: rest.TraceTransaction},
+		"tracecall":                 {handler: rest.TraceCall},
+		"estimategas":               {handler: rest.EstimateGas},
 		"getcontract":               {handler: rest.GetContractState},
 		"getbalance":                {handler: rest.GetBalance},
+		"gettokenbalance":           {handler: rest.GetTokenBalance},
+		"gettokeninfo":              {handler: rest.GetTokenInfo},
 		"getconnectioncount":        {handler: rest.GetConnectionCount},
 		"getblockbyheight":          {handler: rest.GetBlockByHeight},
 		"getblockhash":              {handler: rest.GetBlockHash},
@@ -192,7 +231,10 @@ func (self *WsServer) registryMethod() {
 		"sendrawtransaction":        {handler: rest.SendRawTransaction, pushFlag: true},
 		"heartbeat":                 {handler: heartbeat},
 		"subscribe":                 {handler: subscribe},
+		"unsubscribe":               {handler: unsubscribe},
 		"getstorage":                {handler: rest.GetStorage},
+		"getstorageproof":           {handler: rest.GetStorageProof},
+		"gettxsbyaddress":           {handler: rest.GetTxsByAddress},
 		"getallowance":              {handler: rest.GetAllowance},
 		"getmerkleproof":            {handler: rest.GetMerkleProof},
 		"getblocktxsbyheight":       {handler: rest.GetBlockTxsByHeight},
@@ -284,7 +326,7 @@ func (self *WsServer) webSocketHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		e, ok := err.(net.Error)
 		if !ok || !e.Timeout() {
-			log.Infof("websocket conn:", err)
+			log.Infof("websocket conn: %s", err)
 			return
 		}
 	}
@@ -308,7 +350,7 @@ func (self *WsServer) OnDataHandle(curSession *session.Session, bysMsg []byte, r
 	if err := json.Unmarshal(bysMsg, &req); err != nil {
 		resp := rest.ResponsePack(Err.ILLEGAL_DATAFORMAT)
 		curSession.Send(marshalResp(resp))
-		log.Infof("websocket OnDataHandle:", err)
+		log.Infof("websocket OnDataHandle: %s", err)
 		return false
 	}
 	actionName, ok := req["Action"].(string)
@@ -355,6 +397,9 @@ func (self *WsServer) OnDataHandle(curSession *session.Session, bysMsg []byte, r
 		}
 	}
 	curSession.Send(marshalResp(resp))
+	if actionName == "subscribe" {
+		self.startPendingReplays(curSession.GetSessionId())
+	}
 
 	return true
 }
@@ -376,13 +421,152 @@ func (self *WsServer) deleteSubscribe(sessionId string) {
 	self.Lock()
 	defer self.Unlock()
 	delete(self.SubscribeMap, sessionId)
+	delete(self.Subscriptions, sessionId)
+}
+
+//addSubscription add a subscription with its own id to the session, a session can hold several subscriptions.
+//The subscription of a transaction confirmed already is answered with the confirmation and not kept.
+func (self *WsServer) addSubscription(cmd map[string]interface{}) map[string]interface{} {
+	sub, err := newSubscription(cmd)
+	if err != nil {
+		resp := rest.ResponsePack(Err.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	self.Lock()
+	defer self.Unlock()
+
+	sessionId, _ := cmd["SessionId"].(string)
+	subs := self.Subscriptions[sessionId]
+	if subs == nil {
+		subs = make(map[string]*Subscription)
+		self.Subscriptions[sessionId] = subs
+	}
+	if len(subs) >= MAX_SESSION_SUBSCRIPTIONS {
+		resp := rest.ResponsePack(Err.INVALID_PARAMS)
+		resp["Result"] = fmt.Sprintf("a session can hold %d subscriptions at most", MAX_SESSION_SUBSCRIPTIONS)
+		return resp
+	}
+	self.subscriptionId++
+	sub.SubscriptionId = strconv.FormatUint(self.subscriptionId, 10)
+	if sub.topic == WSTOPIC_TX_CONFIRM {
+		sub.Confirmation = getTxConfirmation(sub.TxHash)
+	}
+	if sub.Confirmation == nil {
+		subs[sub.SubscriptionId] = sub
+	}
+
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "subscribe"
+	resp["Result"] = sub
+	if sub.FromHeight != nil {
+		resp["Cursor"] = sub.cursor
+	}
+	return resp
+}
+
+//startPendingReplays start replaying the events of the new subscriptions in session, once the subscribe response
+//is sent.
+func (self *WsServer) startPendingReplays(sessionId string) {
+	self.Lock()
+	defer self.Unlock()
+	for _, sub := range self.Subscriptions[sessionId] {
+		if sub.pending {
+			sub.pending = false
+			go self.replayEvents(sessionId, sub)
+		}
+	}
+}
+
+//PushBlockEvents push the events of the block saved to the subscriptions with FromHeight. The events are read from
+//the event store and pushed block by block from the cursor of subscription, so no block is skipped or pushed twice
+//whether the subscription is replaying or following the live blocks.
+func (self *WsServer) PushBlockEvents(height uint32) {
+	self.Lock()
+	defer self.Unlock()
+	for sid, subs := range self.Subscriptions {
+		for _, sub := range subs {
+			if sub.FromHeight == nil || sub.replaying || sub.cursor > height {
+				continue
+			}
+			sub.replaying = true
+			go self.replayEvents(sid, sub)
+		}
+	}
+}
+
+//replayEvents push the events of subscription from its cursor up to the current block height
+func (self *WsServer) replayEvents(sessionId string, sub *Subscription) {
+	for {
+		self.Lock()
+		if self.Subscriptions[sessionId][sub.SubscriptionId] != sub {
+			self.Unlock()
+			return
+		}
+		height := sub.cursor
+		if height > bactor.GetCurrentBlockHeight() {
+			sub.replaying = false
+			self.Unlock()
+			return
+		}
+		self.Unlock()
+
+		notifies, err := bactor.GetEventNotifyByHeight(height)
+		if err != nil && err != scom.ErrNotFound {
+			log.Errorf("websocket replay events of block %d error: %s", height, err)
+			self.Lock()
+			delete(self.Subscriptions[sessionId], sub.SubscriptionId)
+			self.Unlock()
+			if s := self.SessionList.GetSessionById(sessionId); s != nil {
+				resp := rest.ResponsePack(Err.INTERNAL_ERROR)
+				resp["Action"] = "sendblockevents"
+				resp["SubscriptionId"] = sub.SubscriptionId
+				resp["Cursor"] = height
+				s.Send(marshalResp(resp))
+			}
+			return
+		}
+		self.Lock()
+		self.deliverBlockEvents(sessionId, sub, height, notifies)
+		self.Unlock()
+	}
+}
+
+//deliverBlockEvents push the events of block matched by subscription, and move the cursor to the next block.
+//The caller should hold the lock.
+func (self *WsServer) deliverBlockEvents(sessionId string, sub *Subscription, height uint32,
+	notifies []*event.ExecuteNotify) {
+	if sub.cursor != height {
+		return
+	}
+	sub.cursor = height + 1
+	evts := make([]bcomn.ExecuteNotify, 0)
+	for _, n := range notifies {
+		_, notify := bcomn.GetExecuteNotify(n)
+		if sub.matchEvent(notify) {
+			evts = append(evts, notify)
+		}
+	}
+	if len(evts) == 0 {
+		return
+	}
+	s := self.SessionList.GetSessionById(sessionId)
+	if s == nil {
+		return
+	}
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendblockevents"
+	resp["SubscriptionId"] = sub.SubscriptionId
+	resp["Cursor"] = sub.cursor
+	resp["Result"] = &BlockEvents{Height: height, Events: evts}
+	s.Send(marshalResp(resp))
 }
 
 func marshalResp(resp map[string]interface{}) []byte {
 	resp["Desc"] = Err.ErrMap[resp["Error"].(int64)]
 	data, err := json.Marshal(resp)
 	if err != nil {
-		log.Infof("Websocket marshal json error:", err)
+		log.Infof("Websocket marshal json error: %s", err)
 		return nil
 	}
 
@@ -443,6 +627,62 @@ func (self *WsServer) BroadcastToSubscribers(contractAddrs map[string]bool, sub
 			}
 		}
 	}
+	self.pushToSubscriptions(sub, resp, func(s *Subscription) bool {
+		return sub != WSTOPIC_EVENT || (s.FromHeight == nil && s.matchEvent(resp["Result"]))
+	})
+}
+
+//pushToSubscriptions send resp to the subscriptions of topic selected by match, with their subscription id.
+//The caller should hold the lock.
+func (self *WsServer) pushToSubscriptions(topic int, resp map[string]interface{}, match func(*Subscription) bool) {
+	defer delete(resp, "SubscriptionId")
+	for sid, subs := range self.Subscriptions {
+		s := self.SessionList.GetSessionById(sid)
+		if s == nil {
+			continue
+		}
+		for id, sub := range subs {
+			if sub.topic != topic || !match(sub) {
+				continue
+			}
+			resp["SubscriptionId"] = id
+			s.Send(marshalResp(resp))
+		}
+	}
+}
+
+//PushTxConfirmation push the confirmation to the subscriptions waiting for the transactions of block, the
+//subscriptions are removed after that.
+func (self *WsServer) PushTxConfirmation(block *types.Block) {
+	self.Lock()
+	defer self.Unlock()
+	txHashes := make(map[string]bool, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		txHash := tx.Hash()
+		txHashes[txHash.ToHexString()] = true
+	}
+	blockHash := block.Hash()
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendtxconfirmation"
+	for sid, subs := range self.Subscriptions {
+		for id, sub := range subs {
+			if sub.topic != WSTOPIC_TX_CONFIRM || !txHashes[sub.TxHash] {
+				continue
+			}
+			delete(subs, id)
+			s := self.SessionList.GetSessionById(sid)
+			if s == nil {
+				continue
+			}
+			resp["SubscriptionId"] = id
+			resp["Result"] = &TxConfirmation{
+				TxHash:      sub.TxHash,
+				BlockHash:   blockHash.ToHexString(),
+				BlockHeight: block.Header.Height,
+			}
+			s.Send(marshalResp(resp))
+		}
+	}
 }
 
 func (self *WsServer) initTlsListen() (net.Listener, error) {
@@ -0,0 +1,239 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package websocket
+
+import (
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	bactor "github.com/ontio/ontology/http/base/actor"
+	bcomn "github.com/ontio/ontology/http/base/common"
+)
+
+//the subscription types of subscribe action
+const (
+	SUBSCRIBE_EVENT         = "event"
+	SUBSCRIBE_JSON_BLOCK    = "jsonblock"
+	SUBSCRIBE_RAW_BLOCK     = "rawblock"
+	SUBSCRIBE_BLOCK_TXHASHS = "blocktxhashs"
+	SUBSCRIBE_PENDING_TX    = "pendingtx"
+	SUBSCRIBE_TX_CONFIRM    = "txconfirm"
+	SUBSCRIBE_HEADER        = "header"
+)
+
+const (
+	MAX_SESSION_SUBSCRIPTIONS = 32     //max subscriptions held by a session
+	MAX_REPLAY_BLOCKS         = 100000 //max blocks replayed for a subscription from its FromHeight
+)
+
+var subscriptionTopics = map[string]int{
+	SUBSCRIBE_EVENT:         WSTOPIC_EVENT,
+	SUBSCRIBE_JSON_BLOCK:    WSTOPIC_JSON_BLOCK,
+	SUBSCRIBE_RAW_BLOCK:     WSTOPIC_RAW_BLOCK,
+	SUBSCRIBE_BLOCK_TXHASHS: WSTOPIC_TXHASHS,
+	SUBSCRIBE_PENDING_TX:    WSTOPIC_PENDING_TX,
+	SUBSCRIBE_TX_CONFIRM:    WSTOPIC_TX_CONFIRM,
+	SUBSCRIBE_HEADER:        WSTOPIC_HEADER,
+}
+
+//Subscription is one of the subscriptions held by a session, the messages pushed to it carry its id
+type Subscription struct {
+	SubscriptionId  string
+	Type            string
+	ContractsFilter []string        `json:",omitempty"` //contracts of the events, empty to match any contract
+	EventNames      []string        `json:",omitempty"` //names of the events, which is the first notify state
+	Addresses       []string        `json:",omitempty"` //addresses in the notify states
+	TxHash          string          `json:",omitempty"` //the transaction waiting for confirmation
+	Confirmation    *TxConfirmation `json:",omitempty"` //set if the transaction is confirmed already
+	FromHeight      *uint32         `json:",omitempty"` //the events are replayed from the height, and pushed by block
+
+	topic     int
+	contracts map[string]bool
+	addresses []common.Address
+	cursor    uint32 //the height of the next block whose events are pushed, if FromHeight is set
+	replaying bool   //the events are being replayed up to the current height
+	pending   bool   //the first replay waits for the subscribe response sent
+}
+
+//BlockEvents is the events of a block pushed to the subscription with FromHeight, the cursor acknowledged with it
+//is the height to resume from.
+type BlockEvents struct {
+	Height uint32
+	Events []bcomn.ExecuteNotify
+}
+
+//TxConfirmation is pushed once the transaction is saved in block
+type TxConfirmation struct {
+	TxHash      string
+	BlockHash   string
+	BlockHeight uint32
+}
+
+//newSubscription parse the subscription of subscribe request, the id is assigned when it's added to session
+func newSubscription(cmd map[string]interface{}) (*Subscription, error) {
+	subType, _ := cmd["Type"].(string)
+	topic, ok := subscriptionTopics[subType]
+	if !ok {
+		return nil, fmt.Errorf("unknown subscription type %s", subType)
+	}
+	sub := &Subscription{
+		Type:      subType,
+		topic:     topic,
+		contracts: make(map[string]bool),
+	}
+	switch topic {
+	case WSTOPIC_EVENT:
+		contracts, err := getStringList(cmd, "ContractsFilter")
+		if err != nil {
+			return nil, err
+		}
+		for _, str := range contracts {
+			addr, err := bcomn.GetAddress(str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid contract address %s", str)
+			}
+			sub.ContractsFilter = append(sub.ContractsFilter, addr.ToHexString())
+			sub.contracts[addr.ToHexString()] = true
+		}
+		sub.EventNames, err = getStringList(cmd, "EventNames")
+		if err != nil {
+			return nil, err
+		}
+		sub.Addresses, err = getStringList(cmd, "Addresses")
+		if err != nil {
+			return nil, err
+		}
+		for _, str := range sub.Addresses {
+			addr, err := bcomn.GetAddress(str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %s", str)
+			}
+			sub.addresses = append(sub.addresses, addr)
+		}
+		if cmd["FromHeight"] != nil {
+			if err := sub.setFromHeight(cmd["FromHeight"]); err != nil {
+				return nil, err
+			}
+		}
+	case WSTOPIC_TX_CONFIRM:
+		str, _ := cmd["TxHash"].(string)
+		hash, err := common.Uint256FromHexString(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tx hash %s", str)
+		}
+		sub.TxHash = hash.ToHexString()
+	}
+	return sub, nil
+}
+
+//setFromHeight set the cursor of the subscription, whose events are replayed from the event store
+func (self *Subscription) setFromHeight(value interface{}) error {
+	if !config.DefConfig.Common.EnableEventLog {
+		return fmt.Errorf("FromHeight is not supported without event log")
+	}
+	h, ok := value.(float64)
+	if !ok || h < 0 || h != float64(uint32(h)) {
+		return fmt.Errorf("invalid FromHeight %v", value)
+	}
+	fromHeight := uint32(h)
+	nextHeight := bactor.GetCurrentBlockHeight() + 1
+	if fromHeight > nextHeight {
+		return fmt.Errorf("FromHeight %d is greater than next block height %d", fromHeight, nextHeight)
+	}
+	if nextHeight-fromHeight > MAX_REPLAY_BLOCKS {
+		return fmt.Errorf("FromHeight %d is more than %d blocks behind", fromHeight, MAX_REPLAY_BLOCKS)
+	}
+	self.FromHeight = &fromHeight
+	self.cursor = fromHeight
+	self.replaying = true
+	self.pending = true
+	return nil
+}
+
+//getStringList return the string list of the request field, nil if the field is not set
+func getStringList(cmd map[string]interface{}, field string) ([]string, error) {
+	if cmd[field] == nil {
+		return nil, nil
+	}
+	items, ok := cmd[field].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s should be a list of string", field)
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s should be a list of string", field)
+		}
+		list = append(list, str)
+	}
+	return list, nil
+}
+
+//matchEvent return whether the event is selected by the filters of subscription. A notify event matches if one of
+//its notifies matches all the filters, a log event only matches the contracts filter.
+func (self *Subscription) matchEvent(result interface{}) bool {
+	switch evt := result.(type) {
+	case bcomn.ExecuteNotify:
+		for _, n := range evt.Notify {
+			if self.matchContract(n.ContractAddress) && self.matchEventName(n.States) &&
+				bcomn.MatchNotifyAddresses(n.States, self.addresses) {
+				return true
+			}
+		}
+	case bcomn.LogEventArgs:
+		return len(self.EventNames) == 0 && len(self.addresses) == 0 && self.matchContract(evt.ContractAddress)
+	}
+	return false
+}
+
+func (self *Subscription) matchContract(contract string) bool {
+	return len(self.contracts) == 0 || self.contracts[contract]
+}
+
+func (self *Subscription) matchEventName(states interface{}) bool {
+	if len(self.EventNames) == 0 {
+		return true
+	}
+	for _, name := range self.EventNames {
+		if bcomn.MatchNotifyStates(states, []string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+//getTxConfirmation return the confirmation of the transaction saved in ledger, nil if it's not saved yet
+func getTxConfirmation(txHash string) *TxConfirmation {
+	hash, err := common.Uint256FromHexString(txHash)
+	if err != nil {
+		return nil
+	}
+	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err != nil || tx == nil {
+		return nil
+	}
+	blockHash := bactor.GetBlockHashFromStore(height)
+	return &TxConfirmation{
+		TxHash:      txHash,
+		BlockHash:   blockHash.ToHexString(),
+		BlockHeight: height,
+	}
+}
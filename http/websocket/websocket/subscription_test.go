@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package websocket
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
+	bcomn "github.com/ontio/ontology/http/base/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSubscription(t *testing.T) {
+	_, err := newSubscription(map[string]interface{}{"Type": "unknown"})
+	assert.NotNil(t, err)
+	_, err = newSubscription(map[string]interface{}{"Type": SUBSCRIBE_TX_CONFIRM, "TxHash": "00"})
+	assert.NotNil(t, err)
+	_, err = newSubscription(map[string]interface{}{"Type": SUBSCRIBE_EVENT, "EventNames": "transfer"})
+	assert.NotNil(t, err)
+
+	contract := common.Address{1}
+	sub, err := newSubscription(map[string]interface{}{
+		"Type":            SUBSCRIBE_EVENT,
+		"ContractsFilter": []interface{}{contract.ToBase58()},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, WSTOPIC_EVENT, sub.topic)
+	assert.Equal(t, []string{contract.ToHexString()}, sub.ContractsFilter)
+}
+
+func TestMatchEvent(t *testing.T) {
+	contract, from, to := common.Address{1}, common.Address{2}, common.Address{3}
+	sub, err := newSubscription(map[string]interface{}{
+		"Type":            SUBSCRIBE_EVENT,
+		"ContractsFilter": []interface{}{contract.ToHexString()},
+		"EventNames":      []interface{}{"transfer"},
+		"Addresses":       []interface{}{to.ToBase58()},
+	})
+	assert.Nil(t, err)
+
+	notify := func(contract common.Address, states ...interface{}) bcomn.ExecuteNotify {
+		return bcomn.ExecuteNotify{Notify: []bcomn.NotifyEventInfo{{ContractAddress: contract.ToHexString(), States: states}}}
+	}
+	// native contracts notify the name and base58 addresses, neovm contracts notify the hex strings
+	assert.True(t, sub.matchEvent(notify(contract, "transfer", from.ToBase58(), to.ToBase58(), 1)))
+	assert.True(t, sub.matchEvent(notify(contract, hex.EncodeToString([]byte("transfer")),
+		hex.EncodeToString(from[:]), hex.EncodeToString(to[:]))))
+	assert.False(t, sub.matchEvent(notify(from, "transfer", from.ToBase58(), to.ToBase58(), 1)))
+	assert.False(t, sub.matchEvent(notify(contract, "approve", from.ToBase58(), to.ToBase58(), 1)))
+	assert.False(t, sub.matchEvent(notify(contract, "transfer", from.ToBase58(), from.ToBase58(), 1)))
+	assert.False(t, sub.matchEvent(bcomn.LogEventArgs{ContractAddress: contract.ToHexString()}))
+
+	all, err := newSubscription(map[string]interface{}{"Type": SUBSCRIBE_EVENT})
+	assert.Nil(t, err)
+	assert.True(t, all.matchEvent(bcomn.LogEventArgs{ContractAddress: contract.ToHexString()}))
+	assert.True(t, all.matchEvent(notify(from, "approve")))
+}
+
+func TestFromHeight(t *testing.T) {
+	enableEventLog := config.DefConfig.Common.EnableEventLog
+	defer func() { config.DefConfig.Common.EnableEventLog = enableEventLog }()
+
+	cmd := map[string]interface{}{"Type": SUBSCRIBE_EVENT, "FromHeight": float64(1)}
+	config.DefConfig.Common.EnableEventLog = false
+	_, err := newSubscription(cmd)
+	assert.NotNil(t, err)
+	config.DefConfig.Common.EnableEventLog = true
+	for _, h := range []interface{}{"1", float64(-1), float64(1.5)} {
+		cmd["FromHeight"] = h
+		_, err = newSubscription(cmd)
+		assert.NotNil(t, err)
+	}
+
+	// the cursor only moves forward block by block
+	ws := InitWsServer()
+	sub := &Subscription{SubscriptionId: "1", Type: SUBSCRIBE_EVENT, topic: WSTOPIC_EVENT, cursor: 5}
+	ws.deliverBlockEvents("session", sub, 6, nil)
+	assert.Equal(t, uint32(5), sub.cursor)
+	ws.deliverBlockEvents("session", sub, 5, nil)
+	assert.Equal(t, uint32(6), sub.cursor)
+	ws.deliverBlockEvents("session", sub, 5, nil)
+	assert.Equal(t, uint32(6), sub.cursor)
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/consensus"
 	"github.com/ontio/ontology/core/genesis"
 	"github.com/ontio/ontology/core/ledger"
@@ -74,21 +75,32 @@ func setupAPP() *cli.App {
 		cmd.ContractCommand,
 		cmd.ImportCommand,
 		cmd.ExportCommand,
+		cmd.RollbackCommand,
+		cmd.SnapshotCommand,
 		cmd.TxCommond,
 		cmd.SigTxCommand,
 		cmd.MultiSigAddrCommand,
 		cmd.MultiSigTxCommand,
 		cmd.SendTxCommand,
 		cmd.ShowTxCommand,
+		cmd.ConsoleCommand,
 	}
 	app.Flags = []cli.Flag{
 		//common setting
 		utils.ConfigFlag,
 		utils.LogLevelFlag,
+		utils.LogFormatFlag,
+		utils.LogModuleLevelsFlag,
 		utils.LogDirFlag,
 		utils.DisableLogFileFlag,
 		utils.DisableEventLogFlag,
 		utils.DataDirFlag,
+		utils.EnableArchiveFlag,
+		utils.EnableStateProofFlag,
+		utils.EnableAddressIndexFlag,
+		utils.EnableContractIndexFlag,
+		utils.UndoBlockNumFlag,
+		utils.LightModeFlag,
 		utils.WasmVerifyMethodFlag,
 		//account setting
 		utils.WalletFileFlag,
@@ -103,6 +115,10 @@ func setupAPP() *cli.App {
 		utils.TxpoolPreExecDisableFlag,
 		utils.DisableSyncVerifyTxFlag,
 		utils.DisableBroadcastNetTxFlag,
+		utils.EnableTxPoolJournalFlag,
+		utils.TxpoolPriceBumpFlag,
+		utils.TxpoolCapacityFlag,
+		utils.TxpoolPayerCapacityFlag,
 		//p2p setting
 		utils.ReservedPeersOnlyFlag,
 		utils.ReservedPeersFileFlag,
@@ -112,14 +128,24 @@ func setupAPP() *cli.App {
 		utils.MaxConnInBoundFlag,
 		utils.MaxConnOutBoundFlag,
 		utils.MaxConnInBoundForSingleIPFlag,
+		utils.SyncMaxFlightHeadersFlag,
+		utils.SyncMaxFlightBlocksFlag,
+		utils.SyncMaxHeaderForwardFlag,
+		utils.SyncMaxBlockCacheFlag,
+		utils.P2PRateLimitsFlag,
+		utils.P2PPeerRateLimitFlag,
+		utils.P2PRateLimitPolicyFlag,
 		//test mode setting
 		utils.EnableTestModeFlag,
 		utils.TestModeGenBlockTimeFlag,
 		//rpc setting
 		utils.RPCDisabledFlag,
 		utils.RPCPortFlag,
+		utils.RPCMaxBatchSizeFlag,
 		utils.RPCLocalEnableFlag,
 		utils.RPCLocalProtFlag,
+		utils.RPCLocalAdminTokenFlag,
+		utils.RPCLocalAuditLogFlag,
 		//rest setting
 		utils.RestfulEnableFlag,
 		utils.RestfulPortFlag,
@@ -127,6 +153,9 @@ func setupAPP() *cli.App {
 		//ws setting
 		utils.WsEnabledFlag,
 		utils.WsPortFlag,
+		//metrics setting
+		utils.MetricsEnableFlag,
+		utils.MetricsPortFlag,
 	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -143,7 +172,10 @@ func main() {
 }
 
 func startOntology(ctx *cli.Context) {
-	initLog(ctx)
+	if err := initLog(ctx); err != nil {
+		cmd.PrintErrorMsg(err.Error())
+		return
+	}
 
 	log.Infof("ontology version %s", config.Version)
 
@@ -193,13 +225,24 @@ func startOntology(ctx *cli.Context) {
 	initRestful(ctx)
 	initWs(ctx)
 	initNodeInfo(ctx, p2pSvr)
+	err = initMetrics(ctx)
+	if err != nil {
+		log.Errorf("initMetrics error: %s", err)
+		return
+	}
 
 	go logCurrBlockHeight()
-	waitToExit(ldg)
+	waitToExit(ldg, txpool)
 }
 
-func initLog(ctx *cli.Context) {
+func initLog(ctx *cli.Context) error {
 	//init log module
+	if err := log.SetFormat(ctx.GlobalString(utils.GetFlagName(utils.LogFormatFlag))); err != nil {
+		return err
+	}
+	if err := log.SetModuleLevels(ctx.GlobalString(utils.GetFlagName(utils.LogModuleLevelsFlag))); err != nil {
+		return err
+	}
 	logLevel := ctx.GlobalInt(utils.GetFlagName(utils.LogLevelFlag))
 	//if true, the log will not be output to the file
 	disableLogFile := ctx.GlobalBool(utils.GetFlagName(utils.DisableLogFileFlag))
@@ -211,6 +254,7 @@ func initLog(ctx *cli.Context) {
 		alog.InitLog(logFileDir)
 		log.InitLog(logLevel, logFileDir, log.Stdout)
 	}
+	return nil
 }
 
 func initConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
@@ -255,7 +299,11 @@ func initLedger(ctx *cli.Context, stateHashHeight uint32) (*ledger.Ledger, error
 
 	var err error
 	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
-	ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
+	if config.DefConfig.Common.LightMode {
+		ledger.DefLedger, err = ledger.NewLightLedger(dbDir)
+	} else {
+		ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("NewLedger error: %s", err)
 	}
@@ -292,8 +340,19 @@ func initTxPool(ctx *cli.Context) (*proc.TXPoolServer, error) {
 	stfValidator, _ := stateful.NewValidator("stateful_validator")
 	stfValidator.Register(txPoolServer.GetPID(tc.VerifyRspActor))
 
+	if ctx.GlobalBool(utils.GetFlagName(utils.EnableTxPoolJournalFlag)) {
+		dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+		err = txPoolServer.StartJournal(filepath.Join(dbDir, tc.TX_JOURNAL_FILE))
+		if err != nil {
+			return nil, fmt.Errorf("Init txpool journal error: %s", err)
+		}
+	}
+
 	hserver.SetTxnPoolPid(txPoolServer.GetPID(tc.TxPoolActor))
 	hserver.SetTxPid(txPoolServer.GetPID(tc.TxActor))
+	if err = proc.RegisterMetrics(txPoolServer); err != nil {
+		return nil, fmt.Errorf("Init txpool metrics error: %s", err)
+	}
 
 	log.Infof("TxPool init success")
 	return txPoolServer, nil
@@ -315,6 +374,9 @@ func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer) (*p2pserver.P2P
 	netreqactor.SetTxnPoolPid(txpoolSvr.GetPID(tc.TxActor))
 	txpoolSvr.Net = p2p.GetNetwork()
 	hserver.SetNetServer(p2p.GetNetwork())
+	if config.DefConfig.Common.LightMode {
+		hserver.SetBlockFetcher(p2p)
+	}
 	p2p.WaitForPeersStart()
 	log.Infof("P2P init success")
 	return p2p, p2p.GetNetwork(), nil
@@ -416,17 +478,44 @@ func initNodeInfo(ctx *cli.Context, p2pSvr *p2pserver.P2PServer) {
 	log.Infof("Nodeinfo init success")
 }
 
+func initMetrics(ctx *cli.Context) error {
+	if !config.DefConfig.Metrics.EnableMetrics {
+		return nil
+	}
+	var err error
+	exitCh := make(chan interface{}, 0)
+	go func() {
+		err = metrics.StartServer(config.DefConfig.Metrics.MetricsPort)
+		close(exitCh)
+	}()
+
+	flag := false
+	select {
+	case <-exitCh:
+		if !flag {
+			return err
+		}
+	case <-time.After(time.Millisecond * 5):
+		flag = true
+	}
+
+	log.Infof("Metrics init success")
+	return nil
+}
+
 func logCurrBlockHeight() {
 	ticker := time.NewTicker(config.DEFAULT_GEN_BLOCK_TIME * time.Second)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			log.Infof("CurrentBlockHeight = %d", ledger.DefLedger.GetCurrentBlockHeight())
+			height := ledger.DefLedger.GetCurrentBlockHeight()
+			log.WithFields(log.Fields{log.FIELD_HEIGHT: height}).Infof("CurrentBlockHeight = %d", height)
 			isNeedNewFile := log.CheckIfNeedNewFile()
 			if isNeedNewFile {
+				// the format and module levels are kept by the log package
 				log.ClosePrintLog()
-				log.InitLog(int(config.DefConfig.Common.LogLevel), log.PATH, log.Stdout)
+				log.InitLog(log.Log.GetDebugLevel(), log.PATH, log.Stdout)
 			}
 		}
 	}
@@ -445,13 +534,16 @@ func setMaxOpenFiles() {
 	}
 }
 
-func waitToExit(db *ledger.Ledger) {
+func waitToExit(db *ledger.Ledger, txpool *proc.TXPoolServer) {
 	exit := make(chan bool, 0)
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
 		for sig := range sc {
 			log.Infof("Ontology received exit signal: %v.", sig.String())
+			// the tx pool journal is rotated and synced on stop, and the journal replay may still read the ledger
+			log.Infof("stopping tx pool...")
+			txpool.Stop()
 			log.Infof("closing ledger...")
 			db.Close()
 			close(exit)
@@ -65,6 +65,21 @@ func NewFileHashStore(name string, tree_size uint32) (HashStore, error) {
 	return store, nil
 }
 
+// TruncateFileHashStore drops the hashes stored in file beyond the merkle tree of tree_size
+func TruncateFileHashStore(name string, tree_size uint32) error {
+	stat, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	size := getStoredHashNum(tree_size) * int64(common.UINT256_SIZE)
+	if stat.Size() <= size {
+		return nil
+	}
+	return os.Truncate(name, size)
+}
+
 func getStoredHashNum(tree_size uint32) int64 {
 	subtreesize := getSubTreeSize(tree_size)
 	sum := int64(0)
@@ -0,0 +1,377 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ontio/ontology/common"
+)
+
+// SparseMerkleTree is a compact sparse merkle tree over sha256(key). A subtree holding only one
+// key is replaced by its leaf, so a key is found within a few levels instead of 256.
+//   leaf node:     0x00 + sha256(key) + sha256(value)
+//   internal node: 0x01 + left child hash + right child hash
+// node hash is sha256 of the node, and an empty subtree is common.UINT256_EMPTY.
+
+const (
+	SMT_LEAF_NODE     byte = 0
+	SMT_INTERNAL_NODE byte = 1
+	SMT_NODE_SIZE          = 1 + 2*common.UINT256_SIZE
+	SMT_MAX_DEPTH          = 8 * common.UINT256_SIZE
+)
+
+// SparseMerkleNodeStore provides the persisted node by its hash
+type SparseMerkleNodeStore interface {
+	GetSparseMerkleNode(hash common.Uint256) ([]byte, error)
+}
+
+type SparseMerkleTree struct {
+	store SparseMerkleNodeStore
+	root  common.Uint256
+	nodes map[common.Uint256][]byte // nodes created since last commit
+}
+
+func NewSparseMerkleTree(root common.Uint256, store SparseMerkleNodeStore) *SparseMerkleTree {
+	return &SparseMerkleTree{
+		store: store,
+		root:  root,
+		nodes: make(map[common.Uint256][]byte),
+	}
+}
+
+func (self *SparseMerkleTree) Root() common.Uint256 {
+	return self.root
+}
+
+// Update set the value of key, an empty value removes the key from tree
+func (self *SparseMerkleTree) Update(key, value []byte) error {
+	keyHash := common.Uint256(sha256.Sum256(key))
+	var root common.Uint256
+	var err error
+	if len(value) == 0 {
+		root, err = self.delete(self.root, 0, keyHash)
+	} else {
+		leaf := smtLeafNode(keyHash, sha256.Sum256(value))
+		root, err = self.insert(self.root, 0, keyHash, self.putNode(leaf))
+	}
+	if err != nil {
+		return err
+	}
+	self.root = root
+	return nil
+}
+
+// Commit pass the new nodes reachable from current root to save, and drop the others
+func (self *SparseMerkleTree) Commit(save func(hash common.Uint256, node []byte)) {
+	var walk func(hash common.Uint256)
+	walk = func(hash common.Uint256) {
+		node, ok := self.nodes[hash]
+		if !ok {
+			return
+		}
+		save(hash, node)
+		if node[0] == SMT_INTERNAL_NODE {
+			left, right := smtChildren(node)
+			walk(left)
+			walk(right)
+		}
+	}
+	walk(self.root)
+	self.nodes = make(map[common.Uint256][]byte)
+}
+
+// Prove return the proof of key, which proves either the value of key or that key does not exist
+func (self *SparseMerkleTree) Prove(key []byte) (*SparseMerkleProof, error) {
+	keyHash := common.Uint256(sha256.Sum256(key))
+	proof := &SparseMerkleProof{}
+	hash := self.root
+	for depth := 0; hash != common.UINT256_EMPTY; depth++ {
+		node, err := self.getNode(hash)
+		if err != nil {
+			return nil, err
+		}
+		if node[0] == SMT_LEAF_NODE {
+			proof.Leaf = node
+			break
+		}
+		left, right := smtChildren(node)
+		if smtBit(keyHash, depth) == 0 {
+			proof.Siblings = append(proof.Siblings, right)
+			hash = left
+		} else {
+			proof.Siblings = append(proof.Siblings, left)
+			hash = right
+		}
+	}
+	return proof, nil
+}
+
+func (self *SparseMerkleTree) insert(hash common.Uint256, depth int, keyHash, leaf common.Uint256) (common.Uint256, error) {
+	if hash == common.UINT256_EMPTY {
+		return leaf, nil
+	}
+	node, err := self.getNode(hash)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if node[0] == SMT_LEAF_NODE {
+		oldKey, _ := common.Uint256ParseFromBytes(node[1 : 1+common.UINT256_SIZE])
+		if oldKey == keyHash {
+			return leaf, nil
+		}
+		return self.split(depth, hash, oldKey, leaf, keyHash)
+	}
+	left, right := smtChildren(node)
+	if smtBit(keyHash, depth) == 0 {
+		left, err = self.insert(left, depth+1, keyHash, leaf)
+	} else {
+		right, err = self.insert(right, depth+1, keyHash, leaf)
+	}
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	return self.putNode(smtInternalNode(left, right)), nil
+}
+
+// split build the subtree holding two leaves whose key hashes share the first depth bits
+func (self *SparseMerkleTree) split(depth int, oldLeaf, oldKey, newLeaf, newKey common.Uint256) (common.Uint256, error) {
+	if depth >= SMT_MAX_DEPTH {
+		return common.UINT256_EMPTY, errors.New("sparse merkle tree: key hash collision")
+	}
+	oldBit, newBit := smtBit(oldKey, depth), smtBit(newKey, depth)
+	if oldBit != newBit {
+		if newBit == 0 {
+			return self.putNode(smtInternalNode(newLeaf, oldLeaf)), nil
+		}
+		return self.putNode(smtInternalNode(oldLeaf, newLeaf)), nil
+	}
+	child, err := self.split(depth+1, oldLeaf, oldKey, newLeaf, newKey)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if newBit == 0 {
+		return self.putNode(smtInternalNode(child, common.UINT256_EMPTY)), nil
+	}
+	return self.putNode(smtInternalNode(common.UINT256_EMPTY, child)), nil
+}
+
+func (self *SparseMerkleTree) delete(hash common.Uint256, depth int, keyHash common.Uint256) (common.Uint256, error) {
+	if hash == common.UINT256_EMPTY {
+		return hash, nil
+	}
+	node, err := self.getNode(hash)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if node[0] == SMT_LEAF_NODE {
+		if bytes.Equal(node[1:1+common.UINT256_SIZE], keyHash[:]) {
+			return common.UINT256_EMPTY, nil
+		}
+		return hash, nil
+	}
+	left, right := smtChildren(node)
+	child, other := left, right
+	if smtBit(keyHash, depth) == 1 {
+		child, other = right, left
+	}
+	newChild, err := self.delete(child, depth+1, keyHash)
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	if newChild == child {
+		return hash, nil
+	}
+	// a subtree holding only one leaf collapses into the leaf
+	if newChild == common.UINT256_EMPTY {
+		isLeaf, err := self.isLeaf(other)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		if isLeaf {
+			return other, nil
+		}
+	} else if other == common.UINT256_EMPTY {
+		isLeaf, err := self.isLeaf(newChild)
+		if err != nil {
+			return common.UINT256_EMPTY, err
+		}
+		if isLeaf {
+			return newChild, nil
+		}
+	}
+	if smtBit(keyHash, depth) == 0 {
+		return self.putNode(smtInternalNode(newChild, other)), nil
+	}
+	return self.putNode(smtInternalNode(other, newChild)), nil
+}
+
+func (self *SparseMerkleTree) isLeaf(hash common.Uint256) (bool, error) {
+	if hash == common.UINT256_EMPTY {
+		return true, nil
+	}
+	node, err := self.getNode(hash)
+	if err != nil {
+		return false, err
+	}
+	return node[0] == SMT_LEAF_NODE, nil
+}
+
+func (self *SparseMerkleTree) getNode(hash common.Uint256) ([]byte, error) {
+	if node, ok := self.nodes[hash]; ok {
+		return node, nil
+	}
+	node, err := self.store.GetSparseMerkleNode(hash)
+	if err != nil {
+		return nil, fmt.Errorf("sparse merkle tree: get node %s error: %s", hash.ToHexString(), err)
+	}
+	if len(node) != SMT_NODE_SIZE {
+		return nil, fmt.Errorf("sparse merkle tree: invalid node %s", hash.ToHexString())
+	}
+	return node, nil
+}
+
+func (self *SparseMerkleTree) putNode(node []byte) common.Uint256 {
+	hash := common.Uint256(sha256.Sum256(node))
+	self.nodes[hash] = node
+	return hash
+}
+
+func smtLeafNode(keyHash, valueHash common.Uint256) []byte {
+	node := make([]byte, 0, SMT_NODE_SIZE)
+	node = append(node, SMT_LEAF_NODE)
+	node = append(node, keyHash[:]...)
+	return append(node, valueHash[:]...)
+}
+
+func smtInternalNode(left, right common.Uint256) []byte {
+	node := make([]byte, 0, SMT_NODE_SIZE)
+	node = append(node, SMT_INTERNAL_NODE)
+	node = append(node, left[:]...)
+	return append(node, right[:]...)
+}
+
+func smtChildren(node []byte) (left, right common.Uint256) {
+	copy(left[:], node[1:1+common.UINT256_SIZE])
+	copy(right[:], node[1+common.UINT256_SIZE:])
+	return
+}
+
+// smtBit return the bit of hash at depth, from the most significant bit
+func smtBit(hash common.Uint256, depth int) byte {
+	return (hash[depth/8] >> uint(7-depth%8)) & 1
+}
+
+// SparseMerkleProof holds the sibling hashes from root to the node where the key path ends,
+// and the leaf node there, which is nil if the path ends at an empty subtree.
+type SparseMerkleProof struct {
+	Siblings []common.Uint256
+	Leaf     []byte
+}
+
+func (self *SparseMerkleProof) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarUint(uint64(len(self.Siblings)))
+	for _, hash := range self.Siblings {
+		sink.WriteHash(hash)
+	}
+	sink.WriteVarBytes(self.Leaf)
+}
+
+func (self *SparseMerkleProof) Deserialization(source *common.ZeroCopySource) error {
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if n > SMT_MAX_DEPTH {
+		return errors.New("sparse merkle proof: too many siblings")
+	}
+	self.Siblings = make([]common.Uint256, 0, n)
+	for i := uint64(0); i < n; i++ {
+		hash, eof := source.NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		self.Siblings = append(self.Siblings, hash)
+	}
+	leaf, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if len(leaf) == 0 {
+		leaf = nil
+	}
+	self.Leaf = leaf
+	return nil
+}
+
+// VerifySparseMerkleProof check the proof of key against root. An empty value means to prove key does not exist.
+func VerifySparseMerkleProof(root common.Uint256, key, value []byte, proof *SparseMerkleProof) error {
+	if len(proof.Siblings) > SMT_MAX_DEPTH {
+		return errors.New("too many siblings")
+	}
+	keyHash := common.Uint256(sha256.Sum256(key))
+	hash := common.UINT256_EMPTY
+	if proof.Leaf == nil {
+		if len(value) != 0 {
+			return errors.New("proof shows key does not exist")
+		}
+	} else {
+		if len(proof.Leaf) != SMT_NODE_SIZE || proof.Leaf[0] != SMT_LEAF_NODE {
+			return errors.New("invalid leaf node")
+		}
+		leafKey, _ := common.Uint256ParseFromBytes(proof.Leaf[1 : 1+common.UINT256_SIZE])
+		if len(value) != 0 {
+			valueHash := sha256.Sum256(value)
+			if leafKey != keyHash || !bytes.Equal(proof.Leaf[1+common.UINT256_SIZE:], valueHash[:]) {
+				return errors.New("leaf node does not match key and value")
+			}
+		} else {
+			if leafKey == keyHash {
+				return errors.New("proof shows key exists")
+			}
+			for i := range proof.Siblings {
+				if smtBit(leafKey, i) != smtBit(keyHash, i) {
+					return errors.New("leaf node is not on the path of key")
+				}
+			}
+		}
+		hash = sha256.Sum256(proof.Leaf)
+	}
+	for i := len(proof.Siblings) - 1; i >= 0; i-- {
+		if smtBit(keyHash, i) == 0 {
+			hash = sha256.Sum256(smtInternalNode(hash, proof.Siblings[i]))
+		} else {
+			hash = sha256.Sum256(smtInternalNode(proof.Siblings[i], hash))
+		}
+	}
+	if hash != root {
+		return fmt.Errorf("root mismatch, expect %s, got %s", root.ToHexString(), hash.ToHexString())
+	}
+	return nil
+}
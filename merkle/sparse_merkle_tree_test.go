@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type memSparseMerkleNodeStore map[common.Uint256][]byte
+
+func (self memSparseMerkleNodeStore) GetSparseMerkleNode(hash common.Uint256) ([]byte, error) {
+	node, ok := self[hash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return node, nil
+}
+
+func TestSparseMerkleTree(t *testing.T) {
+	store := make(memSparseMerkleNodeStore)
+	tree := NewSparseMerkleTree(common.UINT256_EMPTY, store)
+	states := make(map[string][]byte)
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 50; i++ {
+			key := []byte(fmt.Sprintf("key%d", rand.Intn(200)))
+			value := []byte(fmt.Sprintf("value%d", rand.Int()))
+			if rand.Intn(4) == 0 {
+				value = nil
+				delete(states, string(key))
+			} else {
+				states[string(key)] = value
+			}
+			assert.Nil(t, tree.Update(key, value))
+		}
+		tree.Commit(func(hash common.Uint256, node []byte) {
+			store[hash] = node
+		})
+
+		// the root only depends on the states, not the updating history
+		rebuilt := NewSparseMerkleTree(common.UINT256_EMPTY, make(memSparseMerkleNodeStore))
+		for key, value := range states {
+			assert.Nil(t, rebuilt.Update([]byte(key), value))
+		}
+		assert.Equal(t, rebuilt.Root(), tree.Root())
+
+		reader := NewSparseMerkleTree(tree.Root(), store)
+		for i := 0; i < 200; i++ {
+			key := []byte(fmt.Sprintf("key%d", i))
+			proof, err := reader.Prove(key)
+			assert.Nil(t, err)
+
+			sink := common.NewZeroCopySink(nil)
+			proof.Serialization(sink)
+			decoded := &SparseMerkleProof{}
+			assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+			assert.Equal(t, proof, decoded)
+
+			value := states[string(key)]
+			assert.Nil(t, VerifySparseMerkleProof(tree.Root(), key, value, decoded))
+			if value == nil {
+				assert.NotNil(t, VerifySparseMerkleProof(tree.Root(), key, []byte("value"), decoded))
+			} else {
+				assert.NotNil(t, VerifySparseMerkleProof(tree.Root(), key, nil, decoded))
+				assert.NotNil(t, VerifySparseMerkleProof(tree.Root(), key, append(value, 0), decoded))
+			}
+		}
+	}
+
+	for key := range states {
+		assert.Nil(t, tree.Update([]byte(key), nil))
+	}
+	assert.Equal(t, common.UINT256_EMPTY, tree.Root())
+}
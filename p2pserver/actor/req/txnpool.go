@@ -22,6 +22,7 @@ import (
 	"github.com/ontio/ontology-eventbus/actor"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/p2pserver/common"
 	tc "github.com/ontio/ontology/txnpool/common"
 )
 
@@ -31,8 +32,8 @@ func SetTxnPoolPid(txnPid *actor.PID) {
 	txnPoolPid = txnPid
 }
 
-//add txn to txnpool
-func AddTransaction(transaction *types.Transaction) {
+//add txn from peer to txnpool, the peer is penalized by txnpool if the txn fails the stateless verification
+func AddTransaction(transaction *types.Transaction, peerId common.PeerId) {
 	if txnPoolPid == nil {
 		log.Error("[p2p]net_server AddTransaction(): txnpool pid is nil")
 		return
@@ -41,6 +42,7 @@ func AddTransaction(transaction *types.Transaction) {
 		Tx:         transaction,
 		Sender:     tc.NetSender,
 		TxResultCh: nil,
+		PeerId:     peerId,
 	}
 	txnPoolPid.Tell(txReq)
 }
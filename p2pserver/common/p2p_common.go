@@ -28,10 +28,13 @@ import (
 const (
 	VERIFY_NODE  = 1 //peer involved in consensus
 	SERVICE_NODE = 2 //peer only sync with consensus peer
+	LIGHT_NODE   = 4 //peer only keep the block headers, and can not serve the blocks
 )
 
 const MIN_VERSION_FOR_DHT = "1.9.1-beta"
 
+const MIN_VERSION_FOR_HEADER_RANGE = "1.11.0-beta" //peers from the version serve headers by height
+
 //link and concurrent const
 const (
 	PER_SEND_LEN        = 1024 * 256 //byte len per conn write
@@ -39,6 +42,7 @@ const (
 	WRITE_DEADLINE      = 5          //deadline of conn write
 	REQ_INTERVAL        = 3          //single request max interval in second
 	MAX_REQ_RECORD_SIZE = 1000       //the maximum request record size
+	MAX_DUP_REQ_TIMES   = 3          //the maximum times a request can be repeated within the request interval
 	MAX_RESP_CACHE_SIZE = 50         //the maximum response cache
 	MAX_TX_CACHE_SIZE   = 100000     //the maximum txHash cache size
 )
@@ -85,6 +89,9 @@ const (
 	RECENT_FILE_NAME = "peers.recent"
 )
 
+//banned peers const
+const BAN_FILE_NAME = "peers.banned"
+
 //PeerAddr represent peer`s net information
 type PeerAddr struct {
 	Time     int64    //latest timestamp
@@ -105,6 +112,7 @@ const (
 	PING_TYPE          = "ping"        //ping  sync height
 	PONG_TYPE          = "pong"        //pong  recv nbr height
 	GET_HEADERS_TYPE   = "getheaders"  //req blk hdr
+	GET_HDR_RANGE_TYPE = "gethdrrange" //req blk hdr by height
 	HEADERS_TYPE       = "headers"     //blk hdr
 	INV_TYPE           = "inv"         //inv payload
 	GET_DATA_TYPE      = "getdata"     //req data from peer
@@ -18,13 +18,17 @@
 package connect_controller
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	comm "github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/p2pserver/common"
 	"github.com/ontio/ontology/p2pserver/handshake"
@@ -52,6 +56,11 @@ type ConnectController struct {
 	inboundListenAddress *strset.Set    // in bound listen address
 	connecting           *strset.Set
 	peers                map[common.PeerId]*connectedPeer // all connected peers
+	banned               map[string]int64                 // banned peer ip to the unix time the ban expires at
+	banFile              string                           // file the ban list persists to
+	saveMutex            sync.Mutex                       // serialize the writes of ban file
+
+	rsvMutex sync.RWMutex // guards the reserved peers updated at runtime
 
 	ownAddr       string
 	nextConnectId uint64
@@ -144,9 +153,65 @@ func (self *ConnectController) removeConnecting(addr string) {
 }
 
 func (self *ConnectController) reserveEnabled() bool {
+	self.rsvMutex.RLock()
+	defer self.rsvMutex.RUnlock()
 	return len(self.ReservedPeers) > 0
 }
 
+//GetReservedPeers return a copy of the reserved peer list
+func (self *ConnectController) GetReservedPeers() []string {
+	self.rsvMutex.RLock()
+	defer self.rsvMutex.RUnlock()
+	return append([]string{}, self.ReservedPeers...)
+}
+
+//AddReservedPeer add an ip or domain to the reserved peer list. The list can only be updated when the reserved
+//peers are enabled, since a node with empty list accepts all the peers.
+func (self *ConnectController) AddReservedPeer(ipOrName string) error {
+	if ipOrName == "" {
+		return fmt.Errorf("empty reserved peer")
+	}
+	self.rsvMutex.Lock()
+	defer self.rsvMutex.Unlock()
+	if len(self.ReservedPeers) == 0 {
+		return fmt.Errorf("reserved peers are not enabled")
+	}
+	for _, curIPOrName := range self.ReservedPeers {
+		if curIPOrName == ipOrName {
+			return fmt.Errorf("%s already in reserved list", ipOrName)
+		}
+	}
+	peers := append([]string{}, self.ReservedPeers...)
+	peers = append(peers, ipOrName)
+	// put domain to the end
+	sort.SliceStable(peers, func(i, j int) bool {
+		return net.ParseIP(peers[i]) != nil && net.ParseIP(peers[j]) == nil
+	})
+	self.ReservedPeers = peers
+	return nil
+}
+
+//RemoveReservedPeer remove an ip or domain from the reserved peer list. The last one can not be removed, otherwise
+//the node accepts all the peers. The connected peers are not affected.
+func (self *ConnectController) RemoveReservedPeer(ipOrName string) error {
+	self.rsvMutex.Lock()
+	defer self.rsvMutex.Unlock()
+	peers := make([]string, 0, len(self.ReservedPeers))
+	for _, curIPOrName := range self.ReservedPeers {
+		if curIPOrName != ipOrName {
+			peers = append(peers, curIPOrName)
+		}
+	}
+	if len(peers) == len(self.ReservedPeers) {
+		return fmt.Errorf("%s not in reserved list", ipOrName)
+	}
+	if len(peers) == 0 {
+		return fmt.Errorf("can not remove the last reserved peer %s", ipOrName)
+	}
+	self.ReservedPeers = peers
+	return nil
+}
+
 // remoteAddr format 192.168.1.1:61234
 func (self *ConnectController) inReserveList(remoteIPPort string) bool {
 	// 192.168.1.1 in reserve list, 192.168.1.111:61234 and 192.168.1.11:61234 can connect in if we are using prefix matching
@@ -156,7 +221,7 @@ func (self *ConnectController) inReserveList(remoteIPPort string) bool {
 		return false
 	}
 	// we don't load domain in start because we consider domain's A/AAAA record may change sometimes
-	for _, curIPOrName := range self.ReservedPeers {
+	for _, curIPOrName := range self.GetReservedPeers() {
 		curIPs, err := net.LookupHost(curIPOrName)
 		if err != nil {
 			continue
@@ -171,6 +236,11 @@ func (self *ConnectController) inReserveList(remoteIPPort string) bool {
 	return false
 }
 
+//IsReservedPeer return true if the remote addr is in the reserved peer list
+func (self *ConnectController) IsReservedPeer(remoteAddr string) bool {
+	return self.reserveEnabled() && self.inReserveList(remoteAddr)
+}
+
 func (self *ConnectController) checkReservedPeers(remoteAddr string) error {
 	if !self.reserveEnabled() || self.inReserveList(remoteAddr) {
 		return nil
@@ -178,6 +248,137 @@ func (self *ConnectController) checkReservedPeers(remoteAddr string) error {
 	return fmt.Errorf("the remote addr: %s not in reserved list", remoteAddr)
 }
 
+//BannedPeer is a banned ip with the unix time the ban expires at, 0 means never
+type BannedPeer struct {
+	Ip    string `json:"ip"`
+	Until int64  `json:"until"`
+}
+
+//BanPeer ban the ip for duration, or forever if duration is 0, the connections with it are refused
+func (self *ConnectController) BanPeer(ip string, duration time.Duration) {
+	until := int64(0)
+	if duration > 0 {
+		until = time.Now().Add(duration).Unix()
+	}
+	self.mutex.Lock()
+	if self.banned == nil {
+		self.banned = make(map[string]int64)
+	}
+	self.banned[ip] = until
+	self.mutex.Unlock()
+	self.saveBannedPeers()
+}
+
+//UnbanPeer remove the ip from the ban list, return false if it is not banned
+func (self *ConnectController) UnbanPeer(ip string) bool {
+	if !self.isBanned(ip) {
+		return false
+	}
+	self.mutex.Lock()
+	delete(self.banned, ip)
+	self.mutex.Unlock()
+	self.saveBannedPeers()
+	return true
+}
+
+//GetBannedPeers return the unexpired banned peers sorted by ip
+func (self *ConnectController) GetBannedPeers() []BannedPeer {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	now := time.Now().Unix()
+	peers := make([]BannedPeer, 0, len(self.banned))
+	for ip, until := range self.banned {
+		if until != 0 && until <= now {
+			delete(self.banned, ip)
+			continue
+		}
+		peers = append(peers, BannedPeer{Ip: ip, Until: until})
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Ip < peers[j].Ip
+	})
+	return peers
+}
+
+func (self *ConnectController) isBanned(ip string) bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	until, ok := self.banned[ip]
+	if !ok {
+		return false
+	}
+	if until != 0 && until <= time.Now().Unix() {
+		delete(self.banned, ip)
+		return false
+	}
+	return true
+}
+
+//LoadBannedPeers load the ban list from file, and save it to the file whenever it changes
+func (self *ConnectController) LoadBannedPeers(file string) error {
+	self.mutex.Lock()
+	self.banFile = file
+	self.mutex.Unlock()
+	if !comm.FileExisted(file) {
+		return nil
+	}
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var peers []BannedPeer
+	err = json.Unmarshal(buf, &peers)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.banned == nil {
+		self.banned = make(map[string]int64)
+	}
+	for _, p := range peers {
+		if p.Until != 0 && p.Until <= now {
+			continue
+		}
+		self.banned[p.Ip] = p.Until
+	}
+	return nil
+}
+
+//saveBannedPeers write the ban list to file. The writes are serialized, so that the file ends with the latest list.
+func (self *ConnectController) saveBannedPeers() {
+	self.mutex.Lock()
+	file := self.banFile
+	self.mutex.Unlock()
+	if file == "" {
+		return
+	}
+	self.saveMutex.Lock()
+	defer self.saveMutex.Unlock()
+	buf, err := json.Marshal(self.GetBannedPeers())
+	if err != nil {
+		log.Warn("[p2p]package banned peers fail: ", err)
+		return
+	}
+	err = ioutil.WriteFile(file, buf, 0600)
+	if err != nil {
+		log.Warn("[p2p]write banned peers fail: ", err)
+	}
+}
+
+// remoteAddr format 192.168.1.1:61234
+func (self *ConnectController) checkBannedPeers(remoteAddr string) error {
+	remoteIp, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return fmt.Errorf("[p2p]parse ip error %v", err.Error())
+	}
+	if self.isBanned(remoteIp) {
+		return fmt.Errorf("the remote addr: %s is banned", remoteAddr)
+	}
+	return nil
+}
+
 func (self *ConnectController) getInboundCountWithIp(ip string) uint {
 	self.mutex.Lock()
 	defer self.mutex.Unlock()
@@ -213,7 +414,8 @@ func (self *ConnectController) AcceptConnect(conn net.Conn) (*peer.PeerInfo, net
 
 	wrapped := self.savePeer(conn, peerInfo, INBOUND_INDEX)
 
-	log.Infof("inbound peer %s connected, %s", conn.RemoteAddr().String(), peerInfo)
+	log.WithFields(log.Fields{log.FIELD_PEER_ID: peerInfo.Id.ToHexString()}).Infof("inbound peer %s connected, %s",
+		conn.RemoteAddr().String(), peerInfo)
 	return peerInfo, wrapped, nil
 }
 
@@ -249,7 +451,8 @@ func (self *ConnectController) Connect(addr string) (*peer.PeerInfo, net.Conn, e
 
 	wrapped := self.savePeer(conn, peerInfo, OUTBOUND_INDEX)
 
-	log.Infof("outbound peer %s connected. %s", conn.RemoteAddr().String(), peerInfo)
+	log.WithFields(log.Fields{log.FIELD_PEER_ID: peerInfo.Id.ToHexString()}).Infof("outbound peer %s connected. %s",
+		conn.RemoteAddr().String(), peerInfo)
 	return peerInfo, wrapped, nil
 }
 
@@ -262,7 +465,12 @@ func (self *ConnectController) afterHandshakeCheck(remotePeer *peer.PeerInfo, re
 }
 
 func (self *ConnectController) beforeHandshakeCheck(addr string, index int) error {
-	err := self.checkReservedPeers(addr)
+	err := self.checkBannedPeers(addr)
+	if err != nil {
+		return err
+	}
+
+	err = self.checkReservedPeers(addr)
 	if err != nil {
 		return err
 	}
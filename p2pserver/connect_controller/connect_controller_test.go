@@ -20,6 +20,8 @@ package connect_controller
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"sort"
 	"sync"
 	"testing"
@@ -259,3 +261,72 @@ func TestCheckReserveWithDomain(t *testing.T) {
 	})
 	a.Equal(cc.ReservedPeers[len(cc.ReservedPeers)-1], "www.baidu.com", "fail")
 }
+
+func TestReservedPeersUpdate(t *testing.T) {
+	a := assert.New(t)
+	cc := &ConnectController{}
+	a.NotNil(cc.AddReservedPeer("192.168.1.1"), "reserved peers not enabled")
+
+	cc.ReservedPeers = []string{"localhost", "192.168.1.1"}
+	a.NotNil(cc.AddReservedPeer("192.168.1.1"))
+	a.Nil(cc.AddReservedPeer("192.168.1.2"))
+	a.Equal([]string{"192.168.1.1", "192.168.1.2", "localhost"}, cc.GetReservedPeers())
+	a.True(cc.inReserveList("192.168.1.2:1234"))
+
+	a.Nil(cc.RemoveReservedPeer("192.168.1.2"))
+	a.NotNil(cc.RemoveReservedPeer("192.168.1.2"))
+	a.False(cc.inReserveList("192.168.1.2:1234"))
+	a.Nil(cc.RemoveReservedPeer("localhost"))
+	a.NotNil(cc.RemoveReservedPeer("192.168.1.1"), "can not remove the last one")
+	a.Equal([]string{"192.168.1.1"}, cc.GetReservedPeers())
+}
+
+func TestBanPeer(t *testing.T) {
+	trans := NewTransport(t)
+	server := NewNode(NewConnCtrlOption())
+
+	server.BanPeer("127.0.0.1", 0)
+	a := assert.New(t)
+	a.Equal([]BannedPeer{{Ip: "127.0.0.1"}}, server.GetBannedPeers())
+	_, conn := trans.Pipe()
+	_, _, err := server.AcceptConnect(conn)
+	a.NotNil(err)
+	a.Contains(err.Error(), "banned")
+	_ = conn.Close()
+
+	a.True(server.UnbanPeer("127.0.0.1"))
+	a.False(server.UnbanPeer("127.0.0.1"))
+	a.Equal(0, len(server.GetBannedPeers()))
+	a.Nil(server.checkBannedPeers("127.0.0.1:1234"))
+}
+
+func TestBanPeerExpire(t *testing.T) {
+	a := assert.New(t)
+	server := NewNode(NewConnCtrlOption())
+
+	server.BanPeer("192.168.1.1", time.Hour)
+	a.NotNil(server.checkBannedPeers("192.168.1.1:1234"))
+	server.mutex.Lock()
+	server.banned["192.168.1.1"] = time.Now().Unix() - 1
+	server.mutex.Unlock()
+	a.Nil(server.checkBannedPeers("192.168.1.1:1234"))
+	a.Equal(0, len(server.GetBannedPeers()))
+}
+
+func TestBannedPeersPersist(t *testing.T) {
+	a := assert.New(t)
+	file := filepath.Join(os.TempDir(), fmt.Sprintf("peers.banned.%d", time.Now().UnixNano()))
+	defer os.Remove(file)
+
+	server := NewNode(NewConnCtrlOption())
+	a.Nil(server.LoadBannedPeers(file))
+	server.BanPeer("192.168.1.1", 0)
+	server.BanPeer("192.168.1.2", time.Hour)
+	server.BanPeer("192.168.1.3", time.Hour)
+	a.True(server.UnbanPeer("192.168.1.3"))
+
+	restarted := NewNode(NewConnCtrlOption())
+	a.Nil(restarted.LoadBannedPeers(file))
+	a.Equal(server.GetBannedPeers(), restarted.GetBannedPeers())
+	a.Equal(2, len(restarted.GetBannedPeers()))
+}
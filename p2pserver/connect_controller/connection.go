@@ -38,7 +38,8 @@ type Conn struct {
 // Close overwrite net.Conn
 // warning: this method will try to lock the controller, be carefull to avoid deadlock
 func (self *Conn) Close() error {
-	log.Infof("closing connection: peer %s, address: %s", self.kid.ToHexString(), self.addr)
+	log.WithFields(log.Fields{log.FIELD_PEER_ID: self.kid.ToHexString()}).Infof("closing connection: peer %s, address: %s",
+		self.kid.ToHexString(), self.addr)
 
 	self.controller.removePeer(self)
 
@@ -22,13 +22,17 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
 	comm "github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/p2pserver/common"
 	"github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/p2pserver/peer_score"
+	"github.com/ontio/ontology/p2pserver/rate_limit"
 )
 
 //Link used to establish
@@ -39,11 +43,15 @@ type Link struct {
 	time      time.Time              // The latest time the node activity
 	recvChan  chan *types.MsgPayload //msgpayload channel
 	reqRecord map[string]int64       //Map RequestId to Timestamp, using for rejecting duplicate request in specific time
+	dupRecord map[string]uint32      //Map RequestId to the times it is rejected since the last time it was accepted
+	penalize  func(id common.PeerId, m peer_score.Misbehaviour)
+	limiter   *rate_limit.PeerLimiter //limits the messages received, no limit if nil
 }
 
 func NewLink() *Link {
 	link := &Link{
 		reqRecord: make(map[string]int64),
+		dupRecord: make(map[string]uint32),
 	}
 	return link
 }
@@ -68,6 +76,22 @@ func (this *Link) SetChan(msgchan chan *types.MsgPayload) {
 	this.recvChan = msgchan
 }
 
+//set the rate limiter of the messages received
+func (this *Link) SetRateLimiter(limiter *rate_limit.PeerLimiter) {
+	this.limiter = limiter
+}
+
+//set the function called when the peer misbehaves on the link
+func (this *Link) SetPenalizer(penalize func(id common.PeerId, m peer_score.Misbehaviour)) {
+	this.penalize = penalize
+}
+
+func (this *Link) misbehave(m peer_score.Misbehaviour) {
+	if this.penalize != nil {
+		this.penalize(this.id, m)
+	}
+}
+
 //get address
 func (this *Link) GetAddr() string {
 	return this.addr
@@ -109,15 +133,31 @@ func (this *Link) Rx() {
 	for {
 		msg, payloadSize, err := types.ReadMessage(reader)
 		if err != nil {
-			log.Infof("[p2p]error read from %s :%s", this.GetAddr(), err.Error())
+			log.WithFields(log.Fields{log.FIELD_PEER_ID: this.id.ToHexString()}).Infof("[p2p]error read from %s :%s",
+				this.GetAddr(), err.Error())
+			if !isConnError(err) {
+				this.misbehave(peer_score.MALFORMED_MSG)
+			}
 			break
 		}
 
 		t := time.Now()
 		this.UpdateRXTime(t)
+		metrics.P2PMessage(metrics.P2P_RECV, msg.CmdType(), int(payloadSize)+common.MSG_HDR_LEN)
+
+		if !this.allowMsg(msg) {
+			if this.limiter.Policy() == rate_limit.POLICY_DISCONNECT {
+				this.misbehave(peer_score.SPAM)
+				break
+			}
+			continue
+		}
 
 		if !this.needSendMsg(msg) {
 			log.Debugf("skip handle msgType:%s from:%d", msg.CmdType(), this.id)
+			if this.addDupRecord(msg) > common.MAX_DUP_REQ_TIMES {
+				this.misbehave(peer_score.SPAM)
+			}
 			continue
 		}
 
@@ -134,6 +174,30 @@ func (this *Link) Rx() {
 	this.CloseConn()
 }
 
+//allowMsg check the msg against the rate limits, and record the limit hit if it is not allowed
+func (this *Link) allowMsg(msg types.Message) bool {
+	if this.limiter == nil {
+		return true
+	}
+	ok, limit := this.limiter.Allow(msg.CmdType())
+	if ok {
+		return true
+	}
+	policy := this.limiter.Policy()
+	metrics.P2PRateLimited(limit, msg.CmdType(), policy.String())
+	log.Debugf("[p2p]msgType:%s from %s over the %s rate limit, %s it", msg.CmdType(), this.addr, limit, policy)
+	return false
+}
+
+//isConnError return true if the error is caused by the connection instead of the message content
+func isConnError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
 //close connection
 func (this *Link) CloseConn() {
 	if this.conn != nil {
@@ -164,7 +228,8 @@ func (this *Link) SendRaw(rawPacket []byte) error {
 	_ = conn.SetWriteDeadline(time.Now().Add(time.Duration(nCount*common.WRITE_DEADLINE) * time.Second))
 	_, err := conn.Write(rawPacket)
 	if err != nil {
-		log.Infof("[p2p] error sending messge to %s :%s", this.GetAddr(), err.Error())
+		log.WithFields(log.Fields{log.FIELD_PEER_ID: this.id.ToHexString()}).Infof("[p2p] error sending messge to %s :%s",
+			this.GetAddr(), err.Error())
 		this.CloseConn()
 		return err
 	}
@@ -200,10 +265,21 @@ func (this *Link) addReqRecord(msg types.Message) {
 			t := this.reqRecord[id]
 			if int(now-t) > common.REQ_INTERVAL {
 				delete(this.reqRecord, id)
+				delete(this.dupRecord, id)
 			}
 		}
 	}
 	var dataReq = msg.(*types.DataReq)
 	reqID := fmt.Sprintf("%x%s", dataReq.DataType, dataReq.Hash.ToHexString())
 	this.reqRecord[reqID] = now
+	delete(this.dupRecord, reqID)
+}
+
+//addDupRecord count the rejected duplicate request, return the times it is rejected within the request interval.
+//An honest peer may retry a request before the interval elapses, so only the frequent duplicates are spam.
+func (this *Link) addDupRecord(msg types.Message) uint32 {
+	var dataReq = msg.(*types.DataReq)
+	reqID := fmt.Sprintf("%x%s", dataReq.DataType, dataReq.Hash.ToHexString())
+	this.dupRecord[reqID] += 1
+	return this.dupRecord[reqID]
 }
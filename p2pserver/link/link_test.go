@@ -20,6 +20,7 @@ package link
 
 import (
 	"math/rand"
+	"net"
 	"testing"
 	"time"
 
@@ -30,6 +31,9 @@ import (
 	ct "github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/p2pserver/common"
 	mt "github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/p2pserver/peer_score"
+	"github.com/ontio/ontology/p2pserver/rate_limit"
+	"github.com/stretchr/testify/assert"
 )
 
 var (
@@ -183,3 +187,62 @@ func TestUnpackBufNode(t *testing.T) {
 	sink := comm.NewZeroCopySink(nil)
 	mt.WriteMessage(sink, msg)
 }
+
+func TestRxPenalize(t *testing.T) {
+	local, remote := net.Pipe()
+	link := NewLink()
+	link.SetConn(local)
+	link.SetChan(make(chan *mt.MsgPayload, 10))
+	var penalties []peer_score.Misbehaviour
+	link.SetPenalizer(func(id common.PeerId, m peer_score.Misbehaviour) {
+		penalties = append(penalties, m)
+	})
+	done := make(chan struct{})
+	go func() {
+		link.Rx()
+		close(done)
+	}()
+
+	sink := comm.NewZeroCopySink(nil)
+	mt.WriteMessage(sink, &mt.DataReq{DataType: comm.BLOCK, Hash: comm.UINT256_EMPTY})
+	for i := 0; i < common.MAX_DUP_REQ_TIMES+2; i++ {
+		_, _ = remote.Write(sink.Bytes())
+	}
+	_, _ = remote.Write([]byte("a message with unmatched magic"))
+	<-done
+	_ = remote.Close()
+
+	assert.Equal(t, []peer_score.Misbehaviour{peer_score.SPAM, peer_score.MALFORMED_MSG}, penalties)
+}
+
+func TestRxRateLimit(t *testing.T) {
+	local, remote := net.Pipe()
+	link := NewLink()
+	link.SetConn(local)
+	recvChan := make(chan *mt.MsgPayload, 10)
+	link.SetChan(recvChan)
+	var penalties []peer_score.Misbehaviour
+	link.SetPenalizer(func(id common.PeerId, m peer_score.Misbehaviour) {
+		penalties = append(penalties, m)
+	})
+	link.SetRateLimiter(rate_limit.NewPeerLimiter(&rate_limit.LimitConfig{
+		MsgLimits: map[string]rate_limit.Limit{common.PING_TYPE: {Rate: 1, Burst: 2}},
+		Policy:    rate_limit.POLICY_DISCONNECT,
+	}))
+	done := make(chan struct{})
+	go func() {
+		link.Rx()
+		close(done)
+	}()
+
+	sink := comm.NewZeroCopySink(nil)
+	mt.WriteMessage(sink, &mt.Ping{Height: 1})
+	for i := 0; i < 3; i++ {
+		_, _ = remote.Write(sink.Bytes())
+	}
+	<-done
+	_ = remote.Close()
+
+	assert.Equal(t, 2, len(recvChan))
+	assert.Equal(t, []peer_score.Misbehaviour{peer_score.SPAM}, penalties)
+}
@@ -72,6 +72,16 @@ func NewHeadersReq(curHdrHash common.Uint256) mt.Message {
 	return &h
 }
 
+//blk hdr range req package
+func NewHeaderRangeReq(start uint32, count uint32) mt.Message {
+	log.Trace()
+	var h mt.HeaderRangeReq
+	h.Start = start
+	h.Count = count
+
+	return &h
+}
+
 ////Consensus info package
 func NewConsensus(cp *mt.ConsensusPayload) mt.Message {
 	log.Trace()
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"io"
+
+	"github.com/ontio/ontology/common"
+	comm "github.com/ontio/ontology/p2pserver/common"
+)
+
+//HeaderRangeReq requests the headers from the start height, unlike
+//HeadersReq it does not depend on a known header, so several ranges can be
+//requested from different peers at the same time
+type HeaderRangeReq struct {
+	Start uint32
+	Count uint32
+}
+
+//Serialize message payload
+func (this *HeaderRangeReq) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint32(this.Start)
+	sink.WriteUint32(this.Count)
+}
+
+func (this *HeaderRangeReq) CmdType() string {
+	return comm.GET_HDR_RANGE_TYPE
+}
+
+//Deserialize message payload
+func (this *HeaderRangeReq) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Start, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.Count, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
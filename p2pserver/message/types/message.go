@@ -161,6 +161,8 @@ func MakeEmptyMessage(cmdType string) (Message, error) {
 		return &Pong{}, nil
 	case common.GET_HEADERS_TYPE:
 		return &HeadersReq{}, nil
+	case common.GET_HDR_RANGE_TYPE:
+		return &HeaderRangeReq{}, nil
 	case common.HEADERS_TYPE:
 		return &BlkHeader{}, nil
 	case common.INV_TYPE:
@@ -54,7 +54,8 @@ func (self *Conn) Close() error {
 	} else if n.session == self.session { // connection not replaced
 		delete(self.netServer.Np.List, self.id)
 		// need handle asynchronously since we hold Np.Lock
-		log.Infof("remove peer %s from net server", self.id.ToHexString())
+		log.WithFields(log.Fields{log.FIELD_PEER_ID: self.id.ToHexString()}).Infof("remove peer %s from net server",
+			self.id.ToHexString())
 		go self.netServer.notifyPeerDisconnected(n.Peer.Info)
 	}
 
@@ -21,6 +21,7 @@ package netserver
 import (
 	"errors"
 	"net"
+	"path/filepath"
 	"time"
 
 	"github.com/ontio/ontology/common/config"
@@ -30,6 +31,8 @@ import (
 	"github.com/ontio/ontology/p2pserver/message/types"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
 	"github.com/ontio/ontology/p2pserver/peer"
+	"github.com/ontio/ontology/p2pserver/peer_score"
+	"github.com/ontio/ontology/p2pserver/rate_limit"
 )
 
 //NewNetServer return the net object in p2p
@@ -39,6 +42,7 @@ func NewNetServer(protocol p2p.Protocol, conf *config.P2PNodeConfig) (*NetServer
 		base:       &peer.PeerInfo{},
 		Np:         NewNbrPeers(),
 		protocol:   protocol,
+		scores:     peer_score.NewPeerScores(),
 		stopRecvCh: make(chan bool),
 	}
 
@@ -57,6 +61,7 @@ func NewCustomNetServer(id *common.PeerKeyId, info *peer.PeerInfo, proto p2p.Pro
 		protocol:   proto,
 		NetChan:    make(chan *types.MsgPayload, common.CHAN_CAPABILITY),
 		Np:         NewNbrPeers(),
+		scores:     peer_score.NewPeerScores(),
 		stopRecvCh: make(chan bool),
 	}
 	n.connCtrl = connect_controller.NewConnectController(info, id, opt)
@@ -73,6 +78,8 @@ type NetServer struct {
 	Np       *NbrPeers
 
 	connCtrl *connect_controller.ConnectController
+	scores   *peer_score.PeerScores
+	limits   *rate_limit.LimitConfig // rate limits of the messages from peers, no limit if nil
 
 	stopRecvCh chan bool // To stop sync channel
 }
@@ -86,7 +93,8 @@ func (this *NetServer) processMessage(channel chan *types.MsgPayload,
 			if ok {
 				sender := this.GetPeer(data.Id)
 				if sender == nil {
-					log.Warnf("[router] remote peer %s invalid.", data.Id.ToHexString())
+					log.WithFields(log.Fields{log.FIELD_PEER_ID: data.Id.ToHexString()}).Warnf(
+						"[router] remote peer %s invalid.", data.Id.ToHexString())
 					continue
 				}
 
@@ -110,7 +118,11 @@ func (this *NetServer) init(conf *config.P2PNodeConfig) error {
 		return errors.New("[p2p]invalid link port")
 	}
 
-	this.base = peer.NewPeerInfo(keyId.Id, common.PROTOCOL_VERSION, common.SERVICE_NODE, true, httpInfo,
+	services := uint64(common.SERVICE_NODE)
+	if config.DefConfig.Common.LightMode {
+		services = common.LIGHT_NODE
+	}
+	this.base = peer.NewPeerInfo(keyId.Id, common.PROTOCOL_VERSION, services, true, httpInfo,
 		nodePort, 0, config.Version, "")
 
 	option, err := connect_controller.ConnCtrlOptionFromConfig(conf)
@@ -118,6 +130,16 @@ func (this *NetServer) init(conf *config.P2PNodeConfig) error {
 		return err
 	}
 	this.connCtrl = connect_controller.NewConnectController(this.base, keyId, option)
+	this.limits, err = rate_limit.NewLimitConfig(conf)
+	if err != nil {
+		return err
+	}
+	// the ban list is kept along with the ledger of the network
+	banFile := filepath.Join(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName, common.BAN_FILE_NAME)
+	err = this.connCtrl.LoadBannedPeers(banFile)
+	if err != nil {
+		log.Warnf("[p2p]load %s fail:%s", banFile, err)
+	}
 
 	syncPort := this.base.Port
 	if syncPort == 0 {
@@ -229,6 +251,10 @@ func (this *NetServer) connect(addr string) error {
 	remotePeer := createPeer(peerInfo, conn)
 
 	remotePeer.AttachChan(this.NetChan)
+	remotePeer.Link.SetPenalizer(this.Penalize)
+	if this.limits != nil {
+		remotePeer.Link.SetRateLimiter(rate_limit.NewPeerLimiter(this.limits))
+	}
 	this.ReplacePeer(remotePeer)
 	go remotePeer.Link.Rx()
 
@@ -265,6 +291,10 @@ func (this *NetServer) handleClientConnection(conn net.Conn) error {
 	}
 	remotePeer := createPeer(peerInfo, conn)
 	remotePeer.AttachChan(this.NetChan)
+	remotePeer.Link.SetPenalizer(this.Penalize)
+	if this.limits != nil {
+		remotePeer.Link.SetRateLimiter(rate_limit.NewPeerLimiter(this.limits))
+	}
 	this.ReplacePeer(remotePeer)
 
 	go remotePeer.Link.Rx()
@@ -316,6 +346,53 @@ func (ns *NetServer) ConnectController() *connect_controller.ConnectController {
 	return ns.connCtrl
 }
 
+//BanPeer ban the ip for duration, or forever if duration is 0, and close the connections with it,
+//return the number of the peers disconnected
+func (this *NetServer) BanPeer(ip string, duration time.Duration) int {
+	this.connCtrl.BanPeer(ip, duration)
+	closed := 0
+	for _, p := range this.GetNeighbors() {
+		addrIp, _, err := net.SplitHostPort(p.Link.GetAddr())
+		if err != nil || addrIp != ip {
+			continue
+		}
+		id := p.GetID()
+		log.WithFields(log.Fields{log.FIELD_PEER_ID: id.ToHexString()}).Infof("[p2p]close banned peer %s",
+			p.Link.GetAddr())
+		p.Close()
+		closed++
+	}
+	return closed
+}
+
+//Penalize add the penalty of the misbehaviour to the score of the peer, and ban it temporarily
+//once the score reaches the threshold. The reserved peers are scored but never banned.
+func (this *NetServer) Penalize(id common.PeerId, m peer_score.Misbehaviour) {
+	p := this.GetPeer(id)
+	if p == nil {
+		return
+	}
+	addr := p.Link.GetAddr()
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	score, reached := this.scores.Penalize(ip, id, m)
+	logger := log.WithFields(log.Fields{log.FIELD_PEER_ID: id.ToHexString()})
+	logger.Debugf("[p2p]penalize peer %s for %s, score:%d", addr, m, score)
+	if !reached || this.connCtrl.IsReservedPeer(addr) {
+		return
+	}
+	logger.Warnf("[p2p]ban peer %s for %s, score %d reached %d", ip, peer_score.BAN_DURATION,
+		score, peer_score.BAN_THRESHOLD)
+	this.BanPeer(ip, peer_score.BAN_DURATION)
+}
+
+//GetPeerScores return the scores of the misbehaving peers
+func (this *NetServer) GetPeerScores() []peer_score.PeerScore {
+	return this.scores.GetScores()
+}
+
 func (ns *NetServer) Protocol() p2p.Protocol {
 	return ns.protocol
 }
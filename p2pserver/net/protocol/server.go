@@ -23,6 +23,7 @@ import (
 	"github.com/ontio/ontology/p2pserver/common"
 	"github.com/ontio/ontology/p2pserver/message/types"
 	"github.com/ontio/ontology/p2pserver/peer"
+	"github.com/ontio/ontology/p2pserver/peer_score"
 )
 
 //P2P represent the net interface of p2p package
@@ -41,4 +42,5 @@ type P2P interface {
 	GetOutConnRecordLen() uint
 	Broadcast(msg types.Message)
 	IsOwnAddress(addr string) bool
+	Penalize(id common.PeerId, m peer_score.Misbehaviour)
 }
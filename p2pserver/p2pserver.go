@@ -25,6 +25,7 @@ import (
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/p2pserver/common"
 	"github.com/ontio/ontology/p2pserver/net/netserver"
 	p2pnet "github.com/ontio/ontology/p2pserver/net/protocol"
@@ -33,7 +34,8 @@ import (
 
 //P2PServer control all network activities
 type P2PServer struct {
-	network *netserver.NetServer
+	network  *netserver.NetServer
+	protocol *protocols.MsgHandler
 }
 
 //NewServer return a new p2pserver according to the pubkey
@@ -47,7 +49,8 @@ func NewServer() (*P2PServer, error) {
 	}
 
 	p := &P2PServer{
-		network: n,
+		network:  n,
+		protocol: protocol,
 	}
 
 	return p, nil
@@ -68,6 +71,12 @@ func (this *P2PServer) GetNetwork() p2pnet.P2P {
 	return this.network
 }
 
+//FetchBlock request the block at the height from the full nodes and save it to ledger. It's used by the light node,
+//which only syncs the headers.
+func (this *P2PServer) FetchBlock(height uint32) (*types.Block, error) {
+	return this.protocol.FetchBlock(height)
+}
+
 //WaitForPeersStart check whether enough peer linked in loop
 func (this *P2PServer) WaitForPeersStart() {
 	periodTime := config.DEFAULT_GEN_BLOCK_TIME / common.UPDATE_RATE_PER_BLOCK
@@ -29,6 +29,7 @@ import (
 
 	comm "github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/p2pserver/common"
 	conn "github.com/ontio/ontology/p2pserver/link"
 	"github.com/ontio/ontology/p2pserver/message/types"
@@ -137,7 +138,11 @@ func (this *Peer) GetPort() uint16 {
 //SendTo call sync link to send buffer
 func (this *Peer) SendRaw(msgType string, msgPayload []byte) error {
 	if this.Link != nil && this.Link.Valid() {
-		return this.Link.SendRaw(msgPayload)
+		err := this.Link.SendRaw(msgPayload)
+		if err == nil {
+			metrics.P2PMessage(metrics.P2P_SEND, msgType, len(msgPayload))
+		}
+		return err
 	}
 	return errors.New("[p2p]sync link invalid")
 }
@@ -0,0 +1,223 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package peer_score
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/p2pserver/common"
+)
+
+const (
+	BAN_THRESHOLD   = 100         //Score at which a peer is banned
+	BAN_DURATION    = time.Hour   //How long a peer crossing the threshold is banned
+	DECAY_INTERVAL  = time.Minute //Scores decay by one point per interval
+	MAX_PEER_SCORES = 1024        //Max number of peers tracked at the same time
+)
+
+//Misbehaviour is a kind of peer misbehaviour which is penalized
+type Misbehaviour uint8
+
+const (
+	INVALID_BLOCK Misbehaviour = iota //Sent a header or block rejected by the ledger
+	INVALID_TX                        //Sent a transaction with a bad signature or payload
+	MALFORMED_MSG                     //Sent a message which can not be decoded
+	TIMEOUT                           //Did not answer a sync request in time
+	SPAM                              //Repeated a request within the request interval
+)
+
+var penalties = map[Misbehaviour]int64{
+	INVALID_BLOCK: 50,
+	INVALID_TX:    10,
+	MALFORMED_MSG: 25,
+	TIMEOUT:       1,
+	SPAM:          5,
+}
+
+var misbehaviourNames = map[Misbehaviour]string{
+	INVALID_BLOCK: "invalid_block",
+	INVALID_TX:    "invalid_tx",
+	MALFORMED_MSG: "malformed_msg",
+	TIMEOUT:       "timeout",
+	SPAM:          "spam",
+}
+
+func (self Misbehaviour) String() string {
+	if name, ok := misbehaviourNames[self]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+//Penalty return the score added for the misbehaviour
+func (self Misbehaviour) Penalty() int64 {
+	return penalties[self]
+}
+
+//PeerScore is the snapshot of the score of a peer ip
+type PeerScore struct {
+	Ip            string            `json:"ip"`
+	LastPeerId    string            `json:"last_peer_id"`
+	Score         int64             `json:"score"`
+	Misbehaviours map[string]uint32 `json:"misbehaviours"`
+	LastUpdate    int64             `json:"last_update"`
+}
+
+type peerScore struct {
+	lastPeerId    common.PeerId
+	score         int64
+	misbehaviours map[Misbehaviour]uint32
+	updated       time.Time
+}
+
+//decay drop the points accumulated before now, keeping the remainder of the interval
+func (self *peerScore) decay(now time.Time) {
+	elapsed := int64(now.Sub(self.updated) / DECAY_INTERVAL)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed >= self.score {
+		self.score = 0
+		self.updated = now
+		return
+	}
+	self.score -= elapsed
+	self.updated = self.updated.Add(time.Duration(elapsed) * DECAY_INTERVAL)
+}
+
+//PeerScores keeps the decaying misbehaviour score of the remote peers by ip, so that
+//a peer can not reset its score by reconnecting with another peer id
+type PeerScores struct {
+	lock   sync.Mutex
+	scores map[string]*peerScore
+	now    func() time.Time
+}
+
+func NewPeerScores() *PeerScores {
+	return &PeerScores{
+		scores: make(map[string]*peerScore),
+		now:    time.Now,
+	}
+}
+
+//Penalize add the penalty of the misbehaviour to the score of ip, return the new score and
+//whether the ban threshold is reached. The score is reset once the threshold is reached.
+func (self *PeerScores) Penalize(ip string, id common.PeerId, m Misbehaviour) (int64, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	now := self.now()
+	ps, ok := self.scores[ip]
+	if !ok {
+		if len(self.scores) >= MAX_PEER_SCORES {
+			self.evict(now)
+		}
+		ps = &peerScore{misbehaviours: make(map[Misbehaviour]uint32), updated: now}
+		self.scores[ip] = ps
+	}
+	ps.decay(now)
+	ps.lastPeerId = id
+	ps.score += m.Penalty()
+	ps.misbehaviours[m] += 1
+
+	score := ps.score
+	if score >= BAN_THRESHOLD {
+		delete(self.scores, ip)
+		return score, true
+	}
+	return score, false
+}
+
+//evict remove the decayed scores, and the lowest one if still full
+func (self *PeerScores) evict(now time.Time) {
+	var lowestIp string
+	var lowest *peerScore
+	for ip, ps := range self.scores {
+		ps.decay(now)
+		if ps.score == 0 {
+			delete(self.scores, ip)
+			continue
+		}
+		if lowest == nil || ps.score < lowest.score {
+			lowestIp, lowest = ip, ps
+		}
+	}
+	if len(self.scores) >= MAX_PEER_SCORES && lowest != nil {
+		delete(self.scores, lowestIp)
+	}
+}
+
+//GetScore return the current score of ip
+func (self *PeerScores) GetScore(ip string) int64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	ps, ok := self.scores[ip]
+	if !ok {
+		return 0
+	}
+	ps.decay(self.now())
+	return ps.score
+}
+
+//GetScores return the non zero scores ordered by score descending
+func (self *PeerScores) GetScores() []PeerScore {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	now := self.now()
+	scores := make([]PeerScore, 0, len(self.scores))
+	for ip, ps := range self.scores {
+		ps.decay(now)
+		if ps.score == 0 {
+			delete(self.scores, ip)
+			continue
+		}
+		misbehaviours := make(map[string]uint32, len(ps.misbehaviours))
+		for m, cnt := range ps.misbehaviours {
+			misbehaviours[m.String()] = cnt
+		}
+		scores = append(scores, PeerScore{
+			Ip:            ip,
+			LastPeerId:    ps.lastPeerId.ToHexString(),
+			Score:         ps.score,
+			Misbehaviours: misbehaviours,
+			LastUpdate:    ps.updated.Unix(),
+		})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Ip < scores[j].Ip
+	})
+	return scores
+}
+
+//Reset clear the score of ip, return false if it has no score
+func (self *PeerScores) Reset(ip string) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.scores[ip]; !ok {
+		return false
+	}
+	delete(self.scores, ip)
+	return true
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package peer_score
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScores() (*PeerScores, *time.Time) {
+	now := time.Unix(1600000000, 0)
+	scores := NewPeerScores()
+	scores.now = func() time.Time { return now }
+	return scores, &now
+}
+
+func TestPenalize(t *testing.T) {
+	a := assert.New(t)
+	scores, _ := newTestScores()
+	id := common.PseudoPeerIdFromUint64(1)
+
+	score, banned := scores.Penalize("192.168.1.1", id, INVALID_BLOCK)
+	a.Equal(int64(50), score)
+	a.False(banned)
+	score, banned = scores.Penalize("192.168.1.1", id, MALFORMED_MSG)
+	a.Equal(int64(75), score)
+	a.False(banned)
+
+	list := scores.GetScores()
+	a.Equal(1, len(list))
+	a.Equal(map[string]uint32{"invalid_block": 1, "malformed_msg": 1}, list[0].Misbehaviours)
+
+	score, banned = scores.Penalize("192.168.1.1", id, INVALID_BLOCK)
+	a.Equal(int64(125), score)
+	a.True(banned)
+	a.Equal(int64(0), scores.GetScore("192.168.1.1"), "score is reset once banned")
+}
+
+func TestScoreDecay(t *testing.T) {
+	a := assert.New(t)
+	scores, now := newTestScores()
+	id := common.PseudoPeerIdFromUint64(1)
+
+	scores.Penalize("192.168.1.1", id, INVALID_TX)
+	*now = now.Add(90 * time.Second)
+	a.Equal(int64(9), scores.GetScore("192.168.1.1"))
+	*now = now.Add(30 * time.Second)
+	a.Equal(int64(8), scores.GetScore("192.168.1.1"), "the remainder of the interval is kept")
+	*now = now.Add(time.Hour)
+	a.Equal(int64(0), scores.GetScore("192.168.1.1"))
+	a.Equal(0, len(scores.GetScores()))
+}
+
+func TestScoresBounded(t *testing.T) {
+	a := assert.New(t)
+	scores, _ := newTestScores()
+	id := common.PseudoPeerIdFromUint64(1)
+
+	for i := 0; i < MAX_PEER_SCORES; i++ {
+		scores.Penalize(fmt.Sprintf("10.0.%d.%d", i/256, i%256), id, SPAM)
+	}
+	scores.Penalize("10.0.0.0", id, SPAM)
+	scores.Penalize("192.168.1.1", id, TIMEOUT)
+	list := scores.GetScores()
+	a.Equal(MAX_PEER_SCORES, len(list))
+	a.Equal("10.0.0.0", list[0].Ip)
+	a.Equal(int64(1), scores.GetScore("192.168.1.1"))
+}
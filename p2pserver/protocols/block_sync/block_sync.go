@@ -19,37 +19,85 @@
 package block_sync
 
 import (
+	"fmt"
 	"math"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/blang/semver"
 	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
-	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/store"
 	"github.com/ontio/ontology/core/types"
 	p2pComm "github.com/ontio/ontology/p2pserver/common"
 	msgpack "github.com/ontio/ontology/p2pserver/message/msg_pack"
+	msgTypes "github.com/ontio/ontology/p2pserver/message/types"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
 	"github.com/ontio/ontology/p2pserver/peer"
+	"github.com/ontio/ontology/p2pserver/peer_score"
 )
 
 const (
-	SYNC_MAX_HEADER_FORWARD_SIZE = 5000       //keep CurrentHeaderHeight - CurrentBlockHeight <= SYNC_MAX_HEADER_FORWARD_SIZE
-	SYNC_MAX_FLIGHT_HEADER_SIZE  = 1          //Number of headers on flight
-	SYNC_MAX_FLIGHT_BLOCK_SIZE   = 50         //Number of blocks on flight
-	SYNC_MAX_BLOCK_CACHE_SIZE    = 500        //Cache size of block wait to commit to ledger
-	SYNC_HEADER_REQUEST_TIMEOUT  = 2          //s, Request header timeout time. If header haven't receive after SYNC_HEADER_REQUEST_TIMEOUT second, retry
-	SYNC_BLOCK_REQUEST_TIMEOUT   = 2          //s, Request block timeout time. If block haven't received after SYNC_BLOCK_REQUEST_TIMEOUT second, retry
-	SYNC_NEXT_BLOCK_TIMES        = 3          //Request times of next height block
-	SYNC_NEXT_BLOCKS_HEIGHT      = 2          //for current block height plus next
-	SYNC_NODE_RECORD_SPEED_CNT   = 3          //Record speed count for accuracy
-	SYNC_NODE_RECORD_TIME_CNT    = 3          //Record request time  for accuracy
-	SYNC_NODE_SPEED_INIT         = 100 * 1024 //Init a big speed (100MB/s) for every node in first round
-	SYNC_MAX_ERROR_RESP_TIMES    = 5          //Max error headers/blocks response times, if reaches, delete it
-	SYNC_MAX_HEIGHT_OFFSET       = 5          //Offset of the max height and current height
+	SYNC_HEADER_REQUEST_TIMEOUT = 2          //s, Request header timeout time. If header haven't receive after SYNC_HEADER_REQUEST_TIMEOUT second, retry
+	SYNC_BLOCK_REQUEST_TIMEOUT  = 2          //s, Request block timeout time. If block haven't received after SYNC_BLOCK_REQUEST_TIMEOUT second, retry
+	SYNC_NEXT_BLOCK_TIMES       = 3          //Request times of next height block
+	SYNC_NEXT_BLOCKS_HEIGHT     = 2          //for current block height plus next
+	SYNC_NODE_RECORD_SPEED_CNT  = 3          //Record speed count for accuracy
+	SYNC_NODE_RECORD_TIME_CNT   = 3          //Record request time  for accuracy
+	SYNC_NODE_SPEED_INIT        = 100 * 1024 //Init a big speed (100MB/s) for every node in first round
+	SYNC_MAX_ERROR_RESP_TIMES   = 5          //Max error headers/blocks response times, if reaches, delete it
+	SYNC_MAX_HEIGHT_OFFSET      = 5          //Offset of the max height and current height
+	SYNC_FETCH_BLOCK_TIMEOUT    = 5          //s, Timeout of fetching a block from a node, another node is tried after it
+	SYNC_FETCH_BLOCK_TIMES      = 3          //Max nodes tried to fetch a block
 )
 
+//SyncConfig is the limits of the sync pipeline
+type SyncConfig struct {
+	MaxFlightHeaders int    //Number of header ranges on flight
+	MaxFlightBlocks  int    //Number of blocks on flight
+	MaxHeaderForward uint32 //keep CurrentHeaderHeight - CurrentBlockHeight <= MaxHeaderForward
+	MaxBlockCache    int    //Cache size of block wait to commit to ledger
+}
+
+//NewSyncConfig return the limits set in the p2p config, the default value is used if a limit is not set
+func NewSyncConfig(cfg *config.P2PNodeConfig) *SyncConfig {
+	syncCfg := &SyncConfig{
+		MaxFlightHeaders: config.DEFAULT_SYNC_MAX_FLIGHT_HEADERS,
+		MaxFlightBlocks:  config.DEFAULT_SYNC_MAX_FLIGHT_BLOCKS,
+		MaxHeaderForward: config.DEFAULT_SYNC_MAX_HEADER_FORWARD,
+		MaxBlockCache:    config.DEFAULT_SYNC_MAX_BLOCK_CACHE,
+	}
+	if cfg == nil {
+		return syncCfg
+	}
+	if cfg.SyncMaxFlightHeaders != 0 {
+		syncCfg.MaxFlightHeaders = int(cfg.SyncMaxFlightHeaders)
+	}
+	if cfg.SyncMaxFlightBlocks != 0 {
+		syncCfg.MaxFlightBlocks = int(cfg.SyncMaxFlightBlocks)
+	}
+	if cfg.SyncMaxHeaderForward != 0 {
+		syncCfg.MaxHeaderForward = cfg.SyncMaxHeaderForward
+	}
+	if cfg.SyncMaxBlockCache != 0 {
+		syncCfg.MaxBlockCache = int(cfg.SyncMaxBlockCache)
+	}
+	return syncCfg
+}
+
+//Ledger is the chain the headers and blocks are synced to
+type Ledger interface {
+	GetCurrentBlockHeight() uint32
+	GetCurrentHeaderHeight() uint32
+	GetCurrentHeaderHash() common.Uint256
+	GetBlockHash(height uint32) common.Uint256
+	GetHeaderByHeight(height uint32) (*types.Header, error)
+	AddHeaders(headers []*types.Header) error
+	AddBlock(block *types.Block, ccMsg *types.CrossChainMsg, stateMerkleRoot common.Uint256) error
+}
+
 //NodeWeight record some params of node, using for sort
 type NodeWeight struct {
 	id           p2pComm.PeerId //NodeID
@@ -143,6 +191,7 @@ func (nws NodeWeights) Less(i, j int) bool {
 //SyncFlightInfo record the info of fight object(header or block)
 type SyncFlightInfo struct {
 	Height      uint32                 //BlockHeight of HeaderHeight
+	count       uint32                 //The number of headers requested, only for header flights
 	nodeId      p2pComm.PeerId         //The current node to send msg
 	startTime   time.Time              //Request start time
 	failedNodes map[p2pComm.PeerId]int //Map nodeId => timeout times
@@ -222,31 +271,48 @@ type BlockInfo struct {
 	merkleRoot    common.Uint256
 }
 
-//BlockSyncMgr is the manager class to deal with block sync
+//HeaderRange is the headers received from a node, waiting for the headers before it to be added to ledger
+type HeaderRange struct {
+	nodeID  p2pComm.PeerId
+	headers []*types.Header
+}
+
+//BlockSyncMgr is the manager class to deal with block sync. Headers are requested in ranges from
+//several nodes at the same time within the header forward window, and committed to ledger in order.
+//Blocks are requested as soon as their headers are added, and executed while the later ones are on flight.
 type BlockSyncMgr struct {
-	flightBlocks   map[common.Uint256][]*SyncFlightInfo //Map BlockHash => []SyncFlightInfo, using for manager all of those block flights
-	flightHeaders  map[uint32]*SyncFlightInfo           //Map HeaderHeight => SyncFlightInfo, using for manager all of those header flights
-	blocksCache    *BlockCache                          //Map BlockHash => BlockInfo, using for cache the blocks receive from net, and waiting for commit to ledger
-	server         p2p.P2P                              //Pointer to the local node
-	syncBlockLock  bool                                 //Help to avoid send block sync request duplicate
-	syncHeaderLock bool                                 //Help to avoid send header sync request duplicate
-	saveBlockLock  bool                                 //Help to avoid saving block concurrently
-	exitCh         chan interface{}                     //ExitCh to receive exit signal
-	ledger         *ledger.Ledger                       //ledger
-	lock           sync.RWMutex                         //lock
-	nodeWeights    map[p2pComm.PeerId]*NodeWeight       //Map NodeID => NodeStatus, using for getNextNode
+	flightBlocks   map[common.Uint256][]*SyncFlightInfo   //Map BlockHash => []SyncFlightInfo, using for manager all of those block flights
+	flightHeaders  map[uint32]*SyncFlightInfo             //Map HeaderHeight => SyncFlightInfo, using for manager all of those header flights
+	headersCache   map[uint32]*HeaderRange                //Map HeaderHeight => HeaderRange, using for cache the headers received out of order
+	blocksCache    *BlockCache                            //Map BlockHash => BlockInfo, using for cache the blocks receive from net, and waiting for commit to ledger
+	server         p2p.P2P                                //Pointer to the local node
+	syncBlockLock  bool                                   //Help to avoid send block sync request duplicate
+	syncHeaderLock bool                                   //Help to avoid send header sync request duplicate
+	saveBlockLock  bool                                   //Help to avoid saving block concurrently
+	saveHeaderLock bool                                   //Help to avoid saving header concurrently
+	syncCh         chan struct{}                          //SyncCh to request more headers and blocks once the ledger moves forward
+	exitCh         chan interface{}                       //ExitCh to receive exit signal
+	ledger         Ledger                                 //ledger
+	config         *SyncConfig                            //The limits of the sync pipeline
+	lock           sync.RWMutex                           //lock
+	nodeWeights    map[p2pComm.PeerId]*NodeWeight         //Map NodeID => NodeStatus, using for getNextNode
+	fetchers       map[common.Uint256][]chan *types.Block //Map BlockHash => the channels waiting for the fetched block
 }
 
 //NewBlockSyncMgr return a BlockSyncMgr instance
-func NewBlockSyncMgr(server p2p.P2P, ld *ledger.Ledger) *BlockSyncMgr {
+func NewBlockSyncMgr(server p2p.P2P, ld Ledger) *BlockSyncMgr {
 	return &BlockSyncMgr{
 		flightBlocks:  make(map[common.Uint256][]*SyncFlightInfo),
 		flightHeaders: make(map[uint32]*SyncFlightInfo),
+		headersCache:  make(map[uint32]*HeaderRange),
 		blocksCache:   NewBlockCache(),
 		server:        server,
 		ledger:        ld,
+		config:        NewSyncConfig(config.DefConfig.P2PNode),
+		syncCh:        make(chan struct{}, 1),
 		exitCh:        make(chan interface{}, 1),
 		nodeWeights:   make(map[p2pComm.PeerId]*NodeWeight),
+		fetchers:      make(map[common.Uint256][]chan *types.Block),
 	}
 }
 
@@ -325,23 +391,35 @@ func (this *BlockSyncMgr) getNonEmptyBlockCount() int {
 	return this.blocksCache.getNonEmptyBlockCount()
 }
 
-//Start to sync
+//Start to sync. The requests are sent in the sync loop only, so that a notification is never missed
+//while another goroutine is sending the requests.
 func (this *BlockSyncMgr) Start() {
-	go this.sync()
+	this.notifySync()
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-this.exitCh:
 			return
+		case <-this.syncCh:
+			this.sync()
 		case <-ticker.C:
 			go this.checkTimeout()
-			go this.sync()
+			go this.saveHeaders()
 			go this.saveBlock()
+			this.notifySync()
 		}
 	}
 }
 
+//notifySync request more headers and blocks in the sync loop, without blocking the caller
+func (this *BlockSyncMgr) notifySync() {
+	select {
+	case this.syncCh <- struct{}{}:
+	default:
+	}
+}
+
 func (this *BlockSyncMgr) checkTimeout() {
 	now := time.Now()
 	headerTimeoutFlights := make(map[uint32]*SyncFlightInfo)
@@ -373,19 +451,17 @@ func (this *BlockSyncMgr) checkTimeout() {
 		flightInfo.ResetStartTime()
 		flightInfo.MarkFailedNode()
 		log.Tracef("[block-sync] checkTimeout sync headers from id:%d :%d timeout after:%d s Times:%d", flightInfo.GetNodeId(), height, SYNC_HEADER_REQUEST_TIMEOUT, flightInfo.GetTotalFailedTimes())
-		reqNode := this.getNodeWithMinFailedTimes(flightInfo, curBlockHeight)
+		reqNode := this.getHeaderNodeWithMinFailedTimes(flightInfo, curHeaderHeight)
 		if reqNode == nil {
-			break
+			//request the range again once a node is able to serve it
+			this.delFlightHeader(height)
+			continue
 		}
 		flightInfo.SetNodeId(reqNode.GetID())
 
-		headerHash := this.ledger.GetCurrentHeaderHash()
-		msg := msgpack.NewHeadersReq(headerHash)
-		err := this.server.Send(reqNode, msg)
+		err := this.sendHeadersReq(reqNode, height, flightInfo.count)
 		if err != nil {
 			log.Warn("[block-sync] checkTimeout failed to send a new headersReq:s", err)
-		} else {
-			this.appendReqTime(reqNode.GetID())
 		}
 	}
 	for blockHash, flightInfos := range blockTimeoutFlights {
@@ -430,33 +506,52 @@ func (this *BlockSyncMgr) syncHeader() {
 	}
 	defer this.releaseSyncHeaderLock()
 
-	if this.getFlightHeaderCount() >= SYNC_MAX_FLIGHT_HEADER_SIZE {
-		return
-	}
 	curBlockHeight := this.ledger.GetCurrentBlockHeight()
-
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
 	//Waiting for block catch up header
-	if curHeaderHeight-curBlockHeight >= SYNC_MAX_HEADER_FORWARD_SIZE {
-		return
-	}
-	NextHeaderId := curHeaderHeight + 1
-	reqNode := this.getNextNode(NextHeaderId)
-	if reqNode == nil {
-		return
+	maxHeaderHeight := curBlockHeight + this.config.MaxHeaderForward
+	for this.getFlightHeaderCount() < this.config.MaxFlightHeaders {
+		start := this.getNextHeaderHeight(curHeaderHeight)
+		if start > maxHeaderHeight {
+			return
+		}
+		reqNode := this.getNextHeaderNode(start, curHeaderHeight)
+		if reqNode == nil {
+			return
+		}
+		count := maxHeaderHeight - start + 1
+		if count > p2pComm.MAX_BLK_HDR_CNT {
+			count = p2pComm.MAX_BLK_HDR_CNT
+		}
+		if nodeHeight := uint32(reqNode.GetHeight()); nodeHeight-start+1 < count {
+			count = nodeHeight - start + 1
+		}
+		this.addFlightHeader(reqNode.GetID(), start, count)
+
+		err := this.sendHeadersReq(reqNode, start, count)
+		if err != nil {
+			log.Warn("[block-sync] syncHeader failed to send a new headersReq")
+			return
+		}
+		log.Infof("Header sync request height:%d - %d", start, start+count-1)
 	}
-	this.addFlightHeader(reqNode.GetID(), NextHeaderId)
+}
 
-	headerHash := this.ledger.GetCurrentHeaderHash()
-	msg := msgpack.NewHeadersReq(headerHash)
+//sendHeadersReq request the headers from the start height. The node not supporting range request
+//is asked for the headers following the current header.
+func (this *BlockSyncMgr) sendHeadersReq(reqNode *peer.Peer, start uint32, count uint32) error {
+	var msg msgTypes.Message
+	if supportHeaderRange(reqNode) {
+		msg = msgpack.NewHeaderRangeReq(start, count)
+	} else {
+		msg = msgpack.NewHeadersReq(this.ledger.GetCurrentHeaderHash())
+	}
 	err := this.server.Send(reqNode, msg)
 	if err != nil {
-		log.Warn("[block-sync] syncHeader failed to send a new headersReq")
-	} else {
-		this.appendReqTime(reqNode.GetID())
+		return err
 	}
-
-	log.Infof("Header sync request height:%d", NextHeaderId)
+	this.appendReqTime(reqNode.GetID())
+	return nil
 }
 
 func (this *BlockSyncMgr) syncBlock() {
@@ -465,7 +560,7 @@ func (this *BlockSyncMgr) syncBlock() {
 	}
 	defer this.releaseSyncBlockLock()
 
-	availCount := SYNC_MAX_FLIGHT_BLOCK_SIZE - this.getFlightBlockCount()
+	availCount := this.config.MaxFlightBlocks - this.getFlightBlockCount()
 	if availCount <= 0 {
 		return
 	}
@@ -478,7 +573,7 @@ func (this *BlockSyncMgr) syncBlock() {
 	if count > availCount {
 		count = availCount
 	}
-	cacheCap := SYNC_MAX_BLOCK_CACHE_SIZE - this.getNonEmptyBlockCount()
+	cacheCap := this.config.MaxBlockCache - this.getNonEmptyBlockCount()
 	if count > cacheCap {
 		count = cacheCap
 	}
@@ -496,10 +591,11 @@ func (this *BlockSyncMgr) syncBlock() {
 		if nextBlockHash == common.UINT256_EMPTY {
 			return
 		}
-		if this.isBlockOnFlight(nextBlockHash) {
-			if nextBlockHeight <= curBlockHeight+SYNC_NEXT_BLOCKS_HEIGHT {
+		flightCount := len(this.getFlightBlocks(nextBlockHash))
+		if flightCount != 0 {
+			if nextBlockHeight <= curBlockHeight+SYNC_NEXT_BLOCKS_HEIGHT && flightCount < SYNC_NEXT_BLOCK_TIMES {
 				//request more nodes for next block height
-				reqTimes = SYNC_NEXT_BLOCK_TIMES
+				reqTimes = SYNC_NEXT_BLOCK_TIMES - flightCount
 			} else {
 				continue
 			}
@@ -507,7 +603,11 @@ func (this *BlockSyncMgr) syncBlock() {
 		if this.isInBlockCache(nextBlockHeight) {
 			continue
 		}
-		if nextBlockHeight <= curBlockHeight+SYNC_NEXT_BLOCKS_HEIGHT {
+		//the block may be saved after the sync began
+		if nextBlockHeight <= this.ledger.GetCurrentBlockHeight() {
+			continue
+		}
+		if flightCount == 0 && nextBlockHeight <= curBlockHeight+SYNC_NEXT_BLOCKS_HEIGHT {
 			reqTimes = SYNC_NEXT_BLOCK_TIMES
 		}
 		for t := 0; t < reqTimes; t++ {
@@ -535,6 +635,9 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID p2pComm.PeerId, headers []*type
 	if len(headers) == 0 {
 		return
 	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Height < headers[j].Height
+	})
 	log.Infof("Header receive height:%d - %d", headers[0].Height, headers[len(headers)-1].Height)
 	height := headers[0].Height
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
@@ -543,43 +646,70 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID p2pComm.PeerId, headers []*type
 	if height <= curHeaderHeight {
 		return
 	}
-	if !this.isHeaderOnFlight(height) {
+	if !this.delFlightHeader(height) {
 		return
 	}
-	err := this.ledger.AddHeaders(headers)
-	this.delFlightHeader(height)
-	if err != nil {
-		this.addErrorRespCnt(fromID)
-		n := this.getNodeWeight(fromID)
-		if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
-			this.delNode(fromID)
+	for i := 1; i < len(headers); i++ {
+		if headers[i].Height != headers[i-1].Height+1 || headers[i].PrevBlockHash != headers[i-1].Hash() {
+			this.onErrorResp(fromID, &store.InvalidBlockError{Msg: "discontinuous headers"})
+			log.Warnf("[block-sync] OnHeaderReceive discontinuous headers at height:%d", headers[i].Height)
+			return
 		}
-		log.Warnf("[block-sync] OnHeaderReceive AddHeaders error:%s", err)
-		return
 	}
-	sort.Slice(headers, func(i, j int) bool {
-		return headers[i].Height < headers[j].Height
-	})
-	curHeaderHeight = this.ledger.GetCurrentHeaderHeight()
+	this.addHeadersCache(fromID, headers)
+	this.saveHeaders()
+	this.notifySync()
+}
+
+//saveHeaders add the cached headers to ledger in order
+func (this *BlockSyncMgr) saveHeaders() {
+	for {
+		if this.tryGetSaveHeaderLock() {
+			return
+		}
+		this.saveHeadersLocked()
+		this.releaseSaveHeaderLock()
+		//the headers may be cached after the last check by another goroutine
+		if !this.hasNextHeaders(this.ledger.GetCurrentHeaderHeight()) {
+			return
+		}
+	}
+}
+
+func (this *BlockSyncMgr) saveHeadersLocked() {
+	for {
+		curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
+		fromID, headers := this.popHeadersCache(curHeaderHeight)
+		if len(headers) == 0 {
+			return
+		}
+		err := this.ledger.AddHeaders(headers)
+		if err != nil {
+			this.onErrorResp(fromID, err)
+			log.Warnf("[block-sync] saveHeaders AddHeaders error:%s", err)
+			return
+		}
+		this.addEmptyBlocks(fromID, headers)
+		go this.saveBlock()
+		this.notifySync()
+	}
+}
+
+//addEmptyBlocks add the empty blocks to block cache directly, without requesting them
+func (this *BlockSyncMgr) addEmptyBlocks(fromID p2pComm.PeerId, headers []*types.Header) {
 	curBlockHeight := this.ledger.GetCurrentBlockHeight()
 	for _, header := range headers {
 		prevHeader, err := this.ledger.GetHeaderByHeight(header.Height - 1)
 		if err != nil {
-			log.Debugf("[block-sync] OnHeaderReceive GetHeaderByHeight error:%s", err)
+			log.Debugf("[block-sync] addEmptyBlocks GetHeaderByHeight error:%s", err)
 			continue
 		}
-		log.Debugf("[block-sync] OnHeaderReceive GetHeaderByHeight height:%d, prevHeader transaction root:%+v", header.Height-1, prevHeader.TransactionsRoot)
+		log.Debugf("[block-sync] addEmptyBlocks GetHeaderByHeight height:%d, prevHeader transaction root:%+v", header.Height-1, prevHeader.TransactionsRoot)
 		//handle empty block
 		if header.TransactionsRoot == common.UINT256_EMPTY && prevHeader.TransactionsRoot == common.UINT256_EMPTY {
-			log.Trace("[block-sync] OnHeaderReceive empty block Height:%d", header.Height)
-			height := header.Height
-			blockHash := header.Hash()
-			this.delFlightBlock(blockHash)
-			nextHeader := curHeaderHeight + 1
-			if height > nextHeader {
-				break
-			}
-			if height <= curBlockHeight {
+			log.Trace("[block-sync] addEmptyBlocks empty block Height:%d", header.Height)
+			this.delFlightBlock(header.Hash())
+			if header.Height <= curBlockHeight {
 				continue
 			}
 			block := &types.Block{
@@ -588,8 +718,19 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID p2pComm.PeerId, headers []*type
 			this.addBlockCache(fromID, block, nil, common.UINT256_EMPTY)
 		}
 	}
-	go this.saveBlock()
-	this.syncHeader()
+}
+
+//onErrorResp count the error response of a node, and remove it if too many. The node is penalized only if the
+//headers or blocks it sent are invalid, not for the local failures of ledger.
+func (this *BlockSyncMgr) onErrorResp(nodeId p2pComm.PeerId, err error) {
+	if store.IsInvalidBlock(err) {
+		this.server.Penalize(nodeId, peer_score.INVALID_BLOCK)
+	}
+	this.addErrorRespCnt(nodeId)
+	n := this.getNodeWeight(nodeId)
+	if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
+		this.delNode(nodeId)
+	}
 }
 
 // OnBlockReceive receive block from net
@@ -598,6 +739,9 @@ func (this *BlockSyncMgr) OnBlockReceive(fromID p2pComm.PeerId, blockSize uint32
 	height := block.Header.Height
 	blockHash := block.Hash()
 	log.Tracef("[block-sync] OnBlockReceive Height:%d", height)
+	if this.deliverFetchedBlock(blockHash, block) {
+		return
+	}
 	flightInfo := this.getFlightBlock(blockHash, fromID)
 	if flightInfo != nil {
 		t := (time.Now().UnixNano() - flightInfo.GetStartTime().UnixNano()) / int64(time.Millisecond)
@@ -605,7 +749,8 @@ func (this *BlockSyncMgr) OnBlockReceive(fromID p2pComm.PeerId, blockSize uint32
 		this.addNewSpeed(fromID, s)
 	}
 
-	this.delFlightBlock(blockHash)
+	//the block is cached before the flight is deleted, otherwise it may be requested again in between
+	defer this.delFlightBlock(blockHash)
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
 	nextHeader := curHeaderHeight + 1
 	if height > nextHeader {
@@ -618,7 +763,7 @@ func (this *BlockSyncMgr) OnBlockReceive(fromID p2pComm.PeerId, blockSize uint32
 
 	this.addBlockCache(fromID, block, ccMsg, merkleRoot)
 	go this.saveBlock()
-	this.syncBlock()
+	this.notifySync()
 }
 
 //OnAddPeer to node list when a new node added
@@ -628,6 +773,7 @@ func (this *BlockSyncMgr) OnAddNode(nodeId p2pComm.PeerId) {
 	defer this.lock.Unlock()
 	w := NewNodeWeight(nodeId)
 	this.nodeWeights[nodeId] = w
+	this.notifySync()
 }
 
 //OnDelNode remove from node list. When the node disconnect
@@ -715,10 +861,20 @@ func (this *BlockSyncMgr) releaseSaveBlockLock() {
 }
 
 func (this *BlockSyncMgr) saveBlock() {
-	if this.tryGetSaveBlockLock() {
-		return
+	for {
+		if this.tryGetSaveBlockLock() {
+			return
+		}
+		this.saveBlockLocked()
+		this.releaseSaveBlockLock()
+		//the block may be cached after the last check by another goroutine
+		if !this.isInBlockCache(this.ledger.GetCurrentBlockHeight() + 1) {
+			return
+		}
 	}
-	defer this.releaseSaveBlockLock()
+}
+
+func (this *BlockSyncMgr) saveBlockLocked() {
 	curBlockHeight := this.ledger.GetCurrentBlockHeight()
 	nextBlockHeight := curBlockHeight + 1
 	this.clearBlocks(curBlockHeight)
@@ -730,11 +886,7 @@ func (this *BlockSyncMgr) saveBlock() {
 		err := this.ledger.AddBlock(nextBlock, ccMsg, merkleRoot)
 		this.delBlockCache(nextBlockHeight)
 		if err != nil {
-			this.addErrorRespCnt(fromID)
-			n := this.getNodeWeight(fromID)
-			if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
-				this.delNode(fromID)
-			}
+			this.onErrorResp(fromID, err)
 			log.Warnf("[block-sync] saveBlock Height:%d AddBlock error:%s", nextBlockHeight, err)
 			reqNode := this.getNextNode(nextBlockHeight)
 			if reqNode == nil {
@@ -753,6 +905,8 @@ func (this *BlockSyncMgr) saveBlock() {
 		}
 		nextBlockHeight++
 		this.pingOutsyncNodes(nextBlockHeight - 1)
+		//the cache has room for more blocks
+		this.notifySync()
 	}
 }
 
@@ -762,10 +916,12 @@ func (this *BlockSyncMgr) isInBlockCache(blockHeight uint32) bool {
 	return this.blocksCache.isInBlockCache(blockHeight)
 }
 
-func (this *BlockSyncMgr) addFlightHeader(nodeId p2pComm.PeerId, height uint32) {
+func (this *BlockSyncMgr) addFlightHeader(nodeId p2pComm.PeerId, height uint32, count uint32) {
 	this.lock.Lock()
 	defer this.lock.Unlock()
-	this.flightHeaders[height] = NewSyncFlightInfo(height, nodeId)
+	info := NewSyncFlightInfo(height, nodeId)
+	info.count = count
+	this.flightHeaders[height] = info
 }
 
 func (this *BlockSyncMgr) getFlightHeader(height uint32) *SyncFlightInfo {
@@ -800,6 +956,92 @@ func (this *BlockSyncMgr) isHeaderOnFlight(height uint32) bool {
 	return flightInfo != nil
 }
 
+//getFlightHeaderNodes return the nodes with headers on flight
+func (this *BlockSyncMgr) getFlightHeaderNodes() map[p2pComm.PeerId]bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	nodes := make(map[p2pComm.PeerId]bool)
+	for _, info := range this.flightHeaders {
+		nodes[info.GetNodeId()] = true
+	}
+	return nodes
+}
+
+//getNextHeaderHeight return the lowest header height neither on flight nor in cache
+func (this *BlockSyncMgr) getNextHeaderHeight(curHeaderHeight uint32) uint32 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	next := curHeaderHeight + 1
+	for forward := true; forward; {
+		forward = false
+		for height, info := range this.flightHeaders {
+			if height <= next && height+info.count > next {
+				next = height + info.count
+				forward = true
+			}
+		}
+		for height, headerRange := range this.headersCache {
+			if end := height + uint32(len(headerRange.headers)); height <= next && end > next {
+				next = end
+				forward = true
+			}
+		}
+	}
+	return next
+}
+
+func (this *BlockSyncMgr) addHeadersCache(nodeId p2pComm.PeerId, headers []*types.Header) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.headersCache[headers[0].Height] = &HeaderRange{nodeID: nodeId, headers: headers}
+}
+
+//popHeadersCache remove and return the cached headers following the current header,
+//the headers already in ledger are dropped
+func (this *BlockSyncMgr) popHeadersCache(curHeaderHeight uint32) (p2pComm.PeerId, []*types.Header) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for height, headerRange := range this.headersCache {
+		end := height + uint32(len(headerRange.headers))
+		if end <= curHeaderHeight+1 {
+			delete(this.headersCache, height)
+			continue
+		}
+		if height <= curHeaderHeight+1 {
+			delete(this.headersCache, height)
+			return headerRange.nodeID, headerRange.headers[curHeaderHeight+1-height:]
+		}
+	}
+	return p2pComm.PeerId{}, nil
+}
+
+func (this *BlockSyncMgr) hasNextHeaders(curHeaderHeight uint32) bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	for height, headerRange := range this.headersCache {
+		if height <= curHeaderHeight+1 && height+uint32(len(headerRange.headers)) > curHeaderHeight+1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *BlockSyncMgr) tryGetSaveHeaderLock() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.saveHeaderLock {
+		return true
+	}
+	this.saveHeaderLock = true
+	return false
+}
+
+func (this *BlockSyncMgr) releaseSaveHeaderLock() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.saveHeaderLock = false
+}
+
 func (this *BlockSyncMgr) addFlightBlock(nodeId p2pComm.PeerId, height uint32, blockHash common.Uint256) {
 	this.lock.Lock()
 	defer this.lock.Unlock()
@@ -878,7 +1120,7 @@ func (this *BlockSyncMgr) getNextNode(nextBlockHeight uint32) *peer.Peer {
 		}
 		triedNode[nextNodeId] = true
 		n := this.server.GetPeer(nextNodeId)
-		if n == nil {
+		if n == nil || isLightNode(n) {
 			continue
 		}
 		nodeBlockHeight := n.GetHeight()
@@ -913,7 +1155,151 @@ func (this *BlockSyncMgr) getNodeWithMinFailedTimes(flightInfo *SyncFlightInfo,
 	}
 }
 
+//getHeaderNodes return the nodes able to serve the headers from the start height, sorted by weight.
+//Only the headers following the current header can be requested from the nodes not supporting range request.
+func (this *BlockSyncMgr) getHeaderNodes(start uint32, curHeaderHeight uint32) []*peer.Peer {
+	weights := this.getAllNodeWeights()
+	sort.Sort(sort.Reverse(weights))
+	nodes := make([]*peer.Peer, 0, len(weights))
+	for _, w := range weights {
+		n := this.server.GetPeer(w.id)
+		if n == nil || uint32(n.GetHeight()) < start {
+			continue
+		}
+		if start != curHeaderHeight+1 && !supportHeaderRange(n) {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+//getNextHeaderNode return the node to request the headers from, the nodes without headers on flight
+//are preferred so that the ranges are fetched from different nodes
+func (this *BlockSyncMgr) getNextHeaderNode(start uint32, curHeaderHeight uint32) *peer.Peer {
+	nodes := this.getHeaderNodes(start, curHeaderHeight)
+	if len(nodes) == 0 {
+		return nil
+	}
+	busy := this.getFlightHeaderNodes()
+	for _, n := range nodes {
+		if !busy[n.GetID()] {
+			return n
+		}
+	}
+	return nodes[0]
+}
+
+func (this *BlockSyncMgr) getHeaderNodeWithMinFailedTimes(flightInfo *SyncFlightInfo, curHeaderHeight uint32) *peer.Peer {
+	var minFailedTimes = math.MaxInt64
+	var minFailedTimesNode *peer.Peer
+	for _, n := range this.getHeaderNodes(flightInfo.Height, curHeaderHeight) {
+		failedTimes := flightInfo.GetFailedTimes(n.GetID())
+		if failedTimes < minFailedTimes {
+			minFailedTimes = failedTimes
+			minFailedTimesNode = n
+		}
+	}
+	return minFailedTimesNode
+}
+
 //Stop to sync
+//FetchBlock request the block at the height from the full nodes and save it to ledger. It's used by the light node
+//to get the transactions of a block, whose header has been synced.
+func (this *BlockSyncMgr) FetchBlock(height uint32) (*types.Block, error) {
+	blockHash := this.ledger.GetBlockHash(height)
+	if blockHash == common.UINT256_EMPTY {
+		return nil, fmt.Errorf("header of height %d is not synced", height)
+	}
+	ch := this.addBlockFetcher(blockHash)
+	defer this.delBlockFetcher(blockHash, ch)
+
+	triedNode := make(map[p2pComm.PeerId]bool)
+	for i := 0; i < SYNC_FETCH_BLOCK_TIMES; i++ {
+		reqNode := this.getFetchNode(height, triedNode)
+		if reqNode == nil {
+			break
+		}
+		triedNode[reqNode.GetID()] = true
+		err := this.server.Send(reqNode, msgpack.NewBlkDataReq(blockHash))
+		if err != nil {
+			log.Warnf("[block-sync] FetchBlock Height:%d ReqBlkData error:%s", height, err)
+			continue
+		}
+		select {
+		case block := <-ch:
+			err = this.ledger.AddBlock(block, nil, common.UINT256_EMPTY)
+			if err != nil {
+				this.onErrorResp(reqNode.GetID(), err)
+				return nil, err
+			}
+			return block, nil
+		case <-time.After(SYNC_FETCH_BLOCK_TIMEOUT * time.Second):
+			this.addTimeoutCnt(reqNode.GetID())
+		}
+	}
+	return nil, fmt.Errorf("failed to fetch block of height %d from %d nodes", height, len(triedNode))
+}
+
+func (this *BlockSyncMgr) addBlockFetcher(blockHash common.Uint256) chan *types.Block {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	ch := make(chan *types.Block, 1)
+	this.fetchers[blockHash] = append(this.fetchers[blockHash], ch)
+	return ch
+}
+
+func (this *BlockSyncMgr) delBlockFetcher(blockHash common.Uint256, ch chan *types.Block) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	fetchers := this.fetchers[blockHash]
+	for i, fetcher := range fetchers {
+		if fetcher == ch {
+			fetchers = append(fetchers[:i], fetchers[i+1:]...)
+			break
+		}
+	}
+	if len(fetchers) == 0 {
+		delete(this.fetchers, blockHash)
+	} else {
+		this.fetchers[blockHash] = fetchers
+	}
+}
+
+//deliverFetchedBlock pass the block to the fetchers waiting for it, return false if no one is waiting
+func (this *BlockSyncMgr) deliverFetchedBlock(blockHash common.Uint256, block *types.Block) bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	fetchers, ok := this.fetchers[blockHash]
+	if !ok {
+		return false
+	}
+	for _, ch := range fetchers {
+		select {
+		case ch <- block:
+		default:
+		}
+	}
+	return true
+}
+
+//getFetchNode return the full node with the block of the height, which is not tried yet
+func (this *BlockSyncMgr) getFetchNode(height uint32, triedNode map[p2pComm.PeerId]bool) *peer.Peer {
+	weights := this.getAllNodeWeights()
+	sort.Sort(sort.Reverse(weights))
+	for _, n := range weights {
+		if triedNode[n.id] {
+			continue
+		}
+		p := this.server.GetPeer(n.id)
+		if p == nil || isLightNode(p) || uint32(p.GetHeight()) < height {
+			continue
+		}
+		return p
+	}
+	return nil
+}
+
 func (this *BlockSyncMgr) Stop() {
 	close(this.exitCh)
 }
@@ -938,6 +1324,7 @@ func (this *BlockSyncMgr) getAllNodeWeights() NodeWeights {
 
 //addTimeoutCnt incre a node's timeout count
 func (this *BlockSyncMgr) addTimeoutCnt(nodeId p2pComm.PeerId) {
+	this.server.Penalize(nodeId, peer_score.TIMEOUT)
 	n := this.getNodeWeight(nodeId)
 	if n != nil {
 		n.AddTimeoutCnt()
@@ -1006,6 +1393,24 @@ func getNextNodeId(nextNodeIndex int, nodeList []p2pComm.PeerId) (int, p2pComm.P
 	return nextNodeIndex, nodeList[index]
 }
 
+//isLightNode return whether the node only keeps the headers
+func isLightNode(p *peer.Peer) bool {
+	return p.GetServices()&p2pComm.LIGHT_NODE != 0
+}
+
+//supportHeaderRange return whether the node serve the headers by height
+func supportHeaderRange(p *peer.Peer) bool {
+	version, err := semver.ParseTolerant(p.GetSoftVersion())
+	if err != nil {
+		return false
+	}
+	min, err := semver.ParseTolerant(p2pComm.MIN_VERSION_FOR_HEADER_RANGE)
+	if err != nil {
+		panic(err)
+	}
+	return version.GTE(min)
+}
+
 func pingTo(net p2p.P2P, height uint32, peers []*peer.Peer) {
 	ping := msgpack.NewPingMsg(uint64(height))
 	for _, p := range peers {
@@ -0,0 +1,347 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package block_sync
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/types"
+	p2pComm "github.com/ontio/ontology/p2pserver/common"
+	msgpack "github.com/ontio/ontology/p2pserver/message/msg_pack"
+	msgTypes "github.com/ontio/ontology/p2pserver/message/types"
+	"github.com/ontio/ontology/p2pserver/mock"
+	"github.com/ontio/ontology/p2pserver/net/netserver"
+	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	legacyVersion = "1.10.0"
+	rangeVersion  = "1.11.0"
+)
+
+// syncChain is a chain of blocks with a transaction each, so that every
+// block is requested instead of being made from its header
+type syncChain struct {
+	headers []*types.Header
+	blocks  []*types.Block
+	heights map[common.Uint256]uint32
+}
+
+func newSyncChain(t testing.TB, height uint32) *syncChain {
+	chain := &syncChain{heights: make(map[common.Uint256]uint32)}
+	genesis := &types.Header{}
+	chain.headers = append(chain.headers, genesis)
+	chain.blocks = append(chain.blocks, &types.Block{Header: genesis})
+	chain.heights[genesis.Hash()] = 0
+	for h := uint32(1); h <= height; h++ {
+		code := make([]byte, 4)
+		binary.LittleEndian.PutUint32(code, h)
+		mutable := &types.MutableTransaction{
+			TxType:  types.InvokeNeo,
+			Nonce:   h,
+			Payload: &payload.InvokeCode{Code: code},
+		}
+		tx, err := mutable.IntoImmutable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := &types.Header{
+			PrevBlockHash:    chain.headers[h-1].Hash(),
+			TransactionsRoot: common.ComputeMerkleRoot([]common.Uint256{tx.Hash()}),
+			Timestamp:        h,
+			Height:           h,
+		}
+		chain.headers = append(chain.headers, header)
+		chain.blocks = append(chain.blocks, &types.Block{Header: header, Transactions: []*types.Transaction{tx}})
+		chain.heights[header.Hash()] = h
+	}
+	return chain
+}
+
+func (self *syncChain) height() uint32 {
+	return uint32(len(self.headers) - 1)
+}
+
+func (self *syncChain) rawHeaders(start, count uint32) []*types.RawHeader {
+	headers := make([]*types.RawHeader, 0, count)
+	for h := start; h < start+count && h <= self.height(); h++ {
+		headers = append(headers, &types.RawHeader{Height: h, Payload: self.headers[h].ToArray()})
+	}
+	return headers
+}
+
+// memLedger is a ledger in memory, which only checks the chain of the headers
+type memLedger struct {
+	sync.RWMutex
+	headers     []*types.Header
+	blockHeight uint32
+}
+
+func newMemLedger(genesis *types.Header) *memLedger {
+	return &memLedger{headers: []*types.Header{genesis}}
+}
+
+func (self *memLedger) GetCurrentBlockHeight() uint32 {
+	self.RLock()
+	defer self.RUnlock()
+	return self.blockHeight
+}
+
+func (self *memLedger) GetCurrentHeaderHeight() uint32 {
+	self.RLock()
+	defer self.RUnlock()
+	return uint32(len(self.headers) - 1)
+}
+
+func (self *memLedger) GetCurrentHeaderHash() common.Uint256 {
+	self.RLock()
+	defer self.RUnlock()
+	return self.headers[len(self.headers)-1].Hash()
+}
+
+func (self *memLedger) GetBlockHash(height uint32) common.Uint256 {
+	self.RLock()
+	defer self.RUnlock()
+	if int(height) >= len(self.headers) {
+		return common.UINT256_EMPTY
+	}
+	return self.headers[height].Hash()
+}
+
+func (self *memLedger) GetHeaderByHeight(height uint32) (*types.Header, error) {
+	self.RLock()
+	defer self.RUnlock()
+	if int(height) >= len(self.headers) {
+		return nil, fmt.Errorf("header %d not found", height)
+	}
+	return self.headers[height], nil
+}
+
+func (self *memLedger) AddHeaders(headers []*types.Header) error {
+	self.Lock()
+	defer self.Unlock()
+	for _, header := range headers {
+		last := self.headers[len(self.headers)-1]
+		if header.Height != last.Height+1 || header.PrevBlockHash != last.Hash() {
+			return fmt.Errorf("header %d not linked", header.Height)
+		}
+		self.headers = append(self.headers, header)
+	}
+	return nil
+}
+
+func (self *memLedger) AddBlock(block *types.Block, ccMsg *types.CrossChainMsg, stateMerkleRoot common.Uint256) error {
+	self.Lock()
+	defer self.Unlock()
+	height := block.Header.Height
+	if height != self.blockHeight+1 || int(height) >= len(self.headers) ||
+		self.headers[height].Hash() != block.Hash() {
+		return fmt.Errorf("block %d not linked", height)
+	}
+	self.blockHeight = height
+	return nil
+}
+
+// servingProtocol serves the headers and blocks of a chain with a delay
+type servingProtocol struct {
+	chain      *syncChain
+	supportRng bool
+	latency    time.Duration
+}
+
+func (self *servingProtocol) HandleSystemMessage(net p2p.P2P, msg p2p.SystemMessage) {}
+
+func (self *servingProtocol) HandlePeerMessage(ctx *p2p.Context, msg msgTypes.Message) {
+	var resp msgTypes.Message
+	switch m := msg.(type) {
+	case *msgTypes.HeadersReq:
+		height, ok := self.chain.heights[m.HashEnd]
+		if !ok {
+			return
+		}
+		resp = msgpack.NewHeaders(self.chain.rawHeaders(height+1, p2pComm.MAX_BLK_HDR_CNT))
+	case *msgTypes.HeaderRangeReq:
+		if !self.supportRng {
+			return
+		}
+		count := m.Count
+		if count > p2pComm.MAX_BLK_HDR_CNT {
+			count = p2pComm.MAX_BLK_HDR_CNT
+		}
+		resp = msgpack.NewHeaders(self.chain.rawHeaders(m.Start, count))
+	case *msgTypes.DataReq:
+		height, ok := self.chain.heights[m.Hash]
+		if !ok {
+			return
+		}
+		resp = msgpack.NewBlock(self.chain.blocks[height], nil, common.UINT256_EMPTY)
+	default:
+		return
+	}
+	sender := ctx.Sender()
+	go func() {
+		time.Sleep(self.latency)
+		sender.Send(resp)
+	}()
+}
+
+// syncProtocol syncs the blocks from the connected nodes
+type syncProtocol struct {
+	ledger *memLedger
+	config *SyncConfig
+	mgr    *BlockSyncMgr
+}
+
+func (self *syncProtocol) HandleSystemMessage(net p2p.P2P, msg p2p.SystemMessage) {
+	switch m := msg.(type) {
+	case p2p.NetworkStart:
+		self.mgr = NewBlockSyncMgr(net, self.ledger)
+		self.mgr.config = self.config
+		go self.mgr.Start()
+	case p2p.PeerConnected:
+		self.mgr.OnAddNode(m.Info.Id)
+	case p2p.PeerDisConnected:
+		self.mgr.OnDelNode(m.Info.Id)
+	case p2p.NetworkStop:
+		self.mgr.Stop()
+	}
+}
+
+func (self *syncProtocol) HandlePeerMessage(ctx *p2p.Context, msg msgTypes.Message) {
+	switch m := msg.(type) {
+	case *msgTypes.BlkHeader:
+		self.mgr.OnHeaderReceive(ctx.Sender().GetID(), m.BlkHdr)
+	case *msgTypes.Block:
+		self.mgr.OnBlockReceive(ctx.Sender().GetID(), ctx.MsgSize, m.Blk, m.CCMsg, m.MerkleRoot)
+	}
+}
+
+// syncNetwork is a node syncing the chain from the serving nodes
+type syncNetwork struct {
+	client  *netserver.NetServer
+	servers []*netserver.NetServer
+	proto   *syncProtocol
+	ledger  *memLedger
+}
+
+func newSyncNetwork(chain *syncChain, versions []string, latency time.Duration, cfg *SyncConfig) *syncNetwork {
+	net := mock.NewNetwork()
+	ledger := newMemLedger(chain.headers[0])
+	keyId := p2pComm.RandPeerKeyId()
+	info := peer.NewPeerInfo(keyId.Id, 0, 0, true, 0, 0, 0, rangeVersion, "")
+	proto := &syncProtocol{ledger: ledger, config: cfg}
+	client := mock.NewNode(keyId, info, proto, net, nil)
+	client.Start()
+
+	servers := make([]*netserver.NetServer, 0, len(versions))
+	for _, version := range versions {
+		keyId := p2pComm.RandPeerKeyId()
+		info := peer.NewPeerInfo(keyId.Id, 0, 0, true, 0, 0, uint64(chain.height()), version, "")
+		proto := &servingProtocol{chain: chain, supportRng: version == rangeVersion, latency: latency}
+		server := mock.NewNode(keyId, info, proto, net, nil)
+		server.Start()
+		net.AllowConnect(client.GetID(), server.GetID())
+		servers = append(servers, server)
+	}
+	return &syncNetwork{client: client, servers: servers, proto: proto, ledger: ledger}
+}
+
+func (self *syncNetwork) sync(height uint32, timeout time.Duration) bool {
+	for _, server := range self.servers {
+		self.client.Connect(server.GetHostInfo().Addr)
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if self.ledger.GetCurrentBlockHeight() >= height {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func (self *syncNetwork) stop() {
+	self.client.Stop()
+	for _, server := range self.servers {
+		server.Stop()
+	}
+}
+
+func TestBlockSyncParallel(t *testing.T) {
+	chain := newSyncChain(t, 3000)
+	cfg := &SyncConfig{
+		MaxFlightHeaders: 4,
+		MaxFlightBlocks:  100,
+		MaxHeaderForward: 1000,
+		MaxBlockCache:    500,
+	}
+	versions := []string{rangeVersion, rangeVersion, rangeVersion, legacyVersion}
+	network := newSyncNetwork(chain, versions, time.Millisecond, cfg)
+	defer network.stop()
+
+	assert.True(t, network.sync(chain.height(), 30*time.Second))
+	assert.Equal(t, chain.height(), network.ledger.GetCurrentHeaderHeight())
+	for h := uint32(0); h <= chain.height(); h++ {
+		assert.Equal(t, chain.headers[h].Hash(), network.ledger.GetBlockHash(h))
+	}
+}
+
+func TestSyncConfig(t *testing.T) {
+	cfg := NewSyncConfig(nil)
+	assert.Equal(t, 4, cfg.MaxFlightHeaders)
+	assert.Equal(t, uint32(5000), cfg.MaxHeaderForward)
+}
+
+func BenchmarkBlockSync(b *testing.B) {
+	chain := newSyncChain(b, 5000)
+	cases := []struct {
+		name     string
+		versions []string
+		headers  int
+		blocks   int
+	}{
+		{"single-header-flight", []string{legacyVersion, legacyVersion, legacyVersion, legacyVersion}, 1, 50},
+		{"parallel-header-ranges", []string{rangeVersion, rangeVersion, rangeVersion, rangeVersion}, 4, 100},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cfg := &SyncConfig{
+					MaxFlightHeaders: c.headers,
+					MaxFlightBlocks:  c.blocks,
+					MaxHeaderForward: 5000,
+					MaxBlockCache:    500,
+				}
+				network := newSyncNetwork(chain, c.versions, 5*time.Millisecond, cfg)
+				if !network.sync(chain.height(), time.Minute) {
+					b.Fatalf("sync timeout at height %d", network.ledger.GetCurrentBlockHeight())
+				}
+				network.stop()
+			}
+		})
+	}
+}
@@ -78,6 +78,14 @@ func (self *MsgHandler) start(net p2p.P2P) {
 	go self.bootstrap.Start()
 }
 
+//FetchBlock request the block at the height from the full nodes, it's used by the light node
+func (self *MsgHandler) FetchBlock(height uint32) (*types.Block, error) {
+	if self.blockSync == nil {
+		return nil, errors.New("block sync is not started")
+	}
+	return self.blockSync.FetchBlock(height)
+}
+
 func (self *MsgHandler) stop() {
 	self.blockSync.Stop()
 	self.reconnect.Stop()
@@ -119,6 +127,8 @@ func (self *MsgHandler) HandlePeerMessage(ctx *p2p.Context, msg msgTypes.Message
 		self.discovery.FindNodeHandle(ctx, m)
 	case *msgTypes.HeadersReq:
 		HeadersReqHandle(ctx, m)
+	case *msgTypes.HeaderRangeReq:
+		HeaderRangeReqHandle(ctx, m)
 	case *msgTypes.Ping:
 		self.heatBeat.PingHandle(ctx, m)
 	case *msgTypes.Pong:
@@ -168,6 +178,25 @@ func HeadersReqHandle(ctx *p2p.Context, headersReq *msgTypes.HeadersReq) {
 	}
 }
 
+// HeaderRangeReqHandle handles the header sync req by height from peer
+func HeaderRangeReqHandle(ctx *p2p.Context, req *msgTypes.HeaderRangeReq) {
+	headers, err := GetHeadersFromHeight(req.Start, req.Count)
+	if err != nil {
+		log.Warnf("HeaderRangeReqHandle error: %s,start:%d,count:%d", err.Error(), req.Start, req.Count)
+		return
+	}
+	if len(headers) == 0 {
+		return
+	}
+	remotePeer := ctx.Sender()
+	msg := msgpack.NewHeaders(headers)
+	err = remotePeer.Send(msg)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+}
+
 // blockHandle handles the block message from peer
 func (self *MsgHandler) blockHandle(ctx *p2p.Context, block *msgTypes.Block) {
 	stateHashHeight := config.GetStateHashCheckHeight(config.DefConfig.P2PNode.NetworkId)
@@ -196,7 +225,7 @@ func ConsensusHandle(ctx *p2p.Context, consensus *msgTypes.Consensus) {
 func TransactionHandle(ctx *p2p.Context, trn *msgTypes.Trn) {
 	if !txCache.Contains(trn.Txn.Hash()) {
 		txCache.Add(trn.Txn.Hash(), nil)
-		actor.AddTransaction(trn.Txn)
+		actor.AddTransaction(trn.Txn, ctx.Sender().GetID())
 	} else {
 		log.Tracef("[p2p]receive duplicate Transaction message, txHash: %x\n", trn.Txn.Hash())
 	}
@@ -430,6 +459,38 @@ func GetHeadersFromHash(startHash common.Uint256, stopHash common.Uint256) ([]*t
 	return headers, nil
 }
 
+//GetHeadersFromHeight get at most count headers from the start height
+func GetHeadersFromHeight(start uint32, count uint32) ([]*types.RawHeader, error) {
+	curHeight := ledger.DefLedger.GetCurrentHeaderHeight()
+	if start == 0 || start > curHeight {
+		return nil, nil
+	}
+	if count > msgCommon.MAX_BLK_HDR_CNT {
+		count = msgCommon.MAX_BLK_HDR_CNT
+	}
+	if count > curHeight-start+1 {
+		count = curHeight - start + 1
+	}
+
+	headers := make([]*types.RawHeader, 0, count)
+	for height := start; height < start+count; height++ {
+		hash := ledger.DefLedger.GetBlockHash(height)
+		header, err := ledger.DefLedger.GetHeaderByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		sink := common.NewZeroCopySink(nil)
+		header.Serialization(sink)
+		headers = append(headers, &types.RawHeader{
+			Height:  header.Height,
+			Payload: sink.Bytes(),
+		})
+	}
+
+	return headers, nil
+}
+
 //getRespCacheValue get response data from cache
 func getRespCacheValue(key string) interface{} {
 	if respCache == nil {
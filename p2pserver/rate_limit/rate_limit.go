@@ -0,0 +1,199 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rate_limit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/p2pserver/message/types"
+)
+
+//Policy is the action taken on the messages over the limits
+type Policy uint8
+
+const (
+	POLICY_DROP       Policy = iota //Drop the message and keep the connection
+	POLICY_DISCONNECT               //Drop the message and disconnect the peer
+)
+
+const (
+	LIMIT_PEER = "peer" //The limit of all the messages from a peer
+	LIMIT_TYPE = "type" //The limit of a message type from a peer
+)
+
+//ParsePolicy parse the policy by its name, "drop" or "disconnect"
+func ParsePolicy(name string) (Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "drop":
+		return POLICY_DROP, nil
+	case "disconnect":
+		return POLICY_DISCONNECT, nil
+	}
+	return POLICY_DROP, fmt.Errorf("unknown rate limit policy: %s", name)
+}
+
+func (self Policy) String() string {
+	if self == POLICY_DISCONNECT {
+		return "disconnect"
+	}
+	return "drop"
+}
+
+//Limit is a token bucket limit, the bucket is refilled by Rate tokens per second up to Burst tokens
+type Limit struct {
+	Rate  uint
+	Burst uint
+}
+
+//ParseLimits parse the limits of the message types in the format "type:rate:burst,...", e.g. "getdata:1000:2000",
+//the burst can be omitted and is the rate by default
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		fields := strings.Split(item, ":")
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("invalid rate limit %s, expected type:rate:burst", item)
+		}
+		msgType := strings.TrimSpace(fields[0])
+		if _, err := types.MakeEmptyMessage(msgType); err != nil {
+			return nil, fmt.Errorf("invalid rate limit %s, unknown message type %s", item, msgType)
+		}
+		rate, err := strconv.ParseUint(strings.TrimSpace(fields[1]), 10, 32)
+		if err != nil || rate == 0 {
+			return nil, fmt.Errorf("invalid rate limit %s, the rate should be a positive integer", item)
+		}
+		burst := rate
+		if len(fields) == 3 {
+			burst, err = strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 32)
+			if err != nil || burst < rate {
+				return nil, fmt.Errorf("invalid rate limit %s, the burst should be an integer not less than the rate", item)
+			}
+		}
+		limits[msgType] = Limit{Rate: uint(rate), Burst: uint(burst)}
+	}
+	return limits, nil
+}
+
+//LimitConfig is the limits applied to the messages received from every peer
+type LimitConfig struct {
+	MsgLimits map[string]Limit //Limits per message type
+	PeerLimit Limit            //Limit of all the messages, no limit if the rate is 0
+	Policy    Policy
+}
+
+//NewLimitConfig return the limits set in the p2p config
+func NewLimitConfig(cfg *config.P2PNodeConfig) (*LimitConfig, error) {
+	msgLimits, err := ParseLimits(cfg.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := ParsePolicy(cfg.RateLimitPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return &LimitConfig{
+		MsgLimits: msgLimits,
+		PeerLimit: Limit{Rate: cfg.PeerRateLimit, Burst: 2 * cfg.PeerRateLimit},
+		Policy:    policy,
+	}, nil
+}
+
+//TokenBucket allows the events at the rate of the limit with bursts up to the burst of the limit
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(limit Limit, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		rate:   float64(limit.Rate),
+		burst:  float64(limit.Burst),
+		tokens: float64(limit.Burst),
+		last:   now,
+	}
+}
+
+//Allow take a token from the bucket, return false if the bucket is empty
+func (self *TokenBucket) Allow(now time.Time) bool {
+	if elapsed := now.Sub(self.last).Seconds(); elapsed > 0 {
+		self.tokens += elapsed * self.rate
+		if self.tokens > self.burst {
+			self.tokens = self.burst
+		}
+		self.last = now
+	}
+	if self.tokens < 1 {
+		return false
+	}
+	self.tokens -= 1
+	return true
+}
+
+//PeerLimiter limits the messages received from a peer. It is not thread safe, since the messages
+//of a peer are read by a single goroutine.
+type PeerLimiter struct {
+	cfg  *LimitConfig
+	peer *TokenBucket
+	msgs map[string]*TokenBucket
+	now  func() time.Time
+}
+
+func NewPeerLimiter(cfg *LimitConfig) *PeerLimiter {
+	limiter := &PeerLimiter{
+		cfg:  cfg,
+		msgs: make(map[string]*TokenBucket, len(cfg.MsgLimits)),
+		now:  time.Now,
+	}
+	now := limiter.now()
+	if cfg.PeerLimit.Rate != 0 {
+		limiter.peer = NewTokenBucket(cfg.PeerLimit, now)
+	}
+	for msgType, limit := range cfg.MsgLimits {
+		limiter.msgs[msgType] = NewTokenBucket(limit, now)
+	}
+	return limiter
+}
+
+//Policy return the policy on the messages over the limits
+func (self *PeerLimiter) Policy() Policy {
+	return self.cfg.Policy
+}
+
+//Allow check the message of msgType against the limits, return the limit hit if it is not allowed,
+//LIMIT_TYPE or LIMIT_PEER
+func (self *PeerLimiter) Allow(msgType string) (bool, string) {
+	now := self.now()
+	if bucket, ok := self.msgs[msgType]; ok && !bucket.Allow(now) {
+		return false, LIMIT_TYPE
+	}
+	if self.peer != nil && !self.peer.Allow(now) {
+		return false, LIMIT_PEER
+	}
+	return true, ""
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rate_limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/common/config"
+	"github.com/ontio/ontology/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimits(t *testing.T) {
+	a := assert.New(t)
+	limits, err := ParseLimits("getdata:100:200, tx:50")
+	a.Nil(err)
+	a.Equal(map[string]Limit{
+		common.GET_DATA_TYPE: {Rate: 100, Burst: 200},
+		common.TX_TYPE:       {Rate: 50, Burst: 50},
+	}, limits)
+
+	limits, err = ParseLimits("")
+	a.Nil(err)
+	a.Equal(0, len(limits))
+
+	for _, invalid := range []string{"getdata", "unknown:1:1", "getdata:0:1", "getdata:10:5", "getdata:a:b", "tx:1:2:3"} {
+		_, err = ParseLimits(invalid)
+		a.NotNil(err, invalid)
+	}
+}
+
+func TestNewLimitConfig(t *testing.T) {
+	a := assert.New(t)
+	cfg, err := NewLimitConfig(config.DefConfig.P2PNode)
+	a.Nil(err)
+	a.Equal(POLICY_DROP, cfg.Policy)
+	a.Equal(uint(0), cfg.PeerLimit.Rate)
+	a.Contains(cfg.MsgLimits, common.GET_DATA_TYPE)
+
+	_, err = NewLimitConfig(&config.P2PNodeConfig{RateLimitPolicy: "close"})
+	a.NotNil(err)
+}
+
+func TestTokenBucket(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1600000000, 0)
+	bucket := NewTokenBucket(Limit{Rate: 10, Burst: 20}, now)
+	for i := 0; i < 20; i++ {
+		a.True(bucket.Allow(now))
+	}
+	a.False(bucket.Allow(now))
+
+	now = now.Add(500 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		a.True(bucket.Allow(now))
+	}
+	a.False(bucket.Allow(now))
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 20; i++ {
+		a.True(bucket.Allow(now))
+	}
+	a.False(bucket.Allow(now), "the tokens are capped by the burst")
+}
+
+func TestPeerLimiter(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1600000000, 0)
+	limiter := NewPeerLimiter(&LimitConfig{
+		MsgLimits: map[string]Limit{common.GET_DATA_TYPE: {Rate: 1, Burst: 2}},
+		PeerLimit: Limit{Rate: 2, Burst: 4},
+		Policy:    POLICY_DISCONNECT,
+	})
+	limiter.now = func() time.Time { return now }
+
+	results := make([]string, 0)
+	for _, msgType := range []string{common.GET_DATA_TYPE, common.GET_DATA_TYPE, common.GET_DATA_TYPE,
+		common.TX_TYPE, common.TX_TYPE, common.TX_TYPE} {
+		_, limit := limiter.Allow(msgType)
+		results = append(results, limit)
+	}
+	a.Equal([]string{"", "", LIMIT_TYPE, "", "", LIMIT_PEER}, results)
+	a.Equal(POLICY_DISCONNECT, limiter.Policy())
+}
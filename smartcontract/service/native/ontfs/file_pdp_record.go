@@ -179,7 +179,7 @@ func getPdpRecordList(native *native.NativeService, fileHash []byte, fileOwner c
 		var pdpRecord PdpRecord
 		source := common.NewZeroCopySource(item.Value)
 		if err := pdpRecord.Deserialization(source); err != nil {
-			log.Errorf("getPdpRecordList Deserialization error: ", err.Error())
+			log.Errorf("getPdpRecordList Deserialization error: %s", err.Error())
 			continue
 		}
 		pdpRecordList.PdpRecords = append(pdpRecordList.PdpRecords, pdpRecord)
@@ -185,7 +185,7 @@ func getNodeAddrList(native *native.NativeService) []common.Address {
 
 		nodeAddr, err := common.AddressParseFromBytes(key[nodeInfoPrefixLen:])
 		if err != nil {
-			log.Errorf("getNodeAddrList AddressParseFromBytes error: ", err.Error())
+			log.Errorf("getNodeAddrList AddressParseFromBytes error: %s", err.Error())
 			continue
 		}
 		fsNodeAddrList = append(fsNodeAddrList, nodeAddr)
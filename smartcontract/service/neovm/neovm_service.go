@@ -155,10 +155,14 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 		if this.Engine.Context.GetInstructionPointer() >= len(this.Engine.Context.Code) {
 			break
 		}
+		pc := this.Engine.Context.GetInstructionPointer()
 		opCode, eof := this.Engine.Context.ReadOpCode()
 		if eof {
 			return nil, io.EOF
 		}
+		if this.Engine.Hook != nil {
+			this.Engine.Hook.OnStep(this.Engine, pc, opCode)
+		}
 
 		price := gasTable[opCode]
 		if opCode >= vm.PUSHBYTES1 && opCode <= vm.PUSHBYTES75 {
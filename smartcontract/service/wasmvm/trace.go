@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wasmvm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ontio/ontology/smartcontract/trace"
+	"github.com/ontio/wagon/wasm"
+)
+
+// newTracedHostModule returns the host module whose functions are recorded
+// by the tracer, with the gas consumed and the panic raised by each call.
+func newTracedHostModule(tracer *trace.Tracer) *wasm.Module {
+	m := NewHostModule()
+	names := make(map[uint32]string, len(m.Export.Entries))
+	for name, entry := range m.Export.Entries {
+		names[entry.Index] = name
+	}
+	for i := range m.FunctionIndexSpace {
+		m.FunctionIndexSpace[i].Host = traceHostFunc(tracer, names[uint32(i)], m.FunctionIndexSpace[i].Host)
+	}
+	return m
+}
+
+func traceHostFunc(tracer *trace.Tracer, name string, host reflect.Value) reflect.Value {
+	return reflect.MakeFunc(host.Type(), func(args []reflect.Value) []reflect.Value {
+		step := tracer.EnterHostCall(name)
+		defer func() {
+			if r := recover(); r != nil {
+				tracer.ExitHostCall(step, fmt.Errorf("%v", r))
+				panic(r)
+			}
+		}()
+		results := host.Call(args)
+		tracer.ExitHostCall(step, nil)
+		return results
+	})
+}
@@ -81,10 +81,14 @@ func checkOntoWasm(m *wasm.Module) error {
 }
 
 func ReadWasmModule(code []byte, verify config.VerifyMethod) (*exec.CompiledModule, error) {
+	return readWasmModule(code, verify, NewHostModule)
+}
+
+func readWasmModule(code []byte, verify config.VerifyMethod, newHostModule func() *wasm.Module) (*exec.CompiledModule, error) {
 	m, err := wasm.ReadModule(bytes.NewReader(code), func(name string) (*wasm.Module, error) {
 		switch name {
 		case "env":
-			return NewHostModule(), nil
+			return newHostModule(), nil
 		}
 		return nil, fmt.Errorf("module %q unknown", name)
 	})
@@ -29,7 +29,9 @@ import (
 	"github.com/ontio/ontology/smartcontract/event"
 	"github.com/ontio/ontology/smartcontract/states"
 	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/ontio/ontology/smartcontract/trace"
 	"github.com/ontio/wagon/exec"
+	"github.com/ontio/wagon/wasm"
 )
 
 type WasmVmService struct {
@@ -49,6 +51,7 @@ type WasmVmService struct {
 	IsTerminate   bool
 	JitMode       bool
 	ServiceIndex  uint64
+	Tracer        *trace.Tracer
 	vm            *exec.VM
 }
 
@@ -167,7 +170,16 @@ func invokeInterpreter(this *WasmVmService, contract *states.WasmContractParam,
 	host := &Runtime{Service: this, Input: contract.Args}
 
 	var compiled *exec.CompiledModule
-	if CodeCache != nil {
+	if this.Tracer != nil {
+		// the traced module is bound to the tracer, so it is not cached
+		module, err := readWasmModule(wasmCode, config.NoneVerifyMethod, func() *wasm.Module {
+			return newTracedHostModule(this.Tracer)
+		})
+		if err != nil {
+			return nil, err
+		}
+		compiled = module
+	} else if CodeCache != nil {
 		cached, ok := CodeCache.Get(contract.Address.ToHexString())
 		if ok {
 			compiled = cached.(*exec.CompiledModule)
@@ -31,6 +31,7 @@ import (
 	"github.com/ontio/ontology/smartcontract/service/neovm"
 	"github.com/ontio/ontology/smartcontract/service/wasmvm"
 	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/ontio/ontology/smartcontract/trace"
 	vm "github.com/ontio/ontology/vm/neovm"
 )
 
@@ -53,6 +54,7 @@ type SmartContract struct {
 	PreExec       bool
 	internelErr   bool
 	CrossHashes   []common.Uint256
+	Tracer        *trace.Tracer // execution tracer, nil if not traced
 }
 
 // Config describe smart contract need parameters configuration
@@ -66,6 +68,9 @@ type Config struct {
 // PushContext push current context to smart contract
 func (this *SmartContract) PushContext(context *context.Context) {
 	this.Contexts = append(this.Contexts, context)
+	if this.Tracer != nil {
+		this.Tracer.EnterCall(context.ContractAddress, context.Code)
+	}
 }
 
 // CurrentContext return smart contract current context
@@ -96,9 +101,21 @@ func (this *SmartContract) EntryContext() *context.Context {
 func (this *SmartContract) PopContext() {
 	if len(this.Contexts) > 1 {
 		this.Contexts = this.Contexts[:len(this.Contexts)-1]
+		if this.Tracer != nil {
+			this.Tracer.ExitCall()
+		}
 	}
 }
 
+// SetTracer traces the execution of the smart contract with the tracer.
+// The jit mode is disabled since the host calls of jit can not be traced.
+func (this *SmartContract) SetTracer(tracer *trace.Tracer) {
+	tracer.Attach(&this.Gas)
+	this.Tracer = tracer
+	this.JitMode = false
+	this.CacheDB.SetAccessHook(tracer)
+}
+
 // PushNotifications push smart contract event info
 func (this *SmartContract) PushNotifications(notifications []*event.NotifyEventInfo) {
 	this.Notifications = append(this.Notifications, notifications...)
@@ -151,6 +168,10 @@ func (this *SmartContract) NewExecuteEngine(code []byte, txtype ctypes.Transacti
 	switch txtype {
 	case ctypes.InvokeNeo:
 		feature := NewVmFeatureFlag(this.Config.Height)
+		engine := vm.NewExecutor(code, feature)
+		if this.Tracer != nil {
+			engine.Hook = this.Tracer
+		}
 		service = &neovm.NeoVmService{
 			Store:      this.Store,
 			CacheDB:    this.CacheDB,
@@ -161,7 +182,7 @@ func (this *SmartContract) NewExecuteEngine(code []byte, txtype ctypes.Transacti
 			Time:       this.Config.Time,
 			Height:     this.Config.Height,
 			BlockHash:  this.Config.BlockHash,
-			Engine:     vm.NewExecutor(code, feature),
+			Engine:     engine,
 			PreExec:    this.PreExec,
 		}
 	case ctypes.InvokeWasm:
@@ -183,6 +204,7 @@ func (this *SmartContract) NewExecuteEngine(code []byte, txtype ctypes.Transacti
 			GasLimit:   &this.Gas,
 			GasFactor:  gasFactor,
 			JitMode:    this.JitMode,
+			Tracer:     this.Tracer,
 		}
 	default:
 		return nil, errors.New("failed to construct execute engine, wrong transaction type")
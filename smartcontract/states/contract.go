@@ -67,8 +67,19 @@ func (this *ContractInvokeParam) Deserialization(source *common.ZeroCopySource)
 }
 
 type PreExecResult struct {
-	State  byte
-	Gas    uint64
-	Result interface{}
-	Notify []*event.NotifyEventInfo
+	State    byte
+	Gas      uint64
+	Result   interface{}
+	Notify   []*event.NotifyEventInfo
+	WriteSet []*StorageChange // the storage items written by the pre-executed invoke transaction
+}
+
+// StorageChange is a contract storage item written by the execution,
+// NewValue is empty if the item is deleted.
+type StorageChange struct {
+	Contract common.Address
+	Key      []byte
+	OldValue []byte
+	NewValue []byte
+	Deleted  bool
 }
@@ -19,10 +19,13 @@
 package storage
 
 import (
+	"bytes"
+
 	comm "github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/smartcontract/states"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
@@ -32,6 +35,14 @@ type CacheDB struct {
 	memdb      *overlaydb.MemDB
 	backend    *overlaydb.OverlayDB
 	keyScratch []byte
+	hook       AccessHook
+}
+
+// AccessHook observes the contract storage accesses through the cache
+type AccessHook interface {
+	OnStorageGet(key, value []byte)
+	OnStoragePut(key, value []byte)
+	OnStorageDelete(key []byte)
 }
 
 const initCap = 1024
@@ -49,6 +60,11 @@ func (self *CacheDB) Reset() {
 	self.memdb.Reset()
 }
 
+// SetAccessHook sets the hook to observe the storage accesses, nil to remove it
+func (self *CacheDB) SetAccessHook(hook AccessHook) {
+	self.hook = hook
+}
+
 func ensureBuffer(b []byte, n int) []byte {
 	if cap(b) < n {
 		return make([]byte, n)
@@ -75,6 +91,9 @@ func (self *CacheDB) Commit() {
 }
 
 func (self *CacheDB) Put(key []byte, value []byte) {
+	if self.hook != nil {
+		self.hook.OnStoragePut(key, value)
+	}
 	self.put(common.ST_STORAGE, key, value)
 }
 
@@ -115,7 +134,11 @@ func (self *CacheDB) DeleteContract(address comm.Address) {
 }
 
 func (self *CacheDB) Get(key []byte) ([]byte, error) {
-	return self.get(common.ST_STORAGE, key)
+	value, err := self.get(common.ST_STORAGE, key)
+	if err == nil && self.hook != nil {
+		self.hook.OnStorageGet(key, value)
+	}
+	return value, err
 }
 
 func (self *CacheDB) get(prefix common.DataEntryPrefix, key []byte) ([]byte, error) {
@@ -133,6 +156,9 @@ func (self *CacheDB) get(prefix common.DataEntryPrefix, key []byte) ([]byte, err
 }
 
 func (self *CacheDB) Delete(key []byte) {
+	if self.hook != nil {
+		self.hook.OnStorageDelete(key)
+	}
 	self.delete(common.ST_STORAGE, key)
 }
 
@@ -142,6 +168,38 @@ func (self *CacheDB) delete(prefix common.DataEntryPrefix, key []byte) {
 	self.memdb.Delete(self.keyScratch)
 }
 
+// GetStorageWriteSet returns the contract storage items written to the cache, with the values in the
+// backend before written. The items written with the same values as the backend are skipped.
+func (self *CacheDB) GetStorageWriteSet() ([]*states.StorageChange, error) {
+	changes := make([]*states.StorageChange, 0)
+	var err error
+	self.memdb.ForEach(func(key, val []byte) {
+		if err != nil || len(key) < 1+comm.ADDR_LEN || key[0] != byte(common.ST_STORAGE) {
+			return
+		}
+		old, e := self.backend.Get(key)
+		if e != nil {
+			err = e
+			return
+		}
+		if bytes.Equal(old, val) {
+			return
+		}
+		change := &states.StorageChange{
+			Key:      append([]byte(nil), key[1+comm.ADDR_LEN:]...),
+			OldValue: append([]byte(nil), old...),
+			NewValue: append([]byte(nil), val...),
+			Deleted:  len(val) == 0,
+		}
+		copy(change.Contract[:], key[1:1+comm.ADDR_LEN])
+		changes = append(changes, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 func (self *CacheDB) NewIterator(key []byte) common.StoreIterator {
 	pkey := make([]byte, 1+len(key))
 	pkey[0] = byte(common.ST_STORAGE)
@@ -21,9 +21,11 @@ import (
 	"math/rand"
 	"testing"
 
+	comm "github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/store/common"
 	"github.com/ontio/ontology/core/store/leveldbstore"
 	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/smartcontract/states"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,3 +85,41 @@ func TestCacheDB(t *testing.T) {
 	}
 
 }
+
+func TestCacheDBWriteSet(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	overlay := overlaydb.NewOverlayDB(memback)
+
+	contract := comm.Address{1, 2, 3}
+	key := func(k string) []byte {
+		return append(contract[:], k...)
+	}
+	cache := NewCacheDB(overlay)
+	cache.Put(key("updated"), []byte("old"))
+	cache.Put(key("deleted"), []byte("old"))
+	cache.Put(key("unchanged"), []byte("same"))
+	cache.Commit()
+
+	cache = NewCacheDB(overlay)
+	cache.Put(key("updated"), []byte("new"))
+	cache.Put(key("inserted"), []byte("new"))
+	cache.Delete(key("deleted"))
+	cache.Put(key("unchanged"), []byte("same"))
+	cache.Delete(key("missing"))
+	cache.DeleteContract(contract)
+
+	writeSet, err := cache.GetStorageWriteSet()
+	assert.Nil(t, err)
+	changes := make(map[string]*states.StorageChange)
+	for _, change := range writeSet {
+		assert.Equal(t, contract, change.Contract)
+		changes[string(change.Key)] = change
+	}
+	assert.Equal(t, 3, len(changes))
+	assert.Equal(t, &states.StorageChange{Contract: contract, Key: []byte("updated"),
+		OldValue: []byte("old"), NewValue: []byte("new")}, changes["updated"])
+	assert.Equal(t, &states.StorageChange{Contract: contract, Key: []byte("inserted"),
+		NewValue: []byte("new")}, changes["inserted"])
+	assert.Equal(t, &states.StorageChange{Contract: contract, Key: []byte("deleted"),
+		OldValue: []byte("old"), Deleted: true}, changes["deleted"])
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package trace records the execution of a transaction: the opcodes run by
+// neovm, the host calls made by wasm contracts, the contract call tree and
+// the storage accesses.
+package trace
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/smartcontract/event"
+	vm "github.com/ontio/ontology/vm/neovm"
+)
+
+const (
+	MAX_TRACE_STEPS      = 20000 // The max steps recorded in a trace
+	MAX_STORAGE_ACCESSES = 10000 // The max storage accesses recorded in a trace
+	MAX_STACK_ITEMS      = 8     // The max eval stack items recorded in a step
+	MAX_STACK_ITEM_LEN   = 256   // The max length of a recorded stack item
+)
+
+const (
+	CALL_NEOVM  = "neovm"
+	CALL_WASMVM = "wasmvm"
+	CALL_NATIVE = "native"
+)
+
+const (
+	STORAGE_READ   = "read"
+	STORAGE_WRITE  = "write"
+	STORAGE_DELETE = "delete"
+)
+
+var wasmMagic = []byte{0x00, 0x61, 0x73, 0x6d}
+
+// Step is an opcode executed by neovm, or a host function called by wasm.
+// PC and Stack are only available for neovm, Gas is the gas consumed by the
+// step including the contracts called by it.
+type Step struct {
+	Depth    int
+	Contract string
+	PC       int
+	Op       string
+	Gas      uint64
+	GasLeft  uint64
+	Stack    []string `json:",omitempty"`
+	Error    string   `json:",omitempty"`
+}
+
+// Call is a contract invocation, with the calls made by it
+type Call struct {
+	Type    string
+	From    string
+	To      string
+	Depth   int
+	GasUsed uint64
+	Error   string  `json:",omitempty"`
+	Calls   []*Call `json:",omitempty"`
+}
+
+// StorageAccess is a read or write of the contract storage, Step is the
+// index of the step that accessed it.
+type StorageAccess struct {
+	Step     int
+	Contract string
+	Op       string
+	Key      string
+	Value    string `json:",omitempty"`
+}
+
+// Trace is the execution trace of a transaction
+type Trace struct {
+	State       byte
+	GasConsumed uint64
+	Result      interface{} // the result of the pre-executed transaction
+	Notify      []*event.NotifyEventInfo
+	Error       string
+	Truncated   bool // some steps or storage accesses are not recorded
+	Steps       []*Step
+	Calls       []*Call
+	Storage     []*StorageAccess
+}
+
+type frame struct {
+	call     *Call
+	gasStart uint64
+	last     *Step // The last neovm step of the frame
+}
+
+// Tracer collects the trace of an execution. It is not safe for
+// concurrent use, one tracer traces one transaction.
+type Tracer struct {
+	gas      *uint64
+	trace    *Trace
+	frames   []*frame
+	steps    int
+	finished bool
+}
+
+// NewTracer returns a tracer, it should be attached to the gas counter of
+// the execution before use.
+func NewTracer() *Tracer {
+	return &Tracer{
+		trace: &Trace{
+			Steps:   make([]*Step, 0),
+			Calls:   make([]*Call, 0),
+			Storage: make([]*StorageAccess, 0),
+		},
+	}
+}
+
+// Attach binds the tracer to the gas counter of the execution
+func (self *Tracer) Attach(gas *uint64) {
+	self.gas = gas
+}
+
+// Trace returns the collected trace
+func (self *Tracer) Trace() *Trace {
+	return self.trace
+}
+
+func (self *Tracer) gasLeft() uint64 {
+	if self.gas == nil {
+		return 0
+	}
+	return *self.gas
+}
+
+func (self *Tracer) current() *frame {
+	if len(self.frames) == 0 {
+		return nil
+	}
+	return self.frames[len(self.frames)-1]
+}
+
+func (self *Tracer) currentContract() string {
+	if f := self.current(); f != nil {
+		return f.call.To
+	}
+	return ""
+}
+
+// addStep records a step, false is returned if the step limit is reached
+func (self *Tracer) addStep(step *Step) bool {
+	self.steps += 1
+	if len(self.trace.Steps) >= MAX_TRACE_STEPS {
+		self.trace.Truncated = true
+		return false
+	}
+	self.trace.Steps = append(self.trace.Steps, step)
+	return true
+}
+
+// settle fills the gas of the last neovm step of the frame
+func (f *frame) settle(gasLeft uint64) {
+	if f.last != nil {
+		f.last.Gas = f.last.GasLeft - gasLeft
+		f.last = nil
+	}
+}
+
+// EnterCall records a contract invocation, the type of the contract is
+// decided by its code.
+func (self *Tracer) EnterCall(contract common.Address, code []byte) {
+	if self.finished {
+		return
+	}
+	call := &Call{
+		Type:  callType(code),
+		From:  self.currentContract(),
+		To:    contract.ToHexString(),
+		Depth: len(self.frames),
+	}
+	if parent := self.current(); parent != nil {
+		parent.call.Calls = append(parent.call.Calls, call)
+	} else {
+		self.trace.Calls = append(self.trace.Calls, call)
+	}
+	self.frames = append(self.frames, &frame{call: call, gasStart: self.gasLeft()})
+}
+
+// ExitCall records the return of the current contract invocation
+func (self *Tracer) ExitCall() {
+	if self.finished {
+		return
+	}
+	self.exitCall(nil)
+}
+
+func (self *Tracer) exitCall(err error) {
+	f := self.current()
+	if f == nil {
+		return
+	}
+	gasLeft := self.gasLeft()
+	f.settle(gasLeft)
+	if f.gasStart > gasLeft {
+		f.call.GasUsed = f.gasStart - gasLeft
+	}
+	if err != nil {
+		f.call.Error = err.Error()
+	}
+	self.frames = self.frames[:len(self.frames)-1]
+}
+
+// Finish closes the calls not returned with the error of the execution,
+// nothing is recorded after that.
+func (self *Tracer) Finish(err error) {
+	if self.finished {
+		return
+	}
+	for len(self.frames) > 0 {
+		self.exitCall(err)
+	}
+	self.finished = true
+}
+
+// OnStep implements neovm.StepHook
+func (self *Tracer) OnStep(engine *vm.Executor, pc int, opcode vm.OpCode) {
+	if self.finished {
+		return
+	}
+	gasLeft := self.gasLeft()
+	f := self.current()
+	if f != nil {
+		f.settle(gasLeft)
+	}
+	step := &Step{
+		Depth:    len(self.frames),
+		Contract: self.currentContract(),
+		PC:       pc,
+		Op:       opName(opcode),
+		GasLeft:  gasLeft,
+		Stack:    stackSnapshot(engine.EvalStack),
+	}
+	if self.addStep(step) && f != nil {
+		f.last = step
+	}
+}
+
+// EnterHostCall records a host function called by wasm, the returned step
+// should be passed to ExitHostCall when the function returns.
+func (self *Tracer) EnterHostCall(name string) *Step {
+	if self.finished {
+		return nil
+	}
+	step := &Step{
+		Depth:    len(self.frames),
+		Contract: self.currentContract(),
+		Op:       name,
+		GasLeft:  self.gasLeft(),
+	}
+	if !self.addStep(step) {
+		return nil
+	}
+	return step
+}
+
+// ExitHostCall records the gas consumed by a host function and its error
+func (self *Tracer) ExitHostCall(step *Step, err error) {
+	if step == nil {
+		return
+	}
+	if gasLeft := self.gasLeft(); step.GasLeft > gasLeft {
+		step.Gas = step.GasLeft - gasLeft
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+}
+
+// OnStorageGet implements storage.AccessHook
+func (self *Tracer) OnStorageGet(key, value []byte) {
+	self.addStorage(STORAGE_READ, key, value)
+}
+
+// OnStoragePut implements storage.AccessHook
+func (self *Tracer) OnStoragePut(key, value []byte) {
+	self.addStorage(STORAGE_WRITE, key, value)
+}
+
+// OnStorageDelete implements storage.AccessHook
+func (self *Tracer) OnStorageDelete(key []byte) {
+	self.addStorage(STORAGE_DELETE, key, nil)
+}
+
+// addStorage records a storage access, the storage key is prefixed with
+// the address of the contract owning it.
+func (self *Tracer) addStorage(op string, key, value []byte) {
+	if self.finished {
+		return
+	}
+	if len(self.trace.Storage) >= MAX_STORAGE_ACCESSES {
+		self.trace.Truncated = true
+		return
+	}
+	access := &StorageAccess{
+		Step:  self.steps - 1,
+		Op:    op,
+		Key:   common.ToHexString(key),
+		Value: common.ToHexString(value),
+	}
+	if len(key) >= common.ADDR_LEN {
+		addr, _ := common.AddressParseFromBytes(key[:common.ADDR_LEN])
+		access.Contract = addr.ToHexString()
+		access.Key = common.ToHexString(key[common.ADDR_LEN:])
+	}
+	self.trace.Storage = append(self.trace.Storage, access)
+}
+
+func callType(code []byte) string {
+	switch {
+	case code == nil:
+		return CALL_NATIVE
+	case bytes.HasPrefix(code, wasmMagic):
+		return CALL_WASMVM
+	default:
+		return CALL_NEOVM
+	}
+}
+
+func opName(opcode vm.OpCode) string {
+	if opcode >= vm.PUSHBYTES1 && opcode <= vm.PUSHBYTES75 {
+		return fmt.Sprintf("PUSHBYTES%d", opcode)
+	}
+	if name := vm.OpExecList[opcode].Name; name != "" {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", byte(opcode))
+}
+
+// stackSnapshot dumps the items on the top of the stack, the top first
+func stackSnapshot(stack *vm.ValueStack) []string {
+	count := stack.Count()
+	if count > MAX_STACK_ITEMS {
+		count = MAX_STACK_ITEMS
+	}
+	items := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		value, err := stack.Peek(int64(i))
+		if err != nil {
+			break
+		}
+		item := value.Dump()
+		if len(item) > MAX_STACK_ITEM_LEN {
+			item = item[:MAX_STACK_ITEM_LEN] + "..."
+		}
+		items = append(items, item)
+	}
+	return items
+}
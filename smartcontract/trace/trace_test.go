@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package trace_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/payload"
+	"github.com/ontio/ontology/core/store/leveldbstore"
+	"github.com/ontio/ontology/core/store/overlaydb"
+	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/smartcontract"
+	"github.com/ontio/ontology/smartcontract/service/neovm"
+	"github.com/ontio/ontology/smartcontract/storage"
+	"github.com/ontio/ontology/smartcontract/trace"
+	vm "github.com/ontio/ontology/vm/neovm"
+	"github.com/stretchr/testify/assert"
+)
+
+func sysCall(name string) []byte {
+	return append([]byte{byte(vm.SYSCALL), byte(len(name))}, name...)
+}
+
+func TestTraceNeoVm(t *testing.T) {
+	builder := vm.NewParamsBuilder(new(bytes.Buffer))
+	builder.EmitPushByteArray([]byte("value"))
+	builder.EmitPushByteArray([]byte("key"))
+	code := builder.ToArray()
+	code = append(code, sysCall(neovm.STORAGE_GETCONTEXT_NAME)...)
+	code = append(code, sysCall(neovm.STORAGE_PUT_NAME)...)
+	code = append(code, 0x03, 'k', 'e', 'y') // PUSHBYTES3
+	code = append(code, sysCall(neovm.STORAGE_GETCONTEXT_NAME)...)
+	code = append(code, sysCall(neovm.STORAGE_GET_NAME)...)
+	address := common.AddressFromVmCode(code)
+
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	cache := storage.NewCacheDB(overlaydb.NewOverlayDB(store))
+	contract, err := payload.NewDeployCode(code, payload.NEOVM_TYPE, "", "", "", "", "")
+	assert.Nil(t, err)
+	cache.PutContract(contract)
+
+	gasTable := make(map[string]uint64)
+	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
+		gasTable[k.(string)] = value.(uint64)
+		return true
+	})
+	sc := smartcontract.SmartContract{
+		Config:   &smartcontract.Config{Time: 10, Height: 10, Tx: &types.Transaction{}},
+		CacheDB:  cache,
+		GasTable: gasTable,
+		Gas:      100000,
+	}
+	tracer := trace.NewTracer()
+	sc.SetTracer(tracer)
+	engine, err := sc.NewExecuteEngine(code, types.InvokeNeo)
+	assert.Nil(t, err)
+	_, err = engine.Invoke()
+	assert.Nil(t, err)
+	tracer.Finish(err)
+
+	// storage accesses after finished are ignored
+	cache.Get([]byte("ignored"))
+
+	result := tracer.Trace()
+	assert.False(t, result.Truncated)
+	assert.Equal(t, 1, len(result.Calls))
+	call := result.Calls[0]
+	assert.Equal(t, trace.CALL_NEOVM, call.Type)
+	assert.Equal(t, address.ToHexString(), call.To)
+	assert.Equal(t, 100000-sc.Gas, call.GasUsed)
+
+	ops := make([]string, 0, len(result.Steps))
+	var gas uint64
+	for _, step := range result.Steps {
+		assert.Equal(t, address.ToHexString(), step.Contract)
+		ops = append(ops, step.Op)
+		gas += step.Gas
+	}
+	assert.Equal(t, []string{"PUSHBYTES5", "PUSHBYTES3", "SYSCALL", "SYSCALL", "PUSHBYTES3", "SYSCALL", "SYSCALL"}, ops)
+	assert.Equal(t, call.GasUsed, gas)
+	assert.Equal(t, 0, result.Steps[0].PC)
+	assert.Equal(t, 0, len(result.Steps[0].Stack))
+	assert.Equal(t, 6, result.Steps[1].PC)
+	assert.Equal(t, 1, len(result.Steps[1].Stack))
+	assert.Equal(t, 2, len(result.Steps[2].Stack))
+
+	assert.Equal(t, 2, len(result.Storage))
+	assert.Equal(t, trace.STORAGE_WRITE, result.Storage[0].Op)
+	assert.Equal(t, 3, result.Storage[0].Step)
+	assert.Equal(t, trace.STORAGE_READ, result.Storage[1].Op)
+	assert.Equal(t, 6, result.Storage[1].Step)
+	for _, access := range result.Storage {
+		assert.Equal(t, address.ToHexString(), access.Contract)
+		assert.Equal(t, common.ToHexString([]byte("key")), access.Key)
+		assert.Equal(t, result.Storage[0].Value, access.Value)
+	}
+}
+
+func TestTraceFinish(t *testing.T) {
+	var gas uint64 = 1000
+	tracer := trace.NewTracer()
+	tracer.Attach(&gas)
+
+	tracer.EnterCall(common.ADDRESS_EMPTY, []byte{0x00, 0x61, 0x73, 0x6d, 0x01})
+	step := tracer.EnterHostCall("ontio_call_contract")
+	gas -= 100
+	tracer.EnterCall(common.Address{1}, nil)
+	gas -= 200
+	tracer.ExitHostCall(step, assert.AnError)
+	tracer.Finish(assert.AnError)
+
+	result := tracer.Trace()
+	assert.Equal(t, 1, len(result.Calls))
+	root := result.Calls[0]
+	assert.Equal(t, trace.CALL_WASMVM, root.Type)
+	assert.Equal(t, uint64(300), root.GasUsed)
+	assert.Equal(t, assert.AnError.Error(), root.Error)
+	assert.Equal(t, 1, len(root.Calls))
+	assert.Equal(t, trace.CALL_NATIVE, root.Calls[0].Type)
+	assert.Equal(t, root.To, root.Calls[0].From)
+	assert.Equal(t, 1, root.Calls[0].Depth)
+	assert.Equal(t, uint64(200), root.Calls[0].GasUsed)
+
+	assert.Equal(t, 1, len(result.Steps))
+	assert.Equal(t, "ontio_call_contract", result.Steps[0].Op)
+	assert.Equal(t, uint64(300), result.Steps[0].Gas)
+	assert.Equal(t, assert.AnError.Error(), result.Steps[0].Error)
+
+	// nothing is recorded after finished
+	tracer.EnterCall(common.ADDRESS_EMPTY, nil)
+	assert.Nil(t, tracer.EnterHostCall("ontio_timestamp"))
+	assert.Equal(t, 1, len(result.Calls))
+}
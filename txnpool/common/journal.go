@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/core/types"
+)
+
+// TX_JOURNAL_FILE is the file name of the tx journal in the data directory
+const TX_JOURNAL_FILE = "txpool.journal"
+
+// TXJournal keeps the transactions accepted by the pool in a local file,
+// so that they survive the node restarts. Each record is a serialized
+// transaction with a var-length prefix. Transactions are only appended
+// to the file, and the file is rewritten with the live transactions on
+// rotation. The file is synced to disk only on rotation and close, so the
+// transactions appended since the last rotation may be lost on crash.
+type TXJournal struct {
+	sync.Mutex
+	path   string   // The file path of the journal
+	writer *os.File // The file to append the transactions to
+}
+
+// NewTXJournal creates a journal with the file path.
+func NewTXJournal(path string) *TXJournal {
+	return &TXJournal{path: path}
+}
+
+// Load reads the transactions in the journal, the duplicated ones are
+// ignored. A broken record at the end is dropped, which may be caused by
+// a crash while writing it.
+func (j *TXJournal) Load() ([]*types.Transaction, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	data, err := ioutil.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read tx journal %s: %s", j.path, err)
+	}
+
+	txs := make([]*types.Transaction, 0)
+	loaded := make(map[common.Uint256]bool)
+	source := common.NewZeroCopySource(data)
+	for source.Len() > 0 {
+		offset := source.Pos()
+		raw, _, irregular, eof := source.NextVarBytes()
+		if irregular || eof {
+			log.Warnf("tx journal %s: broken record at offset %d dropped",
+				j.path, offset)
+			break
+		}
+		tx, err := types.TransactionFromRawBytes(raw)
+		if err != nil {
+			log.Warnf("tx journal %s: invalid transaction dropped: %s", j.path, err)
+			continue
+		}
+		if loaded[tx.Hash()] {
+			continue
+		}
+		loaded[tx.Hash()] = true
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// Insert appends a transaction to the journal.
+func (j *TXJournal) Insert(tx *types.Transaction) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.writer == nil {
+		writer, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("open tx journal %s: %s", j.path, err)
+		}
+		j.writer = writer
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarBytes(tx.ToArray())
+	_, err := j.writer.Write(sink.Bytes())
+	return err
+}
+
+// Rotate rewrites the journal with the transactions still alive in the pool.
+func (j *TXJournal) Rotate(txs []*types.Transaction) error {
+	j.Lock()
+	defer j.Unlock()
+
+	sink := common.NewZeroCopySink(nil)
+	for _, tx := range txs {
+		sink.WriteVarBytes(tx.ToArray())
+	}
+	filename := j.path + "~"
+	if err := writeFileSync(filename, sink.Bytes()); err != nil {
+		return fmt.Errorf("write tx journal %s: %s", filename, err)
+	}
+	if j.writer != nil {
+		j.writer.Close()
+		j.writer = nil
+	}
+	if err := os.Rename(filename, j.path); err != nil {
+		return fmt.Errorf("rename tx journal %s: %s", filename, err)
+	}
+	return nil
+}
+
+// Close closes the journal file.
+func (j *TXJournal) Close() error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.writer == nil {
+		return nil
+	}
+	err := j.writer.Sync()
+	if closeErr := j.writer.Close(); err == nil {
+		err = closeErr
+	}
+	j.writer = nil
+	return err
+}
+
+// writeFileSync writes data to the file and syncs it to disk.
+func writeFileSync(filename string, data []byte) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
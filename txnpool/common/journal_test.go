@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTXJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, TX_JOURNAL_FILE)
+
+	journal := NewTXJournal(path)
+	txs, err := journal.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(txs))
+
+	tx1 := newPayerTx(t, common.Address{1}, 1, 500, []byte{1})
+	tx2 := newPayerTx(t, common.Address{2}, 1, 500, []byte{2})
+	tx3 := newPayerTx(t, common.Address{3}, 1, 500, []byte{3})
+	assert.Nil(t, journal.Insert(tx1))
+	assert.Nil(t, journal.Insert(tx2))
+	assert.Nil(t, journal.Insert(tx1))
+	assert.Nil(t, journal.Close())
+
+	txs, err = NewTXJournal(path).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+	assert.Equal(t, tx1.Hash(), txs[0].Hash())
+	assert.Equal(t, tx2.Hash(), txs[1].Hash())
+
+	// a partly written record is dropped
+	assert.Nil(t, journal.Insert(tx3))
+	assert.Nil(t, journal.Close())
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Truncate(path, info.Size()-1))
+	txs, err = NewTXJournal(path).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+
+	assert.Nil(t, journal.Rotate([]*types.Transaction{tx3}))
+	assert.Nil(t, journal.Insert(tx2))
+	txs, err = NewTXJournal(path).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(txs))
+	assert.Equal(t, tx3.Hash(), txs[0].Hash())
+	assert.Equal(t, tx2.Hash(), txs[1].Hash())
+	assert.Nil(t, journal.Close())
+}
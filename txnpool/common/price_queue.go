@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+// txPriceItem keeps the position of a pool transaction in the price queue
+type txPriceItem struct {
+	entry *TXEntry
+	seq   uint64 // The order that the tx entered the pool
+	index int    // The index in the heap
+}
+
+// higherPriority returns whether a transaction should be packed into a
+// block before another one. The gas price is compared first, and the
+// earlier one wins if they have the same gas price.
+func (item *txPriceItem) higherPriority(other *txPriceItem) bool {
+	if item.entry.Tx.GasPrice != other.entry.Tx.GasPrice {
+		return item.entry.Tx.GasPrice > other.entry.Tx.GasPrice
+	}
+	return item.seq < other.seq
+}
+
+// txPriceQueue implements heap.Interface, the transaction with the lowest
+// priority is on the top, which is the first one to evict when the pool
+// is full.
+type txPriceQueue []*txPriceItem
+
+func (q txPriceQueue) Len() int { return len(q) }
+
+func (q txPriceQueue) Less(i, j int) bool { return q[j].higherPriority(q[i]) }
+
+func (q txPriceQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *txPriceQueue) Push(x interface{}) {
+	item := x.(*txPriceItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *txPriceQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*q = old[:n-1]
+	return item
+}
+
+// orderByPriority sorts the transactions from the highest priority to
+// the lowest one.
+type orderByPriority []*txPriceItem
+
+func (n orderByPriority) Len() int { return len(n) }
+
+func (n orderByPriority) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+
+func (n orderByPriority) Less(i, j int) bool { return n[i].higherPriority(n[j]) }
@@ -20,6 +20,7 @@
 package common
 
 import (
+	"container/heap"
 	"sort"
 	"sync"
 
@@ -42,13 +43,31 @@ type TXEntry struct {
 	Attrs []*TXAttr          // the result from each validator
 }
 
+// payerNonce identifies the slot a transaction takes in the pool. Only
+// one transaction of a payer with the same nonce can be pending.
+type payerNonce struct {
+	payer common.Address
+	nonce uint32
+}
+
+func getPayerNonce(tx *types.Transaction) payerNonce {
+	return payerNonce{payer: tx.Payer, nonce: tx.Nonce}
+}
+
 // TXPool contains all currently valid transactions. Transactions
 // enter the pool when they are valid from the network,
 // consensus or submitted. They exit the pool when they are included
 // in the ledger.
 type TXPool struct {
 	sync.RWMutex
-	txList map[common.Uint256]*TXEntry // Transactions which have been verified
+	txList      map[common.Uint256]*TXEntry       // Transactions which have been verified
+	slots       map[payerNonce]common.Uint256     // The pending transaction of each payer and nonce
+	payerTxs    map[common.Address]uint           // The number of pending transactions of each payer
+	prices      map[common.Uint256]*txPriceItem   // The position of each transaction in the price queue
+	priced      txPriceQueue                      // Transactions ordered by gas price
+	seq         uint64                            // The order of the next transaction entering the pool
+	evicted     map[common.Uint256]common.Uint256 // Evicted transactions and the transactions replacing them, empty if evicted as the pool is full
+	evictedList []common.Uint256                  // Evicted transactions in eviction order
 }
 
 // Init creates a new transaction pool to gather.
@@ -56,24 +75,173 @@ func (tp *TXPool) Init() {
 	tp.Lock()
 	defer tp.Unlock()
 	tp.txList = make(map[common.Uint256]*TXEntry)
+	tp.slots = make(map[payerNonce]common.Uint256)
+	tp.payerTxs = make(map[common.Address]uint)
+	tp.prices = make(map[common.Uint256]*txPriceItem)
+	tp.priced = make(txPriceQueue, 0)
+	tp.seq = 0
+	tp.evicted = make(map[common.Uint256]common.Uint256)
+	tp.evictedList = make([]common.Uint256, 0)
 }
 
 // AddTxList adds a valid transaction to the transaction pool. If the
-// transaction is already in the pool, just return false. Parameter
+// transaction is already in the pool, or it fails to replace the one
+// with the same payer and nonce, just return false. Parameter
 // txEntry includes transaction, fee, and verified information(height,
 // validator, error code).
 func (tp *TXPool) AddTxList(txEntry *TXEntry) bool {
+	_, errCode := tp.ReplaceTxList(txEntry)
+	return errCode == errors.ErrNoError
+}
+
+// ReplaceTxList adds a valid transaction to the transaction pool, and
+// returns the transaction evicted to make room for it if any. If a
+// transaction with the same payer and nonce is pending, the new one
+// replaces it only when the gas price is bumped by the configured
+// percentage at least. Otherwise if the payer has too many pending
+// transactions, the new one is rejected; and if the pool is full, the
+// one with the lowest gas price is evicted, unless the new one doesn't
+// pay more than it.
+func (tp *TXPool) ReplaceTxList(txEntry *TXEntry) (*types.Transaction, errors.ErrCode) {
 	tp.Lock()
 	defer tp.Unlock()
 	txHash := txEntry.Tx.Hash()
 	if _, ok := tp.txList[txHash]; ok {
 		log.Infof("AddTxList: transaction %x is already in the pool",
 			txHash)
-		return false
+		return nil, errors.ErrDuplicateInput
+	}
+
+	oldHash, errCode := tp.checkAddTx(txEntry.Tx)
+	if errCode != errors.ErrNoError {
+		log.Infof("AddTxList: transaction %x rejected: %s", txHash, errCode.Error())
+		return nil, errCode
+	}
+
+	var evicted *types.Transaction
+	if oldHash != common.UINT256_EMPTY {
+		evicted = tp.txList[oldHash].Tx
+		if tp.slots[getPayerNonce(txEntry.Tx)] == oldHash {
+			tp.addEvicted(oldHash, txHash)
+			log.Infof("AddTxList: transaction %x replaced by %x", oldHash, txHash)
+		} else {
+			tp.addEvicted(oldHash, common.UINT256_EMPTY)
+			log.Infof("AddTxList: transaction %x evicted as the pool is full", oldHash)
+		}
+		tp.removeTx(oldHash)
 	}
 
 	tp.txList[txHash] = txEntry
-	return true
+	tp.slots[getPayerNonce(txEntry.Tx)] = txHash
+	tp.payerTxs[txEntry.Tx.Payer]++
+	item := &txPriceItem{entry: txEntry, seq: tp.seq}
+	tp.seq++
+	heap.Push(&tp.priced, item)
+	tp.prices[txHash] = item
+	return evicted, errors.ErrNoError
+}
+
+// CheckAddTx checks whether a transaction is able to enter the pool,
+// before it is sent to be verified.
+func (tp *TXPool) CheckAddTx(tx *types.Transaction) errors.ErrCode {
+	tp.RLock()
+	defer tp.RUnlock()
+	if _, ok := tp.txList[tx.Hash()]; ok {
+		return errors.ErrNoError
+	}
+	_, errCode := tp.checkAddTx(tx)
+	return errCode
+}
+
+// checkAddTx returns the transaction to evict for a new transaction
+// entering the pool, the caller must hold the lock.
+func (tp *TXPool) checkAddTx(tx *types.Transaction) (common.Uint256, errors.ErrCode) {
+	if oldHash, ok := tp.slots[getPayerNonce(tx)]; ok {
+		if !CanReplace(tp.txList[oldHash].Tx.GasPrice, tx.GasPrice) {
+			return common.UINT256_EMPTY, errors.ErrReplaceUnderpriced
+		}
+		return oldHash, errors.ErrNoError
+	}
+
+	payerCap := config.DefConfig.Common.TxPoolPayerCapacity
+	if payerCap != 0 && tp.payerTxs[tx.Payer] >= payerCap {
+		return common.UINT256_EMPTY, errors.ErrPayerTxsLimit
+	}
+
+	capacity := config.DefConfig.Common.TxPoolCapacity
+	if capacity != 0 && uint(len(tp.txList)) >= capacity {
+		if len(tp.priced) == 0 || tx.GasPrice <= tp.priced[0].entry.Tx.GasPrice {
+			return common.UINT256_EMPTY, errors.ErrTxPoolFull
+		}
+		return tp.priced[0].entry.Tx.Hash(), errors.ErrNoError
+	}
+	return common.UINT256_EMPTY, errors.ErrNoError
+}
+
+// GetAcceptGasPrice returns the lowest gas price for a transaction to
+// enter the pool, regardless of the gas price enforced by the pool. It is
+// the replacement price if the transaction takes the slot of a pending one,
+// or the price to evict the cheapest one if the pool is full.
+func (tp *TXPool) GetAcceptGasPrice(tx *types.Transaction) uint64 {
+	tp.RLock()
+	defer tp.RUnlock()
+	if oldHash, ok := tp.slots[getPayerNonce(tx)]; ok && oldHash != tx.Hash() {
+		old := tp.txList[oldHash].Tx.GasPrice
+		price := old + old*config.DefConfig.Common.TxPoolPriceBump/100
+		if price <= old {
+			price = old + 1
+		}
+		return price
+	}
+
+	capacity := config.DefConfig.Common.TxPoolCapacity
+	if capacity != 0 && uint(len(tp.txList)) >= capacity && len(tp.priced) > 0 {
+		return tp.priced[0].entry.Tx.GasPrice + 1
+	}
+	return 0
+}
+
+// CanReplace returns whether the new gas price is high enough to replace
+// a pending transaction with the old gas price.
+func CanReplace(oldGasPrice, newGasPrice uint64) bool {
+	bump := config.DefConfig.Common.TxPoolPriceBump
+	return newGasPrice > oldGasPrice &&
+		newGasPrice >= oldGasPrice+oldGasPrice*bump/100
+}
+
+// removeTx deletes a transaction and its slot from the pool, the caller
+// must hold the lock.
+func (tp *TXPool) removeTx(hash common.Uint256) {
+	txEntry, ok := tp.txList[hash]
+	if !ok {
+		return
+	}
+	delete(tp.txList, hash)
+	key := getPayerNonce(txEntry.Tx)
+	if tp.slots[key] == hash {
+		delete(tp.slots, key)
+	}
+	if tp.payerTxs[key.payer] <= 1 {
+		delete(tp.payerTxs, key.payer)
+	} else {
+		tp.payerTxs[key.payer]--
+	}
+	if item, ok := tp.prices[hash]; ok {
+		heap.Remove(&tp.priced, item.index)
+		delete(tp.prices, hash)
+	}
+}
+
+// addEvicted records an evicted transaction with the one replacing it, or
+// an empty hash if it is evicted as the pool is full, and forgets the
+// oldest one when there are too many records.
+func (tp *TXPool) addEvicted(hash, replacedBy common.Uint256) {
+	tp.evicted[hash] = replacedBy
+	tp.evictedList = append(tp.evictedList, hash)
+	if len(tp.evictedList) > MAX_EVICTED_TXS {
+		delete(tp.evicted, tp.evictedList[0])
+		tp.evictedList = tp.evictedList[1:]
+	}
 }
 
 // CleanTransactionList cleans the transaction list included in the ledger.
@@ -84,7 +252,7 @@ func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) error {
 	defer tp.Unlock()
 	for _, tx := range txs {
 		if _, ok := tp.txList[tx.Hash()]; ok {
-			delete(tp.txList, tx.Hash())
+			tp.removeTx(tx.Hash())
 			cleaned++
 		}
 	}
@@ -94,6 +262,21 @@ func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) error {
 	return nil
 }
 
+// RemoveExpiredTxs drops the transactions which can't be packed in the
+// block of the height any more, and returns the number of them.
+func (tp *TXPool) RemoveExpiredTxs(height uint32) int {
+	tp.Lock()
+	defer tp.Unlock()
+	expired := 0
+	for hash, txEntry := range tp.txList {
+		if txEntry.Tx.IsExpiredAt(height) {
+			tp.removeTx(hash)
+			expired++
+		}
+	}
+	return expired
+}
+
 // DelTxList removes a single transaction from the pool.
 func (tp *TXPool) DelTxList(tx *types.Transaction) bool {
 	tp.Lock()
@@ -102,7 +285,7 @@ func (tp *TXPool) DelTxList(tx *types.Transaction) bool {
 	if _, ok := tp.txList[txHash]; !ok {
 		return false
 	}
-	delete(tp.txList, txHash)
+	tp.removeTx(txHash)
 	return true
 }
 
@@ -127,11 +310,9 @@ func (tp *TXPool) GetTxPool(byCount bool, height uint32) ([]*TXEntry,
 	tp.RLock()
 	defer tp.RUnlock()
 
-	orderByFee := make([]*TXEntry, 0, len(tp.txList))
-	for _, txEntry := range tp.txList {
-		orderByFee = append(orderByFee, txEntry)
-	}
-	sort.Sort(OrderByNetWorkFee(orderByFee))
+	orderByFee := make([]*txPriceItem, 0, len(tp.priced))
+	orderByFee = append(orderByFee, tp.priced...)
+	sort.Sort(orderByPriority(orderByFee))
 
 	count := int(config.DefConfig.Consensus.MaxTxInBlock)
 	if count <= 0 {
@@ -144,7 +325,8 @@ func (tp *TXPool) GetTxPool(byCount bool, height uint32) ([]*TXEntry,
 	var num int
 	txList := make([]*TXEntry, 0, count)
 	oldTxList := make([]*types.Transaction, 0)
-	for _, txEntry := range orderByFee {
+	for _, item := range orderByFee {
+		txEntry := item.entry
 		if !tp.compareTxHeight(txEntry, height) {
 			oldTxList = append(oldTxList, txEntry.Tx)
 			continue
@@ -171,12 +353,19 @@ func (tp *TXPool) GetTransaction(hash common.Uint256) *types.Transaction {
 }
 
 // GetTxStatus returns a transaction status if it is contained in the pool
-// and nil otherwise.
+// or it has been replaced recently, and nil otherwise.
 func (tp *TXPool) GetTxStatus(hash common.Uint256) *TxStatus {
 	tp.RLock()
 	defer tp.RUnlock()
 	txEntry, ok := tp.txList[hash]
 	if !ok {
+		if replacedBy, ok := tp.evicted[hash]; ok {
+			return &TxStatus{
+				Hash:       hash,
+				Evicted:    true,
+				ReplacedBy: replacedBy,
+			}
+		}
 		return nil
 	}
 	ret := &TxStatus{
@@ -204,6 +393,22 @@ func (tp *TXPool) GetTransactionHashList() []common.Uint256 {
 	return ret
 }
 
+// GetTransactions returns all the transactions in the pool, from the
+// highest gas price to the lowest one.
+func (tp *TXPool) GetTransactions() []*types.Transaction {
+	tp.RLock()
+	defer tp.RUnlock()
+	items := make([]*txPriceItem, 0, len(tp.priced))
+	items = append(items, tp.priced...)
+	sort.Sort(orderByPriority(items))
+
+	ret := make([]*types.Transaction, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.entry.Tx)
+	}
+	return ret
+}
+
 // GetUnverifiedTxs checks the tx list in the block from consensus,
 // and returns verified tx list, unverified tx list, and
 // the tx list to be re-verified
@@ -225,7 +430,7 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 		}
 
 		if !tp.compareTxHeight(txEntry, height) {
-			delete(tp.txList, tx.Hash())
+			tp.removeTx(tx.Hash())
 			res.OldTxs = append(res.OldTxs, txEntry.Tx)
 			continue
 		}
@@ -251,10 +456,8 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 func (tp *TXPool) RemoveTxsBelowGasPrice(gasPrice uint64) {
 	tp.Lock()
 	defer tp.Unlock()
-	for _, txEntry := range tp.txList {
-		if txEntry.Tx.GasPrice < gasPrice {
-			delete(tp.txList, txEntry.Tx.Hash())
-		}
+	for len(tp.priced) > 0 && tp.priced[0].entry.Tx.GasPrice < gasPrice {
+		tp.removeTx(tp.priced[0].entry.Tx.Hash())
 	}
 }
 
@@ -266,7 +469,7 @@ func (tp *TXPool) Remain() []*types.Transaction {
 	txList := make([]*types.Transaction, 0, len(tp.txList))
 	for _, txEntry := range tp.txList {
 		txList = append(txList, txEntry.Tx)
-		delete(tp.txList, txEntry.Tx.Hash())
+		tp.removeTx(txEntry.Tx.Hash())
 	}
 
 	return txList
@@ -22,9 +22,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
+	"github.com/ontio/ontology/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -99,3 +102,233 @@ func TestTxPool(t *testing.T) {
 		return
 	}
 }
+
+func newPayerTx(t *testing.T, payer common.Address, nonce uint32, gasPrice uint64, code []byte) *types.Transaction {
+	mutable := &types.MutableTransaction{
+		TxType:   types.InvokeNeo,
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Payer:    payer,
+		Payload:  &payload.InvokeCode{Code: code},
+	}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	return tx
+}
+
+func TestTxPoolReplace(t *testing.T) {
+	bump := config.DefConfig.Common.TxPoolPriceBump
+	config.DefConfig.Common.TxPoolPriceBump = 10
+	defer func() { config.DefConfig.Common.TxPoolPriceBump = bump }()
+
+	txPool := &TXPool{}
+	txPool.Init()
+
+	payer := common.Address{1}
+	tx1 := newPayerTx(t, payer, 1, 500, []byte{1})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx1, Attrs: []*TXAttr{}}))
+
+	// another nonce of the same payer takes a different slot
+	other := newPayerTx(t, payer, 2, 500, []byte{1})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: other, Attrs: []*TXAttr{}}))
+
+	// the bump is less than 10 percent
+	tx2 := newPayerTx(t, payer, 1, 549, []byte{2})
+	assert.Equal(t, errors.ErrReplaceUnderpriced, txPool.CheckAddTx(tx2))
+	evicted, errCode := txPool.ReplaceTxList(&TXEntry{Tx: tx2, Attrs: []*TXAttr{}})
+	assert.Equal(t, errors.ErrReplaceUnderpriced, errCode)
+	assert.Nil(t, evicted)
+	assert.NotNil(t, txPool.GetTransaction(tx1.Hash()))
+
+	tx3 := newPayerTx(t, payer, 1, 550, []byte{3})
+	assert.Equal(t, errors.ErrNoError, txPool.CheckAddTx(tx3))
+	evicted, errCode = txPool.ReplaceTxList(&TXEntry{Tx: tx3, Attrs: []*TXAttr{}})
+	assert.Equal(t, errors.ErrNoError, errCode)
+	assert.Equal(t, tx1.Hash(), evicted.Hash())
+	assert.Nil(t, txPool.GetTransaction(tx1.Hash()))
+	assert.Equal(t, 2, txPool.GetTransactionCount())
+
+	status := txPool.GetTxStatus(tx1.Hash())
+	assert.NotNil(t, status)
+	assert.True(t, status.Evicted)
+	assert.Equal(t, tx3.Hash(), status.ReplacedBy)
+	assert.False(t, txPool.GetTxStatus(tx3.Hash()).Evicted)
+
+	// the slot is released once the tx leaves the pool
+	assert.Nil(t, txPool.CleanTransactionList([]*types.Transaction{tx3}))
+	tx4 := newPayerTx(t, payer, 1, 500, []byte{4})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx4, Attrs: []*TXAttr{}}))
+}
+
+func TestTxPoolEvictedLimit(t *testing.T) {
+	bump := config.DefConfig.Common.TxPoolPriceBump
+	config.DefConfig.Common.TxPoolPriceBump = 0
+	defer func() { config.DefConfig.Common.TxPoolPriceBump = bump }()
+
+	txPool := &TXPool{}
+	txPool.Init()
+
+	payer := common.Address{1}
+	hashes := make([]common.Uint256, 0, MAX_EVICTED_TXS+2)
+	for i := 0; i < MAX_EVICTED_TXS+2; i++ {
+		tx := newPayerTx(t, payer, 1, uint64(i+1), []byte{})
+		assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx, Attrs: []*TXAttr{}}))
+		hashes = append(hashes, tx.Hash())
+	}
+	assert.Equal(t, 1, txPool.GetTransactionCount())
+	assert.Equal(t, MAX_EVICTED_TXS, len(txPool.evicted))
+	assert.Equal(t, MAX_EVICTED_TXS, len(txPool.evictedList))
+	assert.Nil(t, txPool.GetTxStatus(hashes[0]))
+	assert.Equal(t, hashes[2], txPool.GetTxStatus(hashes[1]).ReplacedBy)
+}
+
+func TestTxPoolPriority(t *testing.T) {
+	txPool := &TXPool{}
+	txPool.Init()
+
+	prices := []uint64{500, 2500, 1000, 2500, 0}
+	txs := make([]*types.Transaction, 0, len(prices))
+	for i, price := range prices {
+		tx := newPayerTx(t, common.Address{byte(i)}, 1, price, []byte{})
+		assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx, Attrs: []*TXAttr{}}))
+		txs = append(txs, tx)
+	}
+
+	txList, _ := txPool.GetTxPool(false, 0)
+	expected := []*types.Transaction{txs[1], txs[3], txs[2], txs[0], txs[4]}
+	assert.Equal(t, len(expected), len(txList))
+	for i, tx := range expected {
+		assert.Equal(t, tx.Hash(), txList[i].Tx.Hash())
+	}
+
+	maxTxInBlock := config.DefConfig.Consensus.MaxTxInBlock
+	config.DefConfig.Consensus.MaxTxInBlock = 2
+	defer func() { config.DefConfig.Consensus.MaxTxInBlock = maxTxInBlock }()
+	txList, _ = txPool.GetTxPool(true, 0)
+	assert.Equal(t, 2, len(txList))
+	assert.Equal(t, txs[1].Hash(), txList[0].Tx.Hash())
+	assert.Equal(t, txs[3].Hash(), txList[1].Tx.Hash())
+
+	txPool.RemoveTxsBelowGasPrice(1000)
+	assert.Equal(t, 3, txPool.GetTransactionCount())
+	assert.Nil(t, txPool.GetTransaction(txs[0].Hash()))
+	assert.Nil(t, txPool.GetTransaction(txs[4].Hash()))
+	assert.Equal(t, 3, len(txPool.priced))
+}
+
+func TestTxPoolCapacity(t *testing.T) {
+	capacity := config.DefConfig.Common.TxPoolCapacity
+	payerCapacity := config.DefConfig.Common.TxPoolPayerCapacity
+	config.DefConfig.Common.TxPoolCapacity = 3
+	config.DefConfig.Common.TxPoolPayerCapacity = 2
+	defer func() {
+		config.DefConfig.Common.TxPoolCapacity = capacity
+		config.DefConfig.Common.TxPoolPayerCapacity = payerCapacity
+	}()
+
+	txPool := &TXPool{}
+	txPool.Init()
+
+	spammer := common.Address{1}
+	tx1 := newPayerTx(t, spammer, 1, 500, []byte{})
+	tx2 := newPayerTx(t, spammer, 2, 600, []byte{})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx1, Attrs: []*TXAttr{}}))
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx2, Attrs: []*TXAttr{}}))
+
+	// the payer reaches its limit, but replacing is still allowed
+	tx3 := newPayerTx(t, spammer, 3, 5000, []byte{})
+	assert.Equal(t, errors.ErrPayerTxsLimit, txPool.CheckAddTx(tx3))
+	_, errCode := txPool.ReplaceTxList(&TXEntry{Tx: tx3, Attrs: []*TXAttr{}})
+	assert.Equal(t, errors.ErrPayerTxsLimit, errCode)
+	tx4 := newPayerTx(t, spammer, 2, 700, []byte{})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx4, Attrs: []*TXAttr{}}))
+	assert.Equal(t, uint(2), txPool.payerTxs[spammer])
+
+	tx5 := newPayerTx(t, common.Address{2}, 1, 800, []byte{})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx5, Attrs: []*TXAttr{}}))
+
+	// the pool is full, the cheapest one is evicted for a higher gas price
+	tx6 := newPayerTx(t, common.Address{3}, 1, 500, []byte{})
+	assert.Equal(t, errors.ErrTxPoolFull, txPool.CheckAddTx(tx6))
+	_, errCode = txPool.ReplaceTxList(&TXEntry{Tx: tx6, Attrs: []*TXAttr{}})
+	assert.Equal(t, errors.ErrTxPoolFull, errCode)
+
+	tx7 := newPayerTx(t, common.Address{3}, 1, 501, []byte{})
+	assert.Equal(t, errors.ErrNoError, txPool.CheckAddTx(tx7))
+	evicted, errCode := txPool.ReplaceTxList(&TXEntry{Tx: tx7, Attrs: []*TXAttr{}})
+	assert.Equal(t, errors.ErrNoError, errCode)
+	assert.Equal(t, tx1.Hash(), evicted.Hash())
+	assert.Equal(t, 3, txPool.GetTransactionCount())
+	assert.Equal(t, uint(1), txPool.payerTxs[spammer])
+
+	// evicted as the pool is full, not replaced by tx7
+	status := txPool.GetTxStatus(tx1.Hash())
+	assert.True(t, status.Evicted)
+	assert.Equal(t, common.UINT256_EMPTY, status.ReplacedBy)
+
+	txPool.Remain()
+	assert.Equal(t, 0, len(txPool.priced))
+	assert.Equal(t, 0, len(txPool.payerTxs))
+	assert.Equal(t, 0, len(txPool.slots))
+}
+
+func TestTxPoolAcceptGasPrice(t *testing.T) {
+	bump := config.DefConfig.Common.TxPoolPriceBump
+	capacity := config.DefConfig.Common.TxPoolCapacity
+	config.DefConfig.Common.TxPoolPriceBump = 10
+	config.DefConfig.Common.TxPoolCapacity = 2
+	defer func() {
+		config.DefConfig.Common.TxPoolPriceBump = bump
+		config.DefConfig.Common.TxPoolCapacity = capacity
+	}()
+
+	txPool := &TXPool{}
+	txPool.Init()
+
+	tx1 := newPayerTx(t, common.Address{1}, 1, 500, []byte{})
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx1, Attrs: []*TXAttr{}}))
+	tx2 := newPayerTx(t, common.Address{2}, 1, 600, []byte{})
+	assert.Equal(t, uint64(0), txPool.GetAcceptGasPrice(tx2))
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx2, Attrs: []*TXAttr{}}))
+
+	// the pool is full, the cheapest one should be evicted
+	tx3 := newPayerTx(t, common.Address{3}, 1, 0, []byte{})
+	assert.Equal(t, uint64(501), txPool.GetAcceptGasPrice(tx3))
+
+	// replacing a pending one needs the price bump
+	tx4 := newPayerTx(t, common.Address{2}, 1, 0, []byte{1})
+	assert.Equal(t, uint64(660), txPool.GetAcceptGasPrice(tx4))
+	assert.Equal(t, errors.ErrNoError, txPool.CheckAddTx(newPayerTx(t, common.Address{2}, 1, 660, []byte{1})))
+
+	config.DefConfig.Common.TxPoolPriceBump = 0
+	assert.Equal(t, uint64(601), txPool.GetAcceptGasPrice(tx4))
+}
+
+func TestTxPoolRemoveExpired(t *testing.T) {
+	txPool := &TXPool{}
+	txPool.Init()
+
+	payer := common.Address{1}
+	expiring := make([]*types.Transaction, 0)
+	for i, validUntil := range []uint32{0, 10, 11, 20} {
+		mutable := &types.MutableTransaction{
+			TxType:   types.InvokeNeo,
+			Nonce:    uint32(i),
+			GasPrice: 500,
+			Payer:    payer,
+			Payload:  &payload.InvokeCode{Code: []byte{byte(i)}},
+		}
+		mutable.SetValidityWindow(0, validUntil)
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx}))
+		expiring = append(expiring, tx)
+	}
+
+	assert.Equal(t, 0, txPool.RemoveExpiredTxs(10))
+	assert.Equal(t, 1, txPool.RemoveExpiredTxs(11))
+	assert.Nil(t, txPool.GetTransaction(expiring[1].Hash()))
+	assert.Equal(t, 2, txPool.RemoveExpiredTxs(100))
+	assert.Equal(t, 1, txPool.GetTransactionCount())
+	assert.NotNil(t, txPool.GetTransaction(expiring[0].Hash()))
+}
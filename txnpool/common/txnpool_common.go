@@ -22,10 +22,10 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
 )
 
 const (
-	MAX_CAPACITY     = 100140                           // The tx pool's capacity that holds the verified txs
 	MAX_PENDING_TXN  = 4096 * 10                        // The max length of pending txs
 	MAX_WORKER_NUM   = 2                                // The max concurrent workers
 	MAX_RCV_TXN_LEN  = MAX_WORKER_NUM * MAX_PENDING_TXN // The max length of the queue that server can hold
@@ -36,7 +36,9 @@ const (
 	VERIFY_MASK      = STATELESS_MASK | STATEFUL_MASK   // The mask that indicates tx valid
 	MAX_LIMITATION   = 10000                            // The length of pending tx from net and http
 	UPDATE_FREQUENCY = 100                              // The frequency to update gas price from global params
+	JOURNAL_INTERVAL = 100                              // The block interval to rewrite the tx journal
 	MAX_TX_SIZE      = 1024 * 1024                      // The max size of a transaction to prevent DOS attacks
+	MAX_EVICTED_TXS  = 4096                             // The max number of evicted txs to keep track of
 )
 
 // ActorType enumerates the kind of actor
@@ -98,8 +100,10 @@ type CheckBlkResult struct {
 
 // TxStatus contains the attributes of a transaction
 type TxStatus struct {
-	Hash  common.Uint256 // transaction hash
-	Attrs []*TXAttr      // transaction's status
+	Hash       common.Uint256 // transaction hash
+	Attrs      []*TXAttr      // transaction's status
+	Evicted    bool           // whether the transaction has been evicted
+	ReplacedBy common.Uint256 // the transaction which took its place, empty if evicted as the pool is full
 }
 type TxResult struct {
 	Err  errors.ErrCode
@@ -112,7 +116,8 @@ type TxResult struct {
 type TxReq struct {
 	Tx         *types.Transaction
 	Sender     SenderType
-	TxResultCh chan *TxResult
+	TxResultCh chan *TxResult   // The result is replied to HttpSender only, including the replacement failure
+	PeerId     p2pcommon.PeerId // The peer which sends the tx, NetSender only
 }
 
 // TxRsp returns the result of submitting tx, including
@@ -156,10 +161,13 @@ type GetTxnStatusReq struct {
 }
 
 // GetTxnStatusRsp returns a transaction status for GetTxnStatusReq.
-// Output: a transaction hash and it's verified result.
+// Output: a transaction hash and it's verified result, or the hash of
+// the transaction replacing it if evicted.
 type GetTxnStatusRsp struct {
-	Hash     common.Uint256
-	TxStatus []*TXAttr
+	Hash       common.Uint256
+	TxStatus   []*TXAttr
+	Evicted    bool
+	ReplacedBy common.Uint256
 }
 
 // GetTxnStats specifies the api that how to get the tx statistics.
@@ -180,6 +188,17 @@ type GetTxnCountRsp struct {
 	Count []uint32
 }
 
+// GetTxnGasPriceReq specifies the api that how to get the gas price for
+// a tx to be accepted by the pool
+type GetTxnGasPriceReq struct {
+	Tx *types.Transaction
+}
+
+// GetTxnGasPriceRsp returns the lowest gas price accepted for the tx
+type GetTxnGasPriceRsp struct {
+	GasPrice uint64
+}
+
 // GetPendingTxnReq specifies the api that how to get a pending tx list
 // in the pool.
 type GetPendingTxnReq struct {
@@ -201,6 +220,28 @@ type GetPendingTxnHashRsp struct {
 	TxHashs []common.Uint256
 }
 
+// FlushTxnPoolReq specifies the api that how to drop all the verified
+// transactions in the pool.
+type FlushTxnPoolReq struct {
+}
+
+// FlushTxnPoolRsp returns the hashes of the transactions dropped for
+// FlushTxnPoolReq.
+type FlushTxnPoolRsp struct {
+	TxHashs []common.Uint256
+}
+
+// DumpTxnPoolReq specifies the api that how to get all the transactions
+// in the pool, including the ones being verified.
+type DumpTxnPoolReq struct {
+}
+
+// DumpTxnPoolRsp returns the transactions for DumpTxnPoolReq.
+type DumpTxnPoolRsp struct {
+	Txs     []*types.Transaction // Verified transactions, from the highest gas price
+	Pending []*types.Transaction // Transactions being verified
+}
+
 // consensus messages
 // GetTxnPoolReq specifies the api that how to get the valid transaction list.
 type GetTxnPoolReq struct {
@@ -27,11 +27,13 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/core/ledger"
 	tx "github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
 	"github.com/ontio/ontology/events/message"
 	hComm "github.com/ontio/ontology/http/base/common"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
 	"github.com/ontio/ontology/smartcontract/service/native/utils"
 	"github.com/ontio/ontology/smartcontract/service/neovm"
 	tc "github.com/ontio/ontology/txnpool/common"
@@ -87,26 +89,28 @@ func replyTxResult(txResultCh chan *tc.TxResult, hash common.Uint256,
 
 // preExecCheck checks whether preExec pass
 func preExecCheck(txn *tx.Transaction) (bool, string) {
+	txHash := txn.Hash()
+	logger := log.WithFields(log.Fields{log.FIELD_TX_HASH: txHash.ToHexString()})
 	result, err := ledger.DefLedger.PreExecuteContract(txn)
 	if err != nil {
-		log.Debugf("preExecCheck: failed to preExecuteContract tx %x err %v",
+		logger.Debugf("preExecCheck: failed to preExecuteContract tx %x err %v",
 			txn.Hash(), err)
 	}
 	if txn.GasLimit < result.Gas {
-		log.Debugf("preExecCheck: transaction's gasLimit %d is less than preExec gasLimit %d",
+		logger.Debugf("preExecCheck: transaction's gasLimit %d is less than preExec gasLimit %d",
 			txn.GasLimit, result.Gas)
 		return false, fmt.Sprintf("transaction's gasLimit %d is less than preExec gasLimit %d",
 			txn.GasLimit, result.Gas)
 	}
 	gas, overflow := common.SafeMul(txn.GasPrice, result.Gas)
 	if overflow {
-		log.Debugf("preExecCheck: gasPrice %d preExec gasLimit %d overflow",
+		logger.Debugf("preExecCheck: gasPrice %d preExec gasLimit %d overflow",
 			txn.GasPrice, result.Gas)
 		return false, fmt.Sprintf("gasPrice %d preExec gasLimit %d overflow",
 			txn.GasPrice, result.Gas)
 	}
 	if !isBalanceEnough(txn.Payer, gas) {
-		log.Debugf("preExecCheck: transactor %s has no balance enough to cover gas cost %d",
+		logger.Debugf("preExecCheck: transactor %s has no balance enough to cover gas cost %d",
 			txn.Payer.ToHexString(), gas)
 		return false, fmt.Sprintf("transactor %s has no balance enough to cover gas cost %d",
 			txn.Payer.ToHexString(), gas)
@@ -121,10 +125,13 @@ type TxActor struct {
 
 // handleTransaction handles a transaction from network and http
 func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
-	txn *tx.Transaction, txResultCh chan *tc.TxResult) {
+	txn *tx.Transaction, txResultCh chan *tc.TxResult, peerId p2pcommon.PeerId) {
+	txHash := txn.Hash()
+	logger := log.WithFields(log.Fields{log.FIELD_TX_HASH: txHash.ToHexString()})
 	ta.server.increaseStats(tc.RcvStats)
 	if len(txn.ToArray()) > tc.MAX_TX_SIZE {
-		log.Debugf("handleTransaction: reject a transaction due to size over 1M")
+		logger.Debugf("handleTransaction: reject a transaction due to size over 1M")
+		metrics.TxRejected("oversized transaction")
 		if sender == tc.HttpSender && txResultCh != nil {
 			replyTxResult(txResultCh, txn.Hash(), errors.ErrUnknown, "size is over 1M")
 		}
@@ -132,27 +139,29 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 	}
 
 	if ta.server.getTransaction(txn.Hash()) != nil {
-		log.Debugf("handleTransaction: transaction %x already in the txn pool",
+		logger.Debugf("handleTransaction: transaction %x already in the txn pool",
 			txn.Hash())
 
 		ta.server.increaseStats(tc.DuplicateStats)
+		metrics.TxRejected(errors.ErrDuplicateInput.Error())
 		if sender == tc.HttpSender && txResultCh != nil {
 			replyTxResult(txResultCh, txn.Hash(), errors.ErrDuplicateInput,
 				fmt.Sprintf("transaction %x is already in the tx pool", txn.Hash()))
 		}
-	} else if ta.server.getTransactionCount() >= tc.MAX_CAPACITY {
-		log.Debugf("handleTransaction: transaction pool is full for tx %x",
-			txn.Hash())
+	} else if errCode := ta.server.txPool.CheckAddTx(txn); errCode != errors.ErrNoError {
+		logger.Debugf("handleTransaction: transaction %x can't enter the pool: %s",
+			txn.Hash(), errCode.Error())
 
 		ta.server.increaseStats(tc.FailureStats)
+		metrics.TxRejected(errCode.Error())
 		if sender == tc.HttpSender && txResultCh != nil {
-			replyTxResult(txResultCh, txn.Hash(), errors.ErrTxPoolFull,
-				"transaction pool is full")
+			replyTxResult(txResultCh, txn.Hash(), errCode, errCode.Error())
 		}
 	} else {
 		if _, overflow := common.SafeMul(txn.GasLimit, txn.GasPrice); overflow {
-			log.Debugf("handleTransaction: gasLimit %v, gasPrice %v overflow",
+			logger.Debugf("handleTransaction: gasLimit %v, gasPrice %v overflow",
 				txn.GasLimit, txn.GasPrice)
+			metrics.TxRejected("gas overflow")
 			if sender == tc.HttpSender && txResultCh != nil {
 				replyTxResult(txResultCh, txn.Hash(), errors.ErrUnknown,
 					fmt.Sprintf("gasLimit %d * gasPrice %d overflow",
@@ -164,8 +173,9 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 		gasLimitConfig := config.DefConfig.Common.GasLimit
 		gasPriceConfig := ta.server.getGasPrice()
 		if txn.GasLimit < gasLimitConfig || txn.GasPrice < gasPriceConfig {
-			log.Debugf("handleTransaction: invalid gasLimit %v, gasPrice %v",
+			logger.Debugf("handleTransaction: invalid gasLimit %v, gasPrice %v",
 				txn.GasLimit, txn.GasPrice)
+			metrics.TxRejected("gas limit or price too low")
 			if sender == tc.HttpSender && txResultCh != nil {
 				replyTxResult(txResultCh, txn.Hash(), errors.ErrUnknown,
 					fmt.Sprintf("Please input gasLimit >= %d and gasPrice >= %d",
@@ -175,8 +185,9 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 		}
 
 		if txn.TxType == tx.Deploy && txn.GasLimit < neovm.CONTRACT_CREATE_GAS {
-			log.Debugf("handleTransaction: deploy tx invalid gasLimit %v, gasPrice %v",
+			logger.Debugf("handleTransaction: deploy tx invalid gasLimit %v, gasPrice %v",
 				txn.GasLimit, txn.GasPrice)
+			metrics.TxRejected("deploy gas limit too low")
 			if sender == tc.HttpSender && txResultCh != nil {
 				replyTxResult(txResultCh, txn.Hash(), errors.ErrUnknown,
 					fmt.Sprintf("Deploy tx gaslimit should >= %d",
@@ -187,16 +198,17 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 
 		if !ta.server.disablePreExec {
 			if ok, desc := preExecCheck(txn); !ok {
-				log.Debugf("handleTransaction: preExecCheck tx %x failed", txn.Hash())
+				logger.Debugf("handleTransaction: preExecCheck tx %x failed", txn.Hash())
+				metrics.TxRejected("pre-execution failed")
 				if sender == tc.HttpSender && txResultCh != nil {
 					replyTxResult(txResultCh, txn.Hash(), errors.ErrUnknown, desc)
 				}
 				return
 			}
-			log.Debugf("handleTransaction: preExecCheck tx %x passed", txn.Hash())
+			logger.Debugf("handleTransaction: preExecCheck tx %x passed", txn.Hash())
 		}
 		<-ta.server.slots
-		ta.server.assignTxToWorker(txn, sender, txResultCh)
+		ta.server.assignTxToWorker(txn, sender, txResultCh, peerId)
 	}
 }
 
@@ -217,7 +229,7 @@ func (ta *TxActor) Receive(context actor.Context) {
 
 		log.Debugf("txpool-tx actor receives tx from %v ", sender.Sender())
 
-		ta.handleTransaction(sender, context.Self(), msg.Tx, msg.TxResultCh)
+		ta.handleTransaction(sender, context.Self(), msg.Tx, msg.TxResultCh, msg.PeerId)
 
 	case *tc.GetTxnReq:
 		sender := context.Sender()
@@ -264,7 +276,8 @@ func (ta *TxActor) Receive(context actor.Context) {
 					TxStatus: nil}, context.Self())
 			} else {
 				sender.Request(&tc.GetTxnStatusRsp{Hash: res.Hash,
-					TxStatus: res.Attrs}, context.Self())
+					TxStatus: res.Attrs, Evicted: res.Evicted,
+					ReplacedBy: res.ReplacedBy}, context.Self())
 			}
 		}
 
@@ -278,6 +291,16 @@ func (ta *TxActor) Receive(context actor.Context) {
 			sender.Request(&tc.GetTxnCountRsp{Count: res},
 				context.Self())
 		}
+	case *tc.GetTxnGasPriceReq:
+		sender := context.Sender()
+
+		log.Debugf("txpool-tx actor receives getting tx gas price req from %v", sender)
+
+		res := ta.server.getAcceptGasPrice(msg.Tx)
+		if sender != nil {
+			sender.Request(&tc.GetTxnGasPriceRsp{GasPrice: res},
+				context.Self())
+		}
 	case *tc.GetPendingTxnHashReq:
 		sender := context.Sender()
 
@@ -288,6 +311,26 @@ func (ta *TxActor) Receive(context actor.Context) {
 			sender.Request(&tc.GetPendingTxnHashRsp{TxHashs: res}, context.Self())
 		}
 
+	case *tc.FlushTxnPoolReq:
+		sender := context.Sender()
+
+		log.Debugf("txpool-tx actor receives flushing tx pool req from %v", sender)
+
+		res := ta.server.flushTxPool()
+		if sender != nil {
+			sender.Request(&tc.FlushTxnPoolRsp{TxHashs: res}, context.Self())
+		}
+
+	case *tc.DumpTxnPoolReq:
+		sender := context.Sender()
+
+		log.Debugf("txpool-tx actor receives dumping tx pool req from %v", sender)
+
+		txs, pending := ta.server.dumpTxPool()
+		if sender != nil {
+			sender.Request(&tc.DumpTxnPoolRsp{Txs: txs, Pending: pending}, context.Self())
+		}
+
 	default:
 		log.Debugf("txpool-tx actor: unknown msg %v type %v", msg, reflect.TypeOf(msg))
 	}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"github.com/ontio/ontology/common/metrics"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// statsNames are the metric labels of the tx statistics, indexed by
+// TxnStatsType-1
+var statsNames = []string{"received", "verified", "failed", "duplicated", "signature_error", "state_error"}
+
+var (
+	txPoolSizeDesc = prom.NewDesc("ontology_txpool_size",
+		"ontology transactions verified and waiting in the tx pool", nil, nil)
+	txPoolPendingDesc = prom.NewDesc("ontology_txpool_pending",
+		"ontology transactions being verified by the tx pool", nil, nil)
+	txPoolStatsDesc = prom.NewDesc("ontology_txpool_transactions_total",
+		"ontology transaction statistics of the tx pool", []string{"stats"}, nil)
+)
+
+// txPoolCollector collects the tx pool metrics from the server on scraping
+type txPoolCollector struct {
+	server *TXPoolServer
+}
+
+// RegisterMetrics registers the metrics of the tx pool server
+func RegisterMetrics(s *TXPoolServer) error {
+	return metrics.Register(&txPoolCollector{server: s})
+}
+
+// Describe implements prometheus.Collector
+func (c *txPoolCollector) Describe(ch chan<- *prom.Desc) {
+	ch <- txPoolSizeDesc
+	ch <- txPoolPendingDesc
+	ch <- txPoolStatsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *txPoolCollector) Collect(ch chan<- prom.Metric) {
+	count := c.server.getTxCount()
+	ch <- prom.MustNewConstMetric(txPoolSizeDesc, prom.GaugeValue, float64(count[0]))
+	ch <- prom.MustNewConstMetric(txPoolPendingDesc, prom.GaugeValue, float64(count[1]))
+	for i, v := range c.server.getStats() {
+		if i >= len(statsNames) {
+			break
+		}
+		ch <- prom.MustNewConstMetric(txPoolStatsDesc, prom.CounterValue, float64(v), statsNames[i])
+	}
+}
@@ -31,12 +31,17 @@ import (
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/config"
 	"github.com/ontio/ontology/common/log"
+	"github.com/ontio/ontology/common/metrics"
 	"github.com/ontio/ontology/core/ledger"
 	tx "github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
+	"github.com/ontio/ontology/events"
+	"github.com/ontio/ontology/events/message"
 	httpcom "github.com/ontio/ontology/http/base/common"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
 	msgpack "github.com/ontio/ontology/p2pserver/message/msg_pack"
 	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer_score"
 	params "github.com/ontio/ontology/smartcontract/service/native/global_params"
 	nutils "github.com/ontio/ontology/smartcontract/service/native/utils"
 	tc "github.com/ontio/ontology/txnpool/common"
@@ -52,6 +57,7 @@ type serverPendingTx struct {
 	tx     *tx.Transaction   // Pending tx
 	sender tc.SenderType     // Indicate which sender tx is from
 	ch     chan *tc.TxResult // channel to send tx result
+	peerId p2pcommon.PeerId  // The peer which sends the tx, NetSender only
 }
 
 type pendingBlock struct {
@@ -89,6 +95,7 @@ type TXPoolServer struct {
 	gasPrice              uint64              // Gas price to enforce for acceptance into the pool
 	disablePreExec        bool                // Disbale PreExecute a transaction
 	disableBroadcastNetTx bool                // Disable broadcast tx from network
+	journal               *tc.TXJournal       // Journal of the accepted txs, nil if disabled
 }
 
 // NewTxPoolServer creates a new tx pool server to schedule workers to
@@ -254,6 +261,17 @@ func (s *TXPoolServer) getGasPrice() uint64 {
 	return s.gasPrice
 }
 
+// getAcceptGasPrice returns the lowest gas price for a transaction to be
+// accepted by the pool, which is the bigger one between the enforced gas
+// price and the price required by the pool capacity or replacement
+func (s *TXPoolServer) getAcceptGasPrice(tx *tx.Transaction) uint64 {
+	gasPrice := s.getGasPrice()
+	if price := s.txPool.GetAcceptGasPrice(tx); price > gasPrice {
+		gasPrice = price
+	}
+	return gasPrice
+}
+
 // removePendingTx removes a transaction from the pending list
 // when it is handled. And if the submitter of the valid transaction
 // is from http, broadcast it to the network. Meanwhile, check if it
@@ -280,6 +298,14 @@ func (s *TXPoolServer) removePendingTx(hash common.Uint256,
 	if pt.sender == tc.HttpSender && pt.ch != nil {
 		replyTxResult(pt.ch, hash, err, err.Error())
 	}
+	if err != errors.ErrNoError {
+		metrics.TxRejected(err.Error())
+	}
+
+	if err == errors.ErrNoError && pt.sender != tc.NilSender && events.DefActorPublisher != nil {
+		events.DefActorPublisher.Publish(message.TOPIC_PENDING_TX,
+			&message.PendingTxMsg{Tx: pt.tx})
+	}
 
 	delete(s.allPendingTxs, hash)
 
@@ -293,21 +319,50 @@ func (s *TXPoolServer) removePendingTx(hash common.Uint256,
 
 	s.mu.Unlock()
 
+	if err == errors.ErrNoError {
+		log.WithFields(log.Fields{log.FIELD_TX_HASH: hash.ToHexString()}).Debugf(
+			"removePendingTx: transaction accepted")
+	} else {
+		log.WithFields(log.Fields{log.FIELD_TX_HASH: hash.ToHexString()}).Debugf(
+			"removePendingTx: transaction rejected: %s", err)
+	}
+
+	// The txs from consensus or re-verified are journaled already if needed.
+	// The journal is written out of the lock not to block the pool on disk
+	if err == errors.ErrNoError && pt.sender != tc.NilSender && s.journal != nil {
+		if jErr := s.journal.Insert(pt.tx); jErr != nil {
+			log.WithFields(log.Fields{log.FIELD_TX_HASH: hash.ToHexString()}).Warnf(
+				"removePendingTx: failed to journal transaction %x: %s", hash, jErr)
+		}
+	}
+
+	// The tx failing the stateless verification is forged by the peer,
+	// other failures may be caused by the state of the node
+	if pt.sender == tc.NetSender && s.Net != nil &&
+		(err == errors.ErrVerifySignature || err == errors.ErrTransactionPayload) {
+		s.Net.Penalize(pt.peerId, peer_score.INVALID_TX)
+	}
+
 	// Check if the tx is in the pending block and
-	// the pending block is verified
+	// the pending block is verified. A tx rejected by the pool limits
+	// is still valid in the block.
+	if isPoolLimitErr(err) {
+		err = errors.ErrNoError
+	}
 	s.checkPendingBlockOk(hash, err)
 }
 
 // setPendingTx adds a transaction to the pending list, if the
 // transaction is already in the pending list, just return false.
 func (s *TXPoolServer) setPendingTx(tx *tx.Transaction,
-	sender tc.SenderType, txResultCh chan *tc.TxResult) bool {
+	sender tc.SenderType, txResultCh chan *tc.TxResult, peerId p2pcommon.PeerId) bool {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if ok := s.allPendingTxs[tx.Hash()]; ok != nil {
-		log.Debugf("setPendingTx: transaction %x already in the verifying process",
-			tx.Hash())
+	hash := tx.Hash()
+	if ok := s.allPendingTxs[hash]; ok != nil {
+		log.WithFields(log.Fields{log.FIELD_TX_HASH: hash.ToHexString()}).Debugf(
+			"setPendingTx: transaction %x already in the verifying process", hash)
 		return false
 	}
 
@@ -315,22 +370,24 @@ func (s *TXPoolServer) setPendingTx(tx *tx.Transaction,
 		tx:     tx,
 		sender: sender,
 		ch:     txResultCh,
+		peerId: peerId,
 	}
 
-	s.allPendingTxs[tx.Hash()] = pt
+	s.allPendingTxs[hash] = pt
 	return true
 }
 
 // assignTxToWorker assigns a new transaction to a worker by LB
 func (s *TXPoolServer) assignTxToWorker(tx *tx.Transaction,
-	sender tc.SenderType, txResultCh chan *tc.TxResult) bool {
+	sender tc.SenderType, txResultCh chan *tc.TxResult, peerId p2pcommon.PeerId) bool {
 
 	if tx == nil {
 		return false
 	}
 
-	if ok := s.setPendingTx(tx, sender, txResultCh); !ok {
+	if ok := s.setPendingTx(tx, sender, txResultCh, peerId); !ok {
 		s.increaseStats(tc.DuplicateStats)
+		metrics.TxRejected(errors.ErrDuplicateInput.Error())
 		if sender == tc.HttpSender && txResultCh != nil {
 			replyTxResult(txResultCh, tx.Hash(), errors.ErrDuplicateInput,
 				"duplicated transaction input detected")
@@ -474,7 +531,8 @@ func (s *TXPoolServer) getNextValidatorPID(key types.VerifyType) *actor.PID {
 	return entries[next].Sender
 }
 
-// Stop stops server and workers.
+// Stop stops server and workers, and rotates the journal with the txs
+// left in the pool. It should be called before the ledger is closed.
 func (s *TXPoolServer) Stop() {
 	for _, v := range s.actors {
 		v.Stop()
@@ -488,6 +546,61 @@ func (s *TXPoolServer) Stop() {
 	if s.slots != nil {
 		close(s.slots)
 	}
+
+	if s.journal != nil {
+		s.rotateJournal()
+		s.journal.Close()
+	}
+}
+
+// StartJournal loads the txs in the journal file, re-verifies them in
+// background, and journals the txs accepted later. It should be called
+// after the validators are registered.
+func (s *TXPoolServer) StartJournal(path string) error {
+	journal := tc.NewTXJournal(path)
+	txs, err := journal.Load()
+	if err != nil {
+		return err
+	}
+	s.journal = journal
+	log.Infof("tx pool: %d transactions loaded from journal %s", len(txs), path)
+	go s.replayJournal(txs)
+	return nil
+}
+
+// replayJournal re-verifies the txs loaded from the journal and adds the
+// valid ones to the pool. The txs committed while the node was down are
+// dropped.
+func (s *TXPoolServer) replayJournal(txs []*tx.Transaction) {
+	replayed := 0
+	for _, t := range txs {
+		if exist, err := ledger.DefLedger.IsContainTransaction(t.Hash()); err != nil || exist {
+			continue
+		}
+		if t.GasPrice < s.getGasPrice() {
+			continue
+		}
+		if s.txPool.CheckAddTx(t) != errors.ErrNoError {
+			continue
+		}
+		if _, ok := <-s.slots; !ok {
+			return
+		}
+		if s.assignTxToWorker(t, tc.NilSender, nil, p2pcommon.PeerId{}) {
+			replayed++
+		}
+	}
+	log.Infof("tx pool: %d transactions from journal re-verified", replayed)
+}
+
+// rotateJournal rewrites the journal with the txs in the pool and being
+// verified.
+func (s *TXPoolServer) rotateJournal() {
+	txs := s.txPool.GetTransactions()
+	txs = append(txs, s.getPendingTxs(false)...)
+	if err := s.journal.Rotate(txs); err != nil {
+		log.Warnf("tx pool: failed to rotate journal: %s", err)
+	}
 }
 
 // getTransaction returns a transaction with the transaction hash.
@@ -549,9 +662,35 @@ func (s *TXPoolServer) getTxHashList() []common.Uint256 {
 	return ret
 }
 
+// flushTxPool drops all the verified txs in the pool, and rewrites the
+// journal so that they are not replayed. The txs being verified are kept.
+func (s *TXPoolServer) flushTxPool() []common.Uint256 {
+	txs := s.txPool.Remain()
+	hashes := make([]common.Uint256, 0, len(txs))
+	for _, t := range txs {
+		hashes = append(hashes, t.Hash())
+	}
+	if s.journal != nil {
+		s.rotateJournal()
+	}
+	log.Infof("tx pool: %d transactions flushed", len(hashes))
+	return hashes
+}
+
+// dumpTxPool returns the verified txs in the pool from the highest gas
+// price, and the txs being verified.
+func (s *TXPoolServer) dumpTxPool() ([]*tx.Transaction, []*tx.Transaction) {
+	return s.txPool.GetTransactions(), s.getPendingTxs(false)
+}
+
 // cleanTransactionList cleans the txs in the block from the ledger
 func (s *TXPoolServer) cleanTransactionList(txs []*tx.Transaction, height uint32) {
 	s.txPool.CleanTransactionList(txs)
+	// Evict the transactions expired before the next block
+	if expired := s.txPool.RemoveExpiredTxs(height + 1); expired > 0 {
+		log.WithFields(log.Fields{log.FIELD_HEIGHT: height}).Debugf(
+			"cleanTransactionList: %d expired transactions evicted at height %d", expired, height)
+	}
 
 	// Check whether to update the gas price and remove txs below the
 	// threshold
@@ -570,12 +709,18 @@ func (s *TXPoolServer) cleanTransactionList(txs []*tx.Transaction, height uint32
 			s.txPool.RemoveTxsBelowGasPrice(gasPrice)
 		}
 	}
+	if s.journal != nil && height%tc.JOURNAL_INTERVAL == 0 {
+		s.rotateJournal()
+	}
+
 	// Cleanup tx pool
 	if !s.disablePreExec {
 		remain := s.txPool.Remain()
 		for _, t := range remain {
 			if ok, _ := preExecCheck(t); !ok {
-				log.Debugf("cleanTransactionList: preExecCheck tx %x failed", t.Hash())
+				hash := t.Hash()
+				log.WithFields(log.Fields{log.FIELD_HEIGHT: height, log.FIELD_TX_HASH: hash.ToHexString()}).Debugf(
+					"cleanTransactionList: preExecCheck tx %x failed", hash)
 				continue
 			}
 			s.reVerifyStateful(t, tc.NilSender)
@@ -588,13 +733,28 @@ func (s *TXPoolServer) delTransaction(t *tx.Transaction) {
 	s.txPool.DelTxList(t)
 }
 
-// addTxList adds a valid transaction to the tx pool.
-func (s *TXPoolServer) addTxList(txEntry *tc.TXEntry) bool {
-	ret := s.txPool.AddTxList(txEntry)
-	if !ret {
+// isPoolLimitErr returns whether a valid transaction is rejected by the
+// tx pool due to the gas price or capacity limits.
+func isPoolLimitErr(err errors.ErrCode) bool {
+	switch err {
+	case errors.ErrReplaceUnderpriced, errors.ErrPayerTxsLimit, errors.ErrTxPoolFull:
+		return true
+	}
+	return false
+}
+
+// addTxList adds a valid transaction to the tx pool, evicting the pending
+// one with the same payer and nonce or the lowest gas price if needed.
+func (s *TXPoolServer) addTxList(txEntry *tc.TXEntry) errors.ErrCode {
+	_, errCode := s.txPool.ReplaceTxList(txEntry)
+	switch errCode {
+	case errors.ErrNoError:
+	case errors.ErrDuplicateInput:
 		s.increaseStats(tc.DuplicateStats)
+	default:
+		s.increaseStats(tc.FailureStats)
 	}
-	return ret
+	return errCode
 }
 
 // increaseStats increases the count with the stats type
@@ -653,7 +813,7 @@ func (s *TXPoolServer) getTransactionCount() int {
 
 // reVerifyStateful re-verify a transaction's stateful data.
 func (s *TXPoolServer) reVerifyStateful(tx *tx.Transaction, sender tc.SenderType) {
-	if ok := s.setPendingTx(tx, sender, nil); !ok {
+	if ok := s.setPendingTx(tx, sender, nil, p2pcommon.PeerId{}); !ok {
 		s.increaseStats(tc.DuplicateStats)
 		return
 	}
@@ -744,7 +904,7 @@ func (s *TXPoolServer) verifyBlock(req *tc.VerifyBlockReq, sender *actor.PID) {
 	checkBlkResult := s.txPool.GetUnverifiedTxs(req.Txs, req.Height)
 
 	for _, t := range checkBlkResult.UnverifiedTxs {
-		s.assignTxToWorker(t, tc.NilSender, nil)
+		s.assignTxToWorker(t, tc.NilSender, nil, p2pcommon.PeerId{})
 		s.pendingBlock.unProcessedTxs[t.Hash()] = t
 	}
 
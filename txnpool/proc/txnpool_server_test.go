@@ -19,16 +19,24 @@
 package proc
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/core/payload"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
+	p2pcommon "github.com/ontio/ontology/p2pserver/common"
+	p2p "github.com/ontio/ontology/p2pserver/net/protocol"
+	"github.com/ontio/ontology/p2pserver/peer_score"
 	tc "github.com/ontio/ontology/txnpool/common"
 	"github.com/ontio/ontology/validator/stateless"
 	vt "github.com/ontio/ontology/validator/types"
+	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -79,18 +87,18 @@ func TestTxn(t *testing.T) {
 	defer s.Stop()
 
 	// Case 1: Send nil txn to the server, server should reject it
-	s.assignTxToWorker(nil, sender, nil)
+	s.assignTxToWorker(nil, sender, nil, p2pcommon.PeerId{})
 	/* Case 2: send non-nil txn to the server, server should assign
 	 * it to the worker
 	 */
-	s.assignTxToWorker(txn, sender, nil)
+	s.assignTxToWorker(txn, sender, nil, p2pcommon.PeerId{})
 
 	/* Case 3: Duplicate input the tx, server should reject the second
 	 * one
 	 */
 	time.Sleep(10 * time.Second)
-	s.assignTxToWorker(txn, sender, nil)
-	s.assignTxToWorker(txn, sender, nil)
+	s.assignTxToWorker(txn, sender, nil, p2pcommon.PeerId{})
+	s.assignTxToWorker(txn, sender, nil, p2pcommon.PeerId{})
 
 	/* Case 4: Given the tx is in the tx pool, server can get the tx
 	 * with the invalid hash
@@ -285,3 +293,135 @@ func TestValidator(t *testing.T) {
 
 	t.Log("Ending validator testing")
 }
+
+func TestTxPoolMetrics(t *testing.T) {
+	s := NewTxPoolServer(tc.MAX_WORKER_NUM, true, false)
+	defer s.Stop()
+
+	s.increaseStats(tc.RcvStats)
+	s.increaseStats(tc.RcvStats)
+	s.increaseStats(tc.DuplicateStats)
+	s.addTxList(&tc.TXEntry{Tx: txn, Attrs: []*tc.TXAttr{}})
+
+	registry := prom.NewRegistry()
+	assert.Nil(t, registry.Register(&txPoolCollector{server: s}))
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range m.GetLabel() {
+				name += "/" + label.GetValue()
+			}
+			if m.GetCounter() != nil {
+				values[name] = m.GetCounter().GetValue()
+			} else {
+				values[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, float64(1), values["ontology_txpool_size"])
+	assert.Equal(t, float64(0), values["ontology_txpool_pending"])
+	assert.Equal(t, float64(2), values["ontology_txpool_transactions_total/received"])
+	assert.Equal(t, float64(1), values["ontology_txpool_transactions_total/duplicated"])
+	assert.Equal(t, float64(0), values["ontology_txpool_transactions_total/state_error"])
+}
+
+func TestFlushTxPool(t *testing.T) {
+	s := NewTxPoolServer(tc.MAX_WORKER_NUM, true, false)
+	defer s.Stop()
+
+	s.addTxList(&tc.TXEntry{Tx: txn, Attrs: []*tc.TXAttr{}})
+	txs, pending := s.dumpTxPool()
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, txn.Hash(), txs[0].Hash())
+	assert.Equal(t, 0, len(pending))
+
+	hashes := s.flushTxPool()
+	assert.Equal(t, []common.Uint256{txn.Hash()}, hashes)
+	assert.Nil(t, s.getTransaction(txn.Hash()))
+	txs, _ = s.dumpTxPool()
+	assert.Equal(t, 0, len(txs))
+}
+
+// penaltyNet records the penalties of the peers
+type penaltyNet struct {
+	p2p.P2P
+	penalties map[p2pcommon.PeerId][]peer_score.Misbehaviour
+}
+
+func (self *penaltyNet) Penalize(id p2pcommon.PeerId, m peer_score.Misbehaviour) {
+	self.penalties[id] = append(self.penalties[id], m)
+}
+
+func TestPenalizeInvalidNetTx(t *testing.T) {
+	s := NewTxPoolServer(tc.MAX_WORKER_NUM, true, false)
+	defer s.Stop()
+	net := &penaltyNet{penalties: make(map[p2pcommon.PeerId][]peer_score.Misbehaviour)}
+	s.Net = net
+	peerId := p2pcommon.PseudoPeerIdFromUint64(1)
+
+	// the tx failing the state verification may be valid on the peer
+	assert.True(t, s.setPendingTx(txn, tc.NetSender, nil, peerId))
+	s.removePendingTx(txn.Hash(), errors.ErrUnknown)
+	assert.Equal(t, 0, len(net.penalties))
+
+	assert.True(t, s.setPendingTx(txn, tc.NetSender, nil, peerId))
+	s.removePendingTx(txn.Hash(), errors.ErrVerifySignature)
+	assert.Equal(t, []peer_score.Misbehaviour{peer_score.INVALID_TX}, net.penalties[peerId])
+
+	// the tx from http is never penalized
+	assert.True(t, s.setPendingTx(txn, tc.HttpSender, nil, p2pcommon.PeerId{}))
+	s.removePendingTx(txn.Hash(), errors.ErrTransactionPayload)
+	assert.Equal(t, 1, len(net.penalties))
+}
+
+func TestReplyHttpSenderOnly(t *testing.T) {
+	s := NewTxPoolServer(tc.MAX_WORKER_NUM, true, false)
+	defer s.Stop()
+
+	ch := make(chan *tc.TxResult, 1)
+	assert.True(t, s.setPendingTx(txn, tc.NetSender, ch, p2pcommon.PeerId{}))
+	s.removePendingTx(txn.Hash(), errors.ErrReplaceUnderpriced)
+	assert.Equal(t, 0, len(ch))
+
+	assert.True(t, s.setPendingTx(txn, tc.HttpSender, ch, p2pcommon.PeerId{}))
+	s.removePendingTx(txn.Hash(), errors.ErrReplaceUnderpriced)
+	result := <-ch
+	assert.Equal(t, errors.ErrReplaceUnderpriced, result.Err)
+}
+
+func TestJournalReplayAfterStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txpool")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, tc.TX_JOURNAL_FILE)
+
+	s := NewTxPoolServer(tc.MAX_WORKER_NUM, true, false)
+	assert.Nil(t, s.StartJournal(path))
+	mutable := &types.MutableTransaction{
+		TxType:   types.InvokeNeo,
+		Nonce:    uint32(time.Now().Unix()),
+		GasPrice: s.getGasPrice(),
+		Payload:  &payload.InvokeCode{Code: []byte("journal")},
+	}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	assert.True(t, s.setPendingTx(tx, tc.HttpSender, nil, p2pcommon.PeerId{}))
+	assert.Equal(t, errors.ErrNoError, s.addTxList(&tc.TXEntry{Tx: tx}))
+	s.removePendingTx(tx.Hash(), errors.ErrNoError)
+	s.Stop()
+
+	// the tx accepted before stop is re-verified after restart
+	restarted := NewTxPoolServer(tc.MAX_WORKER_NUM, true, false)
+	defer restarted.Stop()
+	txs, err := tc.NewTXJournal(path).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(txs))
+	restarted.replayJournal(txs)
+	pending := restarted.getPendingTxs(false)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, tx.Hash(), pending[0].Hash())
+}
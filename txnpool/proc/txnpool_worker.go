@@ -100,8 +100,8 @@ func (worker *txPoolWorker) handleRsp(rsp *types.CheckResponse) {
 	}
 	if rsp.ErrCode != errors.ErrNoError {
 		//Verify fail
-		log.Debugf("handleRsp: validator %d transaction %x invalid: %s",
-			rsp.Type, rsp.Hash, rsp.ErrCode.Error())
+		log.WithFields(log.Fields{log.FIELD_TX_HASH: rsp.Hash.ToHexString()}).Debugf(
+			"handleRsp: validator %d transaction %x invalid: %s", rsp.Type, rsp.Hash, rsp.ErrCode.Error())
 		delete(worker.pendingTxList, rsp.Hash)
 		worker.server.removePendingTx(rsp.Hash, rsp.ErrCode)
 		return
@@ -150,7 +150,8 @@ func (worker *txPoolWorker) handleTimeoutEvent() {
 				worker.reVerifyTx(k)
 				v.retries++
 			} else {
-				log.Debugf("retry to verify transaction exhausted %x", k.ToArray())
+				log.WithFields(log.Fields{log.FIELD_TX_HASH: k.ToHexString()}).Debugf(
+					"retry to verify transaction exhausted %x", k.ToArray())
 				worker.mu.Lock()
 				delete(worker.pendingTxList, k)
 				worker.mu.Unlock()
@@ -167,23 +168,27 @@ func (worker *txPoolWorker) putTxPool(pt *pendingTx) bool {
 		Tx:    pt.tx,
 		Attrs: pt.ret,
 	}
-	worker.server.addTxList(txEntry)
+	if errCode := worker.server.addTxList(txEntry); isPoolLimitErr(errCode) {
+		worker.server.removePendingTx(pt.tx.Hash(), errCode)
+		return false
+	}
 	worker.server.removePendingTx(pt.tx.Hash(), errors.ErrNoError)
 	return true
 }
 
 // verifyTx prepares a check request and sends it to the validators.
 func (worker *txPoolWorker) verifyTx(tx *tx.Transaction) {
-	if tx := worker.server.getTransaction(tx.Hash()); tx != nil {
-		log.Debugf("verifyTx: transaction %x already in the txn pool",
-			tx.Hash())
-		worker.server.removePendingTx(tx.Hash(), errors.ErrDuplicateInput)
+	hash := tx.Hash()
+	if tx := worker.server.getTransaction(hash); tx != nil {
+		log.WithFields(log.Fields{log.FIELD_TX_HASH: hash.ToHexString()}).Debugf(
+			"verifyTx: transaction %x already in the txn pool", hash)
+		worker.server.removePendingTx(hash, errors.ErrDuplicateInput)
 		return
 	}
 
-	if _, ok := worker.pendingTxList[tx.Hash()]; ok {
-		log.Debugf("verifyTx: transaction %x already in the verifying process",
-			tx.Hash())
+	if _, ok := worker.pendingTxList[hash]; ok {
+		log.WithFields(log.Fields{log.FIELD_TX_HASH: hash.ToHexString()}).Debugf(
+			"verifyTx: transaction %x already in the verifying process", hash)
 		return
 	}
 	// Construct the request and send it to each validator server to verify
@@ -203,7 +208,7 @@ func (worker *txPoolWorker) verifyTx(tx *tx.Transaction) {
 	}
 	// Add it to the pending transaction list
 	worker.mu.Lock()
-	worker.pendingTxList[tx.Hash()] = pt
+	worker.pendingTxList[hash] = pt
 	worker.mu.Unlock()
 	// Record the time per a txn
 	pt.valTime = time.Now()
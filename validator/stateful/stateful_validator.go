@@ -24,6 +24,7 @@ import (
 	"github.com/ontio/ontology-eventbus/actor"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/ledger"
+	"github.com/ontio/ontology/core/store/ledgerstore"
 	"github.com/ontio/ontology/core/types"
 	"github.com/ontio/ontology/errors"
 	"github.com/ontio/ontology/validator/db"
@@ -78,6 +79,8 @@ func (self *validator) Receive(context actor.Context) {
 			errCode = errors.ErrUnknown
 		} else if exist {
 			errCode = errors.ErrDuplicatedTx
+		} else {
+			errCode = ledgerstore.CheckValidityWindow(msg.Tx, height+1)
 		}
 
 		response := &vatypes.CheckResponse{
@@ -48,6 +48,12 @@ func NewExecutor(code []byte, feature VmFeatureFlag) *Executor {
 	return &engine
 }
 
+// StepHook is notified before each opcode is executed, pc is the
+// position of the opcode in the code of the current context.
+type StepHook interface {
+	OnStep(engine *Executor, pc int, opcode OpCode)
+}
+
 type Executor struct {
 	EvalStack *ValueStack
 	AltStack  *ValueStack
@@ -55,6 +61,7 @@ type Executor struct {
 	Features  VmFeatureFlag
 	Callers   []*ExecutionContext
 	Context   *ExecutionContext
+	Hook      StepHook
 }
 
 func (self *Executor) PopContext() (*ExecutionContext, error) {
@@ -85,10 +92,14 @@ func (self *Executor) Execute() error {
 			break
 		}
 
+		pc := self.Context.GetInstructionPointer()
 		opcode, eof := self.Context.ReadOpCode()
 		if eof {
 			break
 		}
+		if self.Hook != nil {
+			self.Hook.OnStep(self, pc, opcode)
+		}
 
 		var err error
 		self.State, err = self.ExecuteOp(opcode, self.Context)